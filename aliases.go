@@ -0,0 +1,218 @@
+package audiocontrol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Alias is a user's name for a device and whether to hide it, such as to
+// call "USB Audio CODEC" "Desk Speakers" or to hide the HDMI outputs of
+// monitors. The zero Alias changes nothing.
+type Alias struct {
+	// Name replaces the name of the device when not empty
+	Name   string `json:",omitempty"`
+	Hidden bool   `json:",omitempty"`
+}
+
+// AliasStore holds aliases by StableID, so they follow a device across
+// ports, reboots and sound server restarts
+type AliasStore interface {
+	Alias(stableID string) (Alias, bool)
+	// SetAlias stores alias for a device. The zero Alias removes it.
+	SetAlias(stableID string, alias Alias) error
+}
+
+var (
+	aliasMutex sync.RWMutex
+	aliasStore AliasStore
+)
+
+// SetAliasStore applies the aliases in store to the devices returned by the
+// package-level functions and to the devices in events passed to
+// OnDeviceChange callbacks. Renamed devices keep their own name in
+// OriginalName. Hidden devices are left out of ListAudioDevices and
+// ListAudioDevicesWithOptions unless ListOptions.IncludeHidden is set, and
+// events describing them are not delivered; other functions report them
+// with Hidden set. Aliases never change the operating system's state.
+// Passing nil removes the store.
+func SetAliasStore(store AliasStore) {
+	aliasMutex.Lock()
+	aliasStore = store
+	aliasMutex.Unlock()
+}
+
+func currentAliasStore() AliasStore {
+	aliasMutex.RLock()
+	defer aliasMutex.RUnlock()
+	return aliasStore
+}
+
+// applyAlias applies the alias of d from the current store. The alias
+// saved for a device reported for both directions also applies when the
+// device is reported for one, and the other way around.
+func applyAlias(d AudioDevice) AudioDevice {
+	store := currentAliasStore()
+	if store == nil {
+		return d
+	}
+
+	direction, key := d.stableDirection(), d.stableKey()
+	candidates := []string{direction}
+	if direction == "io" {
+		candidates = append(candidates, "out", "in")
+	} else {
+		candidates = append(candidates, "io")
+	}
+	for _, candidate := range candidates {
+		alias, ok := store.Alias(candidate + "/" + key)
+		if !ok {
+			continue
+		}
+		if alias.Name != "" && alias.Name != d.Name {
+			if d.OriginalName == "" {
+				d.OriginalName = d.Name
+			}
+			d.Name = alias.Name
+		}
+		d.Hidden = alias.Hidden
+		return d
+	}
+	return d
+}
+
+// applyAliases applies aliases to devices and leaves out the hidden ones
+// unless includeHidden is set
+func applyAliases(devices []AudioDevice, includeHidden bool) []AudioDevice {
+	if currentAliasStore() == nil {
+		return devices
+	}
+	result := make([]AudioDevice, 0, len(devices))
+	for _, d := range devices {
+		d = applyAlias(d)
+		if includeHidden || !d.Hidden {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// applyEventAlias applies aliases to the device of an event and reports
+// whether the event should be delivered
+func applyEventAlias(event Event) (Event, bool) {
+	if event.Info == nil || currentAliasStore() == nil {
+		return event, true
+	}
+	device := applyAlias(*event.Info)
+	event.Info = &device
+	return event, !device.Hidden
+}
+
+// AliasFile is an AliasStore kept in a JSON file mapping stable IDs to
+// aliases. Changes are written to the file as they are made.
+type AliasFile struct {
+	path string
+
+	mu      sync.Mutex
+	aliases map[string]Alias
+}
+
+// OpenAliasFile reads the aliases in the file at path. A file that does not
+// exist yet holds no aliases and is created by the first SetAlias.
+func OpenAliasFile(path string) (*AliasFile, error) {
+	f := &AliasFile{path: path, aliases: make(map[string]Alias)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &f.aliases); err != nil {
+		return nil, fmt.Errorf("failed to parse alias file %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// DefaultAliasFile returns the path of the alias file in the user's
+// configuration directory
+func DefaultAliasFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "go-audio-control", "aliases.json"), nil
+}
+
+// Alias returns the alias stored for a device
+func (f *AliasFile) Alias(stableID string) (Alias, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	alias, ok := f.aliases[stableID]
+	return alias, ok
+}
+
+// Aliases returns every stored alias by stable ID
+func (f *AliasFile) Aliases() map[string]Alias {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	aliases := make(map[string]Alias, len(f.aliases))
+	for id, alias := range f.aliases {
+		aliases[id] = alias
+	}
+	return aliases
+}
+
+// SetAlias stores alias for a device and writes the file. The file is
+// replaced in one step, so readers never see it half written.
+func (f *AliasFile) SetAlias(stableID string, alias Alias) error {
+	if !strings.Contains(stableID, "/") {
+		return fmt.Errorf("invalid stable ID %q", stableID)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	previous, existed := f.aliases[stableID]
+	if alias == (Alias{}) {
+		delete(f.aliases, stableID)
+	} else {
+		f.aliases[stableID] = alias
+	}
+	if err := f.writeLocked(); err != nil {
+		if existed {
+			f.aliases[stableID] = previous
+		} else {
+			delete(f.aliases, stableID)
+		}
+		return err
+	}
+	return nil
+}
+
+func (f *AliasFile) writeLocked() error {
+	data, err := json.MarshalIndent(f.aliases, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(f.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
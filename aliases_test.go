@@ -0,0 +1,126 @@
+package audiocontrol_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+	"github.com/audi70r/go-audio-control/audiotest"
+)
+
+func TestAliasFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", "aliases.json")
+	f, err := audiocontrol.OpenAliasFile(path)
+	if err != nil {
+		t.Fatalf("OpenAliasFile() failed for a missing file: %v", err)
+	}
+	if err := f.SetAlias(usbAfter.StableID(), audiocontrol.Alias{Name: "Desk Speakers"}); err != nil {
+		t.Fatalf("SetAlias() failed: %v", err)
+	}
+	if err := f.SetAlias(hdmi.StableID(), audiocontrol.Alias{Hidden: true}); err != nil {
+		t.Fatalf("SetAlias() failed: %v", err)
+	}
+	if err := f.SetAlias("Speakers", audiocontrol.Alias{Name: "x"}); err == nil {
+		t.Error("SetAlias() accepted a device ID that is not a stable ID")
+	}
+
+	reopened, err := audiocontrol.OpenAliasFile(path)
+	if err != nil {
+		t.Fatalf("OpenAliasFile() failed: %v", err)
+	}
+	if alias, ok := reopened.Alias(usbAfter.StableID()); !ok || alias.Name != "Desk Speakers" {
+		t.Errorf("Alias() = %+v, %v after reopening", alias, ok)
+	}
+	if n := len(reopened.Aliases()); n != 2 {
+		t.Errorf("Aliases() holds %d aliases, want 2", n)
+	}
+
+	// The zero alias removes one
+	if err := reopened.SetAlias(hdmi.StableID(), audiocontrol.Alias{}); err != nil {
+		t.Fatalf("SetAlias() failed: %v", err)
+	}
+	f, err = audiocontrol.OpenAliasFile(path)
+	if err != nil {
+		t.Fatalf("OpenAliasFile() failed: %v", err)
+	}
+	if _, ok := f.Alias(hdmi.StableID()); ok {
+		t.Error("Removed alias is still stored")
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := audiocontrol.OpenAliasFile(path); err == nil {
+		t.Error("OpenAliasFile() accepted a malformed file")
+	}
+}
+
+func TestAliases(t *testing.T) {
+	speakers := usbAfter
+	speakers.IsActive = true
+	b := audiotest.NewBackend(speakers, hdmi, bluetoothAfter)
+	audiocontrol.SetBackend(b)
+	t.Cleanup(func() { audiocontrol.SetBackend(nil) })
+
+	store, err := audiocontrol.OpenAliasFile(filepath.Join(t.TempDir(), "aliases.json"))
+	if err != nil {
+		t.Fatalf("OpenAliasFile() failed: %v", err)
+	}
+	store.SetAlias(usbBefore.StableID(), audiocontrol.Alias{Name: "Desk Speakers"})
+	store.SetAlias(hdmi.StableID(), audiocontrol.Alias{Hidden: true})
+	// Saved for the output alone, applied to a device with both directions
+	store.SetAlias("out/bt:ACBC320A1B2C", audiocontrol.Alias{Name: "My AirPods"})
+	audiocontrol.SetAliasStore(store)
+	t.Cleanup(func() { audiocontrol.SetAliasStore(nil) })
+
+	devices, err := audiocontrol.ListAudioDevices()
+	if err != nil {
+		t.Fatalf("ListAudioDevices() failed: %v", err)
+	}
+	if len(devices) != 2 || devices[0].Name != "Desk Speakers" || devices[0].OriginalName != "USB Audio CODEC" ||
+		devices[1].Name != "My AirPods" {
+		t.Fatalf("ListAudioDevices() = %+v, want the renamed devices without HDMI", devices)
+	}
+	if devices[0].StableID() != usbBefore.StableID() {
+		t.Errorf("StableID() = %q after renaming, want %q", devices[0].StableID(), usbBefore.StableID())
+	}
+
+	devices, err = audiocontrol.ListAudioDevicesWithOptions(audiocontrol.ListOptions{IncludeHidden: true})
+	if err != nil || len(devices) != 3 || !devices[1].Hidden {
+		t.Errorf("ListAudioDevicesWithOptions(IncludeHidden) = %+v, %v", devices, err)
+	}
+
+	// The backend itself is unchanged
+	devices, _ = b.ListAudioDevices()
+	if devices[0].Name != "USB Audio CODEC" || devices[1].Hidden {
+		t.Errorf("Backend devices changed to %+v", devices)
+	}
+
+	var events []audiocontrol.Event
+	audiocontrol.OnDeviceChange(func(e audiocontrol.Event) { events = append(events, e) })
+	if err := b.SetActiveOutputDevice(hdmi.ID); err != nil {
+		t.Fatalf("SetActiveOutputDevice() failed: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Got %+v for a hidden device", events)
+	}
+	active, err := audiocontrol.GetActiveOutputDevice()
+	if err != nil || active.ID != hdmi.ID || !active.Hidden {
+		t.Errorf("GetActiveOutputDevice() = %+v, %v, want the hidden HDMI output", active, err)
+	}
+
+	if err := b.SetActiveOutputDevice(usbAfter.ID); err != nil {
+		t.Fatalf("SetActiveOutputDevice() failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Info == nil || events[0].Info.Name != "Desk Speakers" {
+		t.Errorf("Got %+v, want one event for Desk Speakers", events)
+	}
+
+	// Removing the store restores the platform names
+	audiocontrol.SetAliasStore(nil)
+	devices, _ = audiocontrol.ListAudioDevices()
+	if len(devices) != 3 || devices[0].Name != "USB Audio CODEC" || devices[0].OriginalName != "" {
+		t.Errorf("ListAudioDevices() = %+v without a store", devices)
+	}
+}
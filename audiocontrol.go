@@ -1,6 +1,25 @@
 package audiocontrol
 
-// AudioDevice represents an audio device on the system
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/audi70r/go-audio-control/internal/utils"
+)
+
+// AudioDevice represents an audio device on the system.
+//
+// IsActive is kept for compatibility and is set when the device is the
+// default for any direction. IsDefaultOutput and IsDefaultInput say which.
+// IsDefaultCommunications marks the device used for calls; platforms without
+// a separate communications role report the default device for it. Ports
+// lists the connectors of devices whose platform reports them. Identity
+// holds the durable properties behind StableID. OriginalName and Hidden are
+// set from the alias store; see SetAliasStore.
 type AudioDevice struct {
 	ID          string
 	Name        string
@@ -8,6 +27,64 @@ type AudioDevice struct {
 	IsOutput    bool
 	IsActive    bool
 	IsConnected bool
+
+	IsDefaultOutput         bool
+	IsDefaultInput          bool
+	IsDefaultCommunications bool
+	State                   DeviceState
+	Ports                   []Port
+	Identity                Identity
+
+	// OriginalName is the name the platform reports for a device renamed
+	// by an alias
+	OriginalName string `json:",omitempty"`
+	Hidden       bool   `json:",omitempty"`
+}
+
+// DeviceState is the presence state of a device, mirroring the Windows
+// DEVICE_STATE_* values
+type DeviceState int
+
+const (
+	// StateActive devices are present and enabled
+	StateActive DeviceState = iota
+	// StateDisabled devices are present but disabled by the user
+	StateDisabled
+	// StateNotPresent devices are known to the system but not attached
+	StateNotPresent
+	// StateUnplugged devices are present but nothing is plugged into the jack
+	StateUnplugged
+)
+
+// String returns the name of the state
+func (s DeviceState) String() string {
+	switch s {
+	case StateActive:
+		return "Active"
+	case StateDisabled:
+		return "Disabled"
+	case StateNotPresent:
+		return "NotPresent"
+	case StateUnplugged:
+		return "Unplugged"
+	}
+	return fmt.Sprintf("DeviceState(%d)", int(s))
+}
+
+// MarshalText encodes the state by name
+func (s DeviceState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a state name
+func (s *DeviceState) UnmarshalText(text []byte) error {
+	for state := StateActive; state <= StateUnplugged; state++ {
+		if state.String() == string(text) {
+			*s = state
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown device state %q", text)
 }
 
 // EventType represents the type of audio device event
@@ -18,31 +95,375 @@ const (
 	DeviceRemoved
 	ActiveDeviceChanged
 	DeviceDisconnected
+	PortChanged
+	ProfileChanged
+	SessionAdded
+	SessionRemoved
+	// ServerConnected and ServerDisconnected announce that a backend
+	// reconnected to, or lost, the sound server. They have no DeviceID.
+	ServerConnected
+	ServerDisconnected
 )
 
-// Event represents an audio device event
+// String returns the name of the event type
+func (t EventType) String() string {
+	switch t {
+	case DeviceAdded:
+		return "DeviceAdded"
+	case DeviceRemoved:
+		return "DeviceRemoved"
+	case ActiveDeviceChanged:
+		return "ActiveDeviceChanged"
+	case DeviceDisconnected:
+		return "DeviceDisconnected"
+	case PortChanged:
+		return "PortChanged"
+	case ProfileChanged:
+		return "ProfileChanged"
+	case SessionAdded:
+		return "SessionAdded"
+	case SessionRemoved:
+		return "SessionRemoved"
+	case ServerConnected:
+		return "ServerConnected"
+	case ServerDisconnected:
+		return "ServerDisconnected"
+	}
+	return fmt.Sprintf("EventType(%d)", int(t))
+}
+
+// EventTypes returns every EventType in order
+func EventTypes() []EventType {
+	var types []EventType
+	for t := EventType(0); !strings.HasPrefix(t.String(), "EventType("); t++ {
+		types = append(types, t)
+	}
+	return types
+}
+
+// ParseEventType returns the EventType whose String is name
+func ParseEventType(name string) (EventType, error) {
+	for _, t := range EventTypes() {
+		if t.String() == name {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown event type %q", name)
+}
+
+// Event represents an audio device event. ProfileChanged events describe a
+// card: DeviceID is the card ID and Card is set instead of Info. Session
+// events set Session, with DeviceID naming the device of the session.
 type Event struct {
 	Type     EventType
 	DeviceID string
 	Info     *AudioDevice
+	Card     *Card    `json:",omitempty"`
+	Session  *Session `json:",omitempty"`
+}
+
+// Backend is the set of operations provided by a source of audio devices.
+// The native platform implementation, the audiod client and test fakes all
+// satisfy it, so applications can switch between them transparently.
+type Backend interface {
+	ListAudioDevices() ([]AudioDevice, error)
+	GetActiveOutputDevice() (AudioDevice, error)
+	SetActiveOutputDevice(deviceID string) error
+	GetActiveInputDevice() (AudioDevice, error)
+	SetActiveInputDevice(deviceID string) error
+	GetVolume(deviceID string) (float64, error)
+	SetVolume(deviceID string, volume float64) error
+	GetMute(deviceID string) (bool, error)
+	SetMute(deviceID string, muted bool) error
+	OnDeviceChange(callback func(Event))
+}
+
+var (
+	backendMutex sync.RWMutex
+	backend      Backend = nativeBackend{}
+	// backendID identifies the registration of backend, since backends
+	// cannot be compared in general
+	backendID uint64
+
+	// The platform listeners are started once and fan out to every
+	// callback, so several consumers can share them in one process
+	nativeCallbackMutex sync.Mutex
+	nativeCallbacks     []func(Event)
+)
+
+// Native returns the Backend implemented by the platform audio APIs
+func Native() Backend {
+	return nativeBackend{}
+}
+
+// SetBackend routes the package-level functions to b. Passing nil restores
+// the native platform backend.
+func SetBackend(b Backend) {
+	if b == nil {
+		b = nativeBackend{}
+	}
+	backendMutex.Lock()
+	previous := backendID
+	backend = b
+	backendID++
+	backendMutex.Unlock()
+	unwatchBackend(previous)
+}
+
+func currentBackend() Backend {
+	backendMutex.RLock()
+	defer backendMutex.RUnlock()
+	return backend
+}
+
+// currentBackendID returns the current backend with its registration ID
+func currentBackendID() (Backend, uint64) {
+	backendMutex.RLock()
+	defer backendMutex.RUnlock()
+	return backend, backendID
 }
 
 // ListAudioDevices enumerates all audio devices on the system
 func ListAudioDevices() ([]AudioDevice, error) {
-	return listAudioDevices()
+	devices, err := currentBackend().ListAudioDevices()
+	if err != nil {
+		return nil, err
+	}
+	return applyAliases(devices, false), nil
 }
 
 // GetActiveOutputDevice returns the currently active output device
 func GetActiveOutputDevice() (AudioDevice, error) {
-	return getActiveOutputDevice()
+	device, err := currentBackend().GetActiveOutputDevice()
+	if err != nil {
+		return device, err
+	}
+	return applyAlias(device), nil
 }
 
 // SetActiveOutputDevice sets the active output device by ID
 func SetActiveOutputDevice(deviceID string) error {
-	return setActiveOutputDevice(deviceID)
+	return currentBackend().SetActiveOutputDevice(deviceID)
+}
+
+// GetActiveInputDevice returns the currently active input device
+func GetActiveInputDevice() (AudioDevice, error) {
+	device, err := currentBackend().GetActiveInputDevice()
+	if err != nil {
+		return device, err
+	}
+	return applyAlias(device), nil
+}
+
+// SetActiveInputDevice sets the active input device by ID
+func SetActiveInputDevice(deviceID string) error {
+	return currentBackend().SetActiveInputDevice(deviceID)
+}
+
+// GetVolume returns the volume of a device in the range [0.0, 1.0]
+func GetVolume(deviceID string) (float64, error) {
+	return currentBackend().GetVolume(deviceID)
 }
 
-// OnDeviceChange registers a callback for audio device events
+// SetVolume sets the volume of a device. Values outside [0.0, 1.0] are clamped.
+func SetVolume(deviceID string, volume float64) error {
+	return currentBackend().SetVolume(deviceID, volume)
+}
+
+// GetMute reports whether a device is muted
+func GetMute(deviceID string) (bool, error) {
+	return currentBackend().GetMute(deviceID)
+}
+
+// SetMute mutes or unmutes a device
+func SetMute(deviceID string, muted bool) error {
+	return currentBackend().SetMute(deviceID, muted)
+}
+
+// OnDeviceChange registers a callback for audio device events. Every
+// registered callback receives every event, except those describing
+// devices hidden by an alias.
 func OnDeviceChange(callback func(Event)) {
-	onDeviceChange(callback)
+	currentBackend().OnDeviceChange(func(event Event) {
+		if event, ok := applyEventAlias(event); ok {
+			callback(event)
+		}
+	})
+}
+
+// nativeBackend adapts the platform-specific functions to Backend
+type nativeBackend struct{}
+
+func (b nativeBackend) ListAudioDevices() ([]AudioDevice, error) {
+	return b.ListAudioDevicesWithOptions(ListOptions{})
+}
+
+// ListAudioDevicesWithOptions filters the devices the platform reports for
+// opts. Platforms may return more devices than selected, but not fewer.
+func (nativeBackend) ListAudioDevicesWithOptions(opts ListOptions) ([]AudioDevice, error) {
+	done := logCall("ListAudioDevices", noID)
+	devices, err := listAudioDevices(opts)
+	if err := done(err); err != nil {
+		return nil, err
+	}
+	return opts.Filter(devices), nil
+}
+
+func (nativeBackend) GetActiveOutputDevice() (AudioDevice, error) {
+	done := logCall("GetActiveOutputDevice", noID)
+	device, err := getActiveOutputDevice()
+	return device, done(err)
+}
+
+func (nativeBackend) SetActiveOutputDevice(deviceID string) error {
+	done := logCall("SetActiveOutputDevice", deviceAttr(deviceID))
+	return done(setActiveOutputDevice(deviceID))
+}
+
+func (nativeBackend) GetActiveInputDevice() (AudioDevice, error) {
+	done := logCall("GetActiveInputDevice", noID)
+	device, err := getActiveInputDevice()
+	return device, done(err)
+}
+
+func (nativeBackend) SetActiveInputDevice(deviceID string) error {
+	done := logCall("SetActiveInputDevice", deviceAttr(deviceID))
+	return done(setActiveInputDevice(deviceID))
+}
+
+func (nativeBackend) GetVolume(deviceID string) (float64, error) {
+	done := logCall("GetVolume", deviceAttr(deviceID))
+	volume, err := getVolume(deviceID)
+	return volume, done(err)
+}
+
+func (nativeBackend) SetVolume(deviceID string, volume float64) error {
+	done := logCall("SetVolume", deviceAttr(deviceID))
+	return done(setVolume(deviceID, utils.ClampVolume(volume)))
+}
+
+func (nativeBackend) GetMute(deviceID string) (bool, error) {
+	done := logCall("GetMute", deviceAttr(deviceID))
+	muted, err := getMute(deviceID)
+	return muted, done(err)
+}
+
+func (nativeBackend) SetMute(deviceID string, muted bool) error {
+	done := logCall("SetMute", deviceAttr(deviceID))
+	return done(setMute(deviceID, muted))
+}
+
+func (nativeBackend) ListPorts(deviceID string) ([]Port, error) {
+	done := logCall("ListPorts", deviceAttr(deviceID))
+	ports, err := listPorts(deviceID)
+	return ports, done(err)
+}
+
+func (nativeBackend) SetActivePort(deviceID, port string) error {
+	done := logCall("SetActivePort", deviceAttr(deviceID))
+	return done(setActivePort(deviceID, port))
+}
+
+func (nativeBackend) ListCards() ([]Card, error) {
+	done := logCall("ListCards", noID)
+	cards, err := listCards()
+	return cards, done(err)
+}
+
+func (nativeBackend) ListProfiles(cardID string) ([]Profile, error) {
+	done := logCall("ListProfiles", slog.String("card", cardID))
+	profiles, err := listProfiles(cardID)
+	return profiles, done(err)
+}
+
+func (nativeBackend) SetProfile(cardID, profile string) error {
+	done := logCall("SetProfile", slog.String("card", cardID))
+	return done(setProfile(cardID, profile))
+}
+
+func (nativeBackend) ListSessions(deviceID string) ([]Session, error) {
+	done := logCall("ListSessions", deviceAttr(deviceID))
+	sessions, err := listSessions(deviceID)
+	return sessions, done(err)
+}
+
+func (nativeBackend) SetSessionVolume(sessionID string, volume float64) error {
+	done := logCall("SetSessionVolume", slog.String("session", sessionID))
+	return done(setSessionVolume(sessionID, utils.ClampVolume(volume)))
+}
+
+func (nativeBackend) SetSessionMute(sessionID string, muted bool) error {
+	done := logCall("SetSessionMute", slog.String("session", sessionID))
+	return done(setSessionMute(sessionID, muted))
+}
+
+func (nativeBackend) MoveSession(sessionID, deviceID string) error {
+	done := logCall("MoveSession", slog.String("session", sessionID))
+	return done(moveSession(sessionID, deviceID))
+}
+
+func (nativeBackend) CreateVirtualDevice(spec VirtualDeviceSpec) (VirtualDevice, error) {
+	done := logCall("CreateVirtualDevice", noID)
+	device, err := createVirtualDevice(spec)
+	return device, done(err)
+}
+
+func (nativeBackend) CloseVirtualDevices() error {
+	done := logCall("CloseVirtualDevices", noID)
+	return done(closeVirtualDevices())
+}
+
+func (nativeBackend) Meter(deviceID string, interval time.Duration) (<-chan Level, func(), error) {
+	done := logCall("Meter", deviceAttr(deviceID))
+	levels, stop, err := meter(deviceID, interval)
+	return levels, stop, done(err)
+}
+
+func (nativeBackend) Play(deviceID string, format Format, pcm io.Reader) error {
+	done := logCall("Play", deviceAttr(deviceID))
+	return done(play(deviceID, format, pcm))
+}
+
+func (nativeBackend) OpenCapture(deviceID string, format Format) (CaptureStream, error) {
+	done := logCall("OpenCapture", deviceAttr(deviceID))
+	stream, err := openCapture(deviceID, format)
+	return stream, done(err)
+}
+
+func (nativeBackend) OpenLoopback(deviceID string, format Format) (CaptureStream, error) {
+	done := logCall("OpenLoopback", deviceAttr(deviceID))
+	stream, err := openLoopback(deviceID, format)
+	return stream, done(err)
+}
+
+func (nativeBackend) DeviceLatency(deviceID string) (time.Duration, error) {
+	done := logCall("DeviceLatency", deviceAttr(deviceID))
+	latency, err := deviceLatency(deviceID)
+	return latency, done(err)
+}
+
+func (nativeBackend) OnDeviceChange(callback func(Event)) {
+	nativeCallbackMutex.Lock()
+	first := len(nativeCallbacks) == 0
+	nativeCallbacks = append(nativeCallbacks, callback)
+	nativeCallbackMutex.Unlock()
+
+	if first {
+		// Registration errors are logged by the platform glue
+		done := logCall("OnDeviceChange", noID)
+		onDeviceChange(dispatchNativeEvent)
+		done(nil)
+	}
+}
+
+// dispatchNativeEvent delivers a platform event to every registered callback
+func dispatchNativeEvent(event Event) {
+	nativeCallbackMutex.Lock()
+	callbacks := nativeCallbacks
+	nativeCallbackMutex.Unlock()
+
+	logEvent(event)
+	for _, callback := range callbacks {
+		callback(event)
+	}
 }
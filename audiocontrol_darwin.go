@@ -3,26 +3,64 @@
 
 package audiocontrol
 
-import darwin "github.com/audi70r/go-audio-control/platform/darwin"
+import (
+	"io"
+	"time"
+
+	"github.com/audi70r/go-audio-control/internal/logging"
+	darwin "github.com/audi70r/go-audio-control/platform/darwin"
+)
 
 // Platform-specific function implementations
 
-func listAudioDevices() ([]AudioDevice, error) {
-	devices, err := darwin.ListAudioDevices()
+func convertDarwinDevice(d darwin.AudioDevice) AudioDevice {
+	return AudioDevice{
+		ID:          d.ID,
+		Name:        d.Name,
+		IsInput:     d.IsInput,
+		IsOutput:    d.IsOutput,
+		IsActive:    d.IsActive,
+		IsConnected: d.IsConnected,
+
+		IsDefaultOutput:         d.IsDefaultOutput,
+		IsDefaultInput:          d.IsDefaultInput,
+		IsDefaultCommunications: d.IsDefaultCommunications,
+		State:                   DeviceState(d.State),
+		Ports:                   convertDarwinPorts(d.Ports),
+		Identity:                Identity(d.Identity),
+	}
+}
+
+func convertDarwinPorts(ports []darwin.Port) []Port {
+	if ports == nil {
+		return nil
+	}
+	result := make([]Port, len(ports))
+	for i, p := range ports {
+		result[i] = Port{
+			Name:        p.Name,
+			Description: p.Description,
+			Type:        PortType(p.Type),
+			Available:   PortAvailability(p.Available),
+			Priority:    p.Priority,
+			IsActive:    p.IsActive,
+		}
+	}
+	return result
+}
+
+// listAudioDevices keeps the devices without streams when unplugged ones are
+// requested. CoreAudio does not disable devices, and the caller filters the
+// direction and the other states.
+func listAudioDevices(opts ListOptions) ([]AudioDevice, error) {
+	devices, err := darwin.ListAudioDevicesWithState(opts.IncludeUnplugged)
 	if err != nil {
 		return nil, err
 	}
 
 	result := make([]AudioDevice, len(devices))
 	for i, d := range devices {
-		result[i] = AudioDevice{
-			ID:          d.ID,
-			Name:        d.Name,
-			IsInput:     d.IsInput,
-			IsOutput:    d.IsOutput,
-			IsActive:    d.IsActive,
-			IsConnected: d.IsConnected,
-		}
+		result[i] = convertDarwinDevice(d)
 	}
 	return result, nil
 }
@@ -33,32 +71,125 @@ func getActiveOutputDevice() (AudioDevice, error) {
 		return AudioDevice{}, err
 	}
 
-	return AudioDevice{
-		ID:          device.ID,
-		Name:        device.Name,
-		IsInput:     device.IsInput,
-		IsOutput:    device.IsOutput,
-		IsActive:    device.IsActive,
-		IsConnected: device.IsConnected,
-	}, nil
+	return convertDarwinDevice(device), nil
 }
 
 func setActiveOutputDevice(deviceID string) error {
-	return darwin.SetActiveOutputDevice(deviceID)
+	return platformError(darwin.SetActiveOutputDevice(deviceID), darwin.ErrDeviceNotFound)
+}
+
+func getActiveInputDevice() (AudioDevice, error) {
+	device, err := darwin.GetActiveInputDevice()
+	if err != nil {
+		return AudioDevice{}, err
+	}
+
+	return convertDarwinDevice(device), nil
+}
+
+func setActiveInputDevice(deviceID string) error {
+	return platformError(darwin.SetActiveInputDevice(deviceID), darwin.ErrDeviceNotFound)
+}
+
+func getVolume(deviceID string) (float64, error) {
+	volume, err := darwin.GetVolume(deviceID)
+	return volume, platformError(err, darwin.ErrDeviceNotFound)
+}
+
+func setVolume(deviceID string, volume float64) error {
+	return platformError(darwin.SetVolume(deviceID, volume), darwin.ErrDeviceNotFound)
+}
+
+func getMute(deviceID string) (bool, error) {
+	muted, err := darwin.GetMute(deviceID)
+	return muted, platformError(err, darwin.ErrDeviceNotFound)
+}
+
+func setMute(deviceID string, muted bool) error {
+	return platformError(darwin.SetMute(deviceID, muted), darwin.ErrDeviceNotFound)
+}
+
+func listPorts(deviceID string) ([]Port, error) {
+	ports, err := darwin.ListPorts(deviceID)
+	if err != nil {
+		return nil, platformError(err, darwin.ErrDeviceNotFound)
+	}
+	return convertDarwinPorts(ports), nil
+}
+
+func setActivePort(deviceID, port string) error {
+	return platformError(darwin.SetActivePort(deviceID, port), darwin.ErrDeviceNotFound)
+}
+
+// CoreAudio has no profiles; each configuration is a separate device
+
+func listCards() ([]Card, error) {
+	return nil, ErrNotImplemented
+}
+
+func listProfiles(cardID string) ([]Profile, error) {
+	return nil, ErrNotImplemented
+}
+
+func setProfile(cardID, profile string) error {
+	return ErrNotImplemented
+}
+
+// CoreAudio has no per-application streams to enumerate
+
+func listSessions(deviceID string) ([]Session, error) {
+	return nil, ErrNotImplemented
+}
+
+func setSessionVolume(sessionID string, volume float64) error {
+	return ErrNotImplemented
+}
+
+func setSessionMute(sessionID string, muted bool) error {
+	return ErrNotImplemented
+}
+
+func moveSession(sessionID, deviceID string) error {
+	return ErrNotImplemented
+}
+
+func createVirtualDevice(spec VirtualDeviceSpec) (VirtualDevice, error) {
+	return nil, ErrNotImplemented
+}
+
+func closeVirtualDevices() error {
+	return ErrNotImplemented
+}
+
+// CoreAudio exposes no level property on most devices; metering would need
+// an IOProc on the device
+func meter(deviceID string, interval time.Duration) (<-chan Level, func(), error) {
+	return nil, nil, ErrNotImplemented
+}
+
+func play(deviceID string, format Format, pcm io.Reader) error {
+	return ErrNotImplemented
+}
+
+func openCapture(deviceID string, format Format) (CaptureStream, error) {
+	return nil, ErrNotImplemented
+}
+
+func openLoopback(deviceID string, format Format) (CaptureStream, error) {
+	return nil, ErrNotImplemented
+}
+
+func deviceLatency(deviceID string) (time.Duration, error) {
+	latency, err := darwin.DeviceLatency(deviceID)
+	return latency, platformError(err, darwin.ErrDeviceNotFound)
 }
 
 func onDeviceChange(callback func(Event)) {
-	darwin.OnDeviceChange(func(e darwin.Event) {
+	err := darwin.OnDeviceChange(func(e darwin.Event) {
 		var info *AudioDevice
 		if e.Info != nil {
-			info = &AudioDevice{
-				ID:          e.Info.ID,
-				Name:        e.Info.Name,
-				IsInput:     e.Info.IsInput,
-				IsOutput:    e.Info.IsOutput,
-				IsActive:    e.Info.IsActive,
-				IsConnected: e.Info.IsConnected,
-			}
+			device := convertDarwinDevice(*e.Info)
+			info = &device
 		}
 
 		callback(Event{
@@ -67,4 +198,7 @@ func onDeviceChange(callback func(Event)) {
 			Info:     info,
 		})
 	})
+	if err != nil {
+		logging.Ignored("start monitoring", err)
+	}
 }
@@ -0,0 +1,314 @@
+//go:build linux
+// +build linux
+
+package audiocontrol
+
+import (
+	"io"
+	"time"
+
+	"github.com/audi70r/go-audio-control/internal/logging"
+	"github.com/audi70r/go-audio-control/platform/linux"
+)
+
+// Platform-specific function implementations
+
+func convertLinuxDevice(d linux.AudioDevice) AudioDevice {
+	return AudioDevice{
+		ID:          d.ID,
+		Name:        d.Name,
+		IsInput:     d.IsInput,
+		IsOutput:    d.IsOutput,
+		IsActive:    d.IsActive,
+		IsConnected: d.IsConnected,
+
+		IsDefaultOutput:         d.IsDefaultOutput,
+		IsDefaultInput:          d.IsDefaultInput,
+		IsDefaultCommunications: d.IsDefaultCommunications,
+		State:                   DeviceState(d.State),
+		Ports:                   convertLinuxPorts(d.Ports),
+		Identity:                Identity(d.Identity),
+	}
+}
+
+func convertLinuxPorts(ports []linux.Port) []Port {
+	if ports == nil {
+		return nil
+	}
+	result := make([]Port, len(ports))
+	for i, p := range ports {
+		result[i] = Port{
+			Name:        p.Name,
+			Description: p.Description,
+			Type:        PortType(p.Type),
+			Available:   PortAvailability(p.Available),
+			Priority:    p.Priority,
+			IsActive:    p.IsActive,
+		}
+	}
+	return result
+}
+
+func convertLinuxCard(c linux.Card) Card {
+	profiles := make([]Profile, len(c.Profiles))
+	for i, p := range c.Profiles {
+		profiles[i] = Profile(p)
+	}
+	return Card{
+		ID:            c.ID,
+		Name:          c.Name,
+		Profiles:      profiles,
+		ActiveProfile: c.ActiveProfile,
+		DeviceIDs:     c.DeviceIDs,
+	}
+}
+
+func convertLinuxSession(s linux.Session) Session {
+	return Session{
+		ID:       s.ID,
+		DeviceID: s.DeviceID,
+		AppName:  s.AppName,
+		PID:      s.PID,
+		Volume:   s.Volume,
+		Muted:    s.Muted,
+		State:    SessionState(s.State),
+		IsInput:  s.IsInput,
+	}
+}
+
+func listAudioDevices(opts ListOptions) ([]AudioDevice, error) {
+	devices, err := linux.ListAudioDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]AudioDevice, len(devices))
+	for i, d := range devices {
+		result[i] = convertLinuxDevice(d)
+	}
+	return result, nil
+}
+
+func getActiveOutputDevice() (AudioDevice, error) {
+	device, err := linux.GetActiveOutputDevice()
+	if err != nil {
+		return AudioDevice{}, err
+	}
+	return convertLinuxDevice(device), nil
+}
+
+func setActiveOutputDevice(deviceID string) error {
+	return platformError(linux.SetActiveOutputDevice(deviceID), linux.ErrDeviceNotFound)
+}
+
+func getActiveInputDevice() (AudioDevice, error) {
+	device, err := linux.GetActiveInputDevice()
+	if err != nil {
+		return AudioDevice{}, err
+	}
+	return convertLinuxDevice(device), nil
+}
+
+func setActiveInputDevice(deviceID string) error {
+	return platformError(linux.SetActiveInputDevice(deviceID), linux.ErrDeviceNotFound)
+}
+
+func getVolume(deviceID string) (float64, error) {
+	volume, err := linux.GetVolume(deviceID)
+	return volume, platformError(err, linux.ErrDeviceNotFound)
+}
+
+func setVolume(deviceID string, volume float64) error {
+	return platformError(linux.SetVolume(deviceID, volume), linux.ErrDeviceNotFound)
+}
+
+func getMute(deviceID string) (bool, error) {
+	muted, err := linux.GetMute(deviceID)
+	return muted, platformError(err, linux.ErrDeviceNotFound)
+}
+
+func setMute(deviceID string, muted bool) error {
+	return platformError(linux.SetMute(deviceID, muted), linux.ErrDeviceNotFound)
+}
+
+func listPorts(deviceID string) ([]Port, error) {
+	ports, err := linux.ListPorts(deviceID)
+	if err != nil {
+		return nil, platformError(err, linux.ErrDeviceNotFound)
+	}
+	return convertLinuxPorts(ports), nil
+}
+
+func setActivePort(deviceID, port string) error {
+	return platformError(linux.SetActivePort(deviceID, port), linux.ErrDeviceNotFound)
+}
+
+func listCards() ([]Card, error) {
+	cards, err := linux.ListCards()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Card, len(cards))
+	for i, c := range cards {
+		result[i] = convertLinuxCard(c)
+	}
+	return result, nil
+}
+
+func listProfiles(cardID string) ([]Profile, error) {
+	profiles, err := linux.ListProfiles(cardID)
+	if err != nil {
+		return nil, platformError(err, linux.ErrDeviceNotFound)
+	}
+	result := make([]Profile, len(profiles))
+	for i, p := range profiles {
+		result[i] = Profile(p)
+	}
+	return result, nil
+}
+
+func setProfile(cardID, profile string) error {
+	return platformError(linux.SetProfile(cardID, profile), linux.ErrDeviceNotFound)
+}
+
+func listSessions(deviceID string) ([]Session, error) {
+	sessions, err := linux.ListSessions(deviceID)
+	if err != nil {
+		return nil, platformError(err, linux.ErrDeviceNotFound)
+	}
+	result := make([]Session, len(sessions))
+	for i, s := range sessions {
+		result[i] = convertLinuxSession(s)
+	}
+	return result, nil
+}
+
+func setSessionVolume(sessionID string, volume float64) error {
+	return platformError(linux.SetSessionVolume(sessionID, volume), linux.ErrDeviceNotFound)
+}
+
+func setSessionMute(sessionID string, muted bool) error {
+	return platformError(linux.SetSessionMute(sessionID, muted), linux.ErrDeviceNotFound)
+}
+
+func moveSession(sessionID, deviceID string) error {
+	return platformError(linux.MoveSession(sessionID, deviceID), linux.ErrDeviceNotFound)
+}
+
+func createVirtualDevice(spec VirtualDeviceSpec) (VirtualDevice, error) {
+	d, err := linux.CreateVirtualDevice(linux.VirtualDeviceSpec{
+		Kind:        linux.VirtualDeviceKind(spec.Kind),
+		Name:        spec.Name,
+		Description: spec.Description,
+		Source:      spec.Source,
+		Sink:        spec.Sink,
+		Outputs:     spec.Outputs,
+	})
+	if err != nil {
+		return nil, platformError(err, linux.ErrDeviceNotFound)
+	}
+	return d, nil
+}
+
+func closeVirtualDevices() error {
+	return linux.CloseVirtualDevices()
+}
+
+func meter(deviceID string, interval time.Duration) (<-chan Level, func(), error) {
+	levels, stop, err := linux.Meter(deviceID, interval)
+	if err != nil {
+		return nil, nil, platformError(err, linux.ErrDeviceNotFound)
+	}
+	out := make(chan Level, 1)
+	go func() {
+		defer close(out)
+		for l := range levels {
+			select {
+			case out <- Level{Time: l.Time, Peak: l.Peak, RMS: l.RMS}:
+			default:
+			}
+		}
+	}()
+	return out, stop, nil
+}
+
+func play(deviceID string, format Format, pcm io.Reader) error {
+	if err := linux.Play(deviceID, linuxFormat(format), pcm); err != nil {
+		return platformError(err, linux.ErrDeviceNotFound)
+	}
+	return nil
+}
+
+// linuxCapture reports the format of a capture in the types of this package
+type linuxCapture struct {
+	*linux.Capture
+	format Format
+}
+
+func (c linuxCapture) Format() Format {
+	return c.format
+}
+
+func (c linuxCapture) Read(p []byte) (int, error) {
+	n, err := c.Capture.Read(p)
+	return n, platformError(err, linux.ErrDeviceNotFound)
+}
+
+func openCapture(deviceID string, format Format) (CaptureStream, error) {
+	return convertLinuxCapture(linux.OpenCapture(deviceID, linuxFormat(format)))
+}
+
+func openLoopback(deviceID string, format Format) (CaptureStream, error) {
+	return convertLinuxCapture(linux.OpenLoopback(deviceID, linuxFormat(format)))
+}
+
+func linuxFormat(f Format) linux.Format {
+	return linux.Format{Encoding: linux.SampleEncoding(f.Encoding), Channels: f.Channels, Rate: f.Rate}
+}
+
+func convertLinuxCapture(c *linux.Capture, err error) (CaptureStream, error) {
+	if err != nil {
+		return nil, platformError(err, linux.ErrDeviceNotFound)
+	}
+	f := c.Format()
+	return linuxCapture{c, Format{Encoding: SampleEncoding(f.Encoding), Channels: f.Channels, Rate: f.Rate}}, nil
+}
+
+func deviceLatency(deviceID string) (time.Duration, error) {
+	latency, err := linux.DeviceLatency(deviceID)
+	return latency, platformError(err, linux.ErrDeviceNotFound)
+}
+
+func onDeviceChange(callback func(Event)) {
+	// A failed subscription is retried when the connection is restored
+	err := linux.OnDeviceChange(func(e linux.Event) {
+		var info *AudioDevice
+		if e.Info != nil {
+			device := convertLinuxDevice(*e.Info)
+			info = &device
+		}
+
+		var card *Card
+		if e.Card != nil {
+			c := convertLinuxCard(*e.Card)
+			card = &c
+		}
+
+		var session *Session
+		if e.Session != nil {
+			s := convertLinuxSession(*e.Session)
+			session = &s
+		}
+
+		callback(Event{
+			Type:     EventType(e.Type),
+			DeviceID: e.DeviceID,
+			Info:     info,
+			Card:     card,
+			Session:  session,
+		})
+	})
+	if err != nil {
+		logging.Ignored("subscribe", err)
+	}
+}
@@ -0,0 +1,641 @@
+//go:build linux
+// +build linux
+
+package audiocontrol_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+	"github.com/audi70r/go-audio-control/conformancetest"
+	"github.com/audi70r/go-audio-control/internal/dsp"
+	"github.com/audi70r/go-audio-control/internal/pulse"
+	"github.com/audi70r/go-audio-control/internal/pulse/pulsetest"
+)
+
+// pulseServer stands in for the user's sound server during the tests
+var pulseServer *pulsetest.Server
+
+// TestMain points the native backend at a fake PulseAudio server, so that
+// the tests exercise the real protocol code without a sound server
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "audiocontrol-test")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create temporary directory: %v\n", err)
+		os.Exit(1)
+	}
+	pulseServer, err = pulsetest.Listen(filepath.Join(dir, "native"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start fake PulseAudio server: %v\n", err)
+		os.Exit(1)
+	}
+	os.Setenv("PULSE_SERVER", pulseServer.Address())
+
+	pulseServer.AddSink(pulsetest.NewSink("speakers", "Built-in Speakers"))
+	pulseServer.AddSink(pulsetest.NewSink("headphones", "USB Headphones"))
+	pulseServer.AddSource(pulsetest.NewSource("mic", "Built-in Microphone"))
+	pulseServer.AddSource(pulsetest.NewSource("headset-mic", "USB Headset Microphone"))
+
+	code := m.Run()
+	pulseServer.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestNativeConformance(t *testing.T) {
+	conformancetest.Run(t, func(t *testing.T) audiocontrol.Backend {
+		if err := pulseServer.SetDefaultSink("speakers"); err != nil {
+			t.Fatalf("Failed to reset default sink: %v", err)
+		}
+		if err := pulseServer.SetDefaultSource("mic"); err != nil {
+			t.Fatalf("Failed to reset default source: %v", err)
+		}
+		return audiocontrol.Native()
+	})
+}
+
+func TestNativeListOptions(t *testing.T) {
+	jack := pulsetest.NewSink("analog", "Analog Headphones")
+	jack.Ports = []pulse.PortInfo{{Name: "analog-output-headphones", Description: "Headphones", Available: pulse.AvailableNo}}
+	jack.ActivePort = "analog-output-headphones"
+	pulseServer.AddSink(jack)
+	t.Cleanup(func() { pulseServer.RemoveSink("analog") })
+
+	devices, err := audiocontrol.ListAudioDevices()
+	if err != nil {
+		t.Fatalf("Failed to list audio devices: %v", err)
+	}
+	for _, d := range devices {
+		if d.ID == "analog" {
+			t.Errorf("Unplugged sink listed by default: %+v", d)
+		}
+	}
+
+	devices, err = audiocontrol.ListAudioDevicesWithOptions(audiocontrol.ListOptions{
+		IncludeUnplugged: true,
+		Direction:        audiocontrol.DirectionOutput,
+	})
+	if err != nil {
+		t.Fatalf("Failed to list audio devices: %v", err)
+	}
+	var found bool
+	for _, d := range devices {
+		if d.IsInput {
+			t.Errorf("Input listed for DirectionOutput: %+v", d)
+		}
+		if d.ID == "analog" {
+			found = true
+			if d.State != audiocontrol.StateUnplugged {
+				t.Errorf("Expected StateUnplugged, got %v", d.State)
+			}
+		}
+	}
+	if !found {
+		t.Error("Unplugged sink not listed with IncludeUnplugged")
+	}
+}
+
+func TestNativePorts(t *testing.T) {
+	jack := pulsetest.NewSink("analog", "Analog Output")
+	jack.Ports = []pulse.PortInfo{
+		{Name: "analog-output-speaker", Description: "Speakers", Priority: 100},
+		{Name: "analog-output-headphones", Description: "Headphones", Priority: 200, Available: pulse.AvailableYes},
+	}
+	jack.ActivePort = "analog-output-speaker"
+	pulseServer.AddSink(jack)
+	t.Cleanup(func() { pulseServer.RemoveSink("analog") })
+
+	native := audiocontrol.Native().(audiocontrol.PortController)
+	ports, err := native.ListPorts("analog")
+	if err != nil {
+		t.Fatalf("Failed to list ports: %v", err)
+	}
+	if len(ports) != 2 || !ports[0].IsActive || ports[1].Type != audiocontrol.PortTypeHeadphones || ports[1].Available != audiocontrol.PortAvailable {
+		t.Fatalf("Unexpected ports %+v", ports)
+	}
+
+	events := make(chan audiocontrol.Event, 64)
+	audiocontrol.Native().OnDeviceChange(func(e audiocontrol.Event) {
+		select {
+		case events <- e:
+		default:
+		}
+	})
+
+	if err := native.SetActivePort("analog", "analog-output-headphones"); err != nil {
+		t.Fatalf("Failed to set port: %v", err)
+	}
+	timeout := time.After(conformancetest.EventTimeout)
+	for {
+		select {
+		case e := <-events:
+			if e.Type != audiocontrol.PortChanged || e.DeviceID != "analog" {
+				continue
+			}
+			if port, ok := e.Info.ActivePort(); !ok || port.Name != "analog-output-headphones" {
+				t.Errorf("Unexpected active port in %+v", e.Info)
+			}
+			return
+		case <-timeout:
+			t.Fatal("No PortChanged event")
+		}
+	}
+}
+
+func TestNativeCards(t *testing.T) {
+	index := pulseServer.AddCard(pulse.CardInfo{
+		Name: "bluez_card.headset",
+		Profiles: []pulse.ProfileInfo{
+			{Name: "a2dp_sink", Description: "High Fidelity Playback (A2DP Sink)", Sinks: 1, Available: pulse.AvailableYes},
+			{Name: "headset_head_unit", Description: "Headset Head Unit (HSP/HFP)", Sinks: 1, Sources: 1, Available: pulse.AvailableYes},
+		},
+		ActiveProfile: "a2dp_sink",
+	})
+	t.Cleanup(func() { pulseServer.RemoveCard("bluez_card.headset") })
+	sink := pulsetest.NewSink("bluez_sink.headset", "Headset")
+	sink.Card = index
+	pulseServer.AddSink(sink)
+	t.Cleanup(func() { pulseServer.RemoveSink("bluez_sink.headset") })
+
+	native := audiocontrol.Native().(audiocontrol.CardController)
+	cards, err := native.ListCards()
+	if err != nil {
+		t.Fatalf("Failed to list cards: %v", err)
+	}
+	if len(cards) != 1 || cards[0].Name != "bluez_card.headset" || len(cards[0].DeviceIDs) != 1 {
+		t.Fatalf("Unexpected cards %+v", cards)
+	}
+
+	events := make(chan audiocontrol.Event, 64)
+	audiocontrol.Native().OnDeviceChange(func(e audiocontrol.Event) {
+		select {
+		case events <- e:
+		default:
+		}
+	})
+
+	if err := native.SetProfile("bluez_card.headset", "headset_head_unit"); err != nil {
+		t.Fatalf("Failed to set profile: %v", err)
+	}
+	timeout := time.After(conformancetest.EventTimeout)
+	for {
+		select {
+		case e := <-events:
+			if e.Type != audiocontrol.ProfileChanged {
+				continue
+			}
+			if e.Card == nil || e.Card.ActiveProfile != "headset_head_unit" || !e.Card.Profiles[1].IsActive {
+				t.Errorf("Unexpected ProfileChanged event %+v", e)
+			}
+			return
+		case <-timeout:
+			t.Fatal("No ProfileChanged event")
+		}
+	}
+}
+
+func TestNativeSessions(t *testing.T) {
+	index, err := pulseServer.AddSinkInput("headphones", pulsetest.NewSinkInput("Firefox", 4242))
+	if err != nil {
+		t.Fatalf("Failed to add sink input: %v", err)
+	}
+	t.Cleanup(func() { pulseServer.RemoveSinkInput(index) })
+
+	sessions, err := audiocontrol.ListSessions("headphones")
+	if err != nil {
+		t.Fatalf("Failed to list sessions: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].AppName != "Firefox" || sessions[0].PID != 4242 || sessions[0].State != audiocontrol.SessionActive {
+		t.Fatalf("Unexpected sessions %+v", sessions)
+	}
+
+	// Volumes are clamped like device volumes
+	if err := audiocontrol.SetSessionVolume(sessions[0].ID, 1.5); err != nil {
+		t.Fatalf("Failed to set session volume: %v", err)
+	}
+	if input, _ := pulseServer.SinkInput(index); input.Volume[0] != pulse.VolumeNorm {
+		t.Errorf("Expected a clamped volume, got %v", input.Volume)
+	}
+	if err := audiocontrol.SetSessionMute("sink-input:99999", true); !errors.Is(err, audiocontrol.ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound, got %v", err)
+	}
+
+	events := make(chan audiocontrol.Event, 64)
+	audiocontrol.Native().OnDeviceChange(func(e audiocontrol.Event) {
+		select {
+		case events <- e:
+		default:
+		}
+	})
+
+	// The subscription may still be starting, so keep starting streams
+	// until one is reported
+	timeout := time.After(conformancetest.EventTimeout)
+	for {
+		added, _ := pulseServer.AddSourceOutput("mic", pulsetest.NewSourceOutput("Zoom", 5151))
+		t.Cleanup(func() { pulseServer.RemoveSourceOutput(added) })
+		select {
+		case e := <-events:
+			if e.Type != audiocontrol.SessionAdded {
+				continue
+			}
+			if e.DeviceID != "mic" || e.Session == nil || e.Session.AppName != "Zoom" || !e.Session.IsInput {
+				t.Errorf("Unexpected SessionAdded event %+v", e)
+			}
+			return
+		case <-time.After(50 * time.Millisecond):
+		case <-timeout:
+			t.Fatal("No SessionAdded event")
+		}
+	}
+}
+
+func TestNativeMoveAllSessions(t *testing.T) {
+	music, err := pulseServer.AddSinkInput("speakers", pulsetest.NewSinkInput("Spotify", 1001))
+	if err != nil {
+		t.Fatalf("Failed to add sink input: %v", err)
+	}
+	t.Cleanup(func() { pulseServer.RemoveSinkInput(music) })
+	pinned, err := pulseServer.AddSinkInput("speakers", pulsetest.NewSinkInput("Alarm", 1002))
+	if err != nil {
+		t.Fatalf("Failed to add sink input: %v", err)
+	}
+	t.Cleanup(func() { pulseServer.RemoveSinkInput(pinned) })
+	pulseServer.PinStream(pinned)
+	recording, err := pulseServer.AddSourceOutput("mic", pulsetest.NewSourceOutput("Recorder", 1003))
+	if err != nil {
+		t.Fatalf("Failed to add source output: %v", err)
+	}
+	t.Cleanup(func() { pulseServer.RemoveSourceOutput(recording) })
+	t.Cleanup(func() { audiocontrol.SetActiveOutputDevice("speakers") })
+
+	err = audiocontrol.SetActiveOutputDeviceWithOptions("headphones", audiocontrol.SetActiveOptions{MoveAllSessions: true})
+	var moveErr *audiocontrol.MoveError
+	if !errors.As(err, &moveErr) {
+		t.Fatalf("Expected a MoveError, got %v", err)
+	}
+	if len(moveErr.Failures) != 1 || moveErr.Failures[0].SessionID != fmt.Sprintf("sink-input:%d", pinned) || moveErr.Failures[0].AppName != "Alarm" {
+		t.Errorf("Unexpected failures %+v", moveErr.Failures)
+	}
+	if active, err := audiocontrol.GetActiveOutputDevice(); err != nil || active.ID != "headphones" {
+		t.Errorf("Expected headphones to be active, got %+v, %v", active, err)
+	}
+
+	sessions, err := audiocontrol.ListSessions("headphones")
+	if err != nil {
+		t.Fatalf("Failed to list sessions: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].AppName != "Spotify" {
+		t.Errorf("Expected only Spotify to move, got %+v", sessions)
+	}
+	// Record streams stay on their source
+	if sessions, _ := audiocontrol.ListSessions("mic"); len(sessions) != 1 || sessions[0].AppName != "Recorder" {
+		t.Errorf("Expected the recording to stay on mic, got %+v", sessions)
+	}
+
+	id := fmt.Sprintf("source-output:%d", recording)
+	if err := audiocontrol.MoveSession(id, "speakers"); !errors.Is(err, audiocontrol.ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound for a sink target, got %v", err)
+	}
+	if err := audiocontrol.MoveSession(id, "headset-mic"); err != nil {
+		t.Fatalf("Failed to move session: %v", err)
+	}
+	if sessions, _ := audiocontrol.ListSessions("headset-mic"); len(sessions) != 1 || sessions[0].ID != id {
+		t.Errorf("Expected the recording on headset-mic, got %+v", sessions)
+	}
+}
+
+func TestNativeVirtualDevices(t *testing.T) {
+	capture, err := audiocontrol.CreateVirtualDevice(audiocontrol.VirtualDeviceSpec{Kind: audiocontrol.VirtualNullSink, Name: "capture", Description: "Capture"})
+	if err != nil {
+		t.Fatalf("Failed to create null sink: %v", err)
+	}
+	t.Cleanup(func() { capture.Close() })
+	devices, err := audiocontrol.ListAudioDevices()
+	if err != nil {
+		t.Fatalf("Failed to list devices: %v", err)
+	}
+	found := false
+	for _, d := range devices {
+		found = found || (d.ID == "capture" && d.Name == "Capture" && d.IsOutput)
+	}
+	if !found {
+		t.Errorf("Expected the null sink in %+v", devices)
+	}
+
+	spec := audiocontrol.VirtualDeviceSpec{Kind: audiocontrol.VirtualCombinedSink, Name: "everywhere", Outputs: []string{"speakers", "missing"}}
+	if _, err := audiocontrol.CreateVirtualDevice(spec); !errors.Is(err, audiocontrol.ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound, got %v", err)
+	}
+
+	if _, err := audiocontrol.CreateVirtualDevice(audiocontrol.VirtualDeviceSpec{Kind: audiocontrol.VirtualLoopback, Source: "mic"}); err != nil {
+		t.Fatalf("Failed to create loopback: %v", err)
+	}
+	if err := audiocontrol.CloseVirtualDevices(); err != nil {
+		t.Fatalf("Failed to close virtual devices: %v", err)
+	}
+	if modules := pulseServer.Modules(); len(modules) != 0 {
+		t.Errorf("Expected every module to be unloaded, got %+v", modules)
+	}
+}
+
+func TestNativeMeter(t *testing.T) {
+	if _, _, err := audiocontrol.Meter("missing", 0); !errors.Is(err, audiocontrol.ErrDeviceNotFound) {
+		t.Fatalf("Expected ErrDeviceNotFound, got %v", err)
+	}
+
+	levels, stop, err := audiocontrol.Meter("headset-mic", 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to meter the microphone: %v", err)
+	}
+	samples := make([]float32, 2*480)
+	for i := range samples {
+		samples[i] = 0.1
+	}
+	if err := pulseServer.FeedSource("headset-mic", samples); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case level := <-levels:
+		if db := level.PeakDBFS(); len(db) != 2 || db[0] > -19.99 || db[0] < -20.01 {
+			t.Errorf("Peak is %v dBFS, want -20", db)
+		}
+		if level.Decayed[0] != level.Peak[0] {
+			t.Errorf("First decayed level %v differs from the peak %v", level.Decayed[0], level.Peak[0])
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("No level received")
+	}
+
+	// Stopping closes the channel and the stream
+	stop()
+	for range levels {
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for pulseServer.RecordStreams("headset-mic") != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("The record stream was not closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNativePlayTestTone(t *testing.T) {
+	before := len(pulseServer.Played("headphones"))
+	spec := audiocontrol.ToneSpec{Frequency: 1000, Duration: 20 * time.Millisecond, Level: 0.5, Channel: 2}
+	if err := audiocontrol.PlayTestTone("headphones", spec); err != nil {
+		t.Fatalf("Failed to play a test tone: %v", err)
+	}
+
+	// Tones are stereo float at 48kHz with 5ms fades
+	want := dsp.Tone{Frequency: 1000, Level: 0.5, Rate: 48000, Frames: 960, Channels: 2, Channel: 1, Fade: 240}.Samples()
+	got := dsp.Decode(pulseServer.Played("headphones")[before:], dsp.EncodingFloat32LE)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Server played %d samples that differ from the %d of the tone", len(got), len(want))
+	}
+	for f := 0; f < 960; f++ {
+		if got[2*f] != 0 {
+			t.Fatalf("Left channel is not silent at frame %d", f)
+		}
+	}
+
+	if err := audiocontrol.PlayTestTone("headphones", audiocontrol.ToneSpec{Channel: 3}); err == nil {
+		t.Error("Expected an error for a third channel")
+	}
+	if err := audiocontrol.PlayTestTone("mic", audiocontrol.ToneSpec{}); !errors.Is(err, audiocontrol.ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound for an input, got %v", err)
+	}
+}
+
+func TestNativePlayWAV(t *testing.T) {
+	samples := []byte{0x00, 0x40, 0x00, 0xc0, 0xff, 0x7f, 0x01, 0x80}
+	var file bytes.Buffer
+	file.WriteString("RIFF")
+	binary.Write(&file, binary.LittleEndian, uint32(36+len(samples)))
+	file.WriteString("WAVEfmt ")
+	// 16-bit mono PCM at 22050Hz
+	binary.Write(&file, binary.LittleEndian, []uint32{16, 1 | 1<<16, 22050, 44100, 2 | 16<<16})
+	file.WriteString("data")
+	binary.Write(&file, binary.LittleEndian, uint32(len(samples)))
+	file.Write(samples)
+
+	before := len(pulseServer.Played("speakers"))
+	if err := audiocontrol.PlayWAV("speakers", &file); err != nil {
+		t.Fatalf("Failed to play the file: %v", err)
+	}
+	if got := pulseServer.Played("speakers")[before:]; !bytes.Equal(got, samples) {
+		t.Errorf("Server played % x, want % x", got, samples)
+	}
+
+	if err := audiocontrol.PlayWAV("speakers", strings.NewReader("not a wave file")); err == nil {
+		t.Error("Expected an error for a file that is not WAVE")
+	}
+}
+
+func TestNativeCapture(t *testing.T) {
+	if _, err := audiocontrol.OpenCapture("speakers", audiocontrol.Format{}); !errors.Is(err, audiocontrol.ErrDeviceNotFound) {
+		t.Fatalf("Expected ErrDeviceNotFound for an output, got %v", err)
+	}
+
+	capture, err := audiocontrol.OpenCapture("mic", audiocontrol.Format{Encoding: audiocontrol.EncodingS16LE})
+	if err != nil {
+		t.Fatalf("Failed to capture: %v", err)
+	}
+	format := capture.Format()
+	if format != (audiocontrol.Format{Encoding: audiocontrol.EncodingS16LE, Channels: 2, Rate: 48000}) {
+		t.Errorf("Negotiated format is %+v", format)
+	}
+	samples := []float32{0.5, -0.5, 0.25, -0.25}
+	if err := pulseServer.FeedSource("mic", samples); err != nil {
+		t.Fatal(err)
+	}
+
+	// A clip saved to a file plays back unchanged
+	f, err := os.CreateTemp(t.TempDir(), "clip*.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := audiocontrol.NewWAVWriter(f, format)
+	if err != nil {
+		t.Fatalf("Failed to start the file: %v", err)
+	}
+	if _, err := io.CopyN(w, capture, int64(len(samples)*2)); err != nil {
+		t.Fatalf("Failed to capture the samples: %v", err)
+	}
+	capture.Close()
+	if _, err := io.Copy(w, capture); err != nil {
+		t.Errorf("Copy after Close failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to finish the file: %v", err)
+	}
+
+	f.Seek(0, io.SeekStart)
+	before := len(pulseServer.Played("speakers"))
+	if err := audiocontrol.PlayWAV("speakers", f); err != nil {
+		t.Fatalf("Failed to play the clip: %v", err)
+	}
+	want := dsp.Encode(samples, dsp.EncodingS16LE)
+	if got := pulseServer.Played("speakers")[before:]; !bytes.Equal(got, want) {
+		t.Errorf("Clip played % x, want % x", got, want)
+	}
+}
+
+func TestNativeMeasureLatency(t *testing.T) {
+	// The headset microphone hears the headphones 100ms late
+	if err := pulseServer.Echo("headphones", "headset-mic", 4800); err != nil {
+		t.Fatal(err)
+	}
+	defer pulseServer.Echo("headphones", "", 0)
+	pulseServer.UpdateSink("headphones", func(s *pulse.SinkInfo) { s.Latency = 25000 })
+	defer pulseServer.UpdateSink("headphones", func(s *pulse.SinkInfo) { s.Latency = 0 })
+
+	result, err := audiocontrol.MeasureLatency("headphones", "headset-mic", audiocontrol.LatencyOptions{
+		Duration:   50 * time.Millisecond,
+		MaxLatency: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to measure latency: %v", err)
+	}
+	if result.Latency < 90*time.Millisecond || result.Latency > 101*time.Millisecond {
+		t.Errorf("Measured %v, want about 100ms", result.Latency)
+	}
+	if result.Confidence < 0.99 {
+		t.Errorf("Confidence is %v", result.Confidence)
+	}
+	if result.OutputLatency != 25*time.Millisecond || result.InputLatency != 0 {
+		t.Errorf("Reported latencies are %v and %v", result.OutputLatency, result.InputLatency)
+	}
+
+	if _, err := audiocontrol.MeasureLatency("headphones", "missing", audiocontrol.LatencyOptions{}); !errors.Is(err, audiocontrol.ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound for a missing input, got %v", err)
+	}
+}
+
+func TestNativeLoopback(t *testing.T) {
+	if _, err := audiocontrol.OpenLoopback("mic", audiocontrol.Format{}); !errors.Is(err, audiocontrol.ErrDeviceNotFound) {
+		t.Fatalf("Expected ErrDeviceNotFound for an input, got %v", err)
+	}
+
+	loopback, err := audiocontrol.OpenLoopback("speakers", audiocontrol.Format{Encoding: audiocontrol.EncodingFloat32LE})
+	if err != nil {
+		t.Fatalf("Failed to open loopback: %v", err)
+	}
+	defer loopback.Close()
+	if f := loopback.Format(); f.Channels != 2 || f.Rate != 48000 {
+		t.Errorf("Negotiated format is %+v", f)
+	}
+
+	// A test tone played to the speakers is heard exactly
+	spec := audiocontrol.ToneSpec{Frequency: 1000, Duration: 10 * time.Millisecond}
+	if err := audiocontrol.PlayTestTone("speakers", spec); err != nil {
+		t.Fatalf("Failed to play a test tone: %v", err)
+	}
+	want := dsp.Tone{Frequency: 1000, Level: audiocontrol.DefaultToneLevel, Rate: 48000, Frames: 480, Channels: 2, Channel: -1, Fade: 240}.Samples()
+	got := make([]byte, 4*len(want))
+	if _, err := io.ReadFull(loopback, got); err != nil {
+		t.Fatalf("Failed to read the loopback: %v", err)
+	}
+	if samples := dsp.Decode(got, dsp.EncodingFloat32LE); !reflect.DeepEqual(samples, want) {
+		t.Error("Loopback samples differ from the tone")
+	}
+}
+
+// recordHandler keeps the records logged through SetLogger
+type recordHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	h.records = append(h.records, r.Clone())
+	h.mu.Unlock()
+	return nil
+}
+
+func (h *recordHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *recordHandler) WithGroup(string) slog.Handler      { return h }
+
+// find returns the first record with message msg holding every attribute in
+// attrs
+func (h *recordHandler) find(msg string, attrs map[string]string) (slog.Record, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, r := range h.records {
+		if r.Message != msg {
+			continue
+		}
+		matched := 0
+		r.Attrs(func(a slog.Attr) bool {
+			if want, ok := attrs[a.Key]; ok && a.Value.String() == want {
+				matched++
+			}
+			return true
+		})
+		if matched == len(attrs) {
+			return r, true
+		}
+	}
+	return slog.Record{}, false
+}
+
+func TestNativeLogger(t *testing.T) {
+	h := &recordHandler{}
+	audiocontrol.SetLogger(slog.New(h))
+	t.Cleanup(func() { audiocontrol.SetLogger(nil) })
+
+	native := audiocontrol.Native()
+	if err := native.SetVolume("speakers", 0.5); err != nil {
+		t.Fatalf("Failed to set volume: %v", err)
+	}
+	if r, ok := h.find("native call", map[string]string{"op": "SetVolume", "device": "speakers"}); !ok || r.Level != slog.LevelDebug {
+		t.Errorf("No Debug record for SetVolume, got %+v", r)
+	}
+	if _, err := native.GetVolume("missing"); err == nil {
+		t.Fatal("GetVolume() succeeded for a missing device")
+	}
+	r, ok := h.find("native call", map[string]string{"op": "GetVolume", "device": "missing"})
+	if !ok || r.Level != slog.LevelWarn {
+		t.Errorf("No Warn record for the failed GetVolume, got %+v", r)
+	}
+
+	native.OnDeviceChange(func(audiocontrol.Event) {})
+	pulseServer.AddSink(pulsetest.NewSink("logged", "Logged Output"))
+	t.Cleanup(func() { pulseServer.RemoveSink("logged") })
+	deadline := time.Now().Add(conformancetest.EventTimeout)
+	for {
+		_, notified := h.find("notification received", map[string]string{"facility": "sink", "type": "new"})
+		_, emitted := h.find("event emitted", map[string]string{"event": "DeviceAdded", "device": "logged"})
+		if notified && emitted {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Notification logged: %v, event logged: %v", notified, emitted)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Nothing is logged once the logger is removed
+	audiocontrol.SetLogger(nil)
+	native.GetVolume("recordless")
+	if _, ok := h.find("native call", map[string]string{"device": "recordless"}); ok {
+		t.Error("Record logged after SetLogger(nil)")
+	}
+}
@@ -1,13 +1,27 @@
 package audiocontrol
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"testing"
 	"time"
+
+	"github.com/audi70r/go-audio-control/internal/dsp"
 )
 
+// skipIfUnsupported skips tests that need a native backend on platforms
+// without one
+func skipIfUnsupported(t *testing.T, err error) {
+	t.Helper()
+	if errors.Is(err, ErrNotImplemented) {
+		t.Skip("no native audio backend on this platform")
+	}
+}
+
 func TestListAudioDevices(t *testing.T) {
 	devices, err := ListAudioDevices()
+	skipIfUnsupported(t, err)
 	if err != nil {
 		t.Fatalf("Failed to list audio devices: %v", err)
 	}
@@ -25,6 +39,7 @@ func TestListAudioDevices(t *testing.T) {
 
 func TestGetActiveOutputDevice(t *testing.T) {
 	device, err := GetActiveOutputDevice()
+	skipIfUnsupported(t, err)
 	if err != nil {
 		t.Fatalf("Failed to get active output device: %v", err)
 	}
@@ -54,3 +69,165 @@ func TestDeviceMonitoring(t *testing.T) {
 		fmt.Println("No device events received in 2 seconds (this is normal if no devices were changed)")
 	}
 }
+
+func TestDeviceStateText(t *testing.T) {
+	for state := StateActive; state <= StateUnplugged; state++ {
+		text, err := state.MarshalText()
+		if err != nil {
+			t.Fatalf("Failed to marshal %v: %v", state, err)
+		}
+		var parsed DeviceState
+		if err := parsed.UnmarshalText(text); err != nil {
+			t.Fatalf("Failed to unmarshal %s: %v", text, err)
+		}
+		if parsed != state {
+			t.Errorf("Round trip of %v gave %v", state, parsed)
+		}
+	}
+
+	var state DeviceState
+	if err := state.UnmarshalText([]byte("Sleeping")); err == nil {
+		t.Error("Expected an error for an unknown state")
+	}
+}
+
+func TestPortText(t *testing.T) {
+	for portType := PortTypeUnknown; portType <= PortTypeDigital; portType++ {
+		text, _ := portType.MarshalText()
+		var parsed PortType
+		if err := parsed.UnmarshalText(text); err != nil || parsed != portType {
+			t.Errorf("Round trip of %v gave %v, %v", portType, parsed, err)
+		}
+	}
+	for available := PortAvailabilityUnknown; available <= PortUnavailable; available++ {
+		text, _ := available.MarshalText()
+		var parsed PortAvailability
+		if err := parsed.UnmarshalText(text); err != nil || parsed != available {
+			t.Errorf("Round trip of %v gave %v, %v", available, parsed, err)
+		}
+	}
+
+	var portType PortType
+	if err := portType.UnmarshalText([]byte("Theremin")); err == nil {
+		t.Error("Expected an error for an unknown port type")
+	}
+}
+
+func TestSessionStateText(t *testing.T) {
+	for state := SessionInactive; state <= SessionExpired; state++ {
+		text, _ := state.MarshalText()
+		var parsed SessionState
+		if err := parsed.UnmarshalText(text); err != nil || parsed != state {
+			t.Errorf("Round trip of %v gave %v, %v", state, parsed, err)
+		}
+	}
+	if _, err := ParseEventType("SessionRemoved"); err != nil {
+		t.Errorf("Failed to parse SessionRemoved: %v", err)
+	}
+}
+
+func TestEventTypes(t *testing.T) {
+	types := EventTypes()
+	if len(types) == 0 || types[0] != DeviceAdded {
+		t.Fatalf("EventTypes() = %v", types)
+	}
+	for i, eventType := range types {
+		if parsed, err := ParseEventType(eventType.String()); err != nil || parsed != eventType || int(eventType) != i {
+			t.Errorf("ParseEventType(%q) = %v, %v", eventType, parsed, err)
+		}
+	}
+	if _, err := ParseEventType(EventType(len(types)).String()); err == nil {
+		t.Error("ParseEventType() accepted an unknown type")
+	}
+}
+
+func TestVirtualDeviceKindText(t *testing.T) {
+	for kind := VirtualNullSink; kind <= VirtualCombinedSink; kind++ {
+		text, _ := kind.MarshalText()
+		var parsed VirtualDeviceKind
+		if err := parsed.UnmarshalText(text); err != nil || parsed != kind {
+			t.Errorf("Round trip of %v gave %v, %v", kind, parsed, err)
+		}
+	}
+}
+
+func TestMeterBallistics(t *testing.T) {
+	levels := make(chan Level, 3)
+	start := time.Now()
+	levels <- Level{Time: start, Peak: []float64{1, 0.5}}
+	levels <- Level{Time: start.Add(500 * time.Millisecond), Peak: []float64{0, 0.5}}
+	levels <- Level{Time: start.Add(time.Second), Peak: []float64{0, 0}}
+	close(levels)
+
+	stopped := false
+	out, stop, _ := withBallistics(levels, func() { stopped = true })
+	var got []Level
+	for level := range out {
+		got = append(got, level)
+	}
+	if len(got) != 3 {
+		t.Fatalf("Received %d levels", len(got))
+	}
+	// The left channel falls by MeterDecayRate per second from full scale
+	if db := got[1].DecayedDBFS(); math.Abs(db[0]+MeterDecayRate/2) > 1e-9 || db[1] != got[1].PeakDBFS()[1] {
+		t.Errorf("Decayed levels after 500ms are %v dBFS", db)
+	}
+	if db := got[2].DecayedDBFS(); math.Abs(db[0]+MeterDecayRate) > 1e-9 {
+		t.Errorf("Decayed level after 1s is %v dBFS", db[0])
+	}
+	if got[2].RMSDBFS() != nil {
+		t.Error("Levels without RMS report RMS in dBFS")
+	}
+
+	stop()
+	stop()
+	if !stopped {
+		t.Error("Stop did not stop the backend meter")
+	}
+}
+
+func TestToneSamples(t *testing.T) {
+	samples, err := toneSamples(ToneSpec{})
+	if err != nil {
+		t.Fatalf("Failed to generate the default tone: %v", err)
+	}
+	// Half a second of stereo at 48kHz
+	if len(samples) != 2*24000 {
+		t.Fatalf("Default tone has %d samples", len(samples))
+	}
+	peak := 0.0
+	for _, s := range samples {
+		peak = math.Max(peak, math.Abs(float64(s)))
+	}
+	if math.Abs(peak-DefaultToneLevel) > 1e-3 {
+		t.Errorf("Default tone peaks at %v", peak)
+	}
+
+	for _, spec := range []ToneSpec{{Channel: -1}, {Channel: 3}, {Level: 2}, {Frequency: -440}} {
+		if _, err := toneSamples(spec); err == nil {
+			t.Errorf("Expected an error for %+v", spec)
+		}
+	}
+}
+
+func TestFindChirp(t *testing.T) {
+	chirp := dsp.Chirp{From: chirpFrom, To: chirpTo, Level: 0.5, Rate: 16000, Frames: 1600, Fade: 80}.Samples()
+
+	// A stereo capture hears the chirp 250ms late, louder on the left
+	samples := make([]float32, 2*16000)
+	for i, v := range chirp {
+		samples[2*(4000+i)] = 0.8 * v
+		samples[2*(4000+i)+1] = 0.2 * v
+	}
+	result, err := findChirp(samples, 2, chirp, 16000)
+	if err != nil {
+		t.Fatalf("Failed to find the chirp: %v", err)
+	}
+	if result.Latency != 250*time.Millisecond || result.Confidence < 0.999 {
+		t.Errorf("Found the chirp after %v with confidence %v", result.Latency, result.Confidence)
+	}
+
+	if _, err := findChirp(samples[:1000], 2, chirp, 16000); err == nil {
+		t.Error("Expected an error for a capture shorter than the chirp")
+	}
+}
@@ -1,9 +1,113 @@
-//go:build !darwin && !windows
-// +build !darwin,!windows
+//go:build !darwin && !windows && !linux
+// +build !darwin,!windows,!linux
 
 package audiocontrol
 
-// newPlatformController returns an error for unsupported platforms
-func newPlatformController() (AudioController, error) {
+import (
+	"io"
+	"time"
+)
+
+// Platform-specific function implementations for unsupported platforms
+
+func listAudioDevices(opts ListOptions) ([]AudioDevice, error) {
+	return nil, ErrNotImplemented
+}
+
+func getActiveOutputDevice() (AudioDevice, error) {
+	return AudioDevice{}, ErrNotImplemented
+}
+
+func setActiveOutputDevice(deviceID string) error {
+	return ErrNotImplemented
+}
+
+func getActiveInputDevice() (AudioDevice, error) {
+	return AudioDevice{}, ErrNotImplemented
+}
+
+func setActiveInputDevice(deviceID string) error {
+	return ErrNotImplemented
+}
+
+func getVolume(deviceID string) (float64, error) {
+	return 0, ErrNotImplemented
+}
+
+func setVolume(deviceID string, volume float64) error {
+	return ErrNotImplemented
+}
+
+func getMute(deviceID string) (bool, error) {
+	return false, ErrNotImplemented
+}
+
+func setMute(deviceID string, muted bool) error {
+	return ErrNotImplemented
+}
+
+func listPorts(deviceID string) ([]Port, error) {
+	return nil, ErrNotImplemented
+}
+
+func setActivePort(deviceID, port string) error {
+	return ErrNotImplemented
+}
+
+func listCards() ([]Card, error) {
+	return nil, ErrNotImplemented
+}
+
+func listProfiles(cardID string) ([]Profile, error) {
 	return nil, ErrNotImplemented
 }
+
+func setProfile(cardID, profile string) error {
+	return ErrNotImplemented
+}
+
+func listSessions(deviceID string) ([]Session, error) {
+	return nil, ErrNotImplemented
+}
+
+func setSessionVolume(sessionID string, volume float64) error {
+	return ErrNotImplemented
+}
+
+func setSessionMute(sessionID string, muted bool) error {
+	return ErrNotImplemented
+}
+
+func moveSession(sessionID, deviceID string) error {
+	return ErrNotImplemented
+}
+
+func createVirtualDevice(spec VirtualDeviceSpec) (VirtualDevice, error) {
+	return nil, ErrNotImplemented
+}
+
+func closeVirtualDevices() error {
+	return ErrNotImplemented
+}
+
+func meter(deviceID string, interval time.Duration) (<-chan Level, func(), error) {
+	return nil, nil, ErrNotImplemented
+}
+
+func play(deviceID string, format Format, pcm io.Reader) error {
+	return ErrNotImplemented
+}
+
+func openCapture(deviceID string, format Format) (CaptureStream, error) {
+	return nil, ErrNotImplemented
+}
+
+func openLoopback(deviceID string, format Format) (CaptureStream, error) {
+	return nil, ErrNotImplemented
+}
+
+func deviceLatency(deviceID string) (time.Duration, error) {
+	return 0, ErrNotImplemented
+}
+
+func onDeviceChange(callback func(Event)) {}
@@ -3,12 +3,45 @@
 
 package audiocontrol
 
-import "github.com/audi70r/go-audio-control/platform/windows"
+import (
+	"io"
+	"time"
+
+	"github.com/audi70r/go-audio-control/internal/logging"
+	"github.com/audi70r/go-audio-control/platform/windows"
+)
 
 // Platform-specific function implementations
 
-func listAudioDevices() ([]AudioDevice, error) {
-	devices, err := windows.ListAudioDevices()
+func convertWindowsDevice(d windows.AudioDevice) AudioDevice {
+	return AudioDevice{
+		ID:          d.ID,
+		Name:        d.Name,
+		IsInput:     d.IsInput,
+		IsOutput:    d.IsOutput,
+		IsActive:    d.IsActive,
+		IsConnected: d.IsConnected,
+
+		IsDefaultOutput:         d.IsDefaultOutput,
+		IsDefaultInput:          d.IsDefaultInput,
+		IsDefaultCommunications: d.IsDefaultCommunications,
+		State:                   DeviceState(d.State),
+	}
+}
+
+func listAudioDevices(opts ListOptions) ([]AudioDevice, error) {
+	stateMask := uint32(windows.DEVICE_STATE_ACTIVE)
+	if opts.IncludeDisabled {
+		stateMask |= windows.DEVICE_STATE_DISABLED
+	}
+	if opts.IncludeUnplugged {
+		stateMask |= windows.DEVICE_STATE_UNPLUGGED
+	}
+	if opts.IncludeNotPresent {
+		stateMask |= windows.DEVICE_STATE_NOTPRESENT
+	}
+
+	devices, err := windows.ListAudioDevicesWithState(stateMask)
 	if err != nil {
 		return nil, err
 	}
@@ -16,14 +49,7 @@ func listAudioDevices() ([]AudioDevice, error) {
 	// Convert platform-specific devices to generic AudioDevice
 	result := make([]AudioDevice, len(devices))
 	for i, d := range devices {
-		result[i] = AudioDevice{
-			ID:          d.ID,
-			Name:        d.Name,
-			IsInput:     d.IsInput,
-			IsOutput:    d.IsOutput,
-			IsActive:    d.IsActive,
-			IsConnected: d.IsConnected,
-		}
+		result[i] = convertWindowsDevice(d)
 	}
 
 	return result, nil
@@ -35,46 +61,203 @@ func getActiveOutputDevice() (AudioDevice, error) {
 		return AudioDevice{}, err
 	}
 
-	return AudioDevice{
-		ID:          device.ID,
-		Name:        device.Name,
-		IsInput:     device.IsInput,
-		IsOutput:    device.IsOutput,
-		IsActive:    device.IsActive,
-		IsConnected: device.IsConnected,
-	}, nil
+	return convertWindowsDevice(*device), nil
 }
 
 func setActiveOutputDevice(deviceID string) error {
-	return windows.SetActiveOutputDevice(deviceID)
+	return platformError(windows.SetActiveOutputDevice(deviceID), windows.ErrDeviceNotFound)
+}
+
+func getActiveInputDevice() (AudioDevice, error) {
+	device, err := windows.GetActiveInputDevice()
+	if err != nil {
+		return AudioDevice{}, err
+	}
+
+	return convertWindowsDevice(*device), nil
+}
+
+func setActiveInputDevice(deviceID string) error {
+	return platformError(windows.SetActiveInputDevice(deviceID), windows.ErrDeviceNotFound)
+}
+
+func getVolume(deviceID string) (float64, error) {
+	volume, err := windows.GetVolume(deviceID)
+	return volume, platformError(err, windows.ErrDeviceNotFound)
+}
+
+func setVolume(deviceID string, volume float64) error {
+	return platformError(windows.SetVolume(deviceID, volume), windows.ErrDeviceNotFound)
+}
+
+func getMute(deviceID string) (bool, error) {
+	muted, err := windows.GetMute(deviceID)
+	return muted, platformError(err, windows.ErrDeviceNotFound)
+}
+
+func setMute(deviceID string, muted bool) error {
+	return platformError(windows.SetMute(deviceID, muted), windows.ErrDeviceNotFound)
+}
+
+// Endpoints on Windows are per jack, so there are no ports to switch
+
+func listPorts(deviceID string) ([]Port, error) {
+	return nil, ErrNotImplemented
+}
+
+func setActivePort(deviceID, port string) error {
+	return ErrNotImplemented
+}
+
+// Cards and profiles are a PulseAudio concept
+
+func listCards() ([]Card, error) {
+	return nil, ErrNotImplemented
+}
+
+func listProfiles(cardID string) ([]Profile, error) {
+	return nil, ErrNotImplemented
+}
+
+func setProfile(cardID, profile string) error {
+	return ErrNotImplemented
+}
+
+func convertWindowsSession(s windows.Session) Session {
+	return Session{
+		ID:       s.ID,
+		DeviceID: s.DeviceID,
+		AppName:  s.AppName,
+		PID:      s.PID,
+		Volume:   s.Volume,
+		Muted:    s.Muted,
+		State:    SessionState(s.State),
+		IsInput:  s.IsInput,
+	}
+}
+
+func listSessions(deviceID string) ([]Session, error) {
+	sessions, err := windows.ListSessions(deviceID)
+	if err != nil {
+		return nil, platformError(err, windows.ErrDeviceNotFound)
+	}
+	result := make([]Session, len(sessions))
+	for i, s := range sessions {
+		result[i] = convertWindowsSession(s)
+	}
+	return result, nil
+}
+
+func setSessionVolume(sessionID string, volume float64) error {
+	return platformError(windows.SetSessionVolume(sessionID, volume), windows.ErrDeviceNotFound)
+}
+
+func setSessionMute(sessionID string, muted bool) error {
+	return platformError(windows.SetSessionMute(sessionID, muted), windows.ErrDeviceNotFound)
+}
+
+// Windows offers no public API to move the stream of another process
+func moveSession(sessionID, deviceID string) error {
+	return ErrNotImplemented
+}
+
+// Windows needs a kernel driver to add endpoints
+func createVirtualDevice(spec VirtualDeviceSpec) (VirtualDevice, error) {
+	return nil, ErrNotImplemented
+}
+
+func closeVirtualDevices() error {
+	return ErrNotImplemented
+}
+
+func meter(deviceID string, interval time.Duration) (<-chan Level, func(), error) {
+	levels, stop, err := windows.Meter(deviceID, interval)
+	if err != nil {
+		return nil, nil, platformError(err, windows.ErrDeviceNotFound)
+	}
+	out := make(chan Level, 1)
+	go func() {
+		defer close(out)
+		for l := range levels {
+			select {
+			case out <- Level{Time: l.Time, Peak: l.Peak, RMS: l.RMS}:
+			default:
+			}
+		}
+	}()
+	return out, stop, nil
+}
+
+// Playback would need an IAudioClient render stream on the endpoint
+func play(deviceID string, format Format, pcm io.Reader) error {
+	return ErrNotImplemented
+}
+
+// windowsCapture reports the format of a capture in the types of this
+// package
+type windowsCapture struct {
+	*windows.Capture
+	format Format
+}
+
+func (c windowsCapture) Format() Format {
+	return c.format
+}
+
+func (c windowsCapture) Read(p []byte) (int, error) {
+	n, err := c.Capture.Read(p)
+	return n, platformError(err, windows.ErrDeviceNotFound)
+}
+
+func openCapture(deviceID string, format Format) (CaptureStream, error) {
+	return convertWindowsCapture(windows.OpenCapture(deviceID, windowsFormat(format)))
+}
+
+func openLoopback(deviceID string, format Format) (CaptureStream, error) {
+	return convertWindowsCapture(windows.OpenLoopback(deviceID, windowsFormat(format)))
+}
+
+func windowsFormat(f Format) windows.Format {
+	return windows.Format{Encoding: windows.SampleEncoding(f.Encoding), Channels: f.Channels, Rate: f.Rate}
+}
+
+func convertWindowsCapture(c *windows.Capture, err error) (CaptureStream, error) {
+	if err != nil {
+		return nil, platformError(err, windows.ErrDeviceNotFound)
+	}
+	f := c.Format()
+	return windowsCapture{c, Format{Encoding: SampleEncoding(f.Encoding), Channels: f.Channels, Rate: f.Rate}}, nil
+}
+
+// Endpoint latency is only known from an initialised IAudioClient
+func deviceLatency(deviceID string) (time.Duration, error) {
+	return 0, ErrNotImplemented
 }
 
 func onDeviceChange(callback func(Event)) {
 	// Create listener
 	listener, err := windows.NewDeviceListener()
 	if err != nil {
-		// Handle error - for now just return
+		logging.Ignored("create device listener", err)
 		return
 	}
 
 	// Start listening with callback wrapper
-	listener.Start(func(e windows.DeviceEvent) {
+	err = listener.Start(func(e windows.DeviceEvent) {
 		event := Event{
 			Type:     EventType(e.Type),
 			DeviceID: e.DeviceID,
 		}
 
 		if e.Device != nil {
-			event.Info = &AudioDevice{
-				ID:          e.Device.ID,
-				Name:        e.Device.Name,
-				IsInput:     e.Device.IsInput,
-				IsOutput:    e.Device.IsOutput,
-				IsActive:    e.Device.IsActive,
-				IsConnected: e.Device.IsConnected,
-			}
+			device := convertWindowsDevice(*e.Device)
+			event.Info = &device
 		}
 
 		callback(event)
 	})
+	if err != nil {
+		logging.Ignored("register endpoint notifications", err)
+		listener.Close()
+	}
 }
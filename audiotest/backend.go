@@ -0,0 +1,797 @@
+// Package audiotest provides an in-memory audiocontrol.Backend for tests
+package audiotest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+	"github.com/audi70r/go-audio-control/internal/utils"
+)
+
+// Backend is a fake audiocontrol.Backend holding its device state in memory.
+// State changes made through it are reported to every registered callback
+// synchronously, in the order they happen.
+type Backend struct {
+	mu        sync.Mutex
+	devices   []audiocontrol.AudioDevice
+	cards     []audiocontrol.Card
+	sessions  []audiocontrol.Session
+	virtual   map[*virtualDevice]bool
+	meters    map[*meter]bool
+	played    map[string][]byte
+	captures  map[*capture]bool
+	volumes   map[string]float64
+	mutes     map[string]bool
+	latency   time.Duration
+	callbacks []func(audiocontrol.Event)
+}
+
+// NewBackend returns a Backend populated with devices. Devices start with a
+// volume of 1.0 and unmuted. An active device given without default flags is
+// the default for each of its directions.
+func NewBackend(devices ...audiocontrol.AudioDevice) *Backend {
+	b := &Backend{
+		volumes:  make(map[string]float64),
+		mutes:    make(map[string]bool),
+		virtual:  make(map[*virtualDevice]bool),
+		meters:   make(map[*meter]bool),
+		played:   make(map[string][]byte),
+		captures: make(map[*capture]bool),
+	}
+	for _, d := range devices {
+		b.devices = append(b.devices, withDefaults(d))
+		b.volumes[d.ID] = 1.0
+	}
+	return b
+}
+
+// AddDevice adds a device and emits DeviceAdded
+func (b *Backend) AddDevice(device audiocontrol.AudioDevice) {
+	device = withDefaults(device)
+	b.mu.Lock()
+	b.devices = append(b.devices, device)
+	b.volumes[device.ID] = 1.0
+	b.mu.Unlock()
+
+	b.Emit(audiocontrol.Event{Type: audiocontrol.DeviceAdded, DeviceID: device.ID, Info: &device})
+}
+
+// RemoveDevice removes a device and emits DeviceRemoved
+func (b *Backend) RemoveDevice(deviceID string) error {
+	b.mu.Lock()
+	i := b.indexLocked(deviceID)
+	if i < 0 {
+		b.mu.Unlock()
+		return fmt.Errorf("%w: %s", audiocontrol.ErrDeviceNotFound, deviceID)
+	}
+	device := b.devices[i]
+	b.devices = append(b.devices[:i], b.devices[i+1:]...)
+	delete(b.volumes, deviceID)
+	delete(b.mutes, deviceID)
+	for m := range b.meters {
+		if m.deviceID == deviceID {
+			b.stopMeterLocked(m)
+		}
+	}
+	for c := range b.captures {
+		if c.deviceID == deviceID {
+			delete(b.captures, c)
+			c.end(fmt.Errorf("%w: %s", audiocontrol.ErrDeviceNotFound, deviceID))
+		}
+	}
+	b.mu.Unlock()
+
+	b.Emit(audiocontrol.Event{Type: audiocontrol.DeviceRemoved, DeviceID: deviceID, Info: &device})
+	return nil
+}
+
+// SetLatency makes every call of the Backend interface take at least d
+// before it touches the device state, as a busy sound server or a device
+// mid-handshake would
+func (b *Backend) SetLatency(d time.Duration) {
+	b.mu.Lock()
+	b.latency = d
+	b.mu.Unlock()
+}
+
+// delay waits for the latency set with SetLatency
+func (b *Backend) delay() {
+	b.mu.Lock()
+	d := b.latency
+	b.mu.Unlock()
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// Emit delivers an event to every registered callback
+func (b *Backend) Emit(event audiocontrol.Event) {
+	b.mu.Lock()
+	callbacks := append([]func(audiocontrol.Event){}, b.callbacks...)
+	b.mu.Unlock()
+
+	for _, callback := range callbacks {
+		callback(event)
+	}
+}
+
+// ListAudioDevices returns a copy of the active devices
+func (b *Backend) ListAudioDevices() ([]audiocontrol.AudioDevice, error) {
+	return b.ListAudioDevicesWithOptions(audiocontrol.ListOptions{})
+}
+
+// ListAudioDevicesWithOptions returns a copy of the devices selected by opts
+func (b *Backend) ListAudioDevicesWithOptions(opts audiocontrol.ListOptions) ([]audiocontrol.AudioDevice, error) {
+	b.delay()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	devices := make([]audiocontrol.AudioDevice, 0, len(b.devices))
+	for _, d := range b.devices {
+		if opts.Match(d) {
+			devices = append(devices, d)
+		}
+	}
+	return devices, nil
+}
+
+// SetState changes the state of a device without emitting an event. Devices
+// that are not active are reported as disconnected.
+func (b *Backend) SetState(deviceID string, state audiocontrol.DeviceState) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	i := b.indexLocked(deviceID)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", audiocontrol.ErrDeviceNotFound, deviceID)
+	}
+	b.devices[i].State = state
+	b.devices[i].IsConnected = state == audiocontrol.StateActive
+	return nil
+}
+
+// SetPorts replaces the ports of a device and emits PortChanged
+func (b *Backend) SetPorts(deviceID string, ports []audiocontrol.Port) error {
+	b.mu.Lock()
+	i := b.indexLocked(deviceID)
+	if i < 0 {
+		b.mu.Unlock()
+		return fmt.Errorf("%w: %s", audiocontrol.ErrDeviceNotFound, deviceID)
+	}
+	b.devices[i].Ports = append([]audiocontrol.Port(nil), ports...)
+	device := b.devices[i]
+	b.mu.Unlock()
+
+	b.Emit(audiocontrol.Event{Type: audiocontrol.PortChanged, DeviceID: deviceID, Info: &device})
+	return nil
+}
+
+// ListPorts returns a copy of the ports of a device
+func (b *Backend) ListPorts(deviceID string) ([]audiocontrol.Port, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	i := b.indexLocked(deviceID)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: %s", audiocontrol.ErrDeviceNotFound, deviceID)
+	}
+	return append([]audiocontrol.Port(nil), b.devices[i].Ports...), nil
+}
+
+// SetActivePort makes port the active port of a device and emits PortChanged
+func (b *Backend) SetActivePort(deviceID, port string) error {
+	b.mu.Lock()
+	i := b.indexLocked(deviceID)
+	if i < 0 {
+		b.mu.Unlock()
+		return fmt.Errorf("%w: %s", audiocontrol.ErrDeviceNotFound, deviceID)
+	}
+	ports := append([]audiocontrol.Port(nil), b.devices[i].Ports...)
+	found := false
+	for j := range ports {
+		ports[j].IsActive = ports[j].Name == port
+		found = found || ports[j].IsActive
+	}
+	if !found {
+		b.mu.Unlock()
+		return fmt.Errorf("device %s has no port %s", deviceID, port)
+	}
+	b.devices[i].Ports = ports
+	device := b.devices[i]
+	b.mu.Unlock()
+
+	b.Emit(audiocontrol.Event{Type: audiocontrol.PortChanged, DeviceID: deviceID, Info: &device})
+	return nil
+}
+
+// AddCard adds a card. No event is emitted, as backends report profile
+// changes only.
+func (b *Backend) AddCard(card audiocontrol.Card) {
+	b.mu.Lock()
+	b.cards = append(b.cards, card)
+	b.mu.Unlock()
+}
+
+// ListCards returns a copy of the cards
+func (b *Backend) ListCards() ([]audiocontrol.Card, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cards := make([]audiocontrol.Card, len(b.cards))
+	for i, c := range b.cards {
+		cards[i] = copyCard(c)
+	}
+	return cards, nil
+}
+
+// ListProfiles returns a copy of the profiles of a card
+func (b *Backend) ListProfiles(cardID string) ([]audiocontrol.Profile, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	i := b.cardIndexLocked(cardID)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: card %s", audiocontrol.ErrDeviceNotFound, cardID)
+	}
+	return copyCard(b.cards[i]).Profiles, nil
+}
+
+// SetProfile makes profile the active profile of a card and emits
+// ProfileChanged. The devices of the card are left unchanged.
+func (b *Backend) SetProfile(cardID, profile string) error {
+	b.mu.Lock()
+	i := b.cardIndexLocked(cardID)
+	if i < 0 {
+		b.mu.Unlock()
+		return fmt.Errorf("%w: card %s", audiocontrol.ErrDeviceNotFound, cardID)
+	}
+	card := copyCard(b.cards[i])
+	found := false
+	for j := range card.Profiles {
+		card.Profiles[j].IsActive = card.Profiles[j].Name == profile
+		found = found || card.Profiles[j].IsActive
+	}
+	if !found {
+		b.mu.Unlock()
+		return fmt.Errorf("card %s has no profile %s", cardID, profile)
+	}
+	card.ActiveProfile = profile
+	b.cards[i] = card
+	card = copyCard(card)
+	b.mu.Unlock()
+
+	b.Emit(audiocontrol.Event{Type: audiocontrol.ProfileChanged, DeviceID: cardID, Card: &card})
+	return nil
+}
+
+// AddSession adds a session on its device and emits SessionAdded
+func (b *Backend) AddSession(session audiocontrol.Session) error {
+	b.mu.Lock()
+	if b.indexLocked(session.DeviceID) < 0 {
+		b.mu.Unlock()
+		return fmt.Errorf("%w: %s", audiocontrol.ErrDeviceNotFound, session.DeviceID)
+	}
+	b.sessions = append(b.sessions, session)
+	b.mu.Unlock()
+
+	b.Emit(audiocontrol.Event{Type: audiocontrol.SessionAdded, DeviceID: session.DeviceID, Session: &session})
+	return nil
+}
+
+// RemoveSession removes a session and emits SessionRemoved with the session
+// expired
+func (b *Backend) RemoveSession(sessionID string) error {
+	b.mu.Lock()
+	i := b.sessionIndexLocked(sessionID)
+	if i < 0 {
+		b.mu.Unlock()
+		return fmt.Errorf("%w: session %s", audiocontrol.ErrDeviceNotFound, sessionID)
+	}
+	session := b.sessions[i]
+	session.State = audiocontrol.SessionExpired
+	b.sessions = append(b.sessions[:i], b.sessions[i+1:]...)
+	b.mu.Unlock()
+
+	b.Emit(audiocontrol.Event{Type: audiocontrol.SessionRemoved, DeviceID: session.DeviceID, Session: &session})
+	return nil
+}
+
+// ListSessions returns the sessions on a device, or every session when
+// deviceID is empty
+func (b *Backend) ListSessions(deviceID string) ([]audiocontrol.Session, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if deviceID != "" && b.indexLocked(deviceID) < 0 {
+		return nil, fmt.Errorf("%w: %s", audiocontrol.ErrDeviceNotFound, deviceID)
+	}
+	var sessions []audiocontrol.Session
+	for _, s := range b.sessions {
+		if deviceID == "" || s.DeviceID == deviceID {
+			sessions = append(sessions, s)
+		}
+	}
+	return sessions, nil
+}
+
+// SetSessionVolume sets the volume of a session, clamped to [0.0, 1.0]
+func (b *Backend) SetSessionVolume(sessionID string, volume float64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	i := b.sessionIndexLocked(sessionID)
+	if i < 0 {
+		return fmt.Errorf("%w: session %s", audiocontrol.ErrDeviceNotFound, sessionID)
+	}
+	b.sessions[i].Volume = utils.ClampVolume(volume)
+	return nil
+}
+
+// SetSessionMute mutes or unmutes a session
+func (b *Backend) SetSessionMute(sessionID string, muted bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	i := b.sessionIndexLocked(sessionID)
+	if i < 0 {
+		return fmt.Errorf("%w: session %s", audiocontrol.ErrDeviceNotFound, sessionID)
+	}
+	b.sessions[i].Muted = muted
+	return nil
+}
+
+// MoveSession moves a session to another device of the same direction
+func (b *Backend) MoveSession(sessionID, deviceID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	i := b.sessionIndexLocked(sessionID)
+	if i < 0 {
+		return fmt.Errorf("%w: session %s", audiocontrol.ErrDeviceNotFound, sessionID)
+	}
+	d := b.indexLocked(deviceID)
+	if d < 0 {
+		return fmt.Errorf("%w: %s", audiocontrol.ErrDeviceNotFound, deviceID)
+	}
+	device := b.devices[d]
+	if (b.sessions[i].IsInput && !device.IsInput) || (!b.sessions[i].IsInput && !device.IsOutput) {
+		return fmt.Errorf("%w: %s has no matching direction", audiocontrol.ErrDeviceNotFound, deviceID)
+	}
+	b.sessions[i].DeviceID = deviceID
+	return nil
+}
+
+// GetActiveOutputDevice returns the active output device
+func (b *Backend) GetActiveOutputDevice() (audiocontrol.AudioDevice, error) {
+	return b.active(true)
+}
+
+// SetActiveOutputDevice makes deviceID the active output device
+func (b *Backend) SetActiveOutputDevice(deviceID string) error {
+	return b.setActive(deviceID, true)
+}
+
+// GetActiveInputDevice returns the active input device
+func (b *Backend) GetActiveInputDevice() (audiocontrol.AudioDevice, error) {
+	return b.active(false)
+}
+
+// SetActiveInputDevice makes deviceID the active input device
+func (b *Backend) SetActiveInputDevice(deviceID string) error {
+	return b.setActive(deviceID, false)
+}
+
+// GetVolume returns the volume of a device
+func (b *Backend) GetVolume(deviceID string) (float64, error) {
+	b.delay()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.indexLocked(deviceID) < 0 {
+		return 0, fmt.Errorf("%w: %s", audiocontrol.ErrDeviceNotFound, deviceID)
+	}
+	return b.volumes[deviceID], nil
+}
+
+// SetVolume sets the volume of a device, clamped to [0.0, 1.0]
+func (b *Backend) SetVolume(deviceID string, volume float64) error {
+	b.delay()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.indexLocked(deviceID) < 0 {
+		return fmt.Errorf("%w: %s", audiocontrol.ErrDeviceNotFound, deviceID)
+	}
+	b.volumes[deviceID] = utils.ClampVolume(volume)
+	return nil
+}
+
+// GetMute reports whether a device is muted
+func (b *Backend) GetMute(deviceID string) (bool, error) {
+	b.delay()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.indexLocked(deviceID) < 0 {
+		return false, fmt.Errorf("%w: %s", audiocontrol.ErrDeviceNotFound, deviceID)
+	}
+	return b.mutes[deviceID], nil
+}
+
+// SetMute mutes or unmutes a device
+func (b *Backend) SetMute(deviceID string, muted bool) error {
+	b.delay()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.indexLocked(deviceID) < 0 {
+		return fmt.Errorf("%w: %s", audiocontrol.ErrDeviceNotFound, deviceID)
+	}
+	b.mutes[deviceID] = muted
+	return nil
+}
+
+// OnDeviceChange registers a callback for device events. Unlike the native
+// backends, every registered callback keeps receiving events.
+func (b *Backend) OnDeviceChange(callback func(audiocontrol.Event)) {
+	b.mu.Lock()
+	b.callbacks = append(b.callbacks, callback)
+	b.mu.Unlock()
+}
+
+// virtualDevice is a device created by CreateVirtualDevice
+type virtualDevice struct {
+	backend *Backend
+	id      string
+
+	once sync.Once
+	err  error
+}
+
+func (d *virtualDevice) DeviceID() string {
+	return d.id
+}
+
+// Close removes the device and emits DeviceRemoved. A device removed with
+// RemoveDevice in the meantime is not an error.
+func (d *virtualDevice) Close() error {
+	d.once.Do(func() {
+		d.backend.mu.Lock()
+		delete(d.backend.virtual, d)
+		d.backend.mu.Unlock()
+
+		if d.id != "" {
+			if err := d.backend.RemoveDevice(d.id); err != nil && !errors.Is(err, audiocontrol.ErrDeviceNotFound) {
+				d.err = err
+			}
+		}
+	})
+	return d.err
+}
+
+// CreateVirtualDevice adds an output for null and combined sinks and emits
+// DeviceAdded. Loopbacks only check that their devices exist.
+func (b *Backend) CreateVirtualDevice(spec audiocontrol.VirtualDeviceSpec) (audiocontrol.VirtualDevice, error) {
+	b.mu.Lock()
+	for _, id := range append([]string{spec.Source, spec.Sink}, spec.Outputs...) {
+		if id != "" && b.indexLocked(id) < 0 {
+			b.mu.Unlock()
+			return nil, fmt.Errorf("%w: %s", audiocontrol.ErrDeviceNotFound, id)
+		}
+	}
+	d := &virtualDevice{backend: b}
+	if spec.Kind != audiocontrol.VirtualLoopback {
+		if spec.Name == "" || b.indexLocked(spec.Name) >= 0 {
+			b.mu.Unlock()
+			return nil, fmt.Errorf("invalid virtual device name %q", spec.Name)
+		}
+		d.id = spec.Name
+	}
+	b.virtual[d] = true
+	b.mu.Unlock()
+
+	if d.id != "" {
+		name := spec.Description
+		if name == "" {
+			name = spec.Name
+		}
+		b.AddDevice(audiocontrol.AudioDevice{ID: d.id, Name: name, IsOutput: true, IsConnected: true})
+	}
+	return d, nil
+}
+
+// CloseVirtualDevices closes every device created by CreateVirtualDevice
+func (b *Backend) CloseVirtualDevices() error {
+	b.mu.Lock()
+	devices := make([]*virtualDevice, 0, len(b.virtual))
+	for d := range b.virtual {
+		devices = append(devices, d)
+	}
+	b.mu.Unlock()
+
+	var errs []error
+	for _, d := range devices {
+		if err := d.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// meter is a level meter opened with Meter
+type meter struct {
+	deviceID string
+	levels   chan audiocontrol.Level
+}
+
+// Meter returns a channel of the levels given to SendLevel for the device.
+// The interval is ignored. The channel is closed by stop or when the device
+// is removed.
+func (b *Backend) Meter(deviceID string, interval time.Duration) (<-chan audiocontrol.Level, func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.indexLocked(deviceID) < 0 {
+		return nil, nil, fmt.Errorf("%w: %s", audiocontrol.ErrDeviceNotFound, deviceID)
+	}
+	m := &meter{deviceID: deviceID, levels: make(chan audiocontrol.Level, 1)}
+	b.meters[m] = true
+	stop := func() {
+		b.mu.Lock()
+		b.stopMeterLocked(m)
+		b.mu.Unlock()
+	}
+	return m.levels, stop, nil
+}
+
+// SendLevel delivers a level to the meters of a device. Like a real
+// backend it drops the level for meters whose receiver is behind.
+func (b *Backend) SendLevel(deviceID string, level audiocontrol.Level) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for m := range b.meters {
+		if m.deviceID != deviceID {
+			continue
+		}
+		select {
+		case m.levels <- level:
+		default:
+		}
+	}
+}
+
+func (b *Backend) stopMeterLocked(m *meter) {
+	if b.meters[m] {
+		delete(b.meters, m)
+		close(m.levels)
+	}
+}
+
+// Play reads pcm to the end, records it as played to the output and sends
+// it to the loopbacks of the output. The format is not checked against the
+// data.
+func (b *Backend) Play(deviceID string, format audiocontrol.Format, pcm io.Reader) error {
+	b.mu.Lock()
+	i := b.indexLocked(deviceID)
+	if i < 0 || !b.devices[i].IsOutput {
+		b.mu.Unlock()
+		return fmt.Errorf("%w: no output device %s", audiocontrol.ErrDeviceNotFound, deviceID)
+	}
+	b.mu.Unlock()
+
+	data, err := io.ReadAll(pcm)
+	if err != nil {
+		return err
+	}
+	data = data[:len(data)-len(data)%format.FrameSize()]
+	b.mu.Lock()
+	b.played[deviceID] = append(b.played[deviceID], data...)
+	b.sendCaptureLocked(deviceID, data)
+	b.mu.Unlock()
+	return nil
+}
+
+// Played returns the audio played to an output so far
+func (b *Backend) Played(deviceID string) []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.played[deviceID]...)
+}
+
+// capture is a stream opened with OpenCapture
+type capture struct {
+	b        *Backend
+	deviceID string
+	format   audiocontrol.Format
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	buf  []byte
+	err  error
+}
+
+// OpenCapture returns a stream of the PCM given to SendCapture for the
+// input. A format without a channel count or rate gets stereo at 48kHz.
+// Reads fail with ErrDeviceNotFound once the device is removed.
+func (b *Backend) OpenCapture(deviceID string, format audiocontrol.Format) (audiocontrol.CaptureStream, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	i := b.indexLocked(deviceID)
+	if i < 0 || !b.devices[i].IsInput {
+		return nil, fmt.Errorf("%w: no input device %s", audiocontrol.ErrDeviceNotFound, deviceID)
+	}
+	return b.openCaptureLocked(deviceID, format), nil
+}
+
+// OpenLoopback returns a stream of the PCM played to the output with Play
+// or given to SendCapture, negotiated like OpenCapture
+func (b *Backend) OpenLoopback(deviceID string, format audiocontrol.Format) (audiocontrol.CaptureStream, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	i := b.indexLocked(deviceID)
+	if i < 0 || !b.devices[i].IsOutput {
+		return nil, fmt.Errorf("%w: no output device %s", audiocontrol.ErrDeviceNotFound, deviceID)
+	}
+	return b.openCaptureLocked(deviceID, format), nil
+}
+
+func (b *Backend) openCaptureLocked(deviceID string, format audiocontrol.Format) *capture {
+	if format.Channels == 0 {
+		format.Channels = 2
+	}
+	if format.Rate == 0 {
+		format.Rate = 48000
+	}
+	c := &capture{b: b, deviceID: deviceID, format: format}
+	c.cond = sync.NewCond(&c.mu)
+	b.captures[c] = true
+	return c
+}
+
+// SendCapture delivers PCM to the captures of an input, or the loopbacks
+// of an output, as is, whatever format they asked for
+func (b *Backend) SendCapture(deviceID string, pcm []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sendCaptureLocked(deviceID, pcm)
+}
+
+func (b *Backend) sendCaptureLocked(deviceID string, pcm []byte) {
+	for c := range b.captures {
+		if c.deviceID != deviceID {
+			continue
+		}
+		c.mu.Lock()
+		c.buf = append(c.buf, pcm...)
+		c.cond.Broadcast()
+		c.mu.Unlock()
+	}
+}
+
+func (c *capture) Format() audiocontrol.Format {
+	return c.format
+}
+
+func (c *capture) Read(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.buf) == 0 && c.err == nil {
+		c.cond.Wait()
+	}
+	if len(c.buf) == 0 {
+		return 0, c.err
+	}
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
+
+func (c *capture) Close() error {
+	c.b.mu.Lock()
+	delete(c.b.captures, c)
+	c.b.mu.Unlock()
+	c.end(io.EOF)
+	return nil
+}
+
+// end makes reads fail with err once the buffer is drained
+func (c *capture) end(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = err
+	}
+	c.cond.Broadcast()
+}
+
+// withDefaults fills in the default flags of a device described only by the
+// legacy IsActive field
+func withDefaults(d audiocontrol.AudioDevice) audiocontrol.AudioDevice {
+	if d.IsActive && !d.IsDefaultOutput && !d.IsDefaultInput {
+		d.IsDefaultOutput = d.IsOutput
+		d.IsDefaultInput = d.IsInput
+		d.IsDefaultCommunications = true
+	}
+	return d
+}
+
+func (b *Backend) indexLocked(deviceID string) int {
+	for i, d := range b.devices {
+		if d.ID == deviceID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (b *Backend) cardIndexLocked(cardID string) int {
+	for i, c := range b.cards {
+		if c.ID == cardID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (b *Backend) sessionIndexLocked(sessionID string) int {
+	for i, s := range b.sessions {
+		if s.ID == sessionID {
+			return i
+		}
+	}
+	return -1
+}
+
+func copyCard(c audiocontrol.Card) audiocontrol.Card {
+	c.Profiles = append([]audiocontrol.Profile(nil), c.Profiles...)
+	c.DeviceIDs = append([]string(nil), c.DeviceIDs...)
+	return c
+}
+
+func (b *Backend) active(output bool) (audiocontrol.AudioDevice, error) {
+	b.delay()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, d := range b.devices {
+		if (output && d.IsDefaultOutput) || (!output && d.IsDefaultInput) {
+			return d, nil
+		}
+	}
+	if output {
+		return audiocontrol.AudioDevice{}, fmt.Errorf("no default output device found")
+	}
+	return audiocontrol.AudioDevice{}, fmt.Errorf("no default input device found")
+}
+
+func (b *Backend) setActive(deviceID string, output bool) error {
+	b.delay()
+	b.mu.Lock()
+	i := b.indexLocked(deviceID)
+	if i < 0 || (output && !b.devices[i].IsOutput) || (!output && !b.devices[i].IsInput) {
+		b.mu.Unlock()
+		if output {
+			return fmt.Errorf("%w: no output device %s", audiocontrol.ErrDeviceNotFound, deviceID)
+		}
+		return fmt.Errorf("%w: no input device %s", audiocontrol.ErrDeviceNotFound, deviceID)
+	}
+	for j := range b.devices {
+		d := &b.devices[j]
+		if output && d.IsOutput {
+			d.IsDefaultOutput = j == i
+		} else if !output && d.IsInput {
+			d.IsDefaultInput = j == i
+		}
+		// The fake has no separate communications role
+		d.IsDefaultCommunications = d.IsDefaultOutput || d.IsDefaultInput
+		d.IsActive = d.IsDefaultOutput || d.IsDefaultInput
+	}
+	device := b.devices[i]
+	b.mu.Unlock()
+
+	b.Emit(audiocontrol.Event{Type: audiocontrol.ActiveDeviceChanged, DeviceID: deviceID, Info: &device})
+	return nil
+}
@@ -0,0 +1,85 @@
+package audiocontrol
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/audi70r/go-audio-control/internal/dsp"
+	"github.com/audi70r/go-audio-control/internal/wav"
+)
+
+// CaptureStream is interleaved PCM recorded from a device. Reads block
+// until audio is available; audio that is not read in time is dropped.
+// After Close, reads return io.EOF, so closing from another goroutine ends
+// a copy cleanly.
+type CaptureStream interface {
+	io.ReadCloser
+	// Format returns the format of the PCM, with the channel count and rate
+	// filled in if the request left them zero
+	Format() Format
+}
+
+// Capturer is implemented by backends that can record from an input
+type Capturer interface {
+	OpenCapture(deviceID string, format Format) (CaptureStream, error)
+}
+
+// OpenCapture records an input in format. A format without a channel count
+// or rate takes those of the device; the sound server converts the rest.
+func OpenCapture(deviceID string, format Format) (CaptureStream, error) {
+	if err := format.validateRequest(); err != nil {
+		return nil, err
+	}
+	if c, ok := currentBackend().(Capturer); ok {
+		return c.OpenCapture(deviceID, format)
+	}
+	return nil, ErrNotImplemented
+}
+
+// LoopbackCapturer is implemented by backends that can record what an
+// output plays
+type LoopbackCapturer interface {
+	OpenLoopback(deviceID string, format Format) (CaptureStream, error)
+}
+
+// OpenLoopback records the mix an output plays, "what you hear", such as
+// for a screen recorder. It negotiates the format like OpenCapture.
+func OpenLoopback(deviceID string, format Format) (CaptureStream, error) {
+	if err := format.validateRequest(); err != nil {
+		return nil, err
+	}
+	if c, ok := currentBackend().(LoopbackCapturer); ok {
+		return c.OpenLoopback(deviceID, format)
+	}
+	return nil, ErrNotImplemented
+}
+
+// validateRequest is validate for formats whose channel count and rate may
+// be negotiated
+func (f Format) validateRequest() error {
+	if f.Channels == 0 {
+		f.Channels = 1
+	}
+	if f.Rate == 0 {
+		f.Rate = 1
+	}
+	return f.validate()
+}
+
+// NewWAVWriter writes the header of a WAVE file holding PCM in format to w
+// and returns a writer for the samples, such as a CaptureStream copied with
+// io.Copy. Close fills in the sizes in the header when w is also an
+// io.Seeker, such as an *os.File; otherwise they are left unknown, which
+// most tools read as running to the end of the file. Close does not close
+// w.
+func NewWAVWriter(w io.Writer, format Format) (io.WriteCloser, error) {
+	if err := format.validate(); err != nil {
+		return nil, err
+	}
+	header := wav.Header{Encoding: dsp.Encoding(format.Encoding), Channels: format.Channels, Rate: format.Rate}
+	ww, err := wav.NewWriter(w, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write WAVE header: %w", err)
+	}
+	return ww, nil
+}
@@ -0,0 +1,57 @@
+package audiocontrol
+
+// Card is a physical sound card or headset. Its active profile decides which
+// devices it provides: a Bluetooth headset offers a high quality A2DP profile
+// without a microphone and a headset profile with one.
+type Card struct {
+	ID            string
+	Name          string
+	Profiles      []Profile
+	ActiveProfile string
+	DeviceIDs     []string
+}
+
+// Profile is one configuration of a card. Outputs and Inputs count the
+// devices the card provides while the profile is active.
+type Profile struct {
+	Name        string
+	Description string
+	Outputs     int
+	Inputs      int
+	Priority    uint32
+	Available   bool
+	IsActive    bool
+}
+
+// CardController is implemented by backends that can list cards and switch
+// their profiles. Unknown card IDs are reported with ErrDeviceNotFound.
+type CardController interface {
+	ListCards() ([]Card, error)
+	ListProfiles(cardID string) ([]Profile, error)
+	SetProfile(cardID, profile string) error
+}
+
+// ListCards returns the sound cards of the system
+func ListCards() ([]Card, error) {
+	if c, ok := currentBackend().(CardController); ok {
+		return c.ListCards()
+	}
+	return nil, ErrNotImplemented
+}
+
+// ListProfiles returns the profiles of a card
+func ListProfiles(cardID string) ([]Profile, error) {
+	if c, ok := currentBackend().(CardController); ok {
+		return c.ListProfiles(cardID)
+	}
+	return nil, ErrNotImplemented
+}
+
+// SetProfile switches a card to the named profile. The devices of the card
+// are usually replaced, so expect DeviceRemoved and DeviceAdded events.
+func SetProfile(cardID, profile string) error {
+	if c, ok := currentBackend().(CardController); ok {
+		return c.SetProfile(cardID, profile)
+	}
+	return ErrNotImplemented
+}
@@ -0,0 +1,342 @@
+// Package client talks to an audiod daemon. A Client implements
+// audiocontrol.Backend, so applications can swap the native backend for a
+// shared daemon without other changes:
+//
+//	c, err := client.New(daemon.DefaultAddress(), token)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer c.Close()
+//	audiocontrol.SetBackend(c)
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+	"github.com/audi70r/go-audio-control/daemon"
+)
+
+// reconnectDelay is how long the event stream waits before reconnecting
+// after the daemon goes away
+const reconnectDelay = time.Second
+
+// Client is an audiocontrol.Backend backed by an audiod daemon
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+var (
+	_ audiocontrol.Backend           = (*Client)(nil)
+	_ audiocontrol.PortController    = (*Client)(nil)
+	_ audiocontrol.CardController    = (*Client)(nil)
+	_ audiocontrol.SessionController = (*Client)(nil)
+	_ audiocontrol.SessionMover      = (*Client)(nil)
+)
+
+// New returns a Client for the daemon listening on address, which uses the
+// same format as daemon.Listen. No connection is made until the first call.
+func New(address, token string) (*Client, error) {
+	network, addr, err := daemon.ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &http.Transport{}
+	baseURL := "http://" + addr
+	if network == "unix" {
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", addr)
+		}
+		baseURL = "http://audiod"
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Client{
+		baseURL: baseURL,
+		token:   token,
+		http:    &http.Client{Transport: transport},
+		ctx:     ctx,
+		cancel:  cancel,
+	}, nil
+}
+
+// Close stops all event streams started by OnDeviceChange
+func (c *Client) Close() error {
+	c.cancel()
+	c.wg.Wait()
+	return nil
+}
+
+// ListAudioDevices enumerates all audio devices known to the daemon
+func (c *Client) ListAudioDevices() ([]audiocontrol.AudioDevice, error) {
+	var devices []audiocontrol.AudioDevice
+	err := c.do(http.MethodGet, "/v1/devices", nil, &devices)
+	return devices, err
+}
+
+// ListAudioDevicesWithOptions enumerates the devices selected by opts
+func (c *Client) ListAudioDevicesWithOptions(opts audiocontrol.ListOptions) ([]audiocontrol.AudioDevice, error) {
+	path := "/v1/devices"
+	if q := daemon.ListOptionsQuery(opts); len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	var devices []audiocontrol.AudioDevice
+	err := c.do(http.MethodGet, path, nil, &devices)
+	return devices, err
+}
+
+// GetActiveOutputDevice returns the currently active output device
+func (c *Client) GetActiveOutputDevice() (audiocontrol.AudioDevice, error) {
+	var device audiocontrol.AudioDevice
+	err := c.do(http.MethodGet, "/v1/default/output", nil, &device)
+	return device, err
+}
+
+// SetActiveOutputDevice sets the active output device by ID
+func (c *Client) SetActiveOutputDevice(deviceID string) error {
+	return c.do(http.MethodPut, "/v1/default/output", daemon.DefaultRequest{DeviceID: deviceID}, nil)
+}
+
+// GetActiveInputDevice returns the currently active input device
+func (c *Client) GetActiveInputDevice() (audiocontrol.AudioDevice, error) {
+	var device audiocontrol.AudioDevice
+	err := c.do(http.MethodGet, "/v1/default/input", nil, &device)
+	return device, err
+}
+
+// SetActiveInputDevice sets the active input device by ID
+func (c *Client) SetActiveInputDevice(deviceID string) error {
+	return c.do(http.MethodPut, "/v1/default/input", daemon.DefaultRequest{DeviceID: deviceID}, nil)
+}
+
+// GetVolume returns the volume of a device
+func (c *Client) GetVolume(deviceID string) (float64, error) {
+	var state daemon.VolumeState
+	err := c.do(http.MethodGet, devicePath(deviceID, "volume"), nil, &state)
+	return state.Volume, err
+}
+
+// SetVolume sets the volume of a device
+func (c *Client) SetVolume(deviceID string, volume float64) error {
+	return c.do(http.MethodPut, devicePath(deviceID, "volume"), daemon.VolumeState{Volume: volume}, nil)
+}
+
+// GetMute reports whether a device is muted
+func (c *Client) GetMute(deviceID string) (bool, error) {
+	var state daemon.MuteState
+	err := c.do(http.MethodGet, devicePath(deviceID, "mute"), nil, &state)
+	return state.Muted, err
+}
+
+// SetMute mutes or unmutes a device
+func (c *Client) SetMute(deviceID string, muted bool) error {
+	return c.do(http.MethodPut, devicePath(deviceID, "mute"), daemon.MuteState{Muted: muted}, nil)
+}
+
+// ListPorts returns the ports of a device
+func (c *Client) ListPorts(deviceID string) ([]audiocontrol.Port, error) {
+	var ports []audiocontrol.Port
+	err := c.do(http.MethodGet, devicePath(deviceID, "ports"), nil, &ports)
+	return ports, err
+}
+
+// SetActivePort switches a device to the named port
+func (c *Client) SetActivePort(deviceID, port string) error {
+	return c.do(http.MethodPut, devicePath(deviceID, "ports"), daemon.PortRequest{Port: port}, nil)
+}
+
+// ListCards returns the sound cards known to the daemon
+func (c *Client) ListCards() ([]audiocontrol.Card, error) {
+	var cards []audiocontrol.Card
+	err := c.do(http.MethodGet, "/v1/cards", nil, &cards)
+	return cards, err
+}
+
+// ListProfiles returns the profiles of a card
+func (c *Client) ListProfiles(cardID string) ([]audiocontrol.Profile, error) {
+	var profiles []audiocontrol.Profile
+	err := c.do(http.MethodGet, profilesPath(cardID), nil, &profiles)
+	return profiles, err
+}
+
+// SetProfile switches a card to the named profile
+func (c *Client) SetProfile(cardID, profile string) error {
+	return c.do(http.MethodPut, profilesPath(cardID), daemon.ProfileRequest{Profile: profile}, nil)
+}
+
+// ListSessions returns the application sessions on a device, or on every
+// device when deviceID is empty
+func (c *Client) ListSessions(deviceID string) ([]audiocontrol.Session, error) {
+	path := "/v1/sessions"
+	if deviceID != "" {
+		path += "?" + url.Values{"device": {deviceID}}.Encode()
+	}
+	var sessions []audiocontrol.Session
+	err := c.do(http.MethodGet, path, nil, &sessions)
+	return sessions, err
+}
+
+// SetSessionVolume sets the volume of a session
+func (c *Client) SetSessionVolume(sessionID string, volume float64) error {
+	return c.do(http.MethodPut, sessionPath(sessionID, "volume"), daemon.VolumeState{Volume: volume}, nil)
+}
+
+// SetSessionMute mutes or unmutes a session
+func (c *Client) SetSessionMute(sessionID string, muted bool) error {
+	return c.do(http.MethodPut, sessionPath(sessionID, "mute"), daemon.MuteState{Muted: muted}, nil)
+}
+
+// MoveSession moves a session to another device
+func (c *Client) MoveSession(sessionID, deviceID string) error {
+	return c.do(http.MethodPut, sessionPath(sessionID, "device"), daemon.MoveRequest{DeviceID: deviceID}, nil)
+}
+
+// OnDeviceChange registers a callback for audio device events. Each call
+// opens its own event stream, which reconnects automatically until Close.
+func (c *Client) OnDeviceChange(callback func(audiocontrol.Event)) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for {
+			c.streamEvents(callback)
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-time.After(reconnectDelay):
+			}
+		}
+	}()
+}
+
+// streamEvents reads one event stream until it fails or the client closes
+func (c *Client) streamEvents(callback func(audiocontrol.Event)) {
+	req, err := c.newRequest(c.ctx, http.MethodGet, "/v1/events", nil)
+	if err != nil {
+		return
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var event audiocontrol.Event
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+			continue
+		}
+		callback(event)
+	}
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// do performs a request and decodes a successful response into out
+func (c *Client) do(method, path string, body, out interface{}) error {
+	req, err := c.newRequest(context.Background(), method, path, body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("audiod request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// remoteError carries the message of an error returned by the daemon and,
+// where its code identifies one, the matching sentinel error
+type remoteError struct {
+	message  string
+	sentinel error
+}
+
+func (e *remoteError) Error() string { return e.message }
+
+func (e *remoteError) Unwrap() error { return e.sentinel }
+
+// responseError converts an error response back into the error the backend
+// returned, restoring sentinel errors so errors.Is keeps working
+func responseError(resp *http.Response) error {
+	var body daemon.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error == "" {
+		body.Error = resp.Status
+	}
+
+	err := &remoteError{message: body.Error}
+	switch body.Code {
+	case daemon.CodeNotImplemented:
+		err.sentinel = audiocontrol.ErrNotImplemented
+	case daemon.CodeDeviceNotFound:
+		err.sentinel = audiocontrol.ErrDeviceNotFound
+	}
+	return err
+}
+
+func devicePath(deviceID, property string) string {
+	return "/v1/devices/" + url.PathEscape(deviceID) + "/" + property
+}
+
+func profilesPath(cardID string) string {
+	return "/v1/cards/" + url.PathEscape(cardID) + "/profiles"
+}
+
+func sessionPath(sessionID, property string) string {
+	return "/v1/sessions/" + url.PathEscape(sessionID) + "/" + property
+}
@@ -0,0 +1,369 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+	"github.com/audi70r/go-audio-control/audiotest"
+	"github.com/audi70r/go-audio-control/daemon"
+)
+
+// startDaemon serves backend on a unix socket and returns its address
+func startDaemon(t *testing.T, backend audiocontrol.Backend, token string) string {
+	t.Helper()
+	return serve(t, daemon.NewServer(backend, token))
+}
+
+// serve serves handler on a unix socket and returns its address
+func serve(t *testing.T, handler http.Handler) string {
+	t.Helper()
+	address := "unix:" + filepath.Join(t.TempDir(), "audiod.sock")
+	listener, err := daemon.Listen(address)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := &http.Server{Handler: handler}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+	return address
+}
+
+func newTestClient(t *testing.T) (*Client, *audiotest.Backend) {
+	t.Helper()
+	backend := audiotest.NewBackend(
+		audiocontrol.AudioDevice{ID: "speakers", Name: "Speakers", IsOutput: true, IsActive: true, IsConnected: true},
+		audiocontrol.AudioDevice{ID: "headset", Name: "Headset", IsOutput: true, IsConnected: true},
+		audiocontrol.AudioDevice{ID: "mic", Name: "Microphone", IsInput: true, IsActive: true, IsConnected: true},
+	)
+	c, err := New(startDaemon(t, backend, "secret"), "secret")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c, backend
+}
+
+func TestClientDefaults(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	devices, err := c.ListAudioDevices()
+	if err != nil {
+		t.Fatalf("Failed to list devices: %v", err)
+	}
+	if len(devices) != 3 {
+		t.Fatalf("Expected 3 devices, got %d", len(devices))
+	}
+
+	if err := c.SetActiveOutputDevice("headset"); err != nil {
+		t.Fatalf("Failed to set output device: %v", err)
+	}
+	output, err := c.GetActiveOutputDevice()
+	if err != nil {
+		t.Fatalf("Failed to get output device: %v", err)
+	}
+	if output.ID != "headset" {
+		t.Fatalf("Expected headset to be the output, got %s", output.ID)
+	}
+
+	input, err := c.GetActiveInputDevice()
+	if err != nil {
+		t.Fatalf("Failed to get input device: %v", err)
+	}
+	if input.ID != "mic" {
+		t.Fatalf("Expected mic to be the input, got %s", input.ID)
+	}
+
+	if err := c.SetActiveInputDevice("speakers"); err == nil {
+		t.Fatal("Expected an error setting an output-only device as input")
+	}
+}
+
+func TestClientVolumeAndMute(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	if err := c.SetVolume("speakers", 0.4); err != nil {
+		t.Fatalf("Failed to set volume: %v", err)
+	}
+	volume, err := c.GetVolume("speakers")
+	if err != nil {
+		t.Fatalf("Failed to get volume: %v", err)
+	}
+	if volume != 0.4 {
+		t.Fatalf("Expected volume 0.4, got %v", volume)
+	}
+
+	if err := c.SetMute("speakers", true); err != nil {
+		t.Fatalf("Failed to set mute: %v", err)
+	}
+	muted, err := c.GetMute("speakers")
+	if err != nil {
+		t.Fatalf("Failed to get mute: %v", err)
+	}
+	if !muted {
+		t.Fatal("Expected speakers to be muted")
+	}
+}
+
+func TestClientEvents(t *testing.T) {
+	c, backend := newTestClient(t)
+
+	events := make(chan audiocontrol.Event, 1)
+	c.OnDeviceChange(func(event audiocontrol.Event) {
+		select {
+		case events <- event:
+		default:
+		}
+	})
+
+	// Keep adding until the stream has connected and delivers one
+	deadline := time.After(5 * time.Second)
+	for {
+		backend.AddDevice(audiocontrol.AudioDevice{ID: "hdmi", Name: "HDMI", IsOutput: true})
+		select {
+		case event := <-events:
+			if event.Type != audiocontrol.DeviceAdded || event.DeviceID != "hdmi" || event.Info == nil || event.Info.Name != "HDMI" {
+				t.Fatalf("Unexpected event %+v", event)
+			}
+			return
+		case <-time.After(50 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("No event received")
+		}
+	}
+}
+
+func TestClientBadToken(t *testing.T) {
+	backend := audiotest.NewBackend()
+	c, err := New(startDaemon(t, backend, "secret"), "wrong")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	if _, err := c.ListAudioDevices(); err == nil {
+		t.Fatal("Expected an error with a bad token")
+	}
+}
+
+// notImplementedBackend is a backend that cannot list devices
+type notImplementedBackend struct {
+	*audiotest.Backend
+}
+
+func (notImplementedBackend) ListAudioDevices() ([]audiocontrol.AudioDevice, error) {
+	return nil, audiocontrol.ErrNotImplemented
+}
+
+func (notImplementedBackend) ListAudioDevicesWithOptions(audiocontrol.ListOptions) ([]audiocontrol.AudioDevice, error) {
+	return nil, audiocontrol.ErrNotImplemented
+}
+
+func TestClientNotImplemented(t *testing.T) {
+	backend := notImplementedBackend{audiotest.NewBackend()}
+	c, err := New(startDaemon(t, backend, ""), "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	_, err = c.ListAudioDevices()
+	if !errors.Is(err, audiocontrol.ErrNotImplemented) {
+		t.Fatalf("Expected ErrNotImplemented, got %v", err)
+	}
+}
+
+func TestClientDeviceNotFound(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	if _, err := c.GetVolume("missing"); !errors.Is(err, audiocontrol.ErrDeviceNotFound) {
+		t.Fatalf("Expected ErrDeviceNotFound, got %v", err)
+	}
+	if err := c.SetActiveOutputDevice("missing"); !errors.Is(err, audiocontrol.ErrDeviceNotFound) {
+		t.Fatalf("Expected ErrDeviceNotFound, got %v", err)
+	}
+}
+
+// oldDaemon answers every request with the 404 of an unknown endpoint
+type oldDaemon struct{}
+
+func (oldDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte(`{"Error":"not found"}`))
+}
+
+func TestClientUnknownEndpoint(t *testing.T) {
+	c, err := New(serve(t, oldDaemon{}), "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	_, err = c.ListCards()
+	if err == nil || errors.Is(err, audiocontrol.ErrDeviceNotFound) {
+		t.Fatalf("Expected an error other than ErrDeviceNotFound, got %v", err)
+	}
+}
+
+func TestClientListOptions(t *testing.T) {
+	c, backend := newTestClient(t)
+	if err := backend.SetState("headset", audiocontrol.StateUnplugged); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+
+	devices, err := c.ListAudioDevices()
+	if err != nil {
+		t.Fatalf("Failed to list audio devices: %v", err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("Expected 2 active devices, got %+v", devices)
+	}
+
+	devices, err = c.ListAudioDevicesWithOptions(audiocontrol.ListOptions{
+		IncludeUnplugged: true,
+		Direction:        audiocontrol.DirectionOutput,
+	})
+	if err != nil {
+		t.Fatalf("Failed to list audio devices: %v", err)
+	}
+	if len(devices) != 2 || devices[1].ID != "headset" || devices[1].State != audiocontrol.StateUnplugged {
+		t.Fatalf("Unexpected devices %+v", devices)
+	}
+}
+
+func TestClientPorts(t *testing.T) {
+	c, backend := newTestClient(t)
+	backend.SetPorts("speakers", []audiocontrol.Port{
+		{Name: "speaker", Description: "Speakers", Type: audiocontrol.PortTypeSpeaker, IsActive: true},
+		{Name: "headphones", Description: "Headphones", Type: audiocontrol.PortTypeHeadphones, Available: audiocontrol.PortUnavailable},
+	})
+
+	ports, err := c.ListPorts("speakers")
+	if err != nil {
+		t.Fatalf("Failed to list ports: %v", err)
+	}
+	if len(ports) != 2 || ports[1].Type != audiocontrol.PortTypeHeadphones || ports[1].Available != audiocontrol.PortUnavailable {
+		t.Fatalf("Unexpected ports %+v", ports)
+	}
+
+	if err := c.SetActivePort("speakers", "headphones"); err != nil {
+		t.Fatalf("Failed to set port: %v", err)
+	}
+	ports, _ = backend.ListPorts("speakers")
+	if ports[0].IsActive || !ports[1].IsActive {
+		t.Fatalf("Expected headphones to be active, got %+v", ports)
+	}
+
+	if _, err := c.ListPorts("missing"); !errors.Is(err, audiocontrol.ErrDeviceNotFound) {
+		t.Fatalf("Expected ErrDeviceNotFound, got %v", err)
+	}
+}
+
+func TestClientCards(t *testing.T) {
+	c, backend := newTestClient(t)
+	backend.AddCard(audiocontrol.Card{
+		ID:   "headset-card",
+		Name: "Headset",
+		Profiles: []audiocontrol.Profile{
+			{Name: "a2dp_sink", Outputs: 1, Available: true, IsActive: true},
+			{Name: "headset_head_unit", Outputs: 1, Inputs: 1, Available: true},
+		},
+		ActiveProfile: "a2dp_sink",
+		DeviceIDs:     []string{"headset"},
+	})
+
+	events := make(chan audiocontrol.Event, 1)
+	c.OnDeviceChange(func(e audiocontrol.Event) {
+		select {
+		case events <- e:
+		default:
+		}
+	})
+
+	cards, err := c.ListCards()
+	if err != nil {
+		t.Fatalf("Failed to list cards: %v", err)
+	}
+	if len(cards) != 1 || cards[0].ID != "headset-card" || len(cards[0].Profiles) != 2 {
+		t.Fatalf("Unexpected cards %+v", cards)
+	}
+
+	// Keep switching until the stream has connected and delivers one
+	deadline := time.After(5 * time.Second)
+	for {
+		if err := c.SetProfile("headset-card", "headset_head_unit"); err != nil {
+			t.Fatalf("Failed to set profile: %v", err)
+		}
+		select {
+		case e := <-events:
+			if e.Type != audiocontrol.ProfileChanged || e.Card == nil || e.Card.ActiveProfile != "headset_head_unit" {
+				t.Fatalf("Unexpected event %+v", e)
+			}
+			profiles, err := c.ListProfiles("headset-card")
+			if err != nil {
+				t.Fatalf("Failed to list profiles: %v", err)
+			}
+			if profiles[0].IsActive || !profiles[1].IsActive {
+				t.Fatalf("Unexpected profiles %+v", profiles)
+			}
+			if _, err := c.ListProfiles("missing"); !errors.Is(err, audiocontrol.ErrDeviceNotFound) {
+				t.Fatalf("Expected ErrDeviceNotFound, got %v", err)
+			}
+			return
+		case <-time.After(50 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("No ProfileChanged event")
+		}
+	}
+}
+
+func TestClientSessions(t *testing.T) {
+	c, backend := newTestClient(t)
+	// Windows session IDs contain characters that need escaping
+	browser := `{0.0.0.00000000}.{a1b2}|\Device\HarddiskVolume3\firefox.exe%b{00000000}`
+	backend.AddSession(audiocontrol.Session{ID: browser, DeviceID: "speakers", AppName: "Firefox", PID: 4242, Volume: 1, State: audiocontrol.SessionActive})
+	backend.AddSession(audiocontrol.Session{ID: "call", DeviceID: "mic", AppName: "Zoom", Volume: 1, IsInput: true})
+
+	sessions, err := c.ListSessions("")
+	if err != nil {
+		t.Fatalf("Failed to list sessions: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions, got %+v", sessions)
+	}
+
+	if err := c.SetSessionVolume(browser, 0.3); err != nil {
+		t.Fatalf("Failed to set session volume: %v", err)
+	}
+	if err := c.SetSessionMute(browser, true); err != nil {
+		t.Fatalf("Failed to set session mute: %v", err)
+	}
+	sessions, err = c.ListSessions("speakers")
+	if err != nil {
+		t.Fatalf("Failed to list sessions: %v", err)
+	}
+	want := audiocontrol.Session{ID: browser, DeviceID: "speakers", AppName: "Firefox", PID: 4242, Volume: 0.3, Muted: true, State: audiocontrol.SessionActive}
+	if len(sessions) != 1 || sessions[0] != want {
+		t.Errorf("Sessions are %+v, want %+v", sessions, want)
+	}
+
+	if err := c.SetSessionMute("missing", true); !errors.Is(err, audiocontrol.ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound, got %v", err)
+	}
+	if _, err := c.ListSessions("missing"); !errors.Is(err, audiocontrol.ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound, got %v", err)
+	}
+
+	if err := c.MoveSession(browser, "headset"); err != nil {
+		t.Fatalf("Failed to move session: %v", err)
+	}
+	if sessions, _ := c.ListSessions("headset"); len(sessions) != 1 || sessions[0].ID != browser {
+		t.Errorf("Expected the session on headset, got %+v", sessions)
+	}
+	if err := c.MoveSession("call", "speakers"); !errors.Is(err, audiocontrol.ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound for a wrong direction, got %v", err)
+	}
+}
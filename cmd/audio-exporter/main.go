@@ -0,0 +1,34 @@
+// Command audio-exporter serves audio device metrics for Prometheus on
+// /metrics. See package exporter for the metrics.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+	"github.com/audi70r/go-audio-control/exporter"
+)
+
+func main() {
+	address := flag.String("listen", ":9583", "address to serve /metrics on")
+	flag.Parse()
+
+	collector, err := exporter.NewCollector(audiocontrol.Native())
+	if err != nil {
+		log.Fatal("Failed to create collector:", err)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+
+	http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	fmt.Printf("Serving metrics on %s/metrics\n", *address)
+	log.Fatal(http.ListenAndServe(*address, nil))
+}
@@ -0,0 +1,45 @@
+// Command audio-mqtt mirrors the audio devices of this machine onto an MQTT
+// broker. See package mqttbridge for the topic layout.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+	"github.com/audi70r/go-audio-control/mqttbridge"
+)
+
+func main() {
+	broker := flag.String("broker", "tcp://localhost:1883", "MQTT broker URL")
+	username := flag.String("username", "", "MQTT username (password from $MQTT_PASSWORD)")
+	prefix := flag.String("prefix", "audiocontrol", "topic prefix")
+	node := flag.String("node", "", "node ID used in topics (default host name)")
+	discovery := flag.Bool("discovery", true, "publish Home Assistant discovery payloads")
+	discoveryPrefix := flag.String("discovery-prefix", "homeassistant", "Home Assistant discovery prefix")
+	flag.Parse()
+
+	bridge := mqttbridge.New(audiocontrol.Native(), mqttbridge.Config{
+		Broker:          *broker,
+		Username:        *username,
+		Password:        os.Getenv("MQTT_PASSWORD"),
+		TopicPrefix:     *prefix,
+		NodeID:          *node,
+		Discovery:       *discovery,
+		DiscoveryPrefix: *discoveryPrefix,
+	})
+	if err := bridge.Start(); err != nil {
+		log.Fatal("Failed to start bridge:", err)
+	}
+	defer bridge.Stop()
+
+	fmt.Printf("Bridging audio devices to %s\n", *broker)
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+}
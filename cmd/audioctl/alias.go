@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+)
+
+func runAlias(args []string) error {
+	fs := newFlagSet("alias")
+	file := fs.String("file", "", "alias file (default: aliases.json in the user configuration directory)")
+	device := fs.String("device", "", "device to rename or hide; without it, list the devices and their aliases")
+	name := fs.String("name", "", "name to show for the device")
+	hide := fs.Bool("hide", false, "hide the device")
+	reset := fs.Bool("reset", false, "remove the alias of the device")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *file == "" {
+		path, err := audiocontrol.DefaultAliasFile()
+		if err != nil {
+			return err
+		}
+		*file = path
+	}
+	store, err := audiocontrol.OpenAliasFile(*file)
+	if err != nil {
+		return err
+	}
+	audiocontrol.SetAliasStore(store)
+
+	devices, err := audiocontrol.ListAudioDevicesWithOptions(audiocontrol.ListOptions{
+		IncludeDisabled:  true,
+		IncludeUnplugged: true,
+		IncludeHidden:    true,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *device == "" {
+		sort.Slice(devices, func(i, j int) bool { return devices[i].ID < devices[j].ID })
+		for _, d := range devices {
+			line := d.Name
+			if d.OriginalName != "" {
+				line += " (" + d.OriginalName + ")"
+			}
+			if d.Hidden {
+				line += " [hidden]"
+			}
+			fmt.Printf("%-40s %s\n    %s\n", d.ID, line, d.StableID())
+		}
+		return nil
+	}
+
+	var target *audiocontrol.AudioDevice
+	for i := range devices {
+		if devices[i].ID == *device {
+			target = &devices[i]
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("%w: %s", audiocontrol.ErrDeviceNotFound, *device)
+	}
+	alias := audiocontrol.Alias{Name: *name, Hidden: *hide}
+	if !*reset && alias == (audiocontrol.Alias{}) {
+		return errors.New("one of -name, -hide or -reset is required with -device")
+	}
+	if *reset {
+		alias = audiocontrol.Alias{}
+	}
+	if err := store.SetAlias(target.StableID(), alias); err != nil {
+		return err
+	}
+	fmt.Printf("%s: %+v\n", target.StableID(), alias)
+	return nil
+}
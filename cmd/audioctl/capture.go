@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+)
+
+func runCapture(args []string) error {
+	fs := newFlagSet("capture")
+	device := fs.String("device", "", "device to record (default: the active input, or output with -loopback)")
+	loopback := fs.Bool("loopback", false, "record what an output plays instead of an input")
+	output := fs.String("o", "capture.wav", "WAVE file to write")
+	duration := fs.Duration("duration", 5*time.Second, "length of the recording")
+	rate := fs.Int("rate", 0, "sample rate in Hz (default: that of the device)")
+	channels := fs.Int("channels", 0, "channel count (default: that of the device)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	open := audiocontrol.OpenCapture
+	active := audiocontrol.GetActiveInputDevice
+	if *loopback {
+		open = audiocontrol.OpenLoopback
+		active = audiocontrol.GetActiveOutputDevice
+	}
+	if *device == "" {
+		d, err := active()
+		if err != nil {
+			return err
+		}
+		*device = d.ID
+	}
+	capture, err := open(*device, audiocontrol.Format{
+		Encoding: audiocontrol.EncodingS16LE,
+		Channels: *channels,
+		Rate:     *rate,
+	})
+	if err != nil {
+		return err
+	}
+	defer capture.Close()
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	format := capture.Format()
+	w, err := audiocontrol.NewWAVWriter(f, format)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Recording %s to %s (%d channels at %d Hz); press Ctrl+C to stop\n",
+		*device, *output, format.Channels, format.Rate)
+
+	// Closing the capture ends the copy early
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+	go func() {
+		<-sigChan
+		capture.Close()
+	}()
+
+	frames := int64(duration.Seconds() * float64(format.Rate))
+	_, err = io.CopyN(w, capture, frames*int64(format.FrameSize()))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return w.Close()
+}
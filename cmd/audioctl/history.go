@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+	"github.com/audi70r/go-audio-control/journal"
+)
+
+func runHistory(args []string) error {
+	fs := newFlagSet("history")
+	dir := fs.String("dir", journal.DefaultDir(), "journal directory")
+	device := fs.String("device", "", "only show events for this device ID")
+	types := fs.String("type", "", "comma-separated event types, e.g. DeviceAdded,DeviceRemoved")
+	since := fs.String("since", "", "only show events after this time (RFC 3339) or duration ago (e.g. 24h)")
+	until := fs.String("until", "", "only show events before this time (RFC 3339) or duration ago")
+	limit := fs.Int("limit", 0, "only show the most recent N events")
+	stats := fs.Bool("stats", false, "show per-device statistics instead of events")
+	asJSON := fs.Bool("json", false, "print JSON lines instead of a table")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	query := journal.Query{DeviceID: *device, Limit: *limit}
+	var err error
+	if query.Since, err = parseTime(*since); err != nil {
+		return fmt.Errorf("invalid -since: %w", err)
+	}
+	if query.Until, err = parseTime(*until); err != nil {
+		return fmt.Errorf("invalid -until: %w", err)
+	}
+	if *types != "" {
+		for _, name := range strings.Split(*types, ",") {
+			t, err := audiocontrol.ParseEventType(strings.TrimSpace(name))
+			if err != nil {
+				return err
+			}
+			query.Types = append(query.Types, t)
+		}
+	}
+	if *stats {
+		// Sessions are derived from every event of every device, so only
+		// the time filters apply to the journal read
+		query.Types = nil
+		query.Limit = 0
+		query.DeviceID = ""
+	}
+
+	entries, err := journal.Read(*dir, query)
+	if err != nil {
+		return fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	if *stats {
+		return printStats(journal.Stats(entries), *device, *asJSON)
+	}
+	return printEntries(entries, *asJSON)
+}
+
+// parseTime accepts an RFC 3339 time or a duration before now
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func printEntries(entries []journal.Entry, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tEVENT\tDEVICE\tNAME")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.Time.Local().Format(time.DateTime), e.Type, e.DeviceID, deviceName(e))
+	}
+	return w.Flush()
+}
+
+// deviceName returns the name of the entry's device from its info or snapshot
+func deviceName(e journal.Entry) string {
+	if e.Info != nil && e.Info.Name != "" {
+		return e.Info.Name
+	}
+	for _, d := range e.Devices {
+		if d.ID == e.DeviceID {
+			return d.Name
+		}
+	}
+	return ""
+}
+
+func printStats(stats []journal.DeviceStats, deviceID string, asJSON bool) error {
+	if deviceID != "" {
+		var filtered []journal.DeviceStats
+		for _, s := range stats {
+			if s.DeviceID == deviceID {
+				filtered = append(filtered, s)
+			}
+		}
+		stats = filtered
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, s := range stats {
+			if err := enc.Encode(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DEVICE\tNAME\tCONNECTS\tDISCONNECTS\tSESSIONS\tMEAN SESSION\tLAST SEEN")
+	for _, s := range stats {
+		mean := "-"
+		if s.Sessions > 0 {
+			mean = s.MeanSession.Round(time.Second).String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\t%s\n", s.DeviceID, s.Name, s.Connects, s.Disconnects,
+			s.Sessions, mean, s.LastSeen.Local().Format(time.DateTime))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+)
+
+func runLatency(args []string) error {
+	fs := newFlagSet("latency")
+	output := fs.String("output", "", "output to play the chirp to (default: the active output)")
+	input := fs.String("input", "", "input to listen on (default: the active input)")
+	level := fs.Float64("level", audiocontrol.DefaultChirpLevel, "chirp level, where 1.0 is full scale")
+	maxLatency := fs.Duration("max", audiocontrol.DefaultMaxLatency, "longest latency to look for")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *output == "" {
+		device, err := audiocontrol.GetActiveOutputDevice()
+		if err != nil {
+			return err
+		}
+		*output = device.ID
+	}
+	if *input == "" {
+		device, err := audiocontrol.GetActiveInputDevice()
+		if err != nil {
+			return err
+		}
+		*input = device.ID
+	}
+
+	fmt.Fprintf(os.Stderr, "Playing a chirp to %s and listening on %s\n", *output, *input)
+	result, err := audiocontrol.MeasureLatency(*output, *input, audiocontrol.LatencyOptions{
+		Level:      *level,
+		MaxLatency: *maxLatency,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Round trip:      %v (confidence %.2f)\n", result.Latency, result.Confidence)
+	fmt.Printf("Output reported: %v\n", result.OutputLatency)
+	fmt.Printf("Input reported:  %v\n", result.InputLatency)
+	return nil
+}
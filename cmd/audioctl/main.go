@@ -0,0 +1,80 @@
+// Command audioctl is a command-line tool for audio devices.
+//
+//	audioctl alias [flags]     rename or hide devices in the alias file
+//	audioctl capture [flags]   record an input or output to a WAVE file
+//	audioctl history [flags]   show the event journal written by audiod -journal
+//	audioctl latency [flags]   measure the round trip from an output to an input
+//	audioctl meter [flags]     show the live level of a device
+//	audioctl play [flags]      play a test tone or WAVE file to an output
+//	audioctl record [flags]    record device events to a trace file for replay
+//	audioctl switch [flags]    switch the default output or input and verify it
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "alias":
+		err = runAlias(os.Args[2:])
+	case "capture":
+		err = runCapture(os.Args[2:])
+	case "history":
+		err = runHistory(os.Args[2:])
+	case "latency":
+		err = runLatency(os.Args[2:])
+	case "meter":
+		err = runMeter(os.Args[2:])
+	case "play":
+		err = runPlay(os.Args[2:])
+	case "record":
+		err = runRecord(os.Args[2:])
+	case "switch":
+		err = runSwitch(os.Args[2:])
+	case "help", "-h", "-help", "--help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "audioctl: unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "audioctl:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: audioctl <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	fmt.Fprintln(os.Stderr, "  alias     rename or hide devices in the alias file")
+	fmt.Fprintln(os.Stderr, "  capture   record an input or output to a WAVE file")
+	fmt.Fprintln(os.Stderr, "  history   show recorded device events and per-device statistics")
+	fmt.Fprintln(os.Stderr, "  latency   measure the round trip from an output to an input")
+	fmt.Fprintln(os.Stderr, "  meter     show the live level of a device")
+	fmt.Fprintln(os.Stderr, "  play      play a test tone or WAVE file to an output")
+	fmt.Fprintln(os.Stderr, "  record    record device events to a trace file for replay")
+	fmt.Fprintln(os.Stderr, "  switch    switch the default output or input and verify it")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run 'audioctl <command> -h' for the flags of a command.")
+}
+
+// newFlagSet returns a flag set for a subcommand that reports errors instead
+// of exiting
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("audioctl "+name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+)
+
+// meterFloor is the lowest level drawn by the meter bar, in dBFS
+const meterFloor = -60.0
+
+func runMeter(args []string) error {
+	fs := newFlagSet("meter")
+	device := fs.String("device", "", "device to meter (default: the active input)")
+	interval := fs.Duration("interval", audiocontrol.DefaultMeterInterval, "time between readings")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *device == "" {
+		input, err := audiocontrol.GetActiveInputDevice()
+		if err != nil {
+			return err
+		}
+		*device = input.ID
+	}
+	levels, stop, err := audiocontrol.Meter(*device, *interval)
+	if err != nil {
+		return err
+	}
+	defer stop()
+	fmt.Fprintf(os.Stderr, "Metering %s; press Ctrl+C to stop\n", *device)
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	for {
+		select {
+		case <-sigChan:
+			fmt.Println()
+			return nil
+		case level, ok := <-levels:
+			if !ok {
+				fmt.Println()
+				return errors.New("device went away")
+			}
+			fmt.Print("\r" + meterLine(level))
+		}
+	}
+}
+
+// meterLine draws the decayed peak of each channel as a bar with its level
+func meterLine(level audiocontrol.Level) string {
+	const width = 30
+	var b strings.Builder
+	for ch, db := range level.DecayedDBFS() {
+		filled := int((db - meterFloor) / -meterFloor * width)
+		if filled < 0 {
+			filled = 0
+		}
+		if filled > width {
+			filled = width
+		}
+		fmt.Fprintf(&b, "%d [%s%s] %6.1f dBFS  ", ch+1,
+			strings.Repeat("#", filled), strings.Repeat(" ", width-filled), db)
+	}
+	return b.String()
+}
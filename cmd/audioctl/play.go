@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+)
+
+func runPlay(args []string) error {
+	fs := newFlagSet("play")
+	device := fs.String("device", "", "output to play to (default: the active output)")
+	file := fs.String("file", "", "WAVE file to play instead of a test tone")
+	frequency := fs.Float64("frequency", audiocontrol.DefaultToneFrequency, "test tone frequency in Hz")
+	duration := fs.Duration("duration", audiocontrol.DefaultToneDuration, "test tone duration")
+	level := fs.Float64("level", audiocontrol.DefaultToneLevel, "test tone level, where 1.0 is full scale")
+	channel := fs.Int("channel", 0, "play the test tone on the left (1) or right (2) channel only")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *device == "" {
+		output, err := audiocontrol.GetActiveOutputDevice()
+		if err != nil {
+			return err
+		}
+		*device = output.ID
+	}
+
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		fmt.Fprintf(os.Stderr, "Playing %s to %s\n", *file, *device)
+		return audiocontrol.PlayWAV(*device, f)
+	}
+
+	fmt.Fprintf(os.Stderr, "Playing a %g Hz tone to %s\n", *frequency, *device)
+	return audiocontrol.PlayTestTone(*device, audiocontrol.ToneSpec{
+		Frequency: *frequency,
+		Duration:  *duration,
+		Level:     *level,
+		Channel:   *channel,
+	})
+}
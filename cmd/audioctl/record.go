@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+	"github.com/audi70r/go-audio-control/trace"
+)
+
+func runRecord(args []string) error {
+	fs := newFlagSet("record")
+	output := fs.String("o", "audio-trace.jsonl", "trace file to write")
+	duration := fs.Duration("duration", 0, "stop after this long (default: until interrupted)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	recorder, err := trace.NewRecorder(audiocontrol.Native(), file)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Recording device events to %s; press Ctrl+C to stop\n", *output)
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	var timeout <-chan time.Time
+	if *duration > 0 {
+		timeout = time.After(*duration)
+	}
+	select {
+	case <-sigChan:
+	case <-timeout:
+	}
+
+	if err := recorder.Stop(); err != nil {
+		return fmt.Errorf("failed to write trace: %w", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+)
+
+func runSwitch(args []string) error {
+	fs := newFlagSet("switch")
+	output := fs.String("output", "", "output to make the default")
+	input := fs.String("input", "", "input to make the default")
+	verify := fs.Bool("verify", true, "wait until the device is reported as the default")
+	rollback := fs.Bool("rollback", false, "restore the previous default if the switch fails")
+	timeout := fs.Duration("timeout", audiocontrol.DefaultSwitchTimeout, "longest time to wait for the switch")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if (*output == "") == (*input == "") {
+		return errors.New("exactly one of -output and -input is required")
+	}
+
+	opts := audiocontrol.SwitchOptions{Verify: *verify, Timeout: *timeout, RollbackOnFailure: *rollback}
+	var result audiocontrol.SwitchResult
+	var err error
+	if *output != "" {
+		result, err = audiocontrol.SwitchOutput(context.Background(), *output, opts)
+	} else {
+		result, err = audiocontrol.SwitchInput(context.Background(), *input, opts)
+	}
+
+	if result.Previous.ID != "" {
+		fmt.Printf("Previous:    %s (%s)\n", result.Previous.Name, result.Previous.ID)
+	}
+	if result.Active.ID != "" {
+		fmt.Printf("Default now: %s (%s)\n", result.Active.Name, result.Active.ID)
+	}
+	if result.Verified {
+		how := "read back"
+		if result.Event {
+			how = "event"
+		}
+		fmt.Printf("Verified in %v by %s\n", result.Duration, how)
+	}
+	if result.RolledBack {
+		fmt.Println("Rolled back to the previous device")
+	} else if result.RollbackErr != nil {
+		fmt.Println("Rollback failed:", result.RollbackErr)
+	}
+	return err
+}
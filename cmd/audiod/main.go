@@ -0,0 +1,105 @@
+// Command audiod shares the native audio backend with other processes over a
+// local HTTP/JSON API. See package daemon for the endpoints and package
+// client for a Go client.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/godbus/dbus/v5"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+	"github.com/audi70r/go-audio-control/daemon"
+	"github.com/audi70r/go-audio-control/dbusservice"
+	"github.com/audi70r/go-audio-control/journal"
+)
+
+func main() {
+	address := flag.String("listen", daemon.DefaultAddress(), "unix:/path/to/socket or localhost:port")
+	tokenFile := flag.String("token-file", "", "file containing the bearer token (default $AUDIOD_TOKEN)")
+	exportDBus := flag.Bool("dbus", false, "also export the devices on the D-Bus session bus")
+	journalDir := flag.String("journal", "", "record device events to this directory (\"default\" for "+journal.DefaultDir()+")")
+	flag.Parse()
+
+	token, err := readToken(*tokenFile)
+	if err != nil {
+		log.Fatal("Failed to read token:", err)
+	}
+
+	network, addr, err := daemon.ParseAddress(*address)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if network == "tcp" && token == "" {
+		log.Fatal("A token is required when listening on TCP; set AUDIOD_TOKEN or -token-file")
+	}
+
+	listener, err := daemon.Listen(*address)
+	if err != nil {
+		log.Fatal("Failed to listen:", err)
+	}
+	if network == "unix" {
+		defer os.Remove(addr)
+	}
+
+	backend := audiocontrol.Native()
+	server := &http.Server{Handler: daemon.NewServer(backend, token)}
+
+	if *exportDBus {
+		conn, err := dbus.ConnectSessionBus()
+		if err != nil {
+			log.Fatal("Failed to connect to the session bus:", err)
+		}
+		defer conn.Close()
+		if _, err := dbusservice.Export(conn, backend); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("audiod exported %s on the session bus\n", dbusservice.BusName)
+	}
+
+	if *journalDir != "" {
+		dir := *journalDir
+		if dir == "default" {
+			dir = journal.DefaultDir()
+		}
+		j, err := journal.Open(journal.Options{Dir: dir})
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer j.Close()
+		j.Attach(backend)
+		fmt.Printf("audiod recording events to %s\n", dir)
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		server.Close()
+	}()
+
+	fmt.Printf("audiod listening on %s\n", *address)
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
+
+// readToken returns the token from path, or from $AUDIOD_TOKEN when path is empty
+func readToken(path string) (string, error) {
+	if path == "" {
+		return os.Getenv("AUDIOD_TOKEN"), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,381 @@
+// Package conformancetest checks that an audiocontrol.Backend behaves the way
+// the rest of the module relies on. Backend implementations run it from
+// their own tests:
+//
+//	func TestConformance(t *testing.T) {
+//		conformancetest.Run(t, func(t *testing.T) audiocontrol.Backend {
+//			return newFixtureBackend(t)
+//		})
+//	}
+//
+// Every check that changes state restores it when the subtest ends, so the
+// suite can also be run against real hardware.
+package conformancetest
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+)
+
+// EventTimeout bounds how long the suite waits for an event after a change
+var EventTimeout = 2 * time.Second
+
+// UnknownDeviceID is an ID no backend is expected to know
+const UnknownDeviceID = "conformancetest-no-such-device"
+
+// volumeTolerance allows for backends that store volume in fixed point
+const volumeTolerance = 0.01
+
+// Run runs the conformance tests as subtests of t. newBackend is called for
+// each subtest and must return a backend with at least one output device.
+// Checks that need two outputs, an input, or volume and mute support are
+// skipped when the backend does not have them.
+func Run(t *testing.T, newBackend func(t *testing.T) audiocontrol.Backend) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, b audiocontrol.Backend)
+	}{
+		{"ListAudioDevices", testListAudioDevices},
+		{"StableIDs", testStableIDs},
+		{"OneActiveOutput", testOneActiveOutput},
+		{"ActiveInput", testActiveInput},
+		{"DefaultFlags", testDefaultFlags},
+		{"SetActiveOutputRoundTrip", testSetActiveOutput},
+		{"SetActiveInputRoundTrip", testSetActiveInput},
+		{"EventAfterSet", testEventAfterSet},
+		{"VolumeRoundTrip", testVolume},
+		{"MuteRoundTrip", testMute},
+		{"UnknownDevice", testUnknownDevice},
+		{"WrongDirection", testWrongDirection},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newBackend(t))
+		})
+	}
+}
+
+func listDevices(t *testing.T, b audiocontrol.Backend) []audiocontrol.AudioDevice {
+	t.Helper()
+	devices, err := b.ListAudioDevices()
+	if err != nil {
+		t.Fatalf("Failed to list audio devices: %v", err)
+	}
+	return devices
+}
+
+// devicesWhere returns the devices matching keep
+func devicesWhere(devices []audiocontrol.AudioDevice, keep func(audiocontrol.AudioDevice) bool) []audiocontrol.AudioDevice {
+	var matched []audiocontrol.AudioDevice
+	for _, d := range devices {
+		if keep(d) {
+			matched = append(matched, d)
+		}
+	}
+	return matched
+}
+
+func isOutput(d audiocontrol.AudioDevice) bool { return d.IsOutput }
+
+func isInput(d audiocontrol.AudioDevice) bool { return d.IsInput }
+
+func isActiveOutput(d audiocontrol.AudioDevice) bool { return d.IsOutput && d.IsDefaultOutput }
+
+func isActiveInput(d audiocontrol.AudioDevice) bool { return d.IsInput && d.IsDefaultInput }
+
+// skipIfNotImplemented skips the subtest for optional operations
+func skipIfNotImplemented(t *testing.T, err error) {
+	t.Helper()
+	if errors.Is(err, audiocontrol.ErrNotImplemented) {
+		t.Skipf("not implemented: %v", err)
+	}
+}
+
+func testListAudioDevices(t *testing.T, b audiocontrol.Backend) {
+	devices := listDevices(t, b)
+	if len(devices) == 0 {
+		t.Fatal("No audio devices listed")
+	}
+
+	seen := make(map[string]bool)
+	for _, d := range devices {
+		if d.ID == "" {
+			t.Errorf("Device %q has an empty ID", d.Name)
+		}
+		if seen[d.ID] {
+			t.Errorf("Device ID %q is listed more than once", d.ID)
+		}
+		seen[d.ID] = true
+		if !d.IsInput && !d.IsOutput {
+			t.Errorf("Device %q is neither an input nor an output", d.ID)
+		}
+	}
+	if len(devicesWhere(devices, isOutput)) == 0 {
+		t.Error("No output devices listed")
+	}
+}
+
+func testStableIDs(t *testing.T, b audiocontrol.Backend) {
+	first := listDevices(t, b)
+	second := listDevices(t, b)
+
+	names := make(map[string]string)
+	for _, d := range first {
+		names[d.ID] = d.Name
+	}
+	if len(first) != len(second) {
+		t.Fatalf("Device count changed between calls: %d, then %d", len(first), len(second))
+	}
+	for _, d := range second {
+		name, ok := names[d.ID]
+		if !ok {
+			t.Errorf("Device ID %q appeared between calls", d.ID)
+			continue
+		}
+		if name != d.Name {
+			t.Errorf("Device %q changed name between calls: %q, then %q", d.ID, name, d.Name)
+		}
+	}
+}
+
+func testOneActiveOutput(t *testing.T, b audiocontrol.Backend) {
+	active := devicesWhere(listDevices(t, b), isActiveOutput)
+	if len(active) != 1 {
+		t.Fatalf("Expected exactly one active output, got %d", len(active))
+	}
+
+	device, err := b.GetActiveOutputDevice()
+	if err != nil {
+		t.Fatalf("Failed to get active output device: %v", err)
+	}
+	if device.ID != active[0].ID {
+		t.Errorf("GetActiveOutputDevice returned %q, list marks %q active", device.ID, active[0].ID)
+	}
+	if !device.IsOutput || !device.IsDefaultOutput {
+		t.Errorf("GetActiveOutputDevice returned %+v, want an active output", device)
+	}
+}
+
+func testDefaultFlags(t *testing.T, b audiocontrol.Backend) {
+	for _, d := range listDevices(t, b) {
+		if d.IsDefaultOutput && !d.IsOutput {
+			t.Errorf("Device %q is the default output but not an output", d.ID)
+		}
+		if d.IsDefaultInput && !d.IsInput {
+			t.Errorf("Device %q is the default input but not an input", d.ID)
+		}
+		if d.IsActive != (d.IsDefaultOutput || d.IsDefaultInput) {
+			t.Errorf("Device %q has IsActive %v but default output %v, input %v",
+				d.ID, d.IsActive, d.IsDefaultOutput, d.IsDefaultInput)
+		}
+		if d.State < audiocontrol.StateActive || d.State > audiocontrol.StateUnplugged {
+			t.Errorf("Device %q has unknown state %v", d.ID, d.State)
+		}
+	}
+}
+
+func testActiveInput(t *testing.T, b audiocontrol.Backend) {
+	devices := listDevices(t, b)
+	if len(devicesWhere(devices, isInput)) == 0 {
+		t.Skip("backend has no input devices")
+	}
+	active := devicesWhere(devices, isActiveInput)
+	if len(active) != 1 {
+		t.Fatalf("Expected exactly one active input, got %d", len(active))
+	}
+
+	device, err := b.GetActiveInputDevice()
+	if err != nil {
+		t.Fatalf("Failed to get active input device: %v", err)
+	}
+	if device.ID != active[0].ID {
+		t.Errorf("GetActiveInputDevice returned %q, list marks %q active", device.ID, active[0].ID)
+	}
+}
+
+// otherDevice returns the default device of one direction and another
+// device of that direction
+func otherDevice(t *testing.T, b audiocontrol.Backend, output bool) (current, other audiocontrol.AudioDevice) {
+	t.Helper()
+	keep, isDefault := isInput, isActiveInput
+	if output {
+		keep, isDefault = isOutput, isActiveOutput
+	}
+	devices := devicesWhere(listDevices(t, b), keep)
+	for _, d := range devices {
+		if isDefault(d) {
+			current = d
+		}
+	}
+	for _, d := range devices {
+		if !isDefault(d) {
+			return current, d
+		}
+	}
+	t.Skip("backend needs two devices of the same direction")
+	return
+}
+
+func testSetActiveOutput(t *testing.T, b audiocontrol.Backend) {
+	current, other := otherDevice(t, b, true)
+	t.Cleanup(func() { b.SetActiveOutputDevice(current.ID) })
+
+	if err := b.SetActiveOutputDevice(other.ID); err != nil {
+		t.Fatalf("Failed to set active output device: %v", err)
+	}
+	device, err := b.GetActiveOutputDevice()
+	if err != nil {
+		t.Fatalf("Failed to get active output device: %v", err)
+	}
+	if device.ID != other.ID {
+		t.Errorf("Active output is %q after setting %q", device.ID, other.ID)
+	}
+	active := devicesWhere(listDevices(t, b), isActiveOutput)
+	if len(active) != 1 || active[0].ID != other.ID {
+		t.Errorf("List marks %+v active after setting %q", active, other.ID)
+	}
+}
+
+func testSetActiveInput(t *testing.T, b audiocontrol.Backend) {
+	current, other := otherDevice(t, b, false)
+	t.Cleanup(func() { b.SetActiveInputDevice(current.ID) })
+
+	if err := b.SetActiveInputDevice(other.ID); err != nil {
+		t.Fatalf("Failed to set active input device: %v", err)
+	}
+	device, err := b.GetActiveInputDevice()
+	if err != nil {
+		t.Fatalf("Failed to get active input device: %v", err)
+	}
+	if device.ID != other.ID {
+		t.Errorf("Active input is %q after setting %q", device.ID, other.ID)
+	}
+}
+
+func testEventAfterSet(t *testing.T, b audiocontrol.Backend) {
+	current, other := otherDevice(t, b, true)
+	t.Cleanup(func() { b.SetActiveOutputDevice(current.ID) })
+
+	changed := make(chan audiocontrol.Event, 16)
+	b.OnDeviceChange(func(e audiocontrol.Event) {
+		if e.Type == audiocontrol.ActiveDeviceChanged && e.DeviceID == other.ID {
+			select {
+			case changed <- e:
+			default:
+			}
+		}
+	})
+
+	if err := b.SetActiveOutputDevice(other.ID); err != nil {
+		t.Fatalf("Failed to set active output device: %v", err)
+	}
+	select {
+	case e := <-changed:
+		if e.Info != nil && e.Info.ID != other.ID {
+			t.Errorf("Event info describes %q, want %q", e.Info.ID, other.ID)
+		}
+	case <-time.After(EventTimeout):
+		t.Fatalf("No ActiveDeviceChanged event for %q within %v", other.ID, EventTimeout)
+	}
+}
+
+func testVolume(t *testing.T, b audiocontrol.Backend) {
+	device, err := b.GetActiveOutputDevice()
+	if err != nil {
+		t.Fatalf("Failed to get active output device: %v", err)
+	}
+	original, err := b.GetVolume(device.ID)
+	skipIfNotImplemented(t, err)
+	if err != nil {
+		t.Fatalf("Failed to get volume: %v", err)
+	}
+	t.Cleanup(func() { b.SetVolume(device.ID, original) })
+
+	for _, want := range []float64{0.25, 0.8} {
+		if err := b.SetVolume(device.ID, want); err != nil {
+			t.Fatalf("Failed to set volume: %v", err)
+		}
+		got, err := b.GetVolume(device.ID)
+		if err != nil {
+			t.Fatalf("Failed to get volume: %v", err)
+		}
+		if math.Abs(got-want) > volumeTolerance {
+			t.Errorf("Volume is %v after setting %v", got, want)
+		}
+	}
+
+	if err := b.SetVolume(device.ID, 1.5); err != nil {
+		t.Fatalf("Failed to set volume above range: %v", err)
+	}
+	if got, err := b.GetVolume(device.ID); err != nil || got > 1.0 {
+		t.Errorf("Volume is %v (err %v) after setting 1.5, want at most 1.0", got, err)
+	}
+}
+
+func testMute(t *testing.T, b audiocontrol.Backend) {
+	device, err := b.GetActiveOutputDevice()
+	if err != nil {
+		t.Fatalf("Failed to get active output device: %v", err)
+	}
+	original, err := b.GetMute(device.ID)
+	skipIfNotImplemented(t, err)
+	if err != nil {
+		t.Fatalf("Failed to get mute state: %v", err)
+	}
+	t.Cleanup(func() { b.SetMute(device.ID, original) })
+
+	for _, want := range []bool{!original, original} {
+		if err := b.SetMute(device.ID, want); err != nil {
+			t.Fatalf("Failed to set mute state: %v", err)
+		}
+		got, err := b.GetMute(device.ID)
+		if err != nil {
+			t.Fatalf("Failed to get mute state: %v", err)
+		}
+		if got != want {
+			t.Errorf("Mute state is %v after setting %v", got, want)
+		}
+	}
+}
+
+// checkNotFound reports an error unless err matches ErrDeviceNotFound
+func checkNotFound(t *testing.T, method string, err error) {
+	t.Helper()
+	if errors.Is(err, audiocontrol.ErrNotImplemented) {
+		return
+	}
+	if !errors.Is(err, audiocontrol.ErrDeviceNotFound) {
+		t.Errorf("%s: expected ErrDeviceNotFound, got %v", method, err)
+	}
+}
+
+func testUnknownDevice(t *testing.T, b audiocontrol.Backend) {
+	id := UnknownDeviceID
+	checkNotFound(t, "SetActiveOutputDevice", b.SetActiveOutputDevice(id))
+	checkNotFound(t, "SetActiveInputDevice", b.SetActiveInputDevice(id))
+	_, err := b.GetVolume(id)
+	checkNotFound(t, "GetVolume", err)
+	checkNotFound(t, "SetVolume", b.SetVolume(id, 0.5))
+	_, err = b.GetMute(id)
+	checkNotFound(t, "GetMute", err)
+	checkNotFound(t, "SetMute", b.SetMute(id, true))
+}
+
+func testWrongDirection(t *testing.T, b audiocontrol.Backend) {
+	devices := listDevices(t, b)
+	inputs := devicesWhere(devices, func(d audiocontrol.AudioDevice) bool { return d.IsInput && !d.IsOutput })
+	outputs := devicesWhere(devices, func(d audiocontrol.AudioDevice) bool { return d.IsOutput && !d.IsInput })
+	if len(inputs) == 0 {
+		t.Skip("backend has no input-only devices")
+	}
+
+	checkNotFound(t, "SetActiveOutputDevice", b.SetActiveOutputDevice(inputs[0].ID))
+	if len(outputs) > 0 {
+		checkNotFound(t, "SetActiveInputDevice", b.SetActiveInputDevice(outputs[0].ID))
+	}
+}
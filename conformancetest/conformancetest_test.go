@@ -0,0 +1,20 @@
+package conformancetest_test
+
+import (
+	"testing"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+	"github.com/audi70r/go-audio-control/audiotest"
+	"github.com/audi70r/go-audio-control/conformancetest"
+)
+
+func TestAudiotestBackend(t *testing.T) {
+	conformancetest.Run(t, func(t *testing.T) audiocontrol.Backend {
+		return audiotest.NewBackend(
+			audiocontrol.AudioDevice{ID: "speakers", Name: "Speakers", IsOutput: true, IsActive: true, IsConnected: true},
+			audiocontrol.AudioDevice{ID: "headphones", Name: "Headphones", IsOutput: true, IsConnected: true},
+			audiocontrol.AudioDevice{ID: "mic", Name: "Microphone", IsInput: true, IsActive: true, IsConnected: true},
+			audiocontrol.AudioDevice{ID: "line-in", Name: "Line In", IsInput: true, IsConnected: true},
+		)
+	})
+}
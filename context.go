@@ -0,0 +1,267 @@
+package audiocontrol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// The Context variants of the package functions return ctx.Err() once ctx is
+// done, even while the backend call is still blocked, for example on a
+// Bluetooth device that is mid-handshake or a wedged sound server.
+//
+// The calls are made one at a time by a worker goroutine. A call that is
+// abandoned after it started runs to completion on the worker and may still
+// take effect; its result is discarded. A call whose context is done before
+// the worker reaches it is never made, so a stale change is not applied
+// after its caller has given up. Calls queue behind an abandoned call until
+// it returns, or until it has been abandoned for wedgeTimeout, when a new
+// worker takes over the queue. At most maxWedgedWorkers workers are left
+// behind with calls that do not return.
+
+// ErrBackendWedged is returned, wrapped with the error of the context, by the
+// Context variants whose context was done while they waited behind a backend
+// call that its caller had abandoned and that has not returned
+var ErrBackendWedged = errors.New("audiocontrol: backend call abandoned and still running")
+
+const (
+	// wedgeTimeout is how long an abandoned call may hold up the queue
+	wedgeTimeout = time.Second
+	// maxWedgedWorkers bounds the goroutines left behind by calls that
+	// never return
+	maxWedgedWorkers = 4
+)
+
+// job is a backend call waiting for the worker
+type job struct {
+	ctx context.Context
+	run func()
+
+	mu        sync.Mutex
+	finished  bool
+	abandoned bool
+	// replaced is set when another worker took over the queue while the
+	// call was running, so that its worker exits once the call returns
+	replaced bool
+}
+
+var (
+	workerOnce sync.Once
+	jobs       chan *job
+
+	wedgeMutex sync.Mutex
+	// abandonedCalls counts the calls abandoned by their callers that are
+	// still running
+	abandonedCalls int
+	wedgedWorkers  int
+)
+
+func startWorker() {
+	jobs = make(chan *job)
+	go work()
+}
+
+func work() {
+	for j := range jobs {
+		if j.ctx.Err() == nil {
+			j.run()
+		}
+		if finish(j) {
+			return
+		}
+	}
+}
+
+// finish records that j returned and reports whether another worker took
+// over the queue in the meantime
+func finish(j *job) bool {
+	wedgeMutex.Lock()
+	defer wedgeMutex.Unlock()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.finished = true
+	if j.abandoned {
+		abandonedCalls--
+	}
+	if j.replaced {
+		wedgedWorkers--
+	}
+	return j.replaced
+}
+
+// abandon records that the caller of j gave up while it was running. If it
+// is still running after wedgeTimeout, a new worker takes over the queue.
+func abandon(j *job) {
+	wedgeMutex.Lock()
+	j.mu.Lock()
+	running := !j.finished
+	if running {
+		j.abandoned = true
+		abandonedCalls++
+	}
+	j.mu.Unlock()
+	wedgeMutex.Unlock()
+	if !running {
+		return
+	}
+
+	time.AfterFunc(wedgeTimeout, func() {
+		wedgeMutex.Lock()
+		defer wedgeMutex.Unlock()
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		if j.finished || j.replaced || wedgedWorkers >= maxWedgedWorkers {
+			return
+		}
+		j.replaced = true
+		wedgedWorkers++
+		go work()
+	})
+}
+
+// queueError is the error of a call whose context was done before the
+// worker took it
+func queueError(ctx context.Context) error {
+	wedgeMutex.Lock()
+	wedged := abandonedCalls > 0
+	wedgeMutex.Unlock()
+	if wedged {
+		return fmt.Errorf("%w: %w", ErrBackendWedged, ctx.Err())
+	}
+	return ctx.Err()
+}
+
+// withContext makes call on the worker and waits for its result or for ctx
+// to be done, whichever comes first
+func withContext[T any](ctx context.Context, call func() (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+	// Buffered so an abandoned call does not block the worker
+	done := make(chan result, 1)
+	j := &job{ctx: ctx, run: func() {
+		value, err := call()
+		done <- result{value, err}
+	}}
+
+	workerOnce.Do(startWorker)
+	select {
+	case jobs <- j:
+	case <-ctx.Done():
+		return zero, queueError(ctx)
+	}
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		abandon(j)
+		return zero, ctx.Err()
+	}
+}
+
+// withContextErr is withContext for calls returning only an error
+func withContextErr(ctx context.Context, call func() error) error {
+	_, err := withContext(ctx, func() (struct{}, error) {
+		return struct{}{}, call()
+	})
+	return err
+}
+
+// ListAudioDevicesContext is ListAudioDevices bounded by ctx
+func ListAudioDevicesContext(ctx context.Context) ([]AudioDevice, error) {
+	b := currentBackend()
+	devices, err := withContext(ctx, b.ListAudioDevices)
+	if err != nil {
+		return nil, err
+	}
+	return applyAliases(devices, false), nil
+}
+
+// ListAudioDevicesWithOptionsContext is ListAudioDevicesWithOptions bounded
+// by ctx
+func ListAudioDevicesWithOptionsContext(ctx context.Context, opts ListOptions) ([]AudioDevice, error) {
+	b := currentBackend()
+	devices, err := withContext(ctx, func() ([]AudioDevice, error) {
+		return ListBackendDevices(b, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return applyAliases(devices, opts.IncludeHidden), nil
+}
+
+// GetActiveOutputDeviceContext is GetActiveOutputDevice bounded by ctx
+func GetActiveOutputDeviceContext(ctx context.Context) (AudioDevice, error) {
+	b := currentBackend()
+	device, err := withContext(ctx, b.GetActiveOutputDevice)
+	if err != nil {
+		return device, err
+	}
+	return applyAlias(device), nil
+}
+
+// SetActiveOutputDeviceContext is SetActiveOutputDevice bounded by ctx
+func SetActiveOutputDeviceContext(ctx context.Context, deviceID string) error {
+	b := currentBackend()
+	return withContextErr(ctx, func() error {
+		return b.SetActiveOutputDevice(deviceID)
+	})
+}
+
+// GetActiveInputDeviceContext is GetActiveInputDevice bounded by ctx
+func GetActiveInputDeviceContext(ctx context.Context) (AudioDevice, error) {
+	b := currentBackend()
+	device, err := withContext(ctx, b.GetActiveInputDevice)
+	if err != nil {
+		return device, err
+	}
+	return applyAlias(device), nil
+}
+
+// SetActiveInputDeviceContext is SetActiveInputDevice bounded by ctx
+func SetActiveInputDeviceContext(ctx context.Context, deviceID string) error {
+	b := currentBackend()
+	return withContextErr(ctx, func() error {
+		return b.SetActiveInputDevice(deviceID)
+	})
+}
+
+// GetVolumeContext is GetVolume bounded by ctx
+func GetVolumeContext(ctx context.Context, deviceID string) (float64, error) {
+	b := currentBackend()
+	return withContext(ctx, func() (float64, error) {
+		return b.GetVolume(deviceID)
+	})
+}
+
+// SetVolumeContext is SetVolume bounded by ctx
+func SetVolumeContext(ctx context.Context, deviceID string, volume float64) error {
+	b := currentBackend()
+	return withContextErr(ctx, func() error {
+		return b.SetVolume(deviceID, volume)
+	})
+}
+
+// GetMuteContext is GetMute bounded by ctx
+func GetMuteContext(ctx context.Context, deviceID string) (bool, error) {
+	b := currentBackend()
+	return withContext(ctx, func() (bool, error) {
+		return b.GetMute(deviceID)
+	})
+}
+
+// SetMuteContext is SetMute bounded by ctx
+func SetMuteContext(ctx context.Context, deviceID string, muted bool) error {
+	b := currentBackend()
+	return withContextErr(ctx, func() error {
+		return b.SetMute(deviceID, muted)
+	})
+}
@@ -0,0 +1,165 @@
+package audiocontrol_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+	"github.com/audi70r/go-audio-control/audiotest"
+)
+
+func newContextBackend(t *testing.T, latency time.Duration) *audiotest.Backend {
+	t.Helper()
+	b := audiotest.NewBackend(
+		audiocontrol.AudioDevice{ID: "speakers", Name: "Speakers", IsOutput: true, IsActive: true},
+		audiocontrol.AudioDevice{ID: "headphones", Name: "Headphones", IsOutput: true},
+		audiocontrol.AudioDevice{ID: "hdmi", Name: "HDMI", IsOutput: true},
+	)
+	audiocontrol.SetBackend(b)
+	t.Cleanup(func() { audiocontrol.SetBackend(nil) })
+	// Wait for calls abandoned by earlier tests to finish on the worker
+	if _, err := audiocontrol.ListAudioDevicesContext(context.Background()); err != nil {
+		t.Fatalf("ListAudioDevicesContext() failed: %v", err)
+	}
+	b.SetLatency(latency)
+	return b
+}
+
+func TestContextVariants(t *testing.T) {
+	newContextBackend(t, 0)
+	ctx := context.Background()
+
+	devices, err := audiocontrol.ListAudioDevicesContext(ctx)
+	if err != nil || len(devices) != 3 {
+		t.Fatalf("ListAudioDevicesContext() = %v, %v", devices, err)
+	}
+	if err := audiocontrol.SetActiveOutputDeviceContext(ctx, "headphones"); err != nil {
+		t.Fatalf("SetActiveOutputDeviceContext() failed: %v", err)
+	}
+	if d, err := audiocontrol.GetActiveOutputDeviceContext(ctx); err != nil || d.ID != "headphones" {
+		t.Errorf("GetActiveOutputDeviceContext() = %q, %v, want headphones", d.ID, err)
+	}
+	if err := audiocontrol.SetVolumeContext(ctx, "headphones", 0.3); err != nil {
+		t.Fatalf("SetVolumeContext() failed: %v", err)
+	}
+	if v, err := audiocontrol.GetVolumeContext(ctx, "headphones"); err != nil || v != 0.3 {
+		t.Errorf("GetVolumeContext() = %v, %v, want 0.3", v, err)
+	}
+	if err := audiocontrol.SetMuteContext(ctx, "headphones", true); err != nil {
+		t.Fatalf("SetMuteContext() failed: %v", err)
+	}
+	if m, err := audiocontrol.GetMuteContext(ctx, "headphones"); err != nil || !m {
+		t.Errorf("GetMuteContext() = %v, %v, want true", m, err)
+	}
+	err = audiocontrol.SetActiveOutputDeviceContext(ctx, "missing")
+	if !errors.Is(err, audiocontrol.ErrDeviceNotFound) {
+		t.Errorf("SetActiveOutputDeviceContext(missing) = %v, want ErrDeviceNotFound", err)
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	newContextBackend(t, 500*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := audiocontrol.ListAudioDevicesContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ListAudioDevicesContext() = %v, want DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("ListAudioDevicesContext() returned after %v", elapsed)
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	b := newContextBackend(t, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := audiocontrol.SetActiveOutputDeviceContext(ctx, "hdmi"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("SetActiveOutputDeviceContext() = %v, want Canceled", err)
+	}
+	if d, _ := b.GetActiveOutputDevice(); d.ID != "speakers" {
+		t.Errorf("Active output = %q after canceled call, want speakers", d.ID)
+	}
+}
+
+func TestContextAbandoned(t *testing.T) {
+	b := newContextBackend(t, 100*time.Millisecond)
+
+	// The first call is abandoned while the backend is busy with it and
+	// still takes effect. The second is abandoned while waiting behind it
+	// and is never made.
+	first, cancelFirst := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelFirst()
+	if err := audiocontrol.SetActiveOutputDeviceContext(first, "headphones"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("First SetActiveOutputDeviceContext() = %v, want DeadlineExceeded", err)
+	}
+	second, cancelSecond := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelSecond()
+	if err := audiocontrol.SetActiveOutputDeviceContext(second, "hdmi"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Second SetActiveOutputDeviceContext() = %v, want DeadlineExceeded", err)
+	}
+
+	// A later call waits for the abandoned one to finish
+	d, err := audiocontrol.GetActiveOutputDeviceContext(context.Background())
+	if err != nil {
+		t.Fatalf("GetActiveOutputDeviceContext() failed: %v", err)
+	}
+	if d.ID != "headphones" {
+		t.Errorf("Active output = %q, want headphones", d.ID)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if d, _ := b.GetActiveOutputDevice(); d.ID != "headphones" {
+		t.Errorf("Active output = %q after the abandoned calls, want headphones", d.ID)
+	}
+}
+
+// blockingBackend blocks switching to hdmi until release is closed
+type blockingBackend struct {
+	*audiotest.Backend
+	release chan struct{}
+}
+
+func (b blockingBackend) SetActiveOutputDevice(deviceID string) error {
+	if deviceID == "hdmi" {
+		<-b.release
+	}
+	return b.Backend.SetActiveOutputDevice(deviceID)
+}
+
+func TestContextWedged(t *testing.T) {
+	b := newContextBackend(t, 0)
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	audiocontrol.SetBackend(blockingBackend{Backend: b, release: release})
+
+	wedged, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := audiocontrol.SetActiveOutputDeviceContext(wedged, "hdmi"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("SetActiveOutputDeviceContext() = %v, want DeadlineExceeded", err)
+	}
+
+	// A call that gives up behind the wedged one says so
+	queued, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := audiocontrol.GetActiveOutputDeviceContext(queued)
+	if !errors.Is(err, audiocontrol.ErrBackendWedged) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetActiveOutputDeviceContext() = %v, want ErrBackendWedged", err)
+	}
+
+	// A later call is made by a new worker while the wedged call is still
+	// blocked
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	d, err := audiocontrol.GetActiveOutputDeviceContext(ctx)
+	if err != nil {
+		t.Fatalf("GetActiveOutputDeviceContext() failed: %v", err)
+	}
+	if d.ID != "speakers" {
+		t.Errorf("Active output = %q, want speakers", d.ID)
+	}
+}
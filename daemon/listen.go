@@ -0,0 +1,91 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// unixPrefix marks an address as a unix socket path
+const unixPrefix = "unix:"
+
+// DefaultAddress returns the address audiod listens on when none is given:
+// a per-user unix socket where available, otherwise a localhost TCP port.
+// Without $XDG_RUNTIME_DIR the socket goes in a directory of its own under
+// the shared temporary directory, which Listen creates for the owner only.
+func DefaultAddress() string {
+	if runtime.GOOS == "windows" {
+		return "localhost:7445"
+	}
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), fmt.Sprintf("audiod-%d", os.Getuid()))
+	}
+	return unixPrefix + filepath.Join(dir, "audiod.sock")
+}
+
+// ParseAddress splits an address into a network and dial address. Addresses
+// of the form "unix:/path" name a unix socket; anything else is a TCP
+// host:port that must resolve to a loopback interface.
+func ParseAddress(address string) (network, addr string, err error) {
+	if strings.HasPrefix(address, unixPrefix) {
+		path := strings.TrimPrefix(address, unixPrefix)
+		if path == "" {
+			return "", "", fmt.Errorf("empty unix socket path")
+		}
+		return "unix", path, nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	if host != "localhost" {
+		ip := net.ParseIP(host)
+		if ip == nil || !ip.IsLoopback() {
+			return "", "", fmt.Errorf("refusing to listen on non-loopback address %q", address)
+		}
+	}
+	return "tcp", address, nil
+}
+
+// Listen opens a listener for address. A unix socket must be in a directory
+// that only the owner may write to, which is created if missing. A stale
+// socket left behind by a previous run is removed, and new sockets are only
+// accessible by the owner.
+func Listen(address string) (net.Listener, error) {
+	network, addr, err := ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	if network == "tcp" {
+		return net.Listen(network, addr)
+	}
+
+	dir := filepath.Dir(addr)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	if err := checkSocketDir(dir); err != nil {
+		return nil, err
+	}
+	if conn, err := net.Dial("unix", addr); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("another daemon is already listening on %s", addr)
+	}
+	os.Remove(addr)
+
+	listener, err := listenUnix(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(addr, 0o600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+	return listener, nil
+}
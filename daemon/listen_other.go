@@ -0,0 +1,15 @@
+//go:build !unix
+
+package daemon
+
+import "net"
+
+func listenUnix(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
+
+// checkSocketDir accepts any directory; file permissions do not protect
+// sockets on this platform
+func checkSocketDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,42 @@
+//go:build unix
+
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// socketUmask keeps the socket from being accessible by other users between
+// its creation and the chmod in Listen
+const socketUmask = 0o177
+
+// listenUnix creates the socket at path with only owner permissions. The
+// umask is process-wide, so Listen should be called before other goroutines
+// create files.
+func listenUnix(path string) (net.Listener, error) {
+	old := syscall.Umask(socketUmask)
+	defer syscall.Umask(old)
+	return net.Listen("unix", path)
+}
+
+// checkSocketDir refuses a directory that another user owns or may write to,
+// since they could replace the socket in it
+func checkSocketDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("socket directory %s is owned by another user", dir)
+	}
+	if info.Mode().Perm()&0o022 != 0 {
+		return fmt.Errorf("socket directory %s is writable by other users", dir)
+	}
+	return nil
+}
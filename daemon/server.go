@@ -0,0 +1,604 @@
+// Package daemon serves an audiocontrol.Backend over HTTP so that several
+// processes can share one native device listener.
+//
+// All endpoints live under /v1 and exchange JSON using the field names of the
+// audiocontrol types:
+//
+//	GET  /v1/devices                 list devices; see ListOptionsQuery
+//	GET  /v1/default/output          active output device
+//	PUT  /v1/default/output          {"DeviceID": "..."}
+//	GET  /v1/default/input           active input device
+//	PUT  /v1/default/input           {"DeviceID": "..."}
+//	GET  /v1/devices/{id}/volume     {"Volume": 0.5}
+//	PUT  /v1/devices/{id}/volume     {"Volume": 0.5}
+//	GET  /v1/devices/{id}/mute       {"Muted": false}
+//	PUT  /v1/devices/{id}/mute       {"Muted": true}
+//	GET  /v1/devices/{id}/ports      list ports
+//	PUT  /v1/devices/{id}/ports      {"Port": "..."} selects the active port
+//	GET  /v1/cards                   list cards
+//	GET  /v1/cards/{id}/profiles     list the profiles of a card
+//	PUT  /v1/cards/{id}/profiles     {"Profile": "..."} selects the active profile
+//	GET  /v1/sessions                list sessions; ?device={id} selects a device
+//	PUT  /v1/sessions/{id}/volume    {"Volume": 0.5}
+//	PUT  /v1/sessions/{id}/mute      {"Muted": true}
+//	PUT  /v1/sessions/{id}/device    {"DeviceID": "..."} moves the session
+//	GET  /v1/events                  server-sent event stream of Events
+//
+// Device IDs in paths are escaped with url.PathEscape. Failures are reported
+// as {"Error": "..."} with a non-2xx status.
+package daemon
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+)
+
+// DefaultRequest is the body of a PUT to /v1/default/{output,input}
+type DefaultRequest struct {
+	DeviceID string
+}
+
+// VolumeState is the body of the volume endpoints
+type VolumeState struct {
+	Volume float64
+}
+
+// MuteState is the body of the mute endpoints
+type MuteState struct {
+	Muted bool
+}
+
+// PortRequest is the body of a PUT to /v1/devices/{id}/ports
+type PortRequest struct {
+	Port string
+}
+
+// ProfileRequest is the body of a PUT to /v1/cards/{id}/profiles
+type ProfileRequest struct {
+	Profile string
+}
+
+// MoveRequest is the body of a PUT to /v1/sessions/{id}/device
+type MoveRequest struct {
+	DeviceID string
+}
+
+// ErrorResponse is the body returned with every error status. Code is set
+// when the backend returned one of the audiocontrol sentinel errors.
+type ErrorResponse struct {
+	Error string
+	Code  string
+}
+
+// Codes of ErrorResponse, naming the sentinel error the backend returned
+const (
+	CodeNotImplemented = "not_implemented"
+	CodeDeviceNotFound = "device_not_found"
+)
+
+// ListOptionsQuery encodes opts as the query of GET /v1/devices:
+// "include" lists any of disabled, unplugged and notpresent, and
+// "direction" is output or input
+func ListOptionsQuery(opts audiocontrol.ListOptions) url.Values {
+	q := url.Values{}
+	var include []string
+	if opts.IncludeDisabled {
+		include = append(include, "disabled")
+	}
+	if opts.IncludeUnplugged {
+		include = append(include, "unplugged")
+	}
+	if opts.IncludeNotPresent {
+		include = append(include, "notpresent")
+	}
+	if len(include) > 0 {
+		q.Set("include", strings.Join(include, ","))
+	}
+	if opts.Direction != audiocontrol.DirectionAll {
+		q.Set("direction", opts.Direction.String())
+	}
+	return q
+}
+
+// ParseListOptions decodes a query written by ListOptionsQuery
+func ParseListOptions(q url.Values) (audiocontrol.ListOptions, error) {
+	var opts audiocontrol.ListOptions
+	if include := q.Get("include"); include != "" {
+		for _, state := range strings.Split(include, ",") {
+			switch state {
+			case "disabled":
+				opts.IncludeDisabled = true
+			case "unplugged":
+				opts.IncludeUnplugged = true
+			case "notpresent":
+				opts.IncludeNotPresent = true
+			default:
+				return opts, fmt.Errorf("unknown device state %q", state)
+			}
+		}
+	}
+	if direction := q.Get("direction"); direction != "" {
+		d, err := audiocontrol.ParseDirection(direction)
+		if err != nil {
+			return opts, err
+		}
+		opts.Direction = d
+	}
+	return opts, nil
+}
+
+// subscriberBuffer is the number of events buffered per event stream before
+// events are dropped for a slow client
+const subscriberBuffer = 64
+
+// Server is an http.Handler exposing a Backend
+type Server struct {
+	backend audiocontrol.Backend
+	token   string
+
+	mu          sync.Mutex
+	subscribers map[chan audiocontrol.Event]struct{}
+}
+
+// NewServer returns a Server for backend. When token is non-empty every
+// request must carry it as "Authorization: Bearer <token>". NewServer
+// registers a single device change callback on backend and fans events out
+// to all connected event streams.
+func NewServer(backend audiocontrol.Backend, token string) *Server {
+	s := &Server{
+		backend:     backend,
+		token:       token,
+		subscribers: make(map[chan audiocontrol.Event]struct{}),
+	}
+	backend.OnDeviceChange(s.broadcast)
+	return s
+}
+
+// ServeHTTP dispatches a request to the matching endpoint
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+	if len(parts) < 2 || parts[0] != "v1" {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	switch {
+	case len(parts) == 2 && parts[1] == "devices":
+		s.handleDevices(w, r)
+	case len(parts) == 2 && parts[1] == "events":
+		s.handleEvents(w, r)
+	case len(parts) == 2 && parts[1] == "cards":
+		s.handleCards(w, r)
+	case len(parts) == 4 && parts[1] == "cards" && parts[3] == "profiles":
+		cardID, err := url.PathUnescape(parts[2])
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		s.handleProfiles(w, r, cardID)
+	case len(parts) == 2 && parts[1] == "sessions":
+		s.handleSessions(w, r)
+	case len(parts) == 4 && parts[1] == "sessions" && (parts[3] == "volume" || parts[3] == "mute"):
+		sessionID, err := url.PathUnescape(parts[2])
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		s.handleSessionControl(w, r, sessionID, parts[3] == "volume")
+	case len(parts) == 4 && parts[1] == "sessions" && parts[3] == "device":
+		sessionID, err := url.PathUnescape(parts[2])
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		s.handleSessionMove(w, r, sessionID)
+	case len(parts) == 3 && parts[1] == "default" && (parts[2] == "output" || parts[2] == "input"):
+		s.handleDefault(w, r, parts[2] == "output")
+	case len(parts) == 4 && parts[1] == "devices":
+		deviceID, err := url.PathUnescape(parts[2])
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		switch parts[3] {
+		case "volume":
+			s.handleVolume(w, r, deviceID)
+		case "mute":
+			s.handleMute(w, r, deviceID)
+		case "ports":
+			s.handlePorts(w, r, deviceID)
+		default:
+			writeError(w, http.StatusNotFound, errors.New("not found"))
+		}
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if s.token == "" {
+		return true
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) == 1
+}
+
+func (s *Server) handleDevices(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+	opts, err := ParseListOptions(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	devices, err := audiocontrol.ListBackendDevices(s.backend, opts)
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	if devices == nil {
+		devices = []audiocontrol.AudioDevice{}
+	}
+	writeJSON(w, http.StatusOK, devices)
+}
+
+func (s *Server) handleDefault(w http.ResponseWriter, r *http.Request, output bool) {
+	switch r.Method {
+	case http.MethodGet:
+		var device audiocontrol.AudioDevice
+		var err error
+		if output {
+			device, err = s.backend.GetActiveOutputDevice()
+		} else {
+			device, err = s.backend.GetActiveInputDevice()
+		}
+		if err != nil {
+			writeBackendError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, device)
+	case http.MethodPut:
+		var req DefaultRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		var err error
+		if output {
+			err = s.backend.SetActiveOutputDevice(req.DeviceID)
+		} else {
+			err = s.backend.SetActiveInputDevice(req.DeviceID)
+		}
+		if err != nil {
+			writeBackendError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+func (s *Server) handleVolume(w http.ResponseWriter, r *http.Request, deviceID string) {
+	switch r.Method {
+	case http.MethodGet:
+		volume, err := s.backend.GetVolume(deviceID)
+		if err != nil {
+			writeBackendError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, VolumeState{Volume: volume})
+	case http.MethodPut:
+		var req VolumeState
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := s.backend.SetVolume(deviceID, req.Volume); err != nil {
+			writeBackendError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+func (s *Server) handleMute(w http.ResponseWriter, r *http.Request, deviceID string) {
+	switch r.Method {
+	case http.MethodGet:
+		muted, err := s.backend.GetMute(deviceID)
+		if err != nil {
+			writeBackendError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, MuteState{Muted: muted})
+	case http.MethodPut:
+		var req MuteState
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := s.backend.SetMute(deviceID, req.Muted); err != nil {
+			writeBackendError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+func (s *Server) handlePorts(w http.ResponseWriter, r *http.Request, deviceID string) {
+	controller, ok := s.backend.(audiocontrol.PortController)
+	if !ok {
+		writeBackendError(w, audiocontrol.ErrNotImplemented)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		ports, err := controller.ListPorts(deviceID)
+		if err != nil {
+			writeBackendError(w, err)
+			return
+		}
+		if ports == nil {
+			ports = []audiocontrol.Port{}
+		}
+		writeJSON(w, http.StatusOK, ports)
+	case http.MethodPut:
+		var req PortRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := controller.SetActivePort(deviceID, req.Port); err != nil {
+			writeBackendError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+func (s *Server) handleCards(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+	controller, ok := s.backend.(audiocontrol.CardController)
+	if !ok {
+		writeBackendError(w, audiocontrol.ErrNotImplemented)
+		return
+	}
+	cards, err := controller.ListCards()
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	if cards == nil {
+		cards = []audiocontrol.Card{}
+	}
+	writeJSON(w, http.StatusOK, cards)
+}
+
+func (s *Server) handleProfiles(w http.ResponseWriter, r *http.Request, cardID string) {
+	controller, ok := s.backend.(audiocontrol.CardController)
+	if !ok {
+		writeBackendError(w, audiocontrol.ErrNotImplemented)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		profiles, err := controller.ListProfiles(cardID)
+		if err != nil {
+			writeBackendError(w, err)
+			return
+		}
+		if profiles == nil {
+			profiles = []audiocontrol.Profile{}
+		}
+		writeJSON(w, http.StatusOK, profiles)
+	case http.MethodPut:
+		var req ProfileRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := controller.SetProfile(cardID, req.Profile); err != nil {
+			writeBackendError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+	controller, ok := s.backend.(audiocontrol.SessionController)
+	if !ok {
+		writeBackendError(w, audiocontrol.ErrNotImplemented)
+		return
+	}
+	sessions, err := controller.ListSessions(r.URL.Query().Get("device"))
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	if sessions == nil {
+		sessions = []audiocontrol.Session{}
+	}
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+// handleSessionControl sets the volume or the mute state of a session. The
+// current values are part of the session list, so there is no GET.
+func (s *Server) handleSessionControl(w http.ResponseWriter, r *http.Request, sessionID string, volume bool) {
+	if r.Method != http.MethodPut {
+		writeMethodNotAllowed(w)
+		return
+	}
+	controller, ok := s.backend.(audiocontrol.SessionController)
+	if !ok {
+		writeBackendError(w, audiocontrol.ErrNotImplemented)
+		return
+	}
+
+	var err error
+	if volume {
+		var req VolumeState
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		err = controller.SetSessionVolume(sessionID, req.Volume)
+	} else {
+		var req MuteState
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		err = controller.SetSessionMute(sessionID, req.Muted)
+	}
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleSessionMove(w http.ResponseWriter, r *http.Request, sessionID string) {
+	if r.Method != http.MethodPut {
+		writeMethodNotAllowed(w)
+		return
+	}
+	mover, ok := s.backend.(audiocontrol.SessionMover)
+	if !ok {
+		writeBackendError(w, audiocontrol.ErrNotImplemented)
+		return
+	}
+	var req MoveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := mover.MoveSession(sessionID, req.DeviceID); err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleEvents streams events as server-sent events until the client goes away
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+
+	events := s.subscribe()
+	defer s.unsubscribe(events)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (s *Server) subscribe() chan audiocontrol.Event {
+	events := make(chan audiocontrol.Event, subscriberBuffer)
+	s.mu.Lock()
+	s.subscribers[events] = struct{}{}
+	s.mu.Unlock()
+	return events
+}
+
+func (s *Server) unsubscribe(events chan audiocontrol.Event) {
+	s.mu.Lock()
+	delete(s.subscribers, events)
+	s.mu.Unlock()
+}
+
+// broadcast hands an event to every stream without blocking the backend's
+// notification thread
+func (s *Server) broadcast(event audiocontrol.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for events := range s.subscribers {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, ErrorResponse{Error: err.Error()})
+}
+
+func writeBackendError(w http.ResponseWriter, err error) {
+	if errors.Is(err, audiocontrol.ErrNotImplemented) {
+		writeJSON(w, http.StatusNotImplemented, ErrorResponse{Error: err.Error(), Code: CodeNotImplemented})
+		return
+	}
+	if errors.Is(err, audiocontrol.ErrDeviceNotFound) {
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: err.Error(), Code: CodeDeviceNotFound})
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err)
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter) {
+	writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+}
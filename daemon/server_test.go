@@ -0,0 +1,216 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+	"github.com/audi70r/go-audio-control/audiotest"
+)
+
+func newTestServer(t *testing.T, token string) (*httptest.Server, *audiotest.Backend) {
+	t.Helper()
+	backend := audiotest.NewBackend(
+		audiocontrol.AudioDevice{ID: "speakers", Name: "Speakers", IsOutput: true, IsActive: true, IsConnected: true},
+		audiocontrol.AudioDevice{ID: "usb/headset", Name: "USB Headset", IsOutput: true, IsConnected: true},
+	)
+	server := httptest.NewServer(NewServer(backend, token))
+	t.Cleanup(server.Close)
+	return server, backend
+}
+
+func TestServerRequiresToken(t *testing.T) {
+	server, _ := newTestServer(t, "secret")
+
+	resp, err := http.Get(server.URL + "/v1/devices")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 without token, got %d", resp.StatusCode)
+	}
+
+	for _, header := range []string{"secret", "Basic secret", "Bearer secre"} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/v1/devices", nil)
+		req.Header.Set("Authorization", header)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected 401 with Authorization %q, got %d", header, resp.StatusCode)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/v1/devices", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 with token, got %d", resp.StatusCode)
+	}
+
+	var devices []audiocontrol.AudioDevice
+	if err := json.NewDecoder(resp.Body).Decode(&devices); err != nil {
+		t.Fatalf("Failed to decode devices: %v", err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("Expected 2 devices, got %d", len(devices))
+	}
+}
+
+func TestServerSetVolumeEscapedID(t *testing.T) {
+	server, backend := newTestServer(t, "")
+
+	req, _ := http.NewRequest(http.MethodPut, server.URL+"/v1/devices/usb%2Fheadset/volume", strings.NewReader(`{"Volume":0.25}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", resp.StatusCode)
+	}
+
+	volume, _ := backend.GetVolume("usb/headset")
+	if volume != 0.25 {
+		t.Fatalf("Expected volume 0.25, got %v", volume)
+	}
+}
+
+func TestServerUnknownDevice(t *testing.T) {
+	server, _ := newTestServer(t, "")
+
+	resp, err := http.Get(server.URL + "/v1/devices/missing/mute")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 400 {
+		t.Fatalf("Expected an error status, got %d", resp.StatusCode)
+	}
+
+	var body ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error == "" {
+		t.Fatalf("Expected an error body, got %+v (%v)", body, err)
+	}
+	if body.Code != CodeDeviceNotFound {
+		t.Fatalf("Expected code %q, got %q", CodeDeviceNotFound, body.Code)
+	}
+}
+
+func TestListenRefusesSharedDirectory(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix socket permissions are not enforced on Windows")
+	}
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Listen("unix:" + filepath.Join(dir, "audiod.sock")); err == nil {
+		t.Fatal("Expected Listen to refuse a directory writable by other users")
+	}
+
+	path := filepath.Join(t.TempDir(), "run", "audiod.sock")
+	listener, err := Listen("unix:" + path)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	for _, p := range []string{filepath.Dir(path), path} {
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm&0o077 != 0 {
+			t.Errorf("%s has mode %v, want no access for other users", p, perm)
+		}
+	}
+}
+
+func TestServerEventStream(t *testing.T) {
+	server, backend := newTestServer(t, "")
+
+	resp, err := http.Get(server.URL + "/v1/events")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// The subscription is registered before the headers are flushed
+	if err := backend.SetActiveOutputDevice("usb/headset"); err != nil {
+		t.Fatalf("Failed to set active device: %v", err)
+	}
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal("Event stream closed")
+			}
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+			var event audiocontrol.Event
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+				t.Fatalf("Failed to decode event: %v", err)
+			}
+			if event.Type != audiocontrol.ActiveDeviceChanged || event.DeviceID != "usb/headset" {
+				t.Fatalf("Unexpected event %+v", event)
+			}
+			return
+		case <-timeout:
+			t.Fatal("No event received")
+		}
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		network string
+		wantErr bool
+	}{
+		{"unix:/run/user/1000/audiod.sock", "unix", false},
+		{"localhost:7445", "tcp", false},
+		{"127.0.0.1:7445", "tcp", false},
+		{"[::1]:7445", "tcp", false},
+		{"0.0.0.0:7445", "", true},
+		{"192.168.1.10:7445", "", true},
+		{"unix:", "", true},
+	}
+
+	for _, tt := range tests {
+		network, _, err := ParseAddress(tt.address)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAddress(%q) error = %v, wantErr %v", tt.address, err, tt.wantErr)
+			continue
+		}
+		if network != tt.network {
+			t.Errorf("ParseAddress(%q) network = %q, want %q", tt.address, network, tt.network)
+		}
+	}
+}
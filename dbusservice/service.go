@@ -0,0 +1,269 @@
+// Package dbusservice exports an audiocontrol.Backend on the D-Bus session
+// bus for panel applets and shell extensions.
+//
+// The service owns the bus name io.github.audi70r.AudioControl1 and serves
+// the interface of the same name at /io/github/audi70r/AudioControl1:
+//
+//	ListDevices() -> a(ssbbbb)
+//	GetDefaultOutput() -> (ssbbbb)
+//	SetDefaultOutput(s device_id)
+//	GetDefaultInput() -> (ssbbbb)
+//	SetDefaultInput(s device_id)
+//	GetVolume(s device_id) -> d
+//	SetVolume(s device_id, d volume)
+//	GetMute(s device_id) -> b
+//	SetMute(s device_id, b muted)
+//
+//	signal DeviceAdded(s DeviceID, (ssbbbb) Device)
+//	signal DeviceRemoved(s DeviceID)
+//	signal ActiveDeviceChanged(s DeviceID, (ssbbbb) Device)
+//	signal DeviceDisconnected(s DeviceID)
+//
+// The (ssbbbb) struct is Device. The introspection data is generated from
+// the Go types in this package, so the list above and Introspect() cannot
+// drift apart; the interface name is versioned and changes when they do.
+package dbusservice
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+)
+
+const (
+	// BusName is the well-known name requested on the session bus
+	BusName = "io.github.audi70r.AudioControl1"
+	// InterfaceName is the D-Bus interface implemented by the service
+	InterfaceName = "io.github.audi70r.AudioControl1"
+	// ObjectPath is the path the service object is exported at
+	ObjectPath = dbus.ObjectPath("/io/github/audi70r/AudioControl1")
+)
+
+// Device is the D-Bus representation of an audio device
+type Device struct {
+	ID          string
+	Name        string
+	IsInput     bool
+	IsOutput    bool
+	IsActive    bool
+	IsConnected bool
+}
+
+// DeviceAdded is emitted when a device appears
+type DeviceAdded struct {
+	DeviceID string
+	Device   Device
+}
+
+// DeviceRemoved is emitted when a device goes away
+type DeviceRemoved struct {
+	DeviceID string
+}
+
+// ActiveDeviceChanged is emitted when a default device changes
+type ActiveDeviceChanged struct {
+	DeviceID string
+	Device   Device
+}
+
+// DeviceDisconnected is emitted when a device stops being usable
+type DeviceDisconnected struct {
+	DeviceID string
+}
+
+// signals lists the signal payloads in the order they are documented
+var signals = []interface{}{
+	DeviceAdded{},
+	DeviceRemoved{},
+	ActiveDeviceChanged{},
+	DeviceDisconnected{},
+}
+
+func toDevice(d audiocontrol.AudioDevice) Device {
+	return Device{
+		ID:          d.ID,
+		Name:        d.Name,
+		IsInput:     d.IsInput,
+		IsOutput:    d.IsOutput,
+		IsActive:    d.IsActive,
+		IsConnected: d.IsConnected,
+	}
+}
+
+// Service is the exported D-Bus object. Its exported methods returning
+// *dbus.Error are the D-Bus methods of InterfaceName.
+type Service struct {
+	conn    *dbus.Conn
+	backend audiocontrol.Backend
+}
+
+// Export exports backend on conn, requests BusName and starts emitting
+// signals for device events
+func Export(conn *dbus.Conn, backend audiocontrol.Backend) (*Service, error) {
+	s := &Service{conn: conn, backend: backend}
+
+	if err := conn.Export(s, ObjectPath, InterfaceName); err != nil {
+		return nil, fmt.Errorf("failed to export service: %w", err)
+	}
+	xml := introspect.NewIntrospectable(Introspection())
+	if err := conn.Export(xml, ObjectPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return nil, fmt.Errorf("failed to export introspection data: %w", err)
+	}
+
+	reply, err := conn.RequestName(BusName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request bus name: %w", err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return nil, fmt.Errorf("bus name %s is already taken", BusName)
+	}
+
+	backend.OnDeviceChange(s.emit)
+	return s, nil
+}
+
+// Introspection returns the introspection data of the service object
+func Introspection() *introspect.Node {
+	iface := introspect.Interface{
+		Name:    InterfaceName,
+		Methods: introspect.Methods(&Service{}),
+	}
+	for _, signal := range signals {
+		iface.Signals = append(iface.Signals, signalOf(signal))
+	}
+
+	return &introspect.Node{
+		Name: string(ObjectPath),
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			iface,
+		},
+	}
+}
+
+// signalOf describes a signal payload struct; each field is one argument
+func signalOf(payload interface{}) introspect.Signal {
+	t := reflect.TypeOf(payload)
+	signal := introspect.Signal{Name: t.Name()}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		signal.Args = append(signal.Args, introspect.Arg{
+			Name: field.Name,
+			Type: dbus.SignatureOfType(field.Type).String(),
+		})
+	}
+	return signal
+}
+
+// emitSignal emits a signal payload with its fields as arguments
+func (s *Service) emitSignal(payload interface{}) error {
+	v := reflect.ValueOf(payload)
+	values := make([]interface{}, v.NumField())
+	for i := range values {
+		values[i] = v.Field(i).Interface()
+	}
+	return s.conn.Emit(ObjectPath, InterfaceName+"."+v.Type().Name(), values...)
+}
+
+func (s *Service) emit(event audiocontrol.Event) {
+	var device Device
+	if event.Info != nil {
+		device = toDevice(*event.Info)
+	}
+
+	switch event.Type {
+	case audiocontrol.DeviceAdded:
+		s.emitSignal(DeviceAdded{DeviceID: event.DeviceID, Device: device})
+	case audiocontrol.DeviceRemoved:
+		s.emitSignal(DeviceRemoved{DeviceID: event.DeviceID})
+	case audiocontrol.ActiveDeviceChanged:
+		s.emitSignal(ActiveDeviceChanged{DeviceID: event.DeviceID, Device: device})
+	case audiocontrol.DeviceDisconnected:
+		s.emitSignal(DeviceDisconnected{DeviceID: event.DeviceID})
+	}
+}
+
+// ListDevices returns all audio devices
+func (s *Service) ListDevices() ([]Device, *dbus.Error) {
+	devices, err := s.backend.ListAudioDevices()
+	if err != nil {
+		return nil, dbus.MakeFailedError(err)
+	}
+	result := make([]Device, len(devices))
+	for i, d := range devices {
+		result[i] = toDevice(d)
+	}
+	return result, nil
+}
+
+// GetDefaultOutput returns the active output device
+func (s *Service) GetDefaultOutput() (Device, *dbus.Error) {
+	device, err := s.backend.GetActiveOutputDevice()
+	if err != nil {
+		return Device{}, dbus.MakeFailedError(err)
+	}
+	return toDevice(device), nil
+}
+
+// SetDefaultOutput sets the active output device
+func (s *Service) SetDefaultOutput(deviceID string) *dbus.Error {
+	if err := s.backend.SetActiveOutputDevice(deviceID); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// GetDefaultInput returns the active input device
+func (s *Service) GetDefaultInput() (Device, *dbus.Error) {
+	device, err := s.backend.GetActiveInputDevice()
+	if err != nil {
+		return Device{}, dbus.MakeFailedError(err)
+	}
+	return toDevice(device), nil
+}
+
+// SetDefaultInput sets the active input device
+func (s *Service) SetDefaultInput(deviceID string) *dbus.Error {
+	if err := s.backend.SetActiveInputDevice(deviceID); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// GetVolume returns the volume of a device
+func (s *Service) GetVolume(deviceID string) (float64, *dbus.Error) {
+	volume, err := s.backend.GetVolume(deviceID)
+	if err != nil {
+		return 0, dbus.MakeFailedError(err)
+	}
+	return volume, nil
+}
+
+// SetVolume sets the volume of a device
+func (s *Service) SetVolume(deviceID string, volume float64) *dbus.Error {
+	if err := s.backend.SetVolume(deviceID, volume); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// GetMute reports whether a device is muted
+func (s *Service) GetMute(deviceID string) (bool, *dbus.Error) {
+	muted, err := s.backend.GetMute(deviceID)
+	if err != nil {
+		return false, dbus.MakeFailedError(err)
+	}
+	return muted, nil
+}
+
+// SetMute mutes or unmutes a device
+func (s *Service) SetMute(deviceID string, muted bool) *dbus.Error {
+	if err := s.backend.SetMute(deviceID, muted); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
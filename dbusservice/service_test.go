@@ -0,0 +1,188 @@
+package dbusservice
+
+import (
+	"bufio"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+	"github.com/audi70r/go-audio-control/audiotest"
+)
+
+// startBus launches a private dbus-daemon and returns its address
+func startBus(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("dbus-daemon"); err != nil {
+		t.Skip("dbus-daemon not installed")
+	}
+
+	address := "unix:path=" + filepath.Join(t.TempDir(), "bus")
+	cmd := exec.Command("dbus-daemon", "--session", "--nofork", "--print-address=1", "--address="+address)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	// The daemon prints its address once it accepts connections
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("Failed to read bus address: %v", err)
+	}
+	return strings.TrimSpace(line)
+}
+
+func connect(t *testing.T, address string) *dbus.Conn {
+	t.Helper()
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatalf("Failed to connect to bus: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func newTestService(t *testing.T) (dbus.BusObject, *dbus.Conn, *audiotest.Backend) {
+	t.Helper()
+	address := startBus(t)
+
+	backend := audiotest.NewBackend(
+		audiocontrol.AudioDevice{ID: "speakers", Name: "Speakers", IsOutput: true, IsActive: true, IsConnected: true},
+		audiocontrol.AudioDevice{ID: "headphones", Name: "Headphones", IsOutput: true, IsConnected: true},
+	)
+	if _, err := Export(connect(t, address), backend); err != nil {
+		t.Fatalf("Failed to export service: %v", err)
+	}
+
+	conn := connect(t, address)
+	return conn.Object(BusName, ObjectPath), conn, backend
+}
+
+func TestServiceMethods(t *testing.T) {
+	obj, _, backend := newTestService(t)
+
+	var devices []Device
+	if err := obj.Call(InterfaceName+".ListDevices", 0).Store(&devices); err != nil {
+		t.Fatalf("ListDevices failed: %v", err)
+	}
+	if len(devices) != 2 || devices[0].Name != "Speakers" {
+		t.Fatalf("Unexpected devices %+v", devices)
+	}
+
+	if err := obj.Call(InterfaceName+".SetDefaultOutput", 0, "headphones").Err; err != nil {
+		t.Fatalf("SetDefaultOutput failed: %v", err)
+	}
+	var output Device
+	if err := obj.Call(InterfaceName+".GetDefaultOutput", 0).Store(&output); err != nil {
+		t.Fatalf("GetDefaultOutput failed: %v", err)
+	}
+	if output.ID != "headphones" {
+		t.Fatalf("Expected headphones as default output, got %s", output.ID)
+	}
+
+	if err := obj.Call(InterfaceName+".SetVolume", 0, "speakers", 0.3).Err; err != nil {
+		t.Fatalf("SetVolume failed: %v", err)
+	}
+	if volume, _ := backend.GetVolume("speakers"); volume != 0.3 {
+		t.Fatalf("Expected volume 0.3, got %v", volume)
+	}
+
+	if err := obj.Call(InterfaceName+".SetMute", 0, "speakers", true).Err; err != nil {
+		t.Fatalf("SetMute failed: %v", err)
+	}
+	var muted bool
+	if err := obj.Call(InterfaceName+".GetMute", 0, "speakers").Store(&muted); err != nil {
+		t.Fatalf("GetMute failed: %v", err)
+	}
+	if !muted {
+		t.Fatal("Expected speakers to be muted")
+	}
+
+	if err := obj.Call(InterfaceName+".SetDefaultOutput", 0, "missing").Err; err == nil {
+		t.Fatal("Expected an error for an unknown device")
+	}
+}
+
+func TestServiceSignals(t *testing.T) {
+	_, conn, backend := newTestService(t)
+
+	if err := conn.AddMatchSignal(dbus.WithMatchInterface(InterfaceName)); err != nil {
+		t.Fatalf("Failed to add match: %v", err)
+	}
+	signals := make(chan *dbus.Signal, 10)
+	conn.Signal(signals)
+
+	backend.AddDevice(audiocontrol.AudioDevice{ID: "usb", Name: "USB Audio", IsOutput: true})
+	backend.RemoveDevice("usb")
+
+	want := []string{"DeviceAdded", "DeviceRemoved"}
+	for _, name := range want {
+		select {
+		case signal := <-signals:
+			if signal.Name != InterfaceName+"."+name {
+				t.Fatalf("Expected %s, got %s", name, signal.Name)
+			}
+			if signal.Body[0] != "usb" {
+				t.Fatalf("Expected device ID usb, got %v", signal.Body[0])
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("No %s signal received", name)
+		}
+	}
+}
+
+func TestServiceIntrospection(t *testing.T) {
+	obj, _, _ := newTestService(t)
+
+	node, err := introspect.Call(obj)
+	if err != nil {
+		t.Fatalf("Introspect failed: %v", err)
+	}
+
+	var iface *introspect.Interface
+	for i := range node.Interfaces {
+		if node.Interfaces[i].Name == InterfaceName {
+			iface = &node.Interfaces[i]
+		}
+	}
+	if iface == nil {
+		t.Fatalf("Interface %s not introspected", InterfaceName)
+	}
+
+	methods := make(map[string]bool)
+	for _, m := range iface.Methods {
+		methods[m.Name] = true
+	}
+	for _, name := range []string{"ListDevices", "GetDefaultOutput", "SetDefaultOutput", "GetDefaultInput", "SetDefaultInput", "GetVolume", "SetVolume", "GetMute", "SetMute"} {
+		if !methods[name] {
+			t.Errorf("Method %s missing from introspection", name)
+		}
+	}
+
+	signatures := make(map[string]string)
+	for _, s := range iface.Signals {
+		var sig string
+		for _, arg := range s.Args {
+			sig += arg.Type
+		}
+		signatures[s.Name] = sig
+	}
+	if signatures["DeviceAdded"] != "s(ssbbbb)" {
+		t.Errorf("DeviceAdded signature = %q, want s(ssbbbb)", signatures["DeviceAdded"])
+	}
+	if signatures["DeviceRemoved"] != "s" {
+		t.Errorf("DeviceRemoved signature = %q, want s", signatures["DeviceRemoved"])
+	}
+}
@@ -0,0 +1,32 @@
+package audiocontrol
+
+import "errors"
+
+// ErrNotImplemented is returned when an operation is not supported by the
+// current platform or backend
+var ErrNotImplemented = errors.New("audiocontrol: not implemented on this platform")
+
+// ErrDeviceNotFound is returned when a device ID does not name a device
+// known to the backend
+var ErrDeviceNotFound = errors.New("audiocontrol: device not found")
+
+// notFoundError keeps the message of a platform error while matching
+// ErrDeviceNotFound
+type notFoundError struct {
+	err error
+}
+
+func (e *notFoundError) Error() string { return e.err.Error() }
+
+func (e *notFoundError) Unwrap() error { return e.err }
+
+func (e *notFoundError) Is(target error) bool { return target == ErrDeviceNotFound }
+
+// platformError makes errors matching a platform package's not-found error
+// match ErrDeviceNotFound as well
+func platformError(err, notFound error) error {
+	if err != nil && errors.Is(err, notFound) {
+		return &notFoundError{err: err}
+	}
+	return err
+}
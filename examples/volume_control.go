@@ -1,3 +1,6 @@
+//go:build ignore
+// +build ignore
+
 package main
 
 import (
@@ -8,14 +11,8 @@ import (
 )
 
 func main() {
-	// Create audio controller
-	controller, err := audiocontrol.New()
-	if err != nil {
-		log.Fatal("Failed to create audio controller:", err)
-	}
-
 	// Get default output device
-	device, err := controller.GetDefaultDevice(audiocontrol.DeviceTypeOutput)
+	device, err := audiocontrol.GetActiveOutputDevice()
 	if err != nil {
 		log.Fatal("Failed to get default output device:", err)
 	}
@@ -23,14 +20,14 @@ func main() {
 	fmt.Printf("Controlling volume for: %s\n", device.Name)
 
 	// Get current volume
-	currentVolume, err := controller.GetVolume(device.ID)
+	currentVolume, err := audiocontrol.GetVolume(device.ID)
 	if err != nil {
 		log.Fatal("Failed to get volume:", err)
 	}
 	fmt.Printf("Current volume: %.0f%%\n", currentVolume*100)
 
 	// Get mute state
-	isMuted, err := controller.GetMute(device.ID)
+	isMuted, err := audiocontrol.GetMute(device.ID)
 	if err != nil {
 		log.Fatal("Failed to get mute state:", err)
 	}
@@ -38,21 +35,21 @@ func main() {
 
 	// Set volume to 50%
 	fmt.Println("\nSetting volume to 50%...")
-	err = controller.SetVolume(device.ID, 0.5)
+	err = audiocontrol.SetVolume(device.ID, 0.5)
 	if err != nil {
 		log.Fatal("Failed to set volume:", err)
 	}
 
 	// Toggle mute
 	fmt.Println("Toggling mute...")
-	err = controller.SetMute(device.ID, !isMuted)
+	err = audiocontrol.SetMute(device.ID, !isMuted)
 	if err != nil {
 		log.Fatal("Failed to toggle mute:", err)
 	}
 
 	// Verify changes
-	newVolume, _ := controller.GetVolume(device.ID)
-	newMute, _ := controller.GetMute(device.ID)
+	newVolume, _ := audiocontrol.GetVolume(device.ID)
+	newMute, _ := audiocontrol.GetMute(device.ID)
 	fmt.Printf("\nNew volume: %.0f%%\n", newVolume*100)
 	fmt.Printf("New mute state: %v\n", newMute)
-}
\ No newline at end of file
+}
@@ -0,0 +1,166 @@
+// Package exporter exposes audio device state as Prometheus metrics.
+//
+// The Collector keeps a snapshot of the device list that is refreshed from
+// OnDeviceChange rather than by polling, and remembers every device it has
+// seen so that a device that disappears keeps reporting
+// audiocontrol_device_connected 0 instead of vanishing from the scrape:
+//
+//	audiocontrol_device_connected{device_id, name}  1 if present and connected
+//	audiocontrol_device_default{device_id, name}    1 if a default device
+//	audiocontrol_device_volume{device_id, name}     volume in [0.0, 1.0]
+//	audiocontrol_device_muted{device_id, name}      1 if muted
+//	audiocontrol_events_total{type}                 events seen per EventType
+//
+// Register the collector with any registry:
+//
+//	collector, err := exporter.NewCollector(audiocontrol.Native())
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	prometheus.MustRegister(collector)
+package exporter
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+)
+
+const namespace = "audiocontrol"
+
+var (
+	connectedDesc = prometheus.NewDesc(namespace+"_device_connected",
+		"Whether the audio device is present and connected.", []string{"device_id", "name"}, nil)
+	defaultDesc = prometheus.NewDesc(namespace+"_device_default",
+		"Whether the audio device is a default device.", []string{"device_id", "name"}, nil)
+	volumeDesc = prometheus.NewDesc(namespace+"_device_volume",
+		"Volume of the audio device in the range 0 to 1.", []string{"device_id", "name"}, nil)
+	mutedDesc = prometheus.NewDesc(namespace+"_device_muted",
+		"Whether the audio device is muted.", []string{"device_id", "name"}, nil)
+	eventsDesc = prometheus.NewDesc(namespace+"_events_total",
+		"Number of audio device events received, by type.", []string{"type"}, nil)
+)
+
+// eventTypes are reported even before their first occurrence so that rate()
+// works from the first scrape
+var eventTypes = audiocontrol.EventTypes()
+
+// deviceState is the last known state of a device
+type deviceState struct {
+	device  audiocontrol.AudioDevice
+	present bool
+}
+
+// Collector is a prometheus.Collector for a Backend
+type Collector struct {
+	backend audiocontrol.Backend
+
+	mu      sync.Mutex
+	devices map[string]*deviceState
+	order   []string
+	events  map[audiocontrol.EventType]float64
+}
+
+// NewCollector returns a Collector for backend. It reads the device list once
+// and then follows device events.
+func NewCollector(backend audiocontrol.Backend) (*Collector, error) {
+	c := &Collector{
+		backend: backend,
+		devices: make(map[string]*deviceState),
+		events:  make(map[audiocontrol.EventType]float64),
+	}
+	if err := c.refresh(); err != nil {
+		return nil, err
+	}
+	backend.OnDeviceChange(c.handleEvent)
+	return c, nil
+}
+
+// refresh replaces the snapshot with the current device list
+func (c *Collector) refresh() error {
+	devices, err := c.backend.ListAudioDevices()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, state := range c.devices {
+		state.present = false
+	}
+	for _, d := range devices {
+		state, ok := c.devices[d.ID]
+		if !ok {
+			state = &deviceState{}
+			c.devices[d.ID] = state
+			c.order = append(c.order, d.ID)
+		}
+		state.device = d
+		state.present = true
+	}
+	return nil
+}
+
+func (c *Collector) handleEvent(event audiocontrol.Event) {
+	c.mu.Lock()
+	c.events[event.Type]++
+	c.mu.Unlock()
+
+	c.refresh()
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- connectedDesc
+	ch <- defaultDesc
+	ch <- volumeDesc
+	ch <- mutedDesc
+	ch <- eventsDesc
+}
+
+// Collect implements prometheus.Collector. Volume and mute are read from
+// the backend for present devices, as they have no change events.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	states := make([]deviceState, 0, len(c.order))
+	for _, id := range c.order {
+		states = append(states, *c.devices[id])
+	}
+	events := make(map[audiocontrol.EventType]float64, len(c.events))
+	for t, n := range c.events {
+		events[t] = n
+	}
+	c.mu.Unlock()
+
+	for _, state := range states {
+		d := state.device
+		ch <- prometheus.MustNewConstMetric(connectedDesc, prometheus.GaugeValue,
+			boolValue(state.present && d.IsConnected), d.ID, d.Name)
+		ch <- prometheus.MustNewConstMetric(defaultDesc, prometheus.GaugeValue,
+			boolValue(state.present && d.IsActive), d.ID, d.Name)
+
+		if !state.present {
+			continue
+		}
+		if volume, err := c.backend.GetVolume(d.ID); err == nil {
+			ch <- prometheus.MustNewConstMetric(volumeDesc, prometheus.GaugeValue, volume, d.ID, d.Name)
+		}
+		if muted, err := c.backend.GetMute(d.ID); err == nil {
+			ch <- prometheus.MustNewConstMetric(mutedDesc, prometheus.GaugeValue, boolValue(muted), d.ID, d.Name)
+		}
+	}
+
+	for _, t := range eventTypes {
+		ch <- prometheus.MustNewConstMetric(eventsDesc, prometheus.CounterValue, events[t], t.String())
+	}
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
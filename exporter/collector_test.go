@@ -0,0 +1,130 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+	"github.com/audi70r/go-audio-control/audiotest"
+)
+
+func TestCollector(t *testing.T) {
+	backend := audiotest.NewBackend(
+		audiocontrol.AudioDevice{ID: "speakers", Name: "Speakers", IsOutput: true, IsActive: true, IsConnected: true},
+		audiocontrol.AudioDevice{ID: "usb", Name: "USB Speaker", IsOutput: true, IsConnected: true},
+	)
+	backend.SetVolume("usb", 0.5)
+	backend.SetMute("speakers", true)
+
+	collector, err := NewCollector(backend)
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	// The USB speaker drops off the bus
+	backend.RemoveDevice("usb")
+
+	expected := `
+# HELP audiocontrol_device_connected Whether the audio device is present and connected.
+# TYPE audiocontrol_device_connected gauge
+audiocontrol_device_connected{device_id="speakers",name="Speakers"} 1
+audiocontrol_device_connected{device_id="usb",name="USB Speaker"} 0
+# HELP audiocontrol_device_default Whether the audio device is a default device.
+# TYPE audiocontrol_device_default gauge
+audiocontrol_device_default{device_id="speakers",name="Speakers"} 1
+audiocontrol_device_default{device_id="usb",name="USB Speaker"} 0
+# HELP audiocontrol_device_muted Whether the audio device is muted.
+# TYPE audiocontrol_device_muted gauge
+audiocontrol_device_muted{device_id="speakers",name="Speakers"} 1
+# HELP audiocontrol_device_volume Volume of the audio device in the range 0 to 1.
+# TYPE audiocontrol_device_volume gauge
+audiocontrol_device_volume{device_id="speakers",name="Speakers"} 1
+# HELP audiocontrol_events_total Number of audio device events received, by type.
+# TYPE audiocontrol_events_total counter
+audiocontrol_events_total{type="ActiveDeviceChanged"} 0
+audiocontrol_events_total{type="DeviceAdded"} 0
+audiocontrol_events_total{type="DeviceDisconnected"} 0
+audiocontrol_events_total{type="DeviceRemoved"} 1
+audiocontrol_events_total{type="PortChanged"} 0
+audiocontrol_events_total{type="ProfileChanged"} 0
+audiocontrol_events_total{type="ServerConnected"} 0
+audiocontrol_events_total{type="ServerDisconnected"} 0
+audiocontrol_events_total{type="SessionAdded"} 0
+audiocontrol_events_total{type="SessionRemoved"} 0
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCollectorFollowsEvents(t *testing.T) {
+	backend := audiotest.NewBackend(
+		audiocontrol.AudioDevice{ID: "speakers", Name: "Speakers", IsOutput: true, IsActive: true, IsConnected: true},
+	)
+	collector, err := NewCollector(backend)
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	backend.AddDevice(audiocontrol.AudioDevice{ID: "hdmi", Name: "HDMI", IsOutput: true, IsConnected: true})
+	backend.SetActiveOutputDevice("hdmi")
+
+	expected := `
+# HELP audiocontrol_device_default Whether the audio device is a default device.
+# TYPE audiocontrol_device_default gauge
+audiocontrol_device_default{device_id="hdmi",name="HDMI"} 1
+audiocontrol_device_default{device_id="speakers",name="Speakers"} 0
+# HELP audiocontrol_events_total Number of audio device events received, by type.
+# TYPE audiocontrol_events_total counter
+audiocontrol_events_total{type="ActiveDeviceChanged"} 1
+audiocontrol_events_total{type="DeviceAdded"} 1
+audiocontrol_events_total{type="DeviceDisconnected"} 0
+audiocontrol_events_total{type="DeviceRemoved"} 0
+audiocontrol_events_total{type="PortChanged"} 0
+audiocontrol_events_total{type="ProfileChanged"} 0
+audiocontrol_events_total{type="ServerConnected"} 0
+audiocontrol_events_total{type="ServerDisconnected"} 0
+audiocontrol_events_total{type="SessionAdded"} 0
+audiocontrol_events_total{type="SessionRemoved"} 0
+`
+	err = testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"audiocontrol_device_default", "audiocontrol_events_total")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCollectorReportsEveryEventType(t *testing.T) {
+	collector, err := NewCollector(audiotest.NewBackend())
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(collector)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather: %v", err)
+	}
+
+	series := make(map[string]float64)
+	for _, family := range families {
+		if family.GetName() != "audiocontrol_events_total" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			series[m.GetLabel()[0].GetValue()] = m.GetCounter().GetValue()
+		}
+	}
+	types := audiocontrol.EventTypes()
+	if len(series) != len(types) {
+		t.Errorf("Got %d event series, want %d", len(series), len(types))
+	}
+	for _, eventType := range types {
+		if value, ok := series[eventType.String()]; !ok || value != 0 {
+			t.Errorf("Series for %v is %v, %v, want 0", eventType, value, ok)
+		}
+	}
+}
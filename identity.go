@@ -0,0 +1,199 @@
+package audiocontrol
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Identity holds durable properties of the hardware behind a device, from
+// which StableID is computed. Platforms fill in what they know: Linux the
+// udev and Bluetooth properties of sinks and sources, macOS the model UID
+// and, for Bluetooth devices, the address. Windows reports none yet.
+type Identity struct {
+	// Bus is how the device is attached, such as "usb", "bluetooth" or "pci"
+	Bus string `json:",omitempty"`
+	// VendorID and ProductID are the hexadecimal IDs of the hardware
+	VendorID  string `json:",omitempty"`
+	ProductID string `json:",omitempty"`
+	Serial    string `json:",omitempty"`
+	// BluetoothAddress is the address of a Bluetooth device in any of the
+	// usual formats
+	BluetoothAddress string `json:",omitempty"`
+	// ModelUID identifies the model of a device on macOS
+	ModelUID string `json:",omitempty"`
+	// Endpoint tells the devices of one piece of hardware apart, such as
+	// the ALSA device number of an analog and an HDMI output of one card
+	Endpoint string `json:",omitempty"`
+}
+
+// StableID returns an identifier for the device that, unlike ID, survives
+// reboots, replugging into another port and sound server restarts. Use it
+// to save preferences and ResolveStableID to find the device again.
+//
+// The identifier is "<direction>/<scheme>:<value>". The direction is "out",
+// "in" or "io" for a device that is both. The scheme is the first of these
+// that the Identity allows:
+//
+//	bt:<address>                            Bluetooth address as 12 upper-case hex digits
+//	hw:<vendor>:<product>:<serial>[:<ep>]   hardware IDs with a serial number
+//	model:<model>[:<ep>]:<name>             ModelUID, or vendor:product, with the name
+//	name:<name>                             the name alone
+//
+// Bluetooth addresses are normalized, so the different formats of
+// CoreAudio UIDs give one identifier. Port paths, card indices and other
+// enumeration details are never part of it. Devices of one model that lack
+// a serial number differ only by name; two with the same name as well
+// share an identifier.
+func (d AudioDevice) StableID() string {
+	return d.stableDirection() + "/" + d.stableKey()
+}
+
+func (d AudioDevice) stableDirection() string {
+	switch {
+	case d.IsInput && d.IsOutput:
+		return "io"
+	case d.IsInput:
+		return "in"
+	}
+	return "out"
+}
+
+func (d AudioDevice) stableKey() string {
+	id := d.Identity
+	name := d.Name
+	if d.OriginalName != "" {
+		name = d.OriginalName
+	}
+	name = strings.TrimSpace(name)
+	if address := normalizeBluetoothAddress(id.BluetoothAddress); address != "" {
+		return "bt:" + address
+	}
+	vendor, product := normalizeHexID(id.VendorID), normalizeHexID(id.ProductID)
+	if vendor != "" && product != "" && id.Serial != "" {
+		return "hw:" + vendor + ":" + product + ":" + id.Serial + endpointSuffix(id.Endpoint)
+	}
+	if model := d.stableModel(); model != "" {
+		return "model:" + model + ":" + name
+	}
+	return "name:" + name
+}
+
+// stableModel returns the model part of a model identifier, up to the name
+func (d AudioDevice) stableModel() string {
+	id := d.Identity
+	vendor, product := normalizeHexID(id.VendorID), normalizeHexID(id.ProductID)
+	switch {
+	case id.ModelUID != "":
+		return id.ModelUID + endpointSuffix(id.Endpoint)
+	case vendor != "" && product != "":
+		return vendor + ":" + product + endpointSuffix(id.Endpoint)
+	}
+	return ""
+}
+
+func endpointSuffix(endpoint string) string {
+	if endpoint == "" {
+		return ""
+	}
+	return ":" + endpoint
+}
+
+// normalizeBluetoothAddress returns the 12 hex digits of an address written
+// with any separators, such as "ac:bc:32:0a:1b:2c" or "AC-BC-32-0A-1B-2C",
+// or "" if s holds no address
+func normalizeBluetoothAddress(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9', r >= 'A' && r <= 'F':
+			b.WriteRune(r)
+		case r >= 'a' && r <= 'f':
+			b.WriteRune(r - 'a' + 'A')
+		case r == ':' || r == '-' || r == '.' || r == '_':
+		default:
+			return ""
+		}
+	}
+	if b.Len() != 12 {
+		return ""
+	}
+	return b.String()
+}
+
+// normalizeHexID lower-cases a hexadecimal ID and drops a 0x prefix
+func normalizeHexID(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return strings.TrimPrefix(s, "0x")
+}
+
+// MatchStableID finds the device with a stable ID among devices:
+//
+//  1. Devices with the same key whose direction is compatible, where "io"
+//     is compatible with "out" and "in" as macOS reports one device for
+//     both directions. A present device is preferred over one that is
+//     not, and otherwise the first in devices.
+//  2. For a model identifier, the only compatible device of that model, so
+//     that a device renamed by the user is still found.
+//
+// Identifiers of other schemes match nothing else: a different Bluetooth
+// address or serial number is different hardware.
+func MatchStableID(stableID string, devices []AudioDevice) (AudioDevice, bool) {
+	direction, key, ok := strings.Cut(stableID, "/")
+	if !ok {
+		return AudioDevice{}, false
+	}
+
+	found := -1
+	for i, d := range devices {
+		if d.stableKey() != key || !compatibleDirections(direction, d.stableDirection()) {
+			continue
+		}
+		if found < 0 || (devices[found].State == StateNotPresent && d.State != StateNotPresent) {
+			found = i
+		}
+	}
+	if found >= 0 {
+		return devices[found], true
+	}
+
+	if !strings.HasPrefix(key, "model:") {
+		return AudioDevice{}, false
+	}
+	for i, d := range devices {
+		model := d.stableModel()
+		if model == "" || !strings.HasPrefix(key, "model:"+model+":") ||
+			!compatibleDirections(direction, d.stableDirection()) {
+			continue
+		}
+		if found >= 0 {
+			return AudioDevice{}, false
+		}
+		found = i
+	}
+	if found >= 0 {
+		return devices[found], true
+	}
+	return AudioDevice{}, false
+}
+
+func compatibleDirections(a, b string) bool {
+	return a == b || a == "io" || b == "io"
+}
+
+// ResolveStableID returns the current device with a stable ID, including
+// disabled, unplugged, absent and hidden devices, which the State and
+// Hidden fields of the result report
+func ResolveStableID(stableID string) (AudioDevice, error) {
+	devices, err := ListBackendDevices(currentBackend(), ListOptions{
+		IncludeDisabled:   true,
+		IncludeUnplugged:  true,
+		IncludeNotPresent: true,
+	})
+	if err != nil {
+		return AudioDevice{}, err
+	}
+	if d, ok := MatchStableID(stableID, devices); ok {
+		return applyAlias(d), nil
+	}
+	return AudioDevice{}, fmt.Errorf("%w: stable ID %s", ErrDeviceNotFound, stableID)
+}
@@ -0,0 +1,143 @@
+package audiocontrol_test
+
+import (
+	"errors"
+	"testing"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+	"github.com/audi70r/go-audio-control/audiotest"
+)
+
+func TestStableID(t *testing.T) {
+	tests := []struct {
+		name   string
+		device audiocontrol.AudioDevice
+		want   string
+	}{
+		{"bluetooth", audiocontrol.AudioDevice{Name: "AirPods", IsOutput: true, IsInput: true,
+			Identity: audiocontrol.Identity{BluetoothAddress: "ac-bc-32-0a-1b-2c", ModelUID: "AirPods"}},
+			"io/bt:ACBC320A1B2C"},
+		{"serial", audiocontrol.AudioDevice{Name: "USB Audio CODEC", IsOutput: true,
+			Identity: audiocontrol.Identity{VendorID: "0x08BB", ProductID: "2902", Serial: "ABC123", Endpoint: "0"}},
+			"out/hw:08bb:2902:ABC123:0"},
+		{"model", audiocontrol.AudioDevice{Name: "MacBook Pro Speakers", IsOutput: true,
+			Identity: audiocontrol.Identity{ModelUID: "Codec Output"}},
+			"out/model:Codec Output:MacBook Pro Speakers"},
+		{"vendor and product", audiocontrol.AudioDevice{Name: "Headset Microphone", IsInput: true,
+			Identity: audiocontrol.Identity{VendorID: "046d", ProductID: "0a44"}},
+			"in/model:046d:0a44:Headset Microphone"},
+		{"invalid address", audiocontrol.AudioDevice{Name: "Speaker", IsOutput: true,
+			Identity: audiocontrol.Identity{BluetoothAddress: "/org/bluez/hci0"}},
+			"out/name:Speaker"},
+		{"name", audiocontrol.AudioDevice{Name: " Speakers ", IsOutput: true}, "out/name:Speakers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.device.StableID(); got != tt.want {
+				t.Errorf("StableID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Device sets as one machine reports them before and after a reboot,
+// replug or OS update
+var (
+	bluetoothBefore = audiocontrol.AudioDevice{ID: "AC-BC-32-0A-1B-2C:output", Name: "AirPods", IsOutput: true, IsInput: true,
+		Identity: audiocontrol.Identity{Bus: "bluetooth", BluetoothAddress: "AC-BC-32-0A-1B-2C"}}
+	bluetoothAfter = audiocontrol.AudioDevice{ID: "acbc320a1b2c:output", Name: "AirPods", IsOutput: true, IsInput: true,
+		Identity: audiocontrol.Identity{Bus: "bluetooth", BluetoothAddress: "acbc320a1b2c"}}
+
+	usbBefore = audiocontrol.AudioDevice{ID: "alsa_output.usb-Burr-Brown_USB_Audio_CODEC-00.analog-stereo", Name: "USB Audio CODEC", IsOutput: true,
+		Identity: audiocontrol.Identity{Bus: "usb", VendorID: "08bb", ProductID: "2902", Serial: "Burr-Brown_USB_Audio_CODEC", Endpoint: "0"}}
+	usbAfter = audiocontrol.AudioDevice{ID: "alsa_output.usb-Burr-Brown_USB_Audio_CODEC-01.analog-stereo", Name: "USB Audio CODEC", IsOutput: true,
+		Identity: audiocontrol.Identity{Bus: "usb", VendorID: "08bb", ProductID: "2902", Serial: "Burr-Brown_USB_Audio_CODEC", Endpoint: "0"}}
+	usbInput = audiocontrol.AudioDevice{ID: "alsa_input.usb-Burr-Brown_USB_Audio_CODEC-01.analog-stereo", Name: "USB Audio CODEC", IsInput: true,
+		Identity: audiocontrol.Identity{Bus: "usb", VendorID: "08bb", ProductID: "2902", Serial: "Burr-Brown_USB_Audio_CODEC", Endpoint: "0"}}
+	usbHDMI = audiocontrol.AudioDevice{ID: "alsa_output.usb-Burr-Brown_USB_Audio_CODEC-01.hdmi-stereo", Name: "USB Audio CODEC", IsOutput: true,
+		Identity: audiocontrol.Identity{Bus: "usb", VendorID: "08bb", ProductID: "2902", Serial: "Burr-Brown_USB_Audio_CODEC", Endpoint: "3"}}
+
+	builtinBefore = audiocontrol.AudioDevice{ID: "BuiltInSpeakerDevice", Name: "MacBook Pro Speakers", IsOutput: true,
+		Identity: audiocontrol.Identity{Bus: "builtin", ModelUID: "Codec Output"}}
+	builtinRenamed = audiocontrol.AudioDevice{ID: "BuiltInSpeakerDevice", Name: "Laptop Speakers", IsOutput: true,
+		Identity: audiocontrol.Identity{Bus: "builtin", ModelUID: "Codec Output"}}
+
+	dockA = audiocontrol.AudioDevice{ID: "dock-a", Name: "Dock Audio", IsOutput: true, State: audiocontrol.StateNotPresent,
+		Identity: audiocontrol.Identity{VendorID: "0bda", ProductID: "4014"}}
+	dockB = audiocontrol.AudioDevice{ID: "dock-b", Name: "Dock Audio", IsOutput: true,
+		Identity: audiocontrol.Identity{VendorID: "0bda", ProductID: "4014"}}
+	otherDock = audiocontrol.AudioDevice{ID: "dock-c", Name: "Dock Audio (2)", IsOutput: true,
+		Identity: audiocontrol.Identity{VendorID: "0bda", ProductID: "4014"}}
+
+	hdmi = audiocontrol.AudioDevice{ID: "hdmi.0", Name: "HDMI Output", IsOutput: true}
+)
+
+func TestMatchStableID(t *testing.T) {
+	tests := []struct {
+		name    string
+		saved   audiocontrol.AudioDevice
+		devices []audiocontrol.AudioDevice
+		want    string
+	}{
+		{"bluetooth address format changed", bluetoothBefore,
+			[]audiocontrol.AudioDevice{usbAfter, bluetoothAfter}, bluetoothAfter.ID},
+		{"usb moved to another port", usbBefore,
+			[]audiocontrol.AudioDevice{usbInput, usbHDMI, usbAfter}, usbAfter.ID},
+		{"input of the same hardware", usbInput,
+			[]audiocontrol.AudioDevice{usbAfter, usbHDMI, usbInput}, usbInput.ID},
+		{"one direction of a device with both", audiocontrol.AudioDevice{Name: "AirPods", IsOutput: true,
+			Identity: bluetoothBefore.Identity}, []audiocontrol.AudioDevice{bluetoothAfter}, bluetoothAfter.ID},
+		{"renamed", builtinBefore, []audiocontrol.AudioDevice{hdmi, builtinRenamed}, builtinRenamed.ID},
+		{"identical devices prefer a present one", dockA,
+			[]audiocontrol.AudioDevice{dockA, otherDock, dockB}, dockB.ID},
+		{"identical devices otherwise the first", dockB,
+			[]audiocontrol.AudioDevice{otherDock, dockB, dockA}, dockB.ID},
+		{"name only", hdmi, []audiocontrol.AudioDevice{builtinBefore, hdmi}, hdmi.ID},
+		{"different serial", usbBefore, []audiocontrol.AudioDevice{{ID: "other", Name: "USB Audio CODEC", IsOutput: true,
+			Identity: audiocontrol.Identity{VendorID: "08bb", ProductID: "2902", Serial: "XYZ"}}}, ""},
+		{"renamed among identical models", dockA, []audiocontrol.AudioDevice{
+			{ID: "x", Name: "Left Dock", IsOutput: true, Identity: dockA.Identity},
+			{ID: "y", Name: "Right Dock", IsOutput: true, Identity: dockA.Identity}}, ""},
+		{"wrong direction", usbInput, []audiocontrol.AudioDevice{usbAfter}, ""},
+		{"absent", bluetoothBefore, []audiocontrol.AudioDevice{usbAfter, hdmi}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := audiocontrol.MatchStableID(tt.saved.StableID(), tt.devices)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("MatchStableID() = %q, want no match", got.ID)
+				}
+				return
+			}
+			if !ok || got.ID != tt.want {
+				t.Errorf("MatchStableID() = %q, %v, want %q", got.ID, ok, tt.want)
+			}
+		})
+	}
+
+	if _, ok := audiocontrol.MatchStableID("usb-path", []audiocontrol.AudioDevice{usbAfter}); ok {
+		t.Error("MatchStableID() matched a malformed ID")
+	}
+}
+
+func TestResolveStableID(t *testing.T) {
+	b := audiotest.NewBackend(usbAfter, usbInput, hdmi)
+	if err := b.SetState(hdmi.ID, audiocontrol.StateDisabled); err != nil {
+		t.Fatalf("Failed to set state: %v", err)
+	}
+	audiocontrol.SetBackend(b)
+	t.Cleanup(func() { audiocontrol.SetBackend(nil) })
+
+	d, err := audiocontrol.ResolveStableID(usbBefore.StableID())
+	if err != nil || d.ID != usbAfter.ID {
+		t.Errorf("ResolveStableID() = %q, %v, want %q", d.ID, err, usbAfter.ID)
+	}
+	d, err = audiocontrol.ResolveStableID(hdmi.StableID())
+	if err != nil || d.State != audiocontrol.StateDisabled {
+		t.Errorf("ResolveStableID() = %+v, %v, want the disabled HDMI output", d, err)
+	}
+	if _, err := audiocontrol.ResolveStableID(bluetoothBefore.StableID()); !errors.Is(err, audiocontrol.ErrDeviceNotFound) {
+		t.Errorf("ResolveStableID() = %v, want ErrDeviceNotFound", err)
+	}
+}
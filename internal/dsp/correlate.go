@@ -0,0 +1,102 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// Correlate finds where ref occurs in signal. It returns the offset into
+// signal at which the cross-correlation peaks and the normalised
+// correlation there: 1 when that part of signal is ref scaled, falling
+// towards 0 as noise and distortion grow. Inverted copies are found too.
+// The offset is -1 when signal is shorter than ref or either is silent.
+func Correlate(signal, ref []float32) (offset int, score float64) {
+	if len(ref) == 0 || len(signal) < len(ref) {
+		return -1, 0
+	}
+
+	// Correlate through the frequency domain. The transform is at least as
+	// long as signal, so offsets up to len(signal)-len(ref) do not wrap.
+	n := 1
+	for n < len(signal) {
+		n <<= 1
+	}
+	s := make([]complex128, n)
+	for i, v := range signal {
+		s[i] = complex(float64(v), 0)
+	}
+	r := make([]complex128, n)
+	var refEnergy float64
+	for i, v := range ref {
+		r[i] = complex(float64(v), 0)
+		refEnergy += float64(v) * float64(v)
+	}
+	fft(s, false)
+	fft(r, false)
+	for i := range s {
+		s[i] *= cmplx.Conj(r[i])
+	}
+	fft(s, true)
+
+	// The energy of each window of signal, from a running sum of squares
+	energy := make([]float64, len(signal)+1)
+	for i, v := range signal {
+		energy[i+1] = energy[i] + float64(v)*float64(v)
+	}
+
+	offset = -1
+	best := 0.0
+	for lag := 0; lag <= len(signal)-len(ref); lag++ {
+		if c := math.Abs(real(s[lag])); c > best {
+			best = c
+			offset = lag
+		}
+	}
+	if offset < 0 {
+		return -1, 0
+	}
+	window := energy[offset+len(ref)] - energy[offset]
+	if refEnergy == 0 || window <= 0 {
+		return -1, 0
+	}
+	return offset, math.Min(best/math.Sqrt(refEnergy*window), 1)
+}
+
+// fft transforms x in place. Its length must be a power of two. The
+// inverse transform is scaled by 1/len(x).
+func fft(x []complex128, inverse bool) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	sign := -1.0
+	if inverse {
+		sign = 1
+	}
+	for size := 2; size <= n; size <<= 1 {
+		half := size / 2
+		for k := 0; k < half; k++ {
+			sin, cos := math.Sincos(sign * 2 * math.Pi * float64(k) / float64(size))
+			w := complex(cos, sin)
+			for start := 0; start < n; start += size {
+				a, b := x[start+k], x[start+k+half]*w
+				x[start+k], x[start+k+half] = a+b, a-b
+			}
+		}
+	}
+
+	if inverse {
+		scale := complex(1/float64(n), 0)
+		for i := range x {
+			x[i] *= scale
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package dsp
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// sine returns frames of a stereo sine with the given amplitude on the left
+// channel and half of it on the right
+func sine(frames int, amplitude float64) []float32 {
+	samples := make([]float32, 2*frames)
+	for f := 0; f < frames; f++ {
+		v := amplitude * math.Sin(2*math.Pi*1000*float64(f)/48000)
+		samples[2*f] = float32(v)
+		samples[2*f+1] = float32(v / 2)
+	}
+	return samples
+}
+
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func TestAccumulator(t *testing.T) {
+	a := NewAccumulator(2)
+	// 48 frames are one full period of the sine
+	a.Add(sine(4800, 0.5))
+	peak, rms := a.Levels()
+	if !near(peak[0], 0.5, 1e-3) || !near(peak[1], 0.25, 1e-3) {
+		t.Errorf("Peaks are %v", peak)
+	}
+	if !near(rms[0], 0.5/math.Sqrt2, 1e-3) || !near(rms[1], 0.25/math.Sqrt2, 1e-3) {
+		t.Errorf("RMS levels are %v", rms)
+	}
+	if a.Frames() != 4800 {
+		t.Errorf("Measured %d frames", a.Frames())
+	}
+
+	a.Reset()
+	a.Add([]float32{-1, 0, 0})
+	peak, rms = a.Levels()
+	if peak[0] != 1 || rms[0] != 1 || peak[1] != 0 || a.Frames() != 1 {
+		t.Errorf("Levels of a single frame are %v %v over %d frames", peak, rms, a.Frames())
+	}
+}
+
+func TestDBFS(t *testing.T) {
+	tests := []struct {
+		level, want float64
+	}{
+		{1, 0},
+		{0.5, -6.0206},
+		{0.1, -20},
+		{0, MinDBFS},
+		{1e-9, MinDBFS},
+	}
+	for _, tt := range tests {
+		if got := DBFS(tt.level); !near(got, tt.want, 1e-3) {
+			t.Errorf("DBFS(%v) = %v, want %v", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestDecay(t *testing.T) {
+	d := Decay{Rate: 20}
+	if got := d.Apply([]float64{1}, 100*time.Millisecond); got[0] != 1 {
+		t.Fatalf("Reading rose to %v, want 1", got[0])
+	}
+	// After one second of silence the reading has fallen by 20 dB
+	var got []float64
+	for i := 0; i < 10; i++ {
+		got = d.Apply([]float64{0}, 100*time.Millisecond)
+	}
+	if !near(DBFS(got[0]), -20, 1e-6) {
+		t.Errorf("Reading fell to %v dBFS, want -20", DBFS(got[0]))
+	}
+	// A louder level takes effect at once
+	if got := d.Apply([]float64{0.5}, 100*time.Millisecond); got[0] != 0.5 {
+		t.Errorf("Reading is %v, want 0.5", got[0])
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	samples := []float32{0, 0.5, -0.5, 1, -1}
+	for _, e := range []Encoding{EncodingS16LE, EncodingS24LE, EncodingS32LE, EncodingFloat32LE} {
+		data := Encode(samples, e)
+		if len(data) != len(samples)*e.Size() {
+			t.Fatalf("Encoding %d gave %d bytes", e, len(data))
+		}
+		decoded := Decode(data, e)
+		for i, s := range samples {
+			// The positive limit of integer formats is one step below 1
+			if !near(float64(decoded[i]), float64(s), 1.0/(1<<15)) {
+				t.Errorf("Encoding %d: sample %d decoded as %v, want %v", e, i, decoded[i], s)
+			}
+		}
+	}
+
+	// Exact values of the integer formats
+	if data := Encode([]float32{-1, 0.5}, EncodingS16LE); data[0] != 0x00 || data[1] != 0x80 || data[2] != 0x00 || data[3] != 0x40 {
+		t.Errorf("S16LE encoding is % x", data)
+	}
+	if data := Encode([]float32{-1}, EncodingS24LE); data[0] != 0 || data[1] != 0 || data[2] != 0x80 {
+		t.Errorf("S24LE encoding is % x", data)
+	}
+	if got := Decode([]byte{0xff, 0xff, 0xff}, EncodingS24LE); got[0] != -1.0/(1<<23) {
+		t.Errorf("S24LE -1 decoded as %v", got[0])
+	}
+	if got := Encode([]float32{2}, EncodingFloat32LE); Decode(got, EncodingFloat32LE)[0] != 1 {
+		t.Error("Float samples are not clipped")
+	}
+}
+
+func TestTone(t *testing.T) {
+	tone := Tone{Frequency: 1000, Level: 0.5, Rate: 48000, Frames: 4800, Channels: 2, Channel: 1, Fade: 480}
+	samples := tone.Samples()
+	if len(samples) != 9600 {
+		t.Fatalf("Tone has %d samples", len(samples))
+	}
+
+	a := NewAccumulator(2)
+	a.Add(samples)
+	peak, _ := a.Levels()
+	if peak[0] != 0 || !near(peak[1], 0.5, 1e-3) {
+		t.Errorf("Peaks are %v, want only the second channel at 0.5", peak)
+	}
+	// The ramps start and end at silence
+	if samples[1] != 0 || samples[len(samples)-1] != 0 {
+		t.Errorf("Tone starts at %v and ends at %v", samples[1], samples[len(samples)-1])
+	}
+	// A quarter period after the fade the sine is at its peak
+	if got := samples[2*(480+12)+1]; !near(float64(got), 0.5, 1e-6) {
+		t.Errorf("Sample after the fade is %v, want 0.5", got)
+	}
+
+	tone.Channel = -1
+	for ch, v := range tone.Samples()[2*492 : 2*493] {
+		if !near(float64(v), 0.5, 1e-6) {
+			t.Errorf("Channel %d is %v, want 0.5", ch, v)
+		}
+	}
+}
+
+func TestChirp(t *testing.T) {
+	chirp := Chirp{From: 500, To: 4000, Level: 0.5, Rate: 48000, Frames: 9600, Fade: 480}
+	samples := chirp.Samples()
+	if len(samples) != 9600 || samples[0] != 0 || samples[len(samples)-1] != 0 {
+		t.Fatalf("Chirp of %d samples does not start and end at silence", len(samples))
+	}
+
+	// The sweep crosses zero more often in its second half
+	crossings := func(s []float32) int {
+		n := 0
+		for i := 1; i < len(s); i++ {
+			if (s[i-1] < 0) != (s[i] < 0) {
+				n++
+			}
+		}
+		return n
+	}
+	// 500 to 2250 Hz averages 1375 Hz over 0.1s, 2250 to 4000 Hz 3125 Hz
+	if first := crossings(samples[:4800]); !near(float64(first), 275, 5) {
+		t.Errorf("First half crosses zero %d times, want about 275", first)
+	}
+	if second := crossings(samples[4800:]); !near(float64(second), 625, 5) {
+		t.Errorf("Second half crosses zero %d times, want about 625", second)
+	}
+}
+
+func TestCorrelate(t *testing.T) {
+	ref := Chirp{From: 500, To: 4000, Level: 0.5, Rate: 48000, Frames: 4800, Fade: 240}.Samples()
+	rng := rand.New(rand.NewSource(1))
+	noise := func(n int, level float64) []float32 {
+		s := make([]float32, n)
+		for i := range s {
+			s[i] = float32(level * (2*rng.Float64() - 1))
+		}
+		return s
+	}
+
+	// A quieter, inverted copy of the chirp delayed within noise
+	signal := noise(48000, 0.02)
+	for i, v := range ref {
+		signal[12345+i] -= 0.3 * v
+	}
+	offset, score := Correlate(signal, ref)
+	if offset != 12345 || score < 0.9 {
+		t.Errorf("Found the chirp at %d with score %v, want 12345 above 0.9", offset, score)
+	}
+
+	// An exact copy scores 1
+	if offset, score := Correlate(append(make([]float32, 7), ref...), ref); offset != 7 || !near(score, 1, 1e-9) {
+		t.Errorf("Exact copy found at %d with score %v", offset, score)
+	}
+
+	if _, score := Correlate(noise(48000, 0.5), ref); score > 0.2 {
+		t.Errorf("Noise alone scores %v", score)
+	}
+	if offset, _ := Correlate(ref[:100], ref); offset != -1 {
+		t.Errorf("Offset in a short signal is %d", offset)
+	}
+	if offset, _ := Correlate(make([]float32, 10000), ref); offset != -1 {
+		t.Errorf("Offset in silence is %d", offset)
+	}
+}
@@ -0,0 +1,104 @@
+// Package dsp holds the signal processing shared by the platform backends:
+// PCM decoding, level measurement and meter ballistics. It is pure Go so
+// that it can be tested on synthetic buffers.
+package dsp
+
+import (
+	"math"
+	"time"
+)
+
+// MinDBFS is the level reported for silence, so that levels stay finite
+const MinDBFS = -120.0
+
+// DBFS converts a linear level, where 1.0 is full scale, to decibels
+// relative to full scale
+func DBFS(level float64) float64 {
+	if level <= 0 {
+		return MinDBFS
+	}
+	return math.Max(20*math.Log10(level), MinDBFS)
+}
+
+// Accumulator gathers the peak and RMS of each channel of interleaved
+// samples over one measurement interval
+type Accumulator struct {
+	channels int
+	frames   int
+	peak     []float64
+	sumSq    []float64
+}
+
+// NewAccumulator returns an Accumulator for samples with channels channels
+func NewAccumulator(channels int) *Accumulator {
+	return &Accumulator{
+		channels: channels,
+		peak:     make([]float64, channels),
+		sumSq:    make([]float64, channels),
+	}
+}
+
+// Add measures interleaved samples. A trailing partial frame is ignored.
+func (a *Accumulator) Add(samples []float32) {
+	frames := len(samples) / a.channels
+	for f := 0; f < frames; f++ {
+		for ch := 0; ch < a.channels; ch++ {
+			s := math.Abs(float64(samples[f*a.channels+ch]))
+			if s > a.peak[ch] {
+				a.peak[ch] = s
+			}
+			a.sumSq[ch] += s * s
+		}
+	}
+	a.frames += frames
+}
+
+// Frames returns the number of frames measured since the last Reset
+func (a *Accumulator) Frames() int {
+	return a.frames
+}
+
+// Levels returns the peak and RMS of each channel
+func (a *Accumulator) Levels() (peak, rms []float64) {
+	peak = append([]float64(nil), a.peak...)
+	rms = make([]float64, a.channels)
+	if a.frames > 0 {
+		for ch := range rms {
+			rms[ch] = math.Sqrt(a.sumSq[ch] / float64(a.frames))
+		}
+	}
+	return peak, rms
+}
+
+// Reset starts a new interval
+func (a *Accumulator) Reset() {
+	a.frames = 0
+	for ch := range a.peak {
+		a.peak[ch] = 0
+		a.sumSq[ch] = 0
+	}
+}
+
+// DefaultDecayRate is the fall rate of a peak meter, in dB per second
+const DefaultDecayRate = 20.0
+
+// Decay gives a meter its ballistics: a reading rises to a higher level
+// immediately and falls by at most Rate dB per second, so short peaks
+// remain visible
+type Decay struct {
+	Rate    float64
+	reading []float64
+}
+
+// Apply returns the readings for levels measured dt after the previous
+// call
+func (d *Decay) Apply(levels []float64, dt time.Duration) []float64 {
+	if len(d.reading) != len(levels) {
+		d.reading = make([]float64, len(levels))
+	}
+	fall := math.Pow(10, -d.Rate*dt.Seconds()/20)
+	for ch, level := range levels {
+		d.reading[ch] = math.Max(level, d.reading[ch]*fall)
+	}
+	return append([]float64(nil), d.reading...)
+}
@@ -0,0 +1,82 @@
+package dsp
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Encoding is the layout of one PCM sample
+type Encoding int
+
+const (
+	EncodingS16LE Encoding = iota
+	EncodingS24LE
+	EncodingS32LE
+	EncodingFloat32LE
+)
+
+// Size returns the number of bytes of one sample
+func (e Encoding) Size() int {
+	switch e {
+	case EncodingS16LE:
+		return 2
+	case EncodingS24LE:
+		return 3
+	case EncodingS32LE, EncodingFloat32LE:
+		return 4
+	}
+	return 0
+}
+
+// Decode converts little-endian PCM to samples in [-1, 1]. A trailing
+// partial sample is ignored.
+func Decode(data []byte, e Encoding) []float32 {
+	size := e.Size()
+	if size == 0 {
+		return nil
+	}
+	samples := make([]float32, len(data)/size)
+	for i := range samples {
+		b := data[i*size:]
+		switch e {
+		case EncodingS16LE:
+			samples[i] = float32(int16(binary.LittleEndian.Uint16(b))) / (1 << 15)
+		case EncodingS24LE:
+			v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+			samples[i] = float32(v) / (1 << 23)
+		case EncodingS32LE:
+			samples[i] = float32(float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31))
+		case EncodingFloat32LE:
+			samples[i] = math.Float32frombits(binary.LittleEndian.Uint32(b))
+		}
+	}
+	return samples
+}
+
+// Encode converts samples to little-endian PCM, clipping them to [-1, 1]
+func Encode(samples []float32, e Encoding) []byte {
+	size := e.Size()
+	data := make([]byte, len(samples)*size)
+	for i, s := range samples {
+		b := data[i*size:]
+		v := math.Max(-1, math.Min(1, float64(s)))
+		switch e {
+		case EncodingS16LE:
+			binary.LittleEndian.PutUint16(b, uint16(quantize(v, 1<<15)))
+		case EncodingS24LE:
+			q := uint32(quantize(v, 1<<23))
+			b[0], b[1], b[2] = byte(q), byte(q>>8), byte(q>>16)
+		case EncodingS32LE:
+			binary.LittleEndian.PutUint32(b, uint32(quantize(v, 1<<31)))
+		case EncodingFloat32LE:
+			binary.LittleEndian.PutUint32(b, math.Float32bits(float32(v)))
+		}
+	}
+	return data
+}
+
+// quantize scales v to an integer of the given full scale, saturating at
+// the positive limit
+func quantize(v, scale float64) int64 {
+	return int64(math.Min(math.Round(v*scale), scale-1))
+}
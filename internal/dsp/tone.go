@@ -0,0 +1,71 @@
+package dsp
+
+import "math"
+
+// Tone describes a sine wave. Level is the peak amplitude, where 1.0 is
+// full scale. Channel selects the one channel that carries the sine,
+// counting from 0, or every channel when negative. The first and last Fade
+// frames are ramped so that the tone starts and stops without a click.
+type Tone struct {
+	Frequency float64
+	Level     float64
+	Rate      int
+	Frames    int
+	Channels  int
+	Channel   int
+	Fade      int
+}
+
+// Samples returns the interleaved samples of the tone
+func (t Tone) Samples() []float32 {
+	samples := make([]float32, t.Frames*t.Channels)
+	for f := 0; f < t.Frames; f++ {
+		v := t.Level * math.Sin(2*math.Pi*t.Frequency*float64(f)/float64(t.Rate))
+		v *= ramp(f, t.Frames, t.Fade)
+		for ch := 0; ch < t.Channels; ch++ {
+			if t.Channel < 0 || t.Channel == ch {
+				samples[f*t.Channels+ch] = float32(v)
+			}
+		}
+	}
+	return samples
+}
+
+// ramp returns the gain of frame f of frames that fades in over the first
+// fade frames and out over the last
+func ramp(f, frames, fade int) float64 {
+	if fade > frames/2 {
+		fade = frames / 2
+	}
+	if f < fade {
+		return float64(f) / float64(fade)
+	}
+	if rest := frames - 1 - f; rest < fade {
+		return float64(rest) / float64(fade)
+	}
+	return 1
+}
+
+// Chirp describes a mono sine sweeping linearly from one frequency to
+// another, a signal that is easy to find in a recording. Level and Fade
+// are as for Tone.
+type Chirp struct {
+	From   float64
+	To     float64
+	Level  float64
+	Rate   int
+	Frames int
+	Fade   int
+}
+
+// Samples returns the samples of the chirp
+func (c Chirp) Samples() []float32 {
+	samples := make([]float32, c.Frames)
+	duration := float64(c.Frames) / float64(c.Rate)
+	for f := range samples {
+		t := float64(f) / float64(c.Rate)
+		phase := 2 * math.Pi * (c.From*t + (c.To-c.From)*t*t/(2*duration))
+		samples[f] = float32(c.Level * math.Sin(phase) * ramp(f, c.Frames, c.Fade))
+	}
+	return samples
+}
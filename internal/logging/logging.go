@@ -0,0 +1,61 @@
+// Package logging holds the logger set with audiocontrol.SetLogger, so that
+// the platform packages can report notifications and the errors they cannot
+// return without depending on the root package.
+//
+// Logging is off until a logger is set. Every function checks for one
+// before doing any work, so a disabled logger costs an atomic load.
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+)
+
+var logger atomic.Pointer[slog.Logger]
+
+// Set replaces the logger. Passing nil disables logging.
+func Set(l *slog.Logger) {
+	logger.Store(l)
+}
+
+// Logger returns the logger, or nil when logging is disabled
+func Logger() *slog.Logger {
+	return logger.Load()
+}
+
+// Notification logs a notification received from the operating system or
+// sound server
+func Notification(attrs ...slog.Attr) {
+	if l := logger.Load(); l != nil {
+		l.LogAttrs(context.Background(), slog.LevelDebug, "notification received", attrs...)
+	}
+}
+
+// Ignored logs an error that cannot be returned to a caller, such as one
+// met while handling a notification
+func Ignored(op string, err error, attrs ...slog.Attr) {
+	if l := logger.Load(); l != nil {
+		attrs = append([]slog.Attr{slog.String("op", op)}, attrs...)
+		attrs = append(attrs, ErrorAttrs(err)...)
+		l.LogAttrs(context.Background(), slog.LevelWarn, "error ignored", attrs...)
+	}
+}
+
+// ErrorAttrs describes err, with its OSStatus or HRESULT when it wraps an
+// error carrying one
+func ErrorAttrs(err error) []slog.Attr {
+	attrs := []slog.Attr{slog.String("error", err.Error())}
+	var status interface{ OSStatus() int32 }
+	if errors.As(err, &status) {
+		attrs = append(attrs, slog.Int64("osstatus", int64(status.OSStatus())))
+	}
+	// The HRESULT of a go-ole error
+	var hresult interface{ Code() uintptr }
+	if errors.As(err, &hresult) {
+		attrs = append(attrs, slog.String("hresult", fmt.Sprintf("0x%08X", uint32(hresult.Code()))))
+	}
+	return attrs
+}
@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type statusError int32
+
+func (e statusError) Error() string   { return fmt.Sprintf("OSStatus %d", int32(e)) }
+func (e statusError) OSStatus() int32 { return int32(e) }
+
+type oleError uintptr
+
+func (e oleError) Error() string { return "COM error" }
+func (e oleError) Code() uintptr { return uintptr(e) }
+
+func TestIgnored(t *testing.T) {
+	var buf bytes.Buffer
+	Set(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { Set(nil) })
+
+	Ignored("add property listener", fmt.Errorf("start monitoring: %w", statusError(-50)), slog.String("device", "BuiltInSpeakerDevice"))
+	Ignored("get device", oleError(0x80070490))
+	Notification(slog.String("notification", "OnDeviceAdded"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Logged %q, want two records without the Debug notification", lines)
+	}
+	for i, want := range []string{
+		`level=WARN msg="error ignored" op="add property listener" device=BuiltInSpeakerDevice error="start monitoring: OSStatus -50" osstatus=-50`,
+		`level=WARN msg="error ignored" op="get device" error="COM error" hresult=0x80070490`,
+	} {
+		if !strings.HasSuffix(lines[i], want) {
+			t.Errorf("Record %d = %q, want suffix %q", i, lines[i], want)
+		}
+	}
+
+	Set(nil)
+	buf.Reset()
+	Ignored("get device", errors.New("failed"))
+	if buf.Len() != 0 {
+		t.Errorf("Logged %q while disabled", buf.String())
+	}
+}
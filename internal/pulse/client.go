@@ -0,0 +1,395 @@
+package pulse
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestTimeout bounds how long a request waits for its reply
+const RequestTimeout = 5 * time.Second
+
+// ControlChannel is the channel of command packets
+const ControlChannel = Invalid
+
+// maxPacketSize is the largest packet accepted from the peer
+const maxPacketSize = 16 << 20
+
+// cookieSize is the length of the authentication cookie
+const cookieSize = 256
+
+// ErrClosed is returned by requests on a closed or broken connection
+var ErrClosed = errors.New("pulse: connection closed")
+
+// WritePacket writes one packet. Packets on ControlChannel carry a
+// tagstruct; packets on other channels carry audio for that stream.
+func WritePacket(w io.Writer, channel uint32, payload []byte) error {
+	buf := make([]byte, 20+len(payload))
+	binary.BigEndian.PutUint32(buf[0:], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:], channel)
+	copy(buf[20:], payload)
+	_, err := w.Write(buf)
+	return err
+}
+
+// ReadPacket reads one packet
+func ReadPacket(r io.Reader) (channel uint32, payload []byte, err error) {
+	var header [20]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[0:])
+	if length > maxPacketSize {
+		return 0, nil, fmt.Errorf("pulse: packet of %d bytes too large", length)
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return binary.BigEndian.Uint32(header[4:]), payload, nil
+}
+
+// DefaultServer returns the address of the user's server: $PULSE_SERVER if
+// set, otherwise the native socket in the runtime directory
+func DefaultServer() string {
+	if server := os.Getenv("PULSE_SERVER"); server != "" {
+		return server
+	}
+	if dir := os.Getenv("PULSE_RUNTIME_PATH"); dir != "" {
+		return "unix:" + filepath.Join(dir, "native")
+	}
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = filepath.Join("/run/user", strconv.Itoa(os.Getuid()))
+	}
+	return "unix:" + filepath.Join(dir, "pulse", "native")
+}
+
+// parseServer splits a server address in PULSE_SERVER syntax into a network
+// and address for net.Dial, using the first supported entry of a list
+func parseServer(server string) (network, address string, err error) {
+	for _, entry := range strings.Fields(server) {
+		// A "{machine-id}" prefix restricts an entry to one host
+		if strings.HasPrefix(entry, "{") {
+			end := strings.Index(entry, "}")
+			if end < 0 {
+				continue
+			}
+			entry = entry[end+1:]
+		}
+
+		switch {
+		case strings.HasPrefix(entry, "unix:"):
+			return "unix", strings.TrimPrefix(entry, "unix:"), nil
+		case strings.HasPrefix(entry, "/"):
+			return "unix", entry, nil
+		case strings.HasPrefix(entry, "tcp:"), strings.HasPrefix(entry, "tcp4:"), strings.HasPrefix(entry, "tcp6:"):
+			network = entry[:strings.Index(entry, ":")]
+			return network, withDefaultPort(entry[len(network)+1:]), nil
+		case !strings.Contains(entry, ":") || strings.Count(entry, ":") == 1:
+			return "tcp", withDefaultPort(entry), nil
+		}
+	}
+	return "", "", fmt.Errorf("pulse: unsupported server address %q", server)
+}
+
+func withDefaultPort(host string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, "4713")
+}
+
+// readCookie returns the authentication cookie, or zeros when there is none.
+// Servers that use socket credentials ignore the cookie.
+func readCookie() []byte {
+	var paths []string
+	if path := os.Getenv("PULSE_COOKIE"); path != "" {
+		paths = append(paths, path)
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "pulse", "cookie"))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".pulse-cookie"))
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err == nil && len(data) >= cookieSize {
+			return data[:cookieSize]
+		}
+	}
+	return make([]byte, cookieSize)
+}
+
+// reply is a REPLY or ERROR packet for a pending request
+type reply struct {
+	t   *Tagstruct
+	err error
+}
+
+// Client is a connection to a PulseAudio server. Requests may be issued
+// from any goroutine, including from subscription handlers.
+type Client struct {
+	conn net.Conn
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextTag uint32
+	pending map[uint32]chan reply
+	err     error
+	handler func(SubscriptionEvent)
+	queue   []SubscriptionEvent
+	queued  chan struct{}
+	records map[uint32]*RecordStream
+	// Playback streams have their own channel numbers. Requests for more
+	// audio that arrive before a stream is registered are kept in unclaimed.
+	playbacks map[uint32]*PlaybackStream
+	unclaimed map[uint32]int
+
+	done chan struct{}
+}
+
+// Dial connects to server (see DefaultServer), authenticates and announces
+// the client under name
+func Dial(server, name string) (*Client, error) {
+	network, address, err := parseServer(server)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout(network, address, RequestTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		conn:    conn,
+		pending: make(map[uint32]chan reply),
+		queued:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop()
+	go c.deliverEvents()
+
+	t, err := c.Request(CommandAuth, uint32(Version), readCookie())
+	if err != nil {
+		c.Close()
+		return nil, fmt.Errorf("failed to authenticate: %w", err)
+	}
+	// The high bits of the server version carry shared memory flags
+	serverVersion := t.U32() & 0xffff
+	if serverVersion < Version {
+		c.Close()
+		return nil, fmt.Errorf("pulse: server protocol version %d is older than %d", serverVersion, Version)
+	}
+
+	props := Proplist{
+		"application.name":       name,
+		"application.process.id": strconv.Itoa(os.Getpid()),
+	}
+	if _, err := c.Request(CommandSetClientName, props); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("failed to set client name: %w", err)
+	}
+	return c, nil
+}
+
+// Close closes the connection. Pending requests fail with ErrClosed.
+func (c *Client) Close() error {
+	c.fail(ErrClosed)
+	return c.conn.Close()
+}
+
+// Done is closed when the connection is closed or lost
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the reason the connection ended, or nil while it is open
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// fail ends the connection with err
+func (c *Client) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	for tag, ch := range c.pending {
+		ch <- reply{err: err}
+		delete(c.pending, tag)
+	}
+	for channel, s := range c.records {
+		s.end(err)
+		delete(c.records, channel)
+	}
+	for channel, s := range c.playbacks {
+		s.end(err)
+		delete(c.playbacks, channel)
+	}
+	close(c.done)
+}
+
+// Request sends cmd with args encoded by Tagstruct.Put and waits for the
+// reply. Server errors are returned as Error values.
+func (c *Client) Request(cmd Command, args ...interface{}) (*Tagstruct, error) {
+	ch := make(chan reply, 1)
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	tag := c.nextTag
+	c.nextTag++
+	if c.nextTag == Invalid {
+		c.nextTag = 0
+	}
+	c.pending[tag] = ch
+	c.mu.Unlock()
+
+	t := NewTagstruct()
+	t.PutU32(uint32(cmd))
+	t.PutU32(tag)
+	for _, arg := range args {
+		t.Put(arg)
+	}
+	if err := c.send(ControlChannel, t.Bytes()); err != nil {
+		c.mu.Lock()
+		delete(c.pending, tag)
+		c.mu.Unlock()
+		return nil, err
+	}
+
+	timer := time.NewTimer(RequestTimeout)
+	defer timer.Stop()
+	select {
+	case r := <-ch:
+		return r.t, r.err
+	case <-timer.C:
+		c.mu.Lock()
+		delete(c.pending, tag)
+		c.mu.Unlock()
+		return nil, ErrTimeout
+	}
+}
+
+// send writes one packet
+func (c *Client) send(channel uint32, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := WritePacket(c.conn, channel, payload); err != nil {
+		c.fail(err)
+		return err
+	}
+	return nil
+}
+
+// Subscribe enables change notifications for the facilities in mask.
+// handler is called for each notification, in order, from a goroutine owned
+// by the client.
+func (c *Client) Subscribe(mask uint32, handler func(SubscriptionEvent)) error {
+	c.mu.Lock()
+	c.handler = handler
+	c.mu.Unlock()
+
+	_, err := c.Request(CommandSubscribe, mask)
+	return err
+}
+
+func (c *Client) readLoop() {
+	for {
+		channel, payload, err := ReadPacket(c.conn)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+
+		if channel != ControlChannel {
+			c.deliverData(channel, payload)
+			continue
+		}
+
+		t := ParseTagstruct(payload)
+		cmd := Command(t.U32())
+		tag := t.U32()
+		if t.Err() != nil {
+			c.fail(fmt.Errorf("pulse: malformed packet: %w", t.Err()))
+			return
+		}
+
+		switch cmd {
+		case CommandReply, CommandError:
+			var r reply
+			if cmd == CommandReply {
+				r.t = t
+			} else {
+				r.err = Error(t.U32())
+			}
+			c.mu.Lock()
+			ch, ok := c.pending[tag]
+			delete(c.pending, tag)
+			c.mu.Unlock()
+			if ok {
+				ch <- r
+			}
+		case CommandRecordStreamKilled:
+			c.streamKilled(t.U32())
+		case CommandPlaybackStreamKilled:
+			c.playbackKilled(t.U32())
+		case CommandRequest:
+			c.playbackRequest(t.U32(), t.U32())
+		case CommandSubscribeEvent:
+			event := SubscriptionEvent{Event: t.U32(), Index: t.U32()}
+			c.mu.Lock()
+			c.queue = append(c.queue, event)
+			c.mu.Unlock()
+			select {
+			case c.queued <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// deliverEvents calls the subscription handler outside the read loop so that
+// handlers can issue requests
+func (c *Client) deliverEvents() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-c.queued:
+		}
+
+		for {
+			c.mu.Lock()
+			if len(c.queue) == 0 {
+				c.mu.Unlock()
+				break
+			}
+			event := c.queue[0]
+			c.queue = c.queue[1:]
+			handler := c.handler
+			c.mu.Unlock()
+
+			if handler != nil {
+				handler(event)
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package pulse_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/audi70r/go-audio-control/internal/pulse"
+	"github.com/audi70r/go-audio-control/internal/pulse/pulsetest"
+)
+
+func dial(t *testing.T, server *pulsetest.Server) *pulse.Client {
+	t.Helper()
+	c, err := pulse.Dial(server.Address(), "pulse test")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestClientRequests(t *testing.T) {
+	server := pulsetest.NewServer(t)
+	server.AddSink(pulsetest.NewSink("speakers", "Speakers"))
+	c := dial(t, server)
+
+	reply, err := c.Request(pulse.CommandGetServerInfo)
+	if err != nil {
+		t.Fatalf("Failed to get server info: %v", err)
+	}
+	if info := pulse.ReadServerInfo(reply); info.DefaultSinkName != "speakers" {
+		t.Fatalf("Unexpected server info %+v", info)
+	}
+
+	reply, err = c.Request(pulse.CommandGetSinkInfoList)
+	if err != nil {
+		t.Fatalf("Failed to list sinks: %v", err)
+	}
+	sink := pulse.ReadSinkInfo(reply)
+	if reply.Err() != nil || sink.Name != "speakers" || !reply.Eof() {
+		t.Fatalf("Unexpected sink list: %+v (%v)", sink, reply.Err())
+	}
+
+	_, err = c.Request(pulse.CommandSetDefaultSink, "missing")
+	if !errors.Is(err, pulse.ErrNoEntity) {
+		t.Fatalf("Expected ErrNoEntity, got %v", err)
+	}
+}
+
+func TestClientSubscribe(t *testing.T) {
+	server := pulsetest.NewServer(t)
+	c := dial(t, server)
+
+	events := make(chan pulse.SubscriptionEvent, 10)
+	err := c.Subscribe(pulse.SubscribeSink, func(e pulse.SubscriptionEvent) {
+		// Handlers may issue requests
+		if _, err := c.Request(pulse.CommandGetServerInfo); err != nil {
+			t.Errorf("Failed to request from handler: %v", err)
+		}
+		events <- e
+	})
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	index := server.AddSink(pulsetest.NewSink("hdmi", "HDMI"))
+	select {
+	case e := <-events:
+		if e.Facility() != pulse.FacilitySink || e.Type() != pulse.EventNew || e.Index != index {
+			t.Fatalf("Unexpected event %+v", e)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("No subscription event received")
+	}
+}
+
+func TestClientConnectionLost(t *testing.T) {
+	server := pulsetest.NewServer(t)
+	c := dial(t, server)
+
+	server.DropConnections()
+	select {
+	case <-c.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Connection loss not detected")
+	}
+	if _, err := c.Request(pulse.CommandGetServerInfo); err == nil {
+		t.Fatal("Expected requests to fail after the connection is lost")
+	}
+}
+
+func TestRecordStream(t *testing.T) {
+	server := pulsetest.NewServer(t)
+	server.AddSource(pulsetest.NewSource("mic", "Microphone"))
+	c := dial(t, server)
+
+	s, err := c.CreateRecordStream(pulse.RecordOptions{
+		Source:     "mic",
+		SampleSpec: pulse.SampleSpec{Format: pulse.SampleS16LE, Channels: 2, Rate: 48000},
+		ChannelMap: pulse.StereoMap(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create record stream: %v", err)
+	}
+	if s.SourceName != "mic" || s.SampleSpec.Channels != 2 {
+		t.Fatalf("Stream records %s with %+v", s.SourceName, s.SampleSpec)
+	}
+
+	if err := server.FeedSource("mic", []float32{0.5, -0.5}); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 16)
+	n, err := s.Read(buf)
+	if err != nil || n != 4 {
+		t.Fatalf("Read %d bytes: %v", n, err)
+	}
+	if buf[0] != 0x00 || buf[1] != 0x40 || buf[2] != 0x00 || buf[3] != 0xc0 {
+		t.Errorf("Read % x", buf[:n])
+	}
+
+	// Removing the source kills the stream
+	if err := server.RemoveSource("mic"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Read(buf); !errors.Is(err, pulse.ErrStreamKilled) {
+		t.Errorf("Read from a killed stream returned %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("Failed to close a killed stream: %v", err)
+	}
+}
+
+func TestPlaybackStream(t *testing.T) {
+	server := pulsetest.NewServer(t)
+	server.AddSink(pulsetest.NewSink("speakers", "Speakers"))
+	c := dial(t, server)
+
+	s, err := c.CreatePlaybackStream(pulse.PlaybackOptions{
+		Sink:       "speakers",
+		SampleSpec: pulse.SampleSpec{Format: pulse.SampleS16LE, Channels: 2, Rate: 48000},
+		ChannelMap: pulse.StereoMap(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create playback stream: %v", err)
+	}
+	if s.SinkName != "speakers" {
+		t.Errorf("Stream plays to %s", s.SinkName)
+	}
+
+	// More than the server buffer, so the write waits for requests
+	data := make([]byte, 3*4096+4)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	if n, err := s.Write(data); err != nil || n != len(data) {
+		t.Fatalf("Wrote %d bytes: %v", n, err)
+	}
+	if err := s.Drain(); err != nil {
+		t.Fatalf("Failed to drain: %v", err)
+	}
+	if played := server.Played("speakers"); !bytes.Equal(played, data) {
+		t.Errorf("Played %d bytes, want the %d written", len(played), len(data))
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if _, err := s.Write(data); !errors.Is(err, pulse.ErrStreamClosed) {
+		t.Errorf("Write to a closed stream returned %v", err)
+	}
+	if n := server.PlaybackStreams("speakers"); n != 0 {
+		t.Errorf("%d streams remain after Close", n)
+	}
+}
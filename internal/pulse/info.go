@@ -0,0 +1,548 @@
+package pulse
+
+// Info records are decoded for protocol Version. The Put methods write the
+// same layout and are used by the fake server in package pulsetest.
+
+// Port availability
+const (
+	AvailableUnknown uint32 = 0
+	AvailableNo      uint32 = 1
+	AvailableYes     uint32 = 2
+)
+
+// ServerInfo is the reply to CommandGetServerInfo
+type ServerInfo struct {
+	PackageName       string
+	PackageVersion    string
+	UserName          string
+	HostName          string
+	SampleSpec        SampleSpec
+	DefaultSinkName   string
+	DefaultSourceName string
+	Cookie            uint32
+	ChannelMap        ChannelMap
+}
+
+// ReadServerInfo decodes a ServerInfo
+func ReadServerInfo(t *Tagstruct) ServerInfo {
+	return ServerInfo{
+		PackageName:       t.String(),
+		PackageVersion:    t.String(),
+		UserName:          t.String(),
+		HostName:          t.String(),
+		SampleSpec:        t.SampleSpec(),
+		DefaultSinkName:   t.String(),
+		DefaultSourceName: t.String(),
+		Cookie:            t.U32(),
+		ChannelMap:        t.ChannelMap(),
+	}
+}
+
+// Put encodes s
+func (s ServerInfo) Put(t *Tagstruct) {
+	t.PutString(s.PackageName)
+	t.PutString(s.PackageVersion)
+	t.PutString(s.UserName)
+	t.PutString(s.HostName)
+	t.PutSampleSpec(s.SampleSpec)
+	putOptionalString(t, s.DefaultSinkName)
+	putOptionalString(t, s.DefaultSourceName)
+	t.PutU32(s.Cookie)
+	t.PutChannelMap(s.ChannelMap)
+}
+
+// PortInfo describes a port of a sink or source
+type PortInfo struct {
+	Name        string
+	Description string
+	Priority    uint32
+	Available   uint32
+}
+
+func readPorts(t *Tagstruct) ([]PortInfo, string) {
+	n := t.U32()
+	var ports []PortInfo
+	for i := uint32(0); i < n && t.Err() == nil; i++ {
+		ports = append(ports, PortInfo{
+			Name:        t.String(),
+			Description: t.String(),
+			Priority:    t.U32(),
+			Available:   t.U32(),
+		})
+	}
+	return ports, t.String()
+}
+
+func putPorts(t *Tagstruct, ports []PortInfo, active string) {
+	t.PutU32(uint32(len(ports)))
+	for _, p := range ports {
+		t.PutString(p.Name)
+		t.PutString(p.Description)
+		t.PutU32(p.Priority)
+		t.PutU32(p.Available)
+	}
+	putOptionalString(t, active)
+}
+
+func readFormats(t *Tagstruct) []FormatInfo {
+	n := t.U8()
+	var formats []FormatInfo
+	for i := uint8(0); i < n && t.Err() == nil; i++ {
+		formats = append(formats, t.FormatInfo())
+	}
+	return formats
+}
+
+func putFormats(t *Tagstruct, formats []FormatInfo) {
+	t.PutU8(uint8(len(formats)))
+	for _, f := range formats {
+		t.PutFormatInfo(f)
+	}
+}
+
+// putOptionalString writes s, or a null string when s is empty
+func putOptionalString(t *Tagstruct, s string) {
+	if s == "" {
+		t.PutNullString()
+		return
+	}
+	t.PutString(s)
+}
+
+// SinkInfo describes a sink (an output device)
+type SinkInfo struct {
+	Index             uint32
+	Name              string
+	Description       string
+	SampleSpec        SampleSpec
+	ChannelMap        ChannelMap
+	OwnerModule       uint32
+	Volume            CVolume
+	Mute              bool
+	MonitorSource     uint32
+	MonitorSourceName string
+	Latency           uint64
+	Driver            string
+	Flags             uint32
+	Properties        Proplist
+	ConfiguredLatency uint64
+	BaseVolume        Volume
+	State             uint32
+	VolumeSteps       uint32
+	Card              uint32
+	Ports             []PortInfo
+	ActivePort        string
+	Formats           []FormatInfo
+}
+
+// ReadSinkInfo decodes a SinkInfo
+func ReadSinkInfo(t *Tagstruct) SinkInfo {
+	s := SinkInfo{
+		Index:             t.U32(),
+		Name:              t.String(),
+		Description:       t.String(),
+		SampleSpec:        t.SampleSpec(),
+		ChannelMap:        t.ChannelMap(),
+		OwnerModule:       t.U32(),
+		Volume:            t.CVolume(),
+		Mute:              t.Bool(),
+		MonitorSource:     t.U32(),
+		MonitorSourceName: t.String(),
+		Latency:           t.Usec(),
+		Driver:            t.String(),
+		Flags:             t.U32(),
+		Properties:        t.Proplist(),
+		ConfiguredLatency: t.Usec(),
+		BaseVolume:        t.Volume(),
+		State:             t.U32(),
+		VolumeSteps:       t.U32(),
+		Card:              t.U32(),
+	}
+	s.Ports, s.ActivePort = readPorts(t)
+	s.Formats = readFormats(t)
+	return s
+}
+
+// Put encodes s
+func (s SinkInfo) Put(t *Tagstruct) {
+	t.PutU32(s.Index)
+	t.PutString(s.Name)
+	t.PutString(s.Description)
+	t.PutSampleSpec(s.SampleSpec)
+	t.PutChannelMap(s.ChannelMap)
+	t.PutU32(s.OwnerModule)
+	t.PutCVolume(s.Volume)
+	t.PutBool(s.Mute)
+	t.PutU32(s.MonitorSource)
+	putOptionalString(t, s.MonitorSourceName)
+	t.PutUsec(s.Latency)
+	t.PutString(s.Driver)
+	t.PutU32(s.Flags)
+	t.PutProplist(s.Properties)
+	t.PutUsec(s.ConfiguredLatency)
+	t.PutVolume(s.BaseVolume)
+	t.PutU32(s.State)
+	t.PutU32(s.VolumeSteps)
+	t.PutU32(s.Card)
+	putPorts(t, s.Ports, s.ActivePort)
+	putFormats(t, s.Formats)
+}
+
+// SourceInfo describes a source (an input device or a sink monitor)
+type SourceInfo struct {
+	Index             uint32
+	Name              string
+	Description       string
+	SampleSpec        SampleSpec
+	ChannelMap        ChannelMap
+	OwnerModule       uint32
+	Volume            CVolume
+	Mute              bool
+	MonitorOfSink     uint32
+	MonitorOfSinkName string
+	Latency           uint64
+	Driver            string
+	Flags             uint32
+	Properties        Proplist
+	ConfiguredLatency uint64
+	BaseVolume        Volume
+	State             uint32
+	VolumeSteps       uint32
+	Card              uint32
+	Ports             []PortInfo
+	ActivePort        string
+	Formats           []FormatInfo
+}
+
+// IsMonitor reports whether the source records the output of a sink
+func (s SourceInfo) IsMonitor() bool {
+	return s.MonitorOfSink != Invalid
+}
+
+// ReadSourceInfo decodes a SourceInfo
+func ReadSourceInfo(t *Tagstruct) SourceInfo {
+	s := SourceInfo{
+		Index:             t.U32(),
+		Name:              t.String(),
+		Description:       t.String(),
+		SampleSpec:        t.SampleSpec(),
+		ChannelMap:        t.ChannelMap(),
+		OwnerModule:       t.U32(),
+		Volume:            t.CVolume(),
+		Mute:              t.Bool(),
+		MonitorOfSink:     t.U32(),
+		MonitorOfSinkName: t.String(),
+		Latency:           t.Usec(),
+		Driver:            t.String(),
+		Flags:             t.U32(),
+		Properties:        t.Proplist(),
+		ConfiguredLatency: t.Usec(),
+		BaseVolume:        t.Volume(),
+		State:             t.U32(),
+		VolumeSteps:       t.U32(),
+		Card:              t.U32(),
+	}
+	s.Ports, s.ActivePort = readPorts(t)
+	s.Formats = readFormats(t)
+	return s
+}
+
+// Put encodes s
+func (s SourceInfo) Put(t *Tagstruct) {
+	t.PutU32(s.Index)
+	t.PutString(s.Name)
+	t.PutString(s.Description)
+	t.PutSampleSpec(s.SampleSpec)
+	t.PutChannelMap(s.ChannelMap)
+	t.PutU32(s.OwnerModule)
+	t.PutCVolume(s.Volume)
+	t.PutBool(s.Mute)
+	t.PutU32(s.MonitorOfSink)
+	putOptionalString(t, s.MonitorOfSinkName)
+	t.PutUsec(s.Latency)
+	t.PutString(s.Driver)
+	t.PutU32(s.Flags)
+	t.PutProplist(s.Properties)
+	t.PutUsec(s.ConfiguredLatency)
+	t.PutVolume(s.BaseVolume)
+	t.PutU32(s.State)
+	t.PutU32(s.VolumeSteps)
+	t.PutU32(s.Card)
+	putPorts(t, s.Ports, s.ActivePort)
+	putFormats(t, s.Formats)
+}
+
+// ProfileInfo describes a profile of a card
+type ProfileInfo struct {
+	Name        string
+	Description string
+	Sinks       uint32
+	Sources     uint32
+	Priority    uint32
+	Available   uint32
+}
+
+// CardPortInfo describes a port as listed by its card, with the profiles
+// that provide it
+type CardPortInfo struct {
+	Name          string
+	Description   string
+	Priority      uint32
+	Available     uint32
+	Direction     uint8
+	Properties    Proplist
+	Profiles      []string
+	LatencyOffset int64
+}
+
+// CardInfo describes a card, the physical device behind sinks and sources
+type CardInfo struct {
+	Index         uint32
+	Name          string
+	OwnerModule   uint32
+	Driver        string
+	Profiles      []ProfileInfo
+	ActiveProfile string
+	Properties    Proplist
+	Ports         []CardPortInfo
+}
+
+// ReadCardInfo decodes a CardInfo
+func ReadCardInfo(t *Tagstruct) CardInfo {
+	c := CardInfo{
+		Index:       t.U32(),
+		Name:        t.String(),
+		OwnerModule: t.U32(),
+		Driver:      t.String(),
+	}
+	n := t.U32()
+	for i := uint32(0); i < n && t.Err() == nil; i++ {
+		c.Profiles = append(c.Profiles, ProfileInfo{
+			Name:        t.String(),
+			Description: t.String(),
+			Sinks:       t.U32(),
+			Sources:     t.U32(),
+			Priority:    t.U32(),
+			Available:   t.U32(),
+		})
+	}
+	c.ActiveProfile = t.String()
+	c.Properties = t.Proplist()
+
+	n = t.U32()
+	for i := uint32(0); i < n && t.Err() == nil; i++ {
+		port := CardPortInfo{
+			Name:        t.String(),
+			Description: t.String(),
+			Priority:    t.U32(),
+			Available:   t.U32(),
+			Direction:   t.U8(),
+			Properties:  t.Proplist(),
+		}
+		profiles := t.U32()
+		for j := uint32(0); j < profiles && t.Err() == nil; j++ {
+			port.Profiles = append(port.Profiles, t.String())
+		}
+		port.LatencyOffset = t.S64()
+		c.Ports = append(c.Ports, port)
+	}
+	return c
+}
+
+// Put encodes c
+func (c CardInfo) Put(t *Tagstruct) {
+	t.PutU32(c.Index)
+	t.PutString(c.Name)
+	t.PutU32(c.OwnerModule)
+	t.PutString(c.Driver)
+	t.PutU32(uint32(len(c.Profiles)))
+	for _, p := range c.Profiles {
+		t.PutString(p.Name)
+		t.PutString(p.Description)
+		t.PutU32(p.Sinks)
+		t.PutU32(p.Sources)
+		t.PutU32(p.Priority)
+		t.PutU32(p.Available)
+	}
+	putOptionalString(t, c.ActiveProfile)
+	t.PutProplist(c.Properties)
+	t.PutU32(uint32(len(c.Ports)))
+	for _, p := range c.Ports {
+		t.PutString(p.Name)
+		t.PutString(p.Description)
+		t.PutU32(p.Priority)
+		t.PutU32(p.Available)
+		t.PutU8(p.Direction)
+		t.PutProplist(p.Properties)
+		t.PutU32(uint32(len(p.Profiles)))
+		for _, profile := range p.Profiles {
+			t.PutString(profile)
+		}
+		t.PutS64(p.LatencyOffset)
+	}
+}
+
+// SinkInputInfo describes a sink input, a playback stream of a client
+type SinkInputInfo struct {
+	Index          uint32
+	Name           string
+	OwnerModule    uint32
+	Client         uint32
+	Sink           uint32
+	SampleSpec     SampleSpec
+	ChannelMap     ChannelMap
+	Volume         CVolume
+	BufferLatency  uint64
+	SinkLatency    uint64
+	ResampleMethod string
+	Driver         string
+	Mute           bool
+	Properties     Proplist
+	Corked         bool
+	HasVolume      bool
+	VolumeWritable bool
+	Format         FormatInfo
+}
+
+// ReadSinkInputInfo decodes a SinkInputInfo
+func ReadSinkInputInfo(t *Tagstruct) SinkInputInfo {
+	return SinkInputInfo{
+		Index:          t.U32(),
+		Name:           t.String(),
+		OwnerModule:    t.U32(),
+		Client:         t.U32(),
+		Sink:           t.U32(),
+		SampleSpec:     t.SampleSpec(),
+		ChannelMap:     t.ChannelMap(),
+		Volume:         t.CVolume(),
+		BufferLatency:  t.Usec(),
+		SinkLatency:    t.Usec(),
+		ResampleMethod: t.String(),
+		Driver:         t.String(),
+		Mute:           t.Bool(),
+		Properties:     t.Proplist(),
+		Corked:         t.Bool(),
+		HasVolume:      t.Bool(),
+		VolumeWritable: t.Bool(),
+		Format:         t.FormatInfo(),
+	}
+}
+
+// Put encodes s
+func (s SinkInputInfo) Put(t *Tagstruct) {
+	t.PutU32(s.Index)
+	t.PutString(s.Name)
+	t.PutU32(s.OwnerModule)
+	t.PutU32(s.Client)
+	t.PutU32(s.Sink)
+	t.PutSampleSpec(s.SampleSpec)
+	t.PutChannelMap(s.ChannelMap)
+	t.PutCVolume(s.Volume)
+	t.PutUsec(s.BufferLatency)
+	t.PutUsec(s.SinkLatency)
+	putOptionalString(t, s.ResampleMethod)
+	t.PutString(s.Driver)
+	t.PutBool(s.Mute)
+	t.PutProplist(s.Properties)
+	t.PutBool(s.Corked)
+	t.PutBool(s.HasVolume)
+	t.PutBool(s.VolumeWritable)
+	t.PutFormatInfo(s.Format)
+}
+
+// SourceOutputInfo describes a source output, a record stream of a client
+type SourceOutputInfo struct {
+	Index          uint32
+	Name           string
+	OwnerModule    uint32
+	Client         uint32
+	Source         uint32
+	SampleSpec     SampleSpec
+	ChannelMap     ChannelMap
+	BufferLatency  uint64
+	SourceLatency  uint64
+	ResampleMethod string
+	Driver         string
+	Properties     Proplist
+	Corked         bool
+	Volume         CVolume
+	Mute           bool
+	HasVolume      bool
+	VolumeWritable bool
+	Format         FormatInfo
+}
+
+// ReadSourceOutputInfo decodes a SourceOutputInfo
+func ReadSourceOutputInfo(t *Tagstruct) SourceOutputInfo {
+	return SourceOutputInfo{
+		Index:          t.U32(),
+		Name:           t.String(),
+		OwnerModule:    t.U32(),
+		Client:         t.U32(),
+		Source:         t.U32(),
+		SampleSpec:     t.SampleSpec(),
+		ChannelMap:     t.ChannelMap(),
+		BufferLatency:  t.Usec(),
+		SourceLatency:  t.Usec(),
+		ResampleMethod: t.String(),
+		Driver:         t.String(),
+		Properties:     t.Proplist(),
+		Corked:         t.Bool(),
+		Volume:         t.CVolume(),
+		Mute:           t.Bool(),
+		HasVolume:      t.Bool(),
+		VolumeWritable: t.Bool(),
+		Format:         t.FormatInfo(),
+	}
+}
+
+// Put encodes s
+func (s SourceOutputInfo) Put(t *Tagstruct) {
+	t.PutU32(s.Index)
+	t.PutString(s.Name)
+	t.PutU32(s.OwnerModule)
+	t.PutU32(s.Client)
+	t.PutU32(s.Source)
+	t.PutSampleSpec(s.SampleSpec)
+	t.PutChannelMap(s.ChannelMap)
+	t.PutUsec(s.BufferLatency)
+	t.PutUsec(s.SourceLatency)
+	putOptionalString(t, s.ResampleMethod)
+	t.PutString(s.Driver)
+	t.PutProplist(s.Properties)
+	t.PutBool(s.Corked)
+	t.PutCVolume(s.Volume)
+	t.PutBool(s.Mute)
+	t.PutBool(s.HasVolume)
+	t.PutBool(s.VolumeWritable)
+	t.PutFormatInfo(s.Format)
+}
+
+// ModuleInfo describes a loaded module
+type ModuleInfo struct {
+	Index      uint32
+	Name       string
+	Argument   string
+	Used       uint32
+	Properties Proplist
+}
+
+// ReadModuleInfo decodes a ModuleInfo
+func ReadModuleInfo(t *Tagstruct) ModuleInfo {
+	return ModuleInfo{
+		Index:      t.U32(),
+		Name:       t.String(),
+		Argument:   t.String(),
+		Used:       t.U32(),
+		Properties: t.Proplist(),
+	}
+}
+
+// Put encodes m
+func (m ModuleInfo) Put(t *Tagstruct) {
+	t.PutU32(m.Index)
+	t.PutString(m.Name)
+	putOptionalString(t, m.Argument)
+	t.PutU32(m.Used)
+	t.PutProplist(m.Properties)
+}
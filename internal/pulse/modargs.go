@@ -0,0 +1,97 @@
+package pulse
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FormatArguments encodes args as a module argument string of
+// whitespace-separated key=value pairs, as passed to CommandLoadModule.
+// Values are quoted when needed, so the result can itself be a value, such
+// as the sink_properties of a module.
+func FormatArguments(args map[string]string) string {
+	keys := make([]string, 0, len(args))
+	for key := range args {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + quoteArgument(args[key])
+	}
+	return strings.Join(pairs, " ")
+}
+
+func quoteArgument(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n'\"\\") {
+		return value
+	}
+	var b strings.Builder
+	b.WriteByte('\'')
+	for _, r := range value {
+		if r == '\'' || r == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// ParseArguments decodes a module argument string or a property list in the
+// same syntax. A value may be enclosed in ' or " and a backslash escapes the
+// next character, as in the parser of the server.
+func ParseArguments(s string) (map[string]string, error) {
+	args := make(map[string]string)
+	for i := 0; i < len(s); {
+		if isSpace(s[i]) {
+			i++
+			continue
+		}
+		eq := strings.IndexByte(s[i:], '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("missing value for argument %q", s[i:])
+		}
+		key := s[i : i+eq]
+		if strings.ContainsAny(key, " \t\n") {
+			return nil, fmt.Errorf("missing value for argument %q", key)
+		}
+		i += eq + 1
+
+		var value strings.Builder
+		var quote byte
+		if i < len(s) && (s[i] == '\'' || s[i] == '"') {
+			quote = s[i]
+			i++
+		}
+		closed := quote == 0
+		for ; i < len(s); i++ {
+			c := s[i]
+			if c == '\\' && i+1 < len(s) {
+				i++
+				value.WriteByte(s[i])
+				continue
+			}
+			if quote != 0 && c == quote {
+				i++
+				closed = true
+				break
+			}
+			if quote == 0 && isSpace(c) {
+				break
+			}
+			value.WriteByte(c)
+		}
+		if !closed {
+			return nil, fmt.Errorf("unterminated quote in argument %q", key)
+		}
+		args[key] = value.String()
+	}
+	return args, nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
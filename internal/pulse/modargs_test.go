@@ -0,0 +1,50 @@
+package pulse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestArgumentsRoundTrip(t *testing.T) {
+	props := map[string]string{"device.description": "Stream 'Capture'", "audiocontrol.owner.pid": "42"}
+	args := map[string]string{
+		"sink_name":       "capture",
+		"sink_properties": FormatArguments(props),
+		"empty":           "",
+		"path":            `C:\temp`,
+	}
+
+	s := FormatArguments(args)
+	parsed, err := ParseArguments(s)
+	if err != nil {
+		t.Fatalf("Failed to parse %q: %v", s, err)
+	}
+	if !reflect.DeepEqual(parsed, args) {
+		t.Fatalf("Parsed %q as %v, want %v", s, parsed, args)
+	}
+	nested, err := ParseArguments(parsed["sink_properties"])
+	if err != nil {
+		t.Fatalf("Failed to parse properties: %v", err)
+	}
+	if !reflect.DeepEqual(nested, props) {
+		t.Errorf("Properties are %v, want %v", nested, props)
+	}
+}
+
+func TestParseArguments(t *testing.T) {
+	// Arguments as typed in default.pa or by pactl
+	args, err := ParseArguments(`sink_name=combined  slaves=a,b sink_properties=device.description="My\ Sink"`)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	want := map[string]string{"sink_name": "combined", "slaves": "a,b", "sink_properties": `device.description="My Sink"`}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("Parsed %v, want %v", args, want)
+	}
+
+	for _, bad := range []string{"novalue", "a='open", "=x"} {
+		if _, err := ParseArguments(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
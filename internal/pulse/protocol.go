@@ -0,0 +1,303 @@
+// Package pulse implements the client side of the PulseAudio native
+// protocol, which is also served by PipeWire's pipewire-pulse.
+//
+// Only the commands used by the Linux backend are covered. Audio data is
+// carried in memblock packets on the stream's channel; shared memory is never
+// negotiated.
+package pulse
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/audi70r/go-audio-control/internal/dsp"
+)
+
+// Version is the protocol version requested by the client. The server
+// answers with the lower of this and its own version, which decides the
+// layout of the info records.
+const Version = 32
+
+// Command identifies a request, reply or server notification
+type Command uint32
+
+// Commands
+const (
+	CommandError                   Command = 0
+	CommandTimeout                 Command = 1
+	CommandReply                   Command = 2
+	CommandCreatePlaybackStream    Command = 3
+	CommandDeletePlaybackStream    Command = 4
+	CommandCreateRecordStream      Command = 5
+	CommandDeleteRecordStream      Command = 6
+	CommandExit                    Command = 7
+	CommandAuth                    Command = 8
+	CommandSetClientName           Command = 9
+	CommandDrainPlaybackStream     Command = 12
+	CommandGetServerInfo           Command = 20
+	CommandGetSinkInfo             Command = 21
+	CommandGetSinkInfoList         Command = 22
+	CommandGetSourceInfo           Command = 23
+	CommandGetSourceInfoList       Command = 24
+	CommandGetModuleInfo           Command = 25
+	CommandGetModuleInfoList       Command = 26
+	CommandGetSinkInputInfo        Command = 29
+	CommandGetSinkInputInfoList    Command = 30
+	CommandGetSourceOutputInfo     Command = 31
+	CommandGetSourceOutputInfoList Command = 32
+	CommandSubscribe               Command = 35
+	CommandSetSinkVolume           Command = 36
+	CommandSetSinkInputVolume      Command = 37
+	CommandSetSourceVolume         Command = 38
+	CommandSetSinkMute             Command = 39
+	CommandSetSourceMute           Command = 40
+	CommandCorkPlaybackStream      Command = 41
+	CommandSetDefaultSink          Command = 44
+	CommandSetDefaultSource        Command = 45
+	CommandLoadModule              Command = 51
+	CommandUnloadModule            Command = 52
+	CommandCorkRecordStream        Command = 58
+	CommandRequest                 Command = 61
+	CommandOverflow                Command = 62
+	CommandUnderflow               Command = 63
+	CommandPlaybackStreamKilled    Command = 64
+	CommandRecordStreamKilled      Command = 65
+	CommandSubscribeEvent          Command = 66
+	CommandMoveSinkInput           Command = 67
+	CommandMoveSourceOutput        Command = 68
+	CommandSetSinkInputMute        Command = 69
+	CommandStarted                 Command = 86
+	CommandGetCardInfo             Command = 88
+	CommandGetCardInfoList         Command = 89
+	CommandSetCardProfile          Command = 90
+	CommandSetSinkPort             Command = 96
+	CommandSetSourcePort           Command = 97
+	CommandSetSourceOutputVolume   Command = 98
+	CommandSetSourceOutputMute     Command = 99
+)
+
+// Invalid is the index used for "no object" and the tag of notifications
+const Invalid = math.MaxUint32
+
+// Error is an error code returned by the server
+type Error uint32
+
+// Error codes
+const (
+	ErrAccess            Error = 1
+	ErrCommand           Error = 2
+	ErrInvalid           Error = 3
+	ErrExist             Error = 4
+	ErrNoEntity          Error = 5
+	ErrConnectionRefused Error = 6
+	ErrProtocol          Error = 7
+	ErrTimeout           Error = 8
+	ErrAuthKey           Error = 9
+	ErrInternal          Error = 10
+	ErrModInitFailed     Error = 11
+	ErrBadState          Error = 15
+	ErrVersion           Error = 17
+	ErrNotSupported      Error = 19
+)
+
+var errorNames = map[Error]string{
+	ErrAccess:            "access denied",
+	ErrCommand:           "unknown command",
+	ErrInvalid:           "invalid argument",
+	ErrExist:             "entity exists",
+	ErrNoEntity:          "no such entity",
+	ErrConnectionRefused: "connection refused",
+	ErrProtocol:          "protocol error",
+	ErrTimeout:           "timeout",
+	ErrAuthKey:           "bad authentication key",
+	ErrInternal:          "internal error",
+	ErrModInitFailed:     "module initialization failed",
+	ErrBadState:          "bad state",
+	ErrVersion:           "incompatible protocol version",
+	ErrNotSupported:      "not supported",
+}
+
+func (e Error) Error() string {
+	if name, ok := errorNames[e]; ok {
+		return "pulse: " + name
+	}
+	return fmt.Sprintf("pulse: error %d", uint32(e))
+}
+
+// Volume is a linear-ish volume where VolumeNorm is 100%
+type Volume uint32
+
+// Volume limits
+const (
+	VolumeMuted Volume = 0
+	VolumeNorm  Volume = 0x10000
+)
+
+// VolumeFromFloat converts a fraction of VolumeNorm to a Volume
+func VolumeFromFloat(f float64) Volume {
+	if f <= 0 {
+		return VolumeMuted
+	}
+	return Volume(math.Round(f * float64(VolumeNorm)))
+}
+
+// Float returns v as a fraction of VolumeNorm
+func (v Volume) Float() float64 {
+	return float64(v) / float64(VolumeNorm)
+}
+
+// Avg returns the average volume of all channels
+func (v CVolume) Avg() Volume {
+	if len(v) == 0 {
+		return VolumeMuted
+	}
+	var sum uint64
+	for _, c := range v {
+		sum += uint64(c)
+	}
+	return Volume(sum / uint64(len(v)))
+}
+
+// NewCVolume returns a CVolume with every one of channels set to v
+func NewCVolume(channels int, v Volume) CVolume {
+	cv := make(CVolume, channels)
+	for i := range cv {
+		cv[i] = v
+	}
+	return cv
+}
+
+// SampleFormat is the encoding of one sample
+type SampleFormat uint8
+
+// Sample formats
+const (
+	SampleU8        SampleFormat = 0
+	SampleALaw      SampleFormat = 1
+	SampleULaw      SampleFormat = 2
+	SampleS16LE     SampleFormat = 3
+	SampleS16BE     SampleFormat = 4
+	SampleFloat32LE SampleFormat = 5
+	SampleFloat32BE SampleFormat = 6
+	SampleS32LE     SampleFormat = 7
+	SampleS32BE     SampleFormat = 8
+	SampleS24LE     SampleFormat = 9
+	SampleS24BE     SampleFormat = 10
+	SampleS24In32LE SampleFormat = 11
+	SampleS24In32BE SampleFormat = 12
+)
+
+// Size returns the number of bytes of one sample, or 0 if unknown
+func (f SampleFormat) Size() int {
+	switch f {
+	case SampleU8, SampleALaw, SampleULaw:
+		return 1
+	case SampleS16LE, SampleS16BE:
+		return 2
+	case SampleS24LE, SampleS24BE:
+		return 3
+	case SampleFloat32LE, SampleFloat32BE, SampleS32LE, SampleS32BE, SampleS24In32LE, SampleS24In32BE:
+		return 4
+	}
+	return 0
+}
+
+// Encoding returns the dsp encoding of f, if dsp can decode it
+func (f SampleFormat) Encoding() (dsp.Encoding, bool) {
+	switch f {
+	case SampleS16LE:
+		return dsp.EncodingS16LE, true
+	case SampleS24LE:
+		return dsp.EncodingS24LE, true
+	case SampleS32LE:
+		return dsp.EncodingS32LE, true
+	case SampleFloat32LE:
+		return dsp.EncodingFloat32LE, true
+	}
+	return 0, false
+}
+
+// FrameSize returns the number of bytes of one frame of s
+func (s SampleSpec) FrameSize() int {
+	return s.Format.Size() * int(s.Channels)
+}
+
+// ChannelPosition is the speaker position of a channel
+type ChannelPosition uint8
+
+// Common channel positions
+const (
+	ChannelMono        ChannelPosition = 0
+	ChannelFrontLeft   ChannelPosition = 1
+	ChannelFrontRight  ChannelPosition = 2
+	ChannelFrontCenter ChannelPosition = 3
+)
+
+// StereoMap returns the channel map of a stereo stream
+func StereoMap() ChannelMap {
+	return ChannelMap{ChannelFrontLeft, ChannelFrontRight}
+}
+
+// DefaultChannelMap returns the map PulseAudio uses for channels channels
+// when none is given: mono, stereo, or front left/right followed by
+// auxiliary positions
+func DefaultChannelMap(channels int) ChannelMap {
+	switch channels {
+	case 1:
+		return ChannelMap{ChannelMono}
+	case 2:
+		return StereoMap()
+	}
+	m := make(ChannelMap, channels)
+	for i := range m {
+		m[i] = ChannelPosition(12 + i) // PA_CHANNEL_POSITION_AUX0
+	}
+	return m
+}
+
+// Subscription facilities and event types
+const (
+	FacilitySink         uint32 = 0
+	FacilitySource       uint32 = 1
+	FacilitySinkInput    uint32 = 2
+	FacilitySourceOutput uint32 = 3
+	FacilityModule       uint32 = 4
+	FacilityClient       uint32 = 5
+	FacilityServer       uint32 = 7
+	FacilityCard         uint32 = 9
+	FacilityMask         uint32 = 0x0f
+
+	EventNew      uint32 = 0x00
+	EventChange   uint32 = 0x10
+	EventRemove   uint32 = 0x20
+	EventTypeMask uint32 = 0x30
+)
+
+// Subscription masks
+const (
+	SubscribeSink         uint32 = 1 << FacilitySink
+	SubscribeSource       uint32 = 1 << FacilitySource
+	SubscribeSinkInput    uint32 = 1 << FacilitySinkInput
+	SubscribeSourceOutput uint32 = 1 << FacilitySourceOutput
+	SubscribeModule       uint32 = 1 << FacilityModule
+	SubscribeClient       uint32 = 1 << FacilityClient
+	SubscribeServer       uint32 = 1 << FacilityServer
+	SubscribeCard         uint32 = 1 << FacilityCard
+)
+
+// SubscriptionEvent is a change notification from the server
+type SubscriptionEvent struct {
+	// Event is a facility combined with an event type
+	Event uint32
+	Index uint32
+}
+
+// Facility returns the kind of object that changed
+func (e SubscriptionEvent) Facility() uint32 {
+	return e.Event & FacilityMask
+}
+
+// Type returns EventNew, EventChange or EventRemove
+func (e SubscriptionEvent) Type() uint32 {
+	return e.Event & EventTypeMask
+}
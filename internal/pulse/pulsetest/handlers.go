@@ -0,0 +1,317 @@
+package pulsetest
+
+import (
+	"github.com/audi70r/go-audio-control/internal/pulse"
+)
+
+// handle executes one request with s.mu held and returns the reply body
+func (s *Server) handle(c *conn, cmd pulse.Command, t *pulse.Tagstruct) (*pulse.Tagstruct, error) {
+	reply := pulse.NewTagstruct()
+
+	switch cmd {
+	case pulse.CommandAuth:
+		t.U32()
+		t.Arbitrary()
+		reply.PutU32(pulse.Version)
+
+	case pulse.CommandSetClientName:
+		t.Proplist()
+		reply.PutU32(s.nextClient)
+		s.nextClient++
+
+	case pulse.CommandSubscribe:
+		c.mask = t.U32()
+
+	case pulse.CommandGetServerInfo:
+		pulse.ServerInfo{
+			PackageName:       "pulsetest",
+			PackageVersion:    "1.0",
+			UserName:          "test",
+			HostName:          "localhost",
+			SampleSpec:        pulse.SampleSpec{Format: pulse.SampleS16LE, Channels: 2, Rate: 48000},
+			DefaultSinkName:   s.defaultSink,
+			DefaultSourceName: s.defaultSource,
+			ChannelMap:        pulse.StereoMap(),
+		}.Put(reply)
+
+	case pulse.CommandGetSinkInfo:
+		i := s.sinkIndex(t.U32(), t.String())
+		if i < 0 {
+			return nil, pulse.ErrNoEntity
+		}
+		s.sinks[i].Put(reply)
+
+	case pulse.CommandGetSinkInfoList:
+		for _, sink := range s.sinks {
+			sink.Put(reply)
+		}
+
+	case pulse.CommandGetSourceInfo:
+		i := s.sourceIndex(t.U32(), t.String())
+		if i < 0 {
+			return nil, pulse.ErrNoEntity
+		}
+		s.sources[i].Put(reply)
+
+	case pulse.CommandGetSourceInfoList:
+		for _, source := range s.sources {
+			source.Put(reply)
+		}
+
+	case pulse.CommandGetCardInfo:
+		i := s.cardIndex(t.U32(), t.String())
+		if i < 0 {
+			return nil, pulse.ErrNoEntity
+		}
+		s.cards[i].Put(reply)
+
+	case pulse.CommandGetCardInfoList:
+		for _, card := range s.cards {
+			card.Put(reply)
+		}
+
+	case pulse.CommandSetCardProfile:
+		i := s.cardIndex(t.U32(), t.String())
+		if err := s.setCardProfile(i, t.String()); err != nil {
+			return nil, err
+		}
+
+	case pulse.CommandGetSinkInputInfo:
+		i := s.sinkInputIndex(t.U32())
+		if i < 0 {
+			return nil, pulse.ErrNoEntity
+		}
+		s.sinkInputs[i].Put(reply)
+
+	case pulse.CommandGetSinkInputInfoList:
+		for _, input := range s.sinkInputs {
+			input.Put(reply)
+		}
+
+	case pulse.CommandGetSourceOutputInfo:
+		i := s.sourceOutputIndex(t.U32())
+		if i < 0 {
+			return nil, pulse.ErrNoEntity
+		}
+		s.sourceOutputs[i].Put(reply)
+
+	case pulse.CommandGetSourceOutputInfoList:
+		for _, output := range s.sourceOutputs {
+			output.Put(reply)
+		}
+
+	case pulse.CommandSetSinkInputVolume, pulse.CommandSetSinkInputMute:
+		i := s.sinkInputIndex(t.U32())
+		if i < 0 {
+			return nil, pulse.ErrNoEntity
+		}
+		if cmd == pulse.CommandSetSinkInputVolume {
+			volume := t.CVolume()
+			if len(volume) != len(s.sinkInputs[i].Volume) && len(volume) != 1 {
+				return nil, pulse.ErrInvalid
+			}
+			s.sinkInputs[i].Volume = expand(volume, len(s.sinkInputs[i].Volume))
+		} else {
+			s.sinkInputs[i].Mute = t.Bool()
+		}
+		s.notify(pulse.FacilitySinkInput|pulse.EventChange, s.sinkInputs[i].Index)
+
+	case pulse.CommandSetSourceOutputVolume, pulse.CommandSetSourceOutputMute:
+		i := s.sourceOutputIndex(t.U32())
+		if i < 0 {
+			return nil, pulse.ErrNoEntity
+		}
+		if cmd == pulse.CommandSetSourceOutputVolume {
+			volume := t.CVolume()
+			if len(volume) != len(s.sourceOutputs[i].Volume) && len(volume) != 1 {
+				return nil, pulse.ErrInvalid
+			}
+			s.sourceOutputs[i].Volume = expand(volume, len(s.sourceOutputs[i].Volume))
+		} else {
+			s.sourceOutputs[i].Mute = t.Bool()
+		}
+		s.notify(pulse.FacilitySourceOutput|pulse.EventChange, s.sourceOutputs[i].Index)
+
+	case pulse.CommandMoveSinkInput:
+		i := s.sinkInputIndex(t.U32())
+		j := s.sinkIndex(t.U32(), t.String())
+		if i < 0 || j < 0 {
+			return nil, pulse.ErrNoEntity
+		}
+		if s.pinned[s.sinkInputs[i].Index] {
+			return nil, pulse.ErrNotSupported
+		}
+		s.sinkInputs[i].Sink = s.sinks[j].Index
+		s.notify(pulse.FacilitySinkInput|pulse.EventChange, s.sinkInputs[i].Index)
+
+	case pulse.CommandMoveSourceOutput:
+		i := s.sourceOutputIndex(t.U32())
+		j := s.sourceIndex(t.U32(), t.String())
+		if i < 0 || j < 0 {
+			return nil, pulse.ErrNoEntity
+		}
+		if s.pinned[s.sourceOutputs[i].Index] {
+			return nil, pulse.ErrNotSupported
+		}
+		s.sourceOutputs[i].Source = s.sources[j].Index
+		s.notify(pulse.FacilitySourceOutput|pulse.EventChange, s.sourceOutputs[i].Index)
+
+	case pulse.CommandSetSinkVolume, pulse.CommandSetSinkMute:
+		i := s.sinkIndex(t.U32(), t.String())
+		if i < 0 {
+			return nil, pulse.ErrNoEntity
+		}
+		if cmd == pulse.CommandSetSinkVolume {
+			volume := t.CVolume()
+			if len(volume) != len(s.sinks[i].Volume) && len(volume) != 1 {
+				return nil, pulse.ErrInvalid
+			}
+			s.sinks[i].Volume = expand(volume, len(s.sinks[i].Volume))
+		} else {
+			s.sinks[i].Mute = t.Bool()
+		}
+		s.notify(pulse.FacilitySink|pulse.EventChange, s.sinks[i].Index)
+
+	case pulse.CommandSetSourceVolume, pulse.CommandSetSourceMute:
+		i := s.sourceIndex(t.U32(), t.String())
+		if i < 0 {
+			return nil, pulse.ErrNoEntity
+		}
+		if cmd == pulse.CommandSetSourceVolume {
+			volume := t.CVolume()
+			if len(volume) != len(s.sources[i].Volume) && len(volume) != 1 {
+				return nil, pulse.ErrInvalid
+			}
+			s.sources[i].Volume = expand(volume, len(s.sources[i].Volume))
+		} else {
+			s.sources[i].Mute = t.Bool()
+		}
+		s.notify(pulse.FacilitySource|pulse.EventChange, s.sources[i].Index)
+
+	case pulse.CommandSetSinkPort:
+		i := s.sinkIndex(t.U32(), t.String())
+		if i < 0 {
+			return nil, pulse.ErrNoEntity
+		}
+		port := t.String()
+		if !hasPort(s.sinks[i].Ports, port) {
+			return nil, pulse.ErrNoEntity
+		}
+		s.sinks[i].ActivePort = port
+		s.notify(pulse.FacilitySink|pulse.EventChange, s.sinks[i].Index)
+
+	case pulse.CommandSetSourcePort:
+		i := s.sourceIndex(t.U32(), t.String())
+		if i < 0 {
+			return nil, pulse.ErrNoEntity
+		}
+		port := t.String()
+		if !hasPort(s.sources[i].Ports, port) {
+			return nil, pulse.ErrNoEntity
+		}
+		s.sources[i].ActivePort = port
+		s.notify(pulse.FacilitySource|pulse.EventChange, s.sources[i].Index)
+
+	case pulse.CommandSetDefaultSink:
+		if err := s.setDefaultSink(t.String()); err != nil {
+			return nil, err
+		}
+
+	case pulse.CommandSetDefaultSource:
+		if err := s.setDefaultSource(t.String()); err != nil {
+			return nil, err
+		}
+
+	case pulse.CommandCreatePlaybackStream:
+		if err := s.createPlaybackStream(c, t, reply); err != nil {
+			return nil, err
+		}
+
+	case pulse.CommandDrainPlaybackStream:
+		// Audio is played as it arrives
+		if _, ok := c.playbacks[t.U32()]; !ok {
+			return nil, pulse.ErrNoEntity
+		}
+
+	case pulse.CommandDeletePlaybackStream:
+		channel := t.U32()
+		if _, ok := c.playbacks[channel]; !ok {
+			return nil, pulse.ErrNoEntity
+		}
+		delete(c.playbacks, channel)
+
+	case pulse.CommandCreateRecordStream:
+		if err := s.createRecordStream(c, t, reply); err != nil {
+			return nil, err
+		}
+
+	case pulse.CommandCorkRecordStream:
+		stream, ok := c.records[t.U32()]
+		if !ok {
+			return nil, pulse.ErrNoEntity
+		}
+		stream.corked = t.Bool()
+
+	case pulse.CommandDeleteRecordStream:
+		channel := t.U32()
+		if _, ok := c.records[channel]; !ok {
+			return nil, pulse.ErrNoEntity
+		}
+		delete(c.records, channel)
+
+	case pulse.CommandGetModuleInfo:
+		index := t.U32()
+		found := false
+		for _, module := range s.modules {
+			if module.Index == index {
+				module.Put(reply)
+				found = true
+			}
+		}
+		if !found {
+			return nil, pulse.ErrNoEntity
+		}
+
+	case pulse.CommandGetModuleInfoList:
+		for _, module := range s.modules {
+			module.Put(reply)
+		}
+
+	case pulse.CommandLoadModule:
+		index, err := s.loadModule(t.String(), t.String())
+		if err != nil {
+			return nil, err
+		}
+		reply.PutU32(index)
+
+	case pulse.CommandUnloadModule:
+		if err := s.unloadModule(t.U32()); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, pulse.ErrCommand
+	}
+
+	if t.Err() != nil {
+		return nil, pulse.ErrProtocol
+	}
+	return reply, nil
+}
+
+// expand applies a single-channel volume to every channel
+func expand(volume pulse.CVolume, channels int) pulse.CVolume {
+	if len(volume) == channels {
+		return volume
+	}
+	return pulse.NewCVolume(channels, volume[0])
+}
+
+func hasPort(ports []pulse.PortInfo, name string) bool {
+	for _, port := range ports {
+		if port.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package pulsetest
+
+import (
+	"strings"
+
+	"github.com/audi70r/go-audio-control/internal/pulse"
+)
+
+// LoadModule loads a module as another client would and returns its index.
+// The server knows module-null-sink, module-combine-sink and module-loopback,
+// which create the same sinks and streams as on a real server.
+func (s *Server) LoadModule(name, argument string) (uint32, error) {
+	s.mu.Lock()
+	index, err := s.loadModule(name, argument)
+	s.mu.Unlock()
+	s.flush()
+	return index, err
+}
+
+// UnloadModule unloads a module and removes the objects it created
+func (s *Server) UnloadModule(index uint32) error {
+	s.mu.Lock()
+	err := s.unloadModule(index)
+	s.mu.Unlock()
+	s.flush()
+	return err
+}
+
+// Modules returns the loaded modules
+func (s *Server) Modules() []pulse.ModuleInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]pulse.ModuleInfo(nil), s.modules...)
+}
+
+func (s *Server) loadModule(name, argument string) (uint32, error) {
+	args, err := pulse.ParseArguments(argument)
+	if err != nil {
+		return 0, pulse.ErrModInitFailed
+	}
+
+	index := s.nextModule
+	switch name {
+	case "module-null-sink":
+		err = s.loadSink(index, args, "null", "Null Output")
+	case "module-combine-sink":
+		for _, slave := range strings.Split(args["slaves"], ",") {
+			if slave != "" && s.sinkIndex(pulse.Invalid, slave) < 0 {
+				return 0, pulse.ErrModInitFailed
+			}
+		}
+		err = s.loadSink(index, args, "combined", "Simultaneous Output")
+	case "module-loopback":
+		err = s.loadLoopback(index, args)
+	default:
+		err = pulse.ErrModInitFailed
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	s.nextModule++
+	s.modules = append(s.modules, pulse.ModuleInfo{
+		Index:      index,
+		Name:       name,
+		Argument:   argument,
+		Used:       pulse.Invalid,
+		Properties: pulse.Proplist{},
+	})
+	s.notify(pulse.FacilityModule|pulse.EventNew, index)
+	return index, nil
+}
+
+// loadSink creates the sink of a null or combined sink module
+func (s *Server) loadSink(module uint32, args map[string]string, name, description string) error {
+	if args["sink_name"] != "" {
+		name = args["sink_name"]
+	}
+	if s.sinkIndex(pulse.Invalid, name) >= 0 {
+		return pulse.ErrModInitFailed
+	}
+	props, err := pulse.ParseArguments(args["sink_properties"])
+	if err != nil {
+		return pulse.ErrModInitFailed
+	}
+	if props["device.description"] != "" {
+		description = props["device.description"]
+	}
+
+	sink := NewSink(name, description)
+	for key, value := range props {
+		sink.Properties[key] = value
+	}
+	sink.OwnerModule = module
+	s.addSink(sink)
+	return nil
+}
+
+// loadLoopback starts the record and playback streams of a loopback
+// between a source and a sink, which default to the server defaults
+func (s *Server) loadLoopback(module uint32, args map[string]string) error {
+	source := args["source"]
+	if source == "" {
+		source = s.defaultSource
+	}
+	sink := args["sink"]
+	if sink == "" {
+		sink = s.defaultSink
+	}
+	i := s.sourceIndex(pulse.Invalid, source)
+	j := s.sinkIndex(pulse.Invalid, sink)
+	if i < 0 || j < 0 {
+		return pulse.ErrModInitFailed
+	}
+	inputProps, err := pulse.ParseArguments(args["sink_input_properties"])
+	if err != nil {
+		return pulse.ErrModInitFailed
+	}
+	outputProps, err := pulse.ParseArguments(args["source_output_properties"])
+	if err != nil {
+		return pulse.ErrModInitFailed
+	}
+
+	output := NewSourceOutput("", 0)
+	output.Name = "Loopback to " + s.sinks[j].Description
+	output.Properties = pulse.Proplist(outputProps)
+	output.OwnerModule = module
+	s.addSourceOutput(i, output)
+
+	input := NewSinkInput("", 0)
+	input.Name = "Loopback from " + s.sources[i].Description
+	input.Properties = pulse.Proplist(inputProps)
+	input.OwnerModule = module
+	s.addSinkInput(j, input)
+	return nil
+}
+
+func (s *Server) unloadModule(index uint32) error {
+	m := -1
+	for i, module := range s.modules {
+		if module.Index == index {
+			m = i
+		}
+	}
+	if m < 0 {
+		return pulse.ErrNoEntity
+	}
+	s.modules = append(s.modules[:m], s.modules[m+1:]...)
+
+	for i := len(s.sinkInputs) - 1; i >= 0; i-- {
+		if s.sinkInputs[i].OwnerModule == index {
+			s.notify(pulse.FacilitySinkInput|pulse.EventRemove, s.sinkInputs[i].Index)
+			s.sinkInputs = append(s.sinkInputs[:i], s.sinkInputs[i+1:]...)
+		}
+	}
+	for i := len(s.sourceOutputs) - 1; i >= 0; i-- {
+		if s.sourceOutputs[i].OwnerModule == index {
+			s.notify(pulse.FacilitySourceOutput|pulse.EventRemove, s.sourceOutputs[i].Index)
+			s.sourceOutputs = append(s.sourceOutputs[:i], s.sourceOutputs[i+1:]...)
+		}
+	}
+	for i := len(s.sinks) - 1; i >= 0; i-- {
+		if s.sinks[i].OwnerModule == index {
+			s.removeSink(i)
+		}
+	}
+	s.notify(pulse.FacilityModule|pulse.EventRemove, index)
+	return nil
+}
@@ -0,0 +1,812 @@
+// Package pulsetest runs an in-process PulseAudio server that speaks enough
+// of the native protocol to test the Linux backend without a sound server.
+//
+// The server keeps its sinks, sources, cards, streams and modules in memory.
+// Requests from clients and the helper methods used by tests both change
+// that state and send the matching subscription events, so tests observe
+// the same notifications a real server would produce.
+package pulsetest
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/audi70r/go-audio-control/internal/pulse"
+)
+
+// Server is a fake PulseAudio server listening on a unix socket
+type Server struct {
+	listener net.Listener
+	path     string
+	address  string
+
+	mu            sync.Mutex
+	conns         map[*conn]struct{}
+	sinks         []pulse.SinkInfo
+	sources       []pulse.SourceInfo
+	cards         []pulse.CardInfo
+	sinkInputs    []pulse.SinkInputInfo
+	sourceOutputs []pulse.SourceOutputInfo
+	modules       []pulse.ModuleInfo
+	pinned        map[uint32]bool
+	defaultSink   string
+	defaultSource string
+	nextIndex     uint32
+	nextCard      uint32
+	nextModule    uint32
+	nextClient    uint32
+	events        []pulse.SubscriptionEvent
+	played        map[string][]byte
+	echoes        map[string]*echo
+}
+
+// NewServer starts a server in a temporary directory. It is closed when the
+// test ends.
+func NewServer(tb testing.TB) *Server {
+	tb.Helper()
+	s, err := Listen(filepath.Join(tb.TempDir(), "native"))
+	if err != nil {
+		tb.Fatalf("Failed to listen: %v", err)
+	}
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// Listen starts a server on a unix socket at path, for use outside a single
+// test such as in TestMain. The caller must Close it.
+func Listen(path string) (*Server, error) {
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		listener:   listener,
+		path:       path,
+		address:    "unix:" + path,
+		conns:      make(map[*conn]struct{}),
+		nextCard:   1,
+		nextModule: 1,
+	}
+	go s.accept(listener)
+	return s, nil
+}
+
+// Address returns the server address in PULSE_SERVER syntax
+func (s *Server) Address() string {
+	return s.address
+}
+
+// Close stops the server and drops every connection
+func (s *Server) Close() {
+	s.Stop()
+}
+
+// Stop stops listening and drops every connection, as a crashed server
+// would, but keeps the server state so it can be changed before Start
+func (s *Server) Stop() {
+	s.mu.Lock()
+	listener := s.listener
+	s.mu.Unlock()
+	listener.Close()
+	s.DropConnections()
+}
+
+// Start listens again at the address of a stopped server
+func (s *Server) Start() error {
+	listener, err := net.Listen("unix", s.path)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+	go s.accept(listener)
+	return nil
+}
+
+// DropConnections closes every client connection, as a server restart would
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	conns := s.conns
+	s.conns = make(map[*conn]struct{})
+	s.mu.Unlock()
+
+	for c := range conns {
+		c.netConn.Close()
+	}
+}
+
+func (s *Server) accept(listener net.Listener) {
+	for {
+		netConn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		c := &conn{server: s, netConn: netConn}
+		s.mu.Lock()
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+		go c.serve()
+	}
+}
+
+// NewSink returns a stereo sink with the given name and description
+func NewSink(name, description string) pulse.SinkInfo {
+	return pulse.SinkInfo{
+		Name:        name,
+		Description: description,
+		SampleSpec:  pulse.SampleSpec{Format: pulse.SampleS16LE, Channels: 2, Rate: 48000},
+		ChannelMap:  pulse.StereoMap(),
+		Volume:      pulse.NewCVolume(2, pulse.VolumeNorm),
+		Driver:      "pulsetest",
+		Properties:  pulse.Proplist{"device.description": description},
+		BaseVolume:  pulse.VolumeNorm,
+		VolumeSteps: 65537,
+	}
+}
+
+// NewSource returns a stereo source with the given name and description
+func NewSource(name, description string) pulse.SourceInfo {
+	return pulse.SourceInfo{
+		Name:          name,
+		Description:   description,
+		SampleSpec:    pulse.SampleSpec{Format: pulse.SampleS16LE, Channels: 2, Rate: 48000},
+		ChannelMap:    pulse.StereoMap(),
+		Volume:        pulse.NewCVolume(2, pulse.VolumeNorm),
+		MonitorOfSink: pulse.Invalid,
+		Driver:        "pulsetest",
+		Properties:    pulse.Proplist{"device.description": description},
+		BaseVolume:    pulse.VolumeNorm,
+		VolumeSteps:   65537,
+	}
+}
+
+// AddSink adds a sink together with its monitor source and returns its
+// index. The first sink becomes the default sink.
+func (s *Server) AddSink(sink pulse.SinkInfo) uint32 {
+	s.mu.Lock()
+	index := s.addSink(sink)
+	s.mu.Unlock()
+
+	s.flush()
+	return index
+}
+
+func (s *Server) addSink(sink pulse.SinkInfo) uint32 {
+	sink.Index = s.allocIndex()
+	sink.Card = orInvalid(sink.Card)
+	sink.OwnerModule = orInvalid(sink.OwnerModule)
+
+	monitor := NewSource(sink.Name+".monitor", "Monitor of "+sink.Description)
+	monitor.Index = s.allocIndex()
+	monitor.MonitorOfSink = sink.Index
+	monitor.MonitorOfSinkName = sink.Name
+	monitor.Card = sink.Card
+	monitor.OwnerModule = sink.OwnerModule
+	sink.MonitorSource = monitor.Index
+	sink.MonitorSourceName = monitor.Name
+
+	s.sinks = append(s.sinks, sink)
+	s.sources = append(s.sources, monitor)
+	s.notify(pulse.FacilitySink|pulse.EventNew, sink.Index)
+	s.notify(pulse.FacilitySource|pulse.EventNew, monitor.Index)
+	if s.defaultSink == "" {
+		s.defaultSink = sink.Name
+		s.notify(pulse.FacilityServer|pulse.EventChange, pulse.Invalid)
+	}
+	return sink.Index
+}
+
+// AddSource adds a source and returns its index. The first source that is
+// not a monitor becomes the default source.
+func (s *Server) AddSource(source pulse.SourceInfo) uint32 {
+	s.mu.Lock()
+	source.Index = s.allocIndex()
+	source.Card = orInvalid(source.Card)
+	source.OwnerModule = orInvalid(source.OwnerModule)
+	if source.MonitorOfSink == 0 && source.MonitorOfSinkName == "" {
+		source.MonitorOfSink = pulse.Invalid
+	}
+
+	s.sources = append(s.sources, source)
+	s.notify(pulse.FacilitySource|pulse.EventNew, source.Index)
+	if s.defaultSource == "" {
+		s.defaultSource = source.Name
+		s.notify(pulse.FacilityServer|pulse.EventChange, pulse.Invalid)
+	}
+	s.mu.Unlock()
+
+	s.flush()
+	return source.Index
+}
+
+// RemoveSink removes a sink and its monitor. If it was the default, the
+// first remaining sink becomes the default.
+func (s *Server) RemoveSink(name string) error {
+	s.mu.Lock()
+	i := s.sinkIndex(pulse.Invalid, name)
+	if i < 0 {
+		s.mu.Unlock()
+		return fmt.Errorf("no sink %s", name)
+	}
+	s.removeSink(i)
+	s.mu.Unlock()
+
+	s.flush()
+	return nil
+}
+
+func (s *Server) removeSink(i int) {
+	sink := s.sinks[i]
+	s.sinks = append(s.sinks[:i], s.sinks[i+1:]...)
+	s.notify(pulse.FacilitySink|pulse.EventRemove, sink.Index)
+	s.killPlaybackStreams(sink.Index)
+	if j := s.sourceIndex(sink.MonitorSource, ""); j >= 0 {
+		s.killRecordStreams(sink.MonitorSource)
+		s.sources = append(s.sources[:j], s.sources[j+1:]...)
+		s.notify(pulse.FacilitySource|pulse.EventRemove, sink.MonitorSource)
+	}
+	if s.defaultSink == sink.Name {
+		s.defaultSink = ""
+		if len(s.sinks) > 0 {
+			s.defaultSink = s.sinks[0].Name
+		}
+		s.notify(pulse.FacilityServer|pulse.EventChange, pulse.Invalid)
+	}
+}
+
+// RemoveSource removes a source. If it was the default, the first remaining
+// source that is not a monitor becomes the default.
+func (s *Server) RemoveSource(name string) error {
+	s.mu.Lock()
+	i := s.sourceIndex(pulse.Invalid, name)
+	if i < 0 {
+		s.mu.Unlock()
+		return fmt.Errorf("no source %s", name)
+	}
+	source := s.sources[i]
+	s.killRecordStreams(source.Index)
+	s.sources = append(s.sources[:i], s.sources[i+1:]...)
+	s.notify(pulse.FacilitySource|pulse.EventRemove, source.Index)
+	if s.defaultSource == name {
+		s.defaultSource = ""
+		for _, other := range s.sources {
+			if !other.IsMonitor() {
+				s.defaultSource = other.Name
+				break
+			}
+		}
+		s.notify(pulse.FacilityServer|pulse.EventChange, pulse.Invalid)
+	}
+	s.mu.Unlock()
+
+	s.flush()
+	return nil
+}
+
+// UpdateSink applies change to the named sink and sends a change event
+func (s *Server) UpdateSink(name string, change func(*pulse.SinkInfo)) error {
+	s.mu.Lock()
+	i := s.sinkIndex(pulse.Invalid, name)
+	if i < 0 {
+		s.mu.Unlock()
+		return fmt.Errorf("no sink %s", name)
+	}
+	change(&s.sinks[i])
+	s.notify(pulse.FacilitySink|pulse.EventChange, s.sinks[i].Index)
+	s.mu.Unlock()
+
+	s.flush()
+	return nil
+}
+
+// UpdateSource applies change to the named source and sends a change event
+func (s *Server) UpdateSource(name string, change func(*pulse.SourceInfo)) error {
+	s.mu.Lock()
+	i := s.sourceIndex(pulse.Invalid, name)
+	if i < 0 {
+		s.mu.Unlock()
+		return fmt.Errorf("no source %s", name)
+	}
+	change(&s.sources[i])
+	s.notify(pulse.FacilitySource|pulse.EventChange, s.sources[i].Index)
+	s.mu.Unlock()
+
+	s.flush()
+	return nil
+}
+
+// AddCard adds a card and returns its index. Card indexes start at 1, so a
+// sink or source can name its card by index and leave Card zero for none.
+func (s *Server) AddCard(card pulse.CardInfo) uint32 {
+	s.mu.Lock()
+	card.Index = s.nextCard
+	s.nextCard++
+	card.OwnerModule = orInvalid(card.OwnerModule)
+	s.cards = append(s.cards, card)
+	s.notify(pulse.FacilityCard|pulse.EventNew, card.Index)
+	s.mu.Unlock()
+
+	s.flush()
+	return card.Index
+}
+
+// RemoveCard removes a card, leaving its sinks and sources in place
+func (s *Server) RemoveCard(name string) error {
+	s.mu.Lock()
+	i := s.cardIndex(pulse.Invalid, name)
+	if i < 0 {
+		s.mu.Unlock()
+		return fmt.Errorf("no card %s", name)
+	}
+	card := s.cards[i]
+	s.cards = append(s.cards[:i], s.cards[i+1:]...)
+	s.notify(pulse.FacilityCard|pulse.EventRemove, card.Index)
+	s.mu.Unlock()
+
+	s.flush()
+	return nil
+}
+
+// SetCardProfile changes the active profile of a card as another client
+// would
+func (s *Server) SetCardProfile(name, profile string) error {
+	s.mu.Lock()
+	err := s.setCardProfile(s.cardIndex(pulse.Invalid, name), profile)
+	s.mu.Unlock()
+	s.flush()
+	return err
+}
+
+// Card returns the named card
+func (s *Server) Card(name string) (pulse.CardInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.cardIndex(pulse.Invalid, name); i >= 0 {
+		return s.cards[i], true
+	}
+	return pulse.CardInfo{}, false
+}
+
+func (s *Server) setCardProfile(i int, profile string) error {
+	if i < 0 {
+		return pulse.ErrNoEntity
+	}
+	found := false
+	for _, p := range s.cards[i].Profiles {
+		found = found || p.Name == profile
+	}
+	if !found {
+		return pulse.ErrNoEntity
+	}
+	if s.cards[i].ActiveProfile != profile {
+		s.cards[i].ActiveProfile = profile
+		s.notify(pulse.FacilityCard|pulse.EventChange, s.cards[i].Index)
+	}
+	return nil
+}
+
+// NewSinkInput returns a stereo playback stream of the named application
+func NewSinkInput(app string, pid int) pulse.SinkInputInfo {
+	return pulse.SinkInputInfo{
+		Name:           "Playback",
+		SampleSpec:     pulse.SampleSpec{Format: pulse.SampleS16LE, Channels: 2, Rate: 48000},
+		ChannelMap:     pulse.StereoMap(),
+		Volume:         pulse.NewCVolume(2, pulse.VolumeNorm),
+		Driver:         "protocol-native.c",
+		Properties:     streamProperties(app, pid),
+		HasVolume:      true,
+		VolumeWritable: true,
+		Format:         pulse.FormatInfo{Encoding: 1, Properties: pulse.Proplist{}},
+	}
+}
+
+// NewSourceOutput returns a mono record stream of the named application
+func NewSourceOutput(app string, pid int) pulse.SourceOutputInfo {
+	return pulse.SourceOutputInfo{
+		Name:           "Recording",
+		SampleSpec:     pulse.SampleSpec{Format: pulse.SampleS16LE, Channels: 1, Rate: 48000},
+		ChannelMap:     pulse.ChannelMap{pulse.ChannelMono},
+		Volume:         pulse.NewCVolume(1, pulse.VolumeNorm),
+		Driver:         "protocol-native.c",
+		Properties:     streamProperties(app, pid),
+		HasVolume:      true,
+		VolumeWritable: true,
+		Format:         pulse.FormatInfo{Encoding: 1, Properties: pulse.Proplist{}},
+	}
+}
+
+func streamProperties(app string, pid int) pulse.Proplist {
+	return pulse.Proplist{
+		"application.name":       app,
+		"application.process.id": strconv.Itoa(pid),
+	}
+}
+
+// AddSinkInput starts a playback stream on the named sink and returns its
+// index
+func (s *Server) AddSinkInput(sink string, input pulse.SinkInputInfo) (uint32, error) {
+	s.mu.Lock()
+	i := s.sinkIndex(pulse.Invalid, sink)
+	if i < 0 {
+		s.mu.Unlock()
+		return 0, fmt.Errorf("no sink %s", sink)
+	}
+	index := s.addSinkInput(i, input)
+	s.mu.Unlock()
+
+	s.flush()
+	return index, nil
+}
+
+func (s *Server) addSinkInput(sink int, input pulse.SinkInputInfo) uint32 {
+	input.Index = s.allocIndex()
+	input.Sink = s.sinks[sink].Index
+	input.Client = orInvalid(input.Client)
+	input.OwnerModule = orInvalid(input.OwnerModule)
+	s.sinkInputs = append(s.sinkInputs, input)
+	s.notify(pulse.FacilitySinkInput|pulse.EventNew, input.Index)
+	return input.Index
+}
+
+// AddSourceOutput starts a record stream on the named source and returns
+// its index
+func (s *Server) AddSourceOutput(source string, output pulse.SourceOutputInfo) (uint32, error) {
+	s.mu.Lock()
+	i := s.sourceIndex(pulse.Invalid, source)
+	if i < 0 {
+		s.mu.Unlock()
+		return 0, fmt.Errorf("no source %s", source)
+	}
+	index := s.addSourceOutput(i, output)
+	s.mu.Unlock()
+
+	s.flush()
+	return index, nil
+}
+
+func (s *Server) addSourceOutput(source int, output pulse.SourceOutputInfo) uint32 {
+	output.Index = s.allocIndex()
+	output.Source = s.sources[source].Index
+	output.Client = orInvalid(output.Client)
+	output.OwnerModule = orInvalid(output.OwnerModule)
+	s.sourceOutputs = append(s.sourceOutputs, output)
+	s.notify(pulse.FacilitySourceOutput|pulse.EventNew, output.Index)
+	return output.Index
+}
+
+// RemoveSinkInput ends a playback stream
+func (s *Server) RemoveSinkInput(index uint32) error {
+	s.mu.Lock()
+	i := s.sinkInputIndex(index)
+	if i < 0 {
+		s.mu.Unlock()
+		return fmt.Errorf("no sink input %d", index)
+	}
+	s.sinkInputs = append(s.sinkInputs[:i], s.sinkInputs[i+1:]...)
+	s.notify(pulse.FacilitySinkInput|pulse.EventRemove, index)
+	s.mu.Unlock()
+
+	s.flush()
+	return nil
+}
+
+// RemoveSourceOutput ends a record stream
+func (s *Server) RemoveSourceOutput(index uint32) error {
+	s.mu.Lock()
+	i := s.sourceOutputIndex(index)
+	if i < 0 {
+		s.mu.Unlock()
+		return fmt.Errorf("no source output %d", index)
+	}
+	s.sourceOutputs = append(s.sourceOutputs[:i], s.sourceOutputs[i+1:]...)
+	s.notify(pulse.FacilitySourceOutput|pulse.EventRemove, index)
+	s.mu.Unlock()
+
+	s.flush()
+	return nil
+}
+
+// PinStream makes the sink input or source output with the given index
+// refuse to move, as streams created with PA_STREAM_DONT_MOVE do
+func (s *Server) PinStream(index uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pinned == nil {
+		s.pinned = make(map[uint32]bool)
+	}
+	s.pinned[index] = true
+}
+
+// SinkInput returns the sink input with the given index
+func (s *Server) SinkInput(index uint32) (pulse.SinkInputInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.sinkInputIndex(index); i >= 0 {
+		return s.sinkInputs[i], true
+	}
+	return pulse.SinkInputInfo{}, false
+}
+
+// SourceOutput returns the source output with the given index
+func (s *Server) SourceOutput(index uint32) (pulse.SourceOutputInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.sourceOutputIndex(index); i >= 0 {
+		return s.sourceOutputs[i], true
+	}
+	return pulse.SourceOutputInfo{}, false
+}
+
+// Sink returns the named sink
+func (s *Server) Sink(name string) (pulse.SinkInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.sinkIndex(pulse.Invalid, name); i >= 0 {
+		return s.sinks[i], true
+	}
+	return pulse.SinkInfo{}, false
+}
+
+// Source returns the named source
+func (s *Server) Source(name string) (pulse.SourceInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.sourceIndex(pulse.Invalid, name); i >= 0 {
+		return s.sources[i], true
+	}
+	return pulse.SourceInfo{}, false
+}
+
+// DefaultSink returns the name of the default sink
+func (s *Server) DefaultSink() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.defaultSink
+}
+
+// DefaultSource returns the name of the default source
+func (s *Server) DefaultSource() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.defaultSource
+}
+
+// SetDefaultSink changes the default sink as another client would
+func (s *Server) SetDefaultSink(name string) error {
+	s.mu.Lock()
+	err := s.setDefaultSink(name)
+	s.mu.Unlock()
+	s.flush()
+	return err
+}
+
+// SetDefaultSource changes the default source as another client would
+func (s *Server) SetDefaultSource(name string) error {
+	s.mu.Lock()
+	err := s.setDefaultSource(name)
+	s.mu.Unlock()
+	s.flush()
+	return err
+}
+
+func (s *Server) setDefaultSink(name string) error {
+	if s.sinkIndex(pulse.Invalid, name) < 0 {
+		return pulse.ErrNoEntity
+	}
+	if s.defaultSink != name {
+		s.defaultSink = name
+		s.notify(pulse.FacilityServer|pulse.EventChange, pulse.Invalid)
+	}
+	return nil
+}
+
+func (s *Server) setDefaultSource(name string) error {
+	if s.sourceIndex(pulse.Invalid, name) < 0 {
+		return pulse.ErrNoEntity
+	}
+	if s.defaultSource != name {
+		s.defaultSource = name
+		s.notify(pulse.FacilityServer|pulse.EventChange, pulse.Invalid)
+	}
+	return nil
+}
+
+// SetNextIndex makes index the index of the next sink, source or stream,
+// as a restarted server hands out indices from the start again
+func (s *Server) SetNextIndex(index uint32) {
+	s.mu.Lock()
+	s.nextIndex = index
+	s.mu.Unlock()
+}
+
+// allocIndex returns the next object index. Indexes are never reused, as on
+// a running real server, unless SetNextIndex says otherwise.
+func (s *Server) allocIndex() uint32 {
+	index := s.nextIndex
+	s.nextIndex++
+	return index
+}
+
+func orInvalid(index uint32) uint32 {
+	if index == 0 {
+		return pulse.Invalid
+	}
+	return index
+}
+
+// sinkIndex finds a sink by index, or by name when index is Invalid
+func (s *Server) sinkIndex(index uint32, name string) int {
+	for i, sink := range s.sinks {
+		if (index != pulse.Invalid && sink.Index == index) || (index == pulse.Invalid && sink.Name == name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sourceIndex finds a source by index, or by name when index is Invalid
+func (s *Server) sourceIndex(index uint32, name string) int {
+	for i, source := range s.sources {
+		if (index != pulse.Invalid && source.Index == index) || (index == pulse.Invalid && source.Name == name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// cardIndex finds a card by index, or by name when index is Invalid
+func (s *Server) cardIndex(index uint32, name string) int {
+	for i, card := range s.cards {
+		if (index != pulse.Invalid && card.Index == index) || (index == pulse.Invalid && card.Name == name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sinkInputIndex finds a sink input by index
+func (s *Server) sinkInputIndex(index uint32) int {
+	for i, input := range s.sinkInputs {
+		if input.Index == index {
+			return i
+		}
+	}
+	return -1
+}
+
+// sourceOutputIndex finds a source output by index
+func (s *Server) sourceOutputIndex(index uint32) int {
+	for i, output := range s.sourceOutputs {
+		if output.Index == index {
+			return i
+		}
+	}
+	return -1
+}
+
+// notify queues a subscription event; flush sends it
+func (s *Server) notify(event, index uint32) {
+	s.events = append(s.events, pulse.SubscriptionEvent{Event: event, Index: index})
+}
+
+// flush sends the queued events to every subscribed connection
+func (s *Server) flush() {
+	s.mu.Lock()
+	events := s.events
+	s.events = nil
+	conns := make([]*conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+
+	for _, event := range events {
+		for _, c := range conns {
+			c.sendEvent(event)
+		}
+	}
+}
+
+// conn is one client connection
+type conn struct {
+	server  *Server
+	netConn net.Conn
+
+	writeMu sync.Mutex
+	mask    uint32
+
+	// Streams by channel, guarded by the server mutex
+	records     map[uint32]*recordStream
+	playbacks   map[uint32]*playbackStream
+	nextChannel uint32
+}
+
+func (c *conn) serve() {
+	defer func() {
+		c.netConn.Close()
+		c.server.mu.Lock()
+		delete(c.server.conns, c)
+		c.server.mu.Unlock()
+	}()
+
+	for {
+		channel, payload, err := pulse.ReadPacket(c.netConn)
+		if err != nil {
+			return
+		}
+		if channel != pulse.ControlChannel {
+			c.server.receiveAudio(c, channel, payload)
+			continue
+		}
+
+		t := pulse.ParseTagstruct(payload)
+		cmd := pulse.Command(t.U32())
+		tag := t.U32()
+		if t.Err() != nil {
+			return
+		}
+
+		c.server.mu.Lock()
+		reply, err := c.server.handle(c, cmd, t)
+		c.server.mu.Unlock()
+
+		if err != nil {
+			var code pulse.Error
+			if !errors.As(err, &code) {
+				code = pulse.ErrInternal
+			}
+			c.sendError(tag, code)
+		} else {
+			c.sendReply(tag, reply)
+		}
+		c.server.flush()
+	}
+}
+
+func (c *conn) write(t *pulse.Tagstruct) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	pulse.WritePacket(c.netConn, pulse.ControlChannel, t.Bytes())
+}
+
+func (c *conn) sendReply(tag uint32, reply *pulse.Tagstruct) {
+	t := pulse.NewTagstruct()
+	t.PutU32(uint32(pulse.CommandReply))
+	t.PutU32(tag)
+	var body []byte
+	if reply != nil {
+		body = reply.Bytes()
+	}
+	c.write(pulse.ParseTagstruct(append(t.Bytes(), body...)))
+}
+
+func (c *conn) sendError(tag uint32, code pulse.Error) {
+	t := pulse.NewTagstruct()
+	t.PutU32(uint32(pulse.CommandError))
+	t.PutU32(tag)
+	t.PutU32(uint32(code))
+	c.write(t)
+}
+
+func (c *conn) sendEvent(event pulse.SubscriptionEvent) {
+	c.server.mu.Lock()
+	mask := c.mask
+	c.server.mu.Unlock()
+	if mask&(1<<event.Facility()) == 0 {
+		return
+	}
+
+	t := pulse.NewTagstruct()
+	t.PutU32(uint32(pulse.CommandSubscribeEvent))
+	t.PutU32(pulse.Invalid)
+	t.PutU32(event.Event)
+	t.PutU32(event.Index)
+	c.write(t)
+}
@@ -0,0 +1,348 @@
+package pulsetest
+
+import (
+	"fmt"
+
+	"github.com/audi70r/go-audio-control/internal/dsp"
+	"github.com/audi70r/go-audio-control/internal/pulse"
+)
+
+// recordStream is a record stream opened by a client. The server sends it
+// the samples fed to its source and does no format conversion, so clients
+// must ask for the channel count of the source.
+type recordStream struct {
+	channel uint32
+	source  uint32
+	spec    pulse.SampleSpec
+	corked  bool
+}
+
+// maxRecordLength is the buffer size the server grants record streams
+const maxRecordLength = 1 << 20
+
+// createRecordStream handles CommandCreateRecordStream with s.mu held
+func (s *Server) createRecordStream(c *conn, t, reply *pulse.Tagstruct) error {
+	spec := t.SampleSpec()
+	channelMap := t.ChannelMap()
+	i := s.sourceIndex(t.U32(), t.String())
+	if i < 0 && s.defaultSource != "" {
+		i = s.sourceIndex(pulse.Invalid, s.defaultSource)
+	}
+	t.U32() // maximum length
+	corked := t.Bool()
+	fragment := t.U32()
+	// The flags, properties and volume that follow do not affect the
+	// fake server
+	if t.Err() != nil {
+		return pulse.ErrProtocol
+	}
+	if i < 0 {
+		return pulse.ErrNoEntity
+	}
+	if _, ok := spec.Format.Encoding(); !ok || spec.Channels == 0 {
+		return pulse.ErrNotSupported
+	}
+	if fragment == pulse.Invalid {
+		fragment = uint32(spec.FrameSize()) * spec.Rate / 40
+	}
+
+	if c.records == nil {
+		c.records = make(map[uint32]*recordStream)
+	}
+	stream := &recordStream{channel: c.nextChannel, source: s.sources[i].Index, spec: spec, corked: corked}
+	c.nextChannel++
+	c.records[stream.channel] = stream
+
+	reply.PutU32(stream.channel)
+	reply.PutU32(s.allocIndex())
+	reply.PutU32(maxRecordLength)
+	reply.PutU32(fragment)
+	reply.PutSampleSpec(spec)
+	reply.PutChannelMap(channelMap)
+	reply.PutU32(s.sources[i].Index)
+	reply.PutString(s.sources[i].Name)
+	reply.PutBool(false) // suspended
+	reply.PutUsec(0)     // configured latency
+	reply.PutFormatInfo(pulse.FormatInfo{Encoding: 1, Properties: pulse.Proplist{}})
+	return nil
+}
+
+// FeedSource sends interleaved samples to every uncorked record stream on
+// the named source, which may be the monitor of a sink
+func (s *Server) FeedSource(name string, samples []float32) error {
+	s.mu.Lock()
+	i := s.sourceIndex(pulse.Invalid, name)
+	if i < 0 {
+		s.mu.Unlock()
+		return fmt.Errorf("no source %s", name)
+	}
+	index := s.sources[i].Index
+	type packet struct {
+		c       *conn
+		channel uint32
+		data    []byte
+	}
+	var packets []packet
+	for c := range s.conns {
+		for _, stream := range c.records {
+			if stream.source != index || stream.corked {
+				continue
+			}
+			encoding, _ := stream.spec.Format.Encoding()
+			packets = append(packets, packet{c, stream.channel, dsp.Encode(samples, encoding)})
+		}
+	}
+	s.mu.Unlock()
+
+	for _, p := range packets {
+		p.c.writeMu.Lock()
+		pulse.WritePacket(p.c.netConn, p.channel, p.data)
+		p.c.writeMu.Unlock()
+	}
+	return nil
+}
+
+// RecordStreams returns the number of record streams open on the named
+// source
+func (s *Server) RecordStreams(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.sourceIndex(pulse.Invalid, name)
+	if i < 0 {
+		return 0
+	}
+	n := 0
+	for c := range s.conns {
+		for _, stream := range c.records {
+			if stream.source == s.sources[i].Index {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// killRecordStreams ends the streams on a removed source with s.mu held,
+// as a real server does when a device goes away
+func (s *Server) killRecordStreams(source uint32) {
+	for c := range s.conns {
+		for channel, stream := range c.records {
+			if stream.source != source {
+				continue
+			}
+			delete(c.records, channel)
+			t := pulse.NewTagstruct()
+			t.PutU32(uint32(pulse.CommandRecordStreamKilled))
+			t.PutU32(pulse.Invalid)
+			t.PutU32(channel)
+			c.write(t)
+		}
+	}
+}
+
+// playbackStream is a playback stream opened by a client. Audio is played
+// as soon as it arrives, so the server asks for more right away.
+type playbackStream struct {
+	channel uint32
+	sink    uint32
+	spec    pulse.SampleSpec
+}
+
+// playbackBuffer is the buffer size granted to new playback streams. It is
+// small so that clients must wait for requests to write longer audio.
+const playbackBuffer = 4096
+
+// createPlaybackStream handles CommandCreatePlaybackStream with s.mu held
+func (s *Server) createPlaybackStream(c *conn, t, reply *pulse.Tagstruct) error {
+	spec := t.SampleSpec()
+	channelMap := t.ChannelMap()
+	i := s.sinkIndex(t.U32(), t.String())
+	if i < 0 && s.defaultSink != "" {
+		i = s.sinkIndex(pulse.Invalid, s.defaultSink)
+	}
+	// The buffer attributes, flags, volume and properties that follow do
+	// not affect the fake server
+	if t.Err() != nil {
+		return pulse.ErrProtocol
+	}
+	if i < 0 {
+		return pulse.ErrNoEntity
+	}
+	if spec.FrameSize() == 0 || len(channelMap) != int(spec.Channels) {
+		return pulse.ErrInvalid
+	}
+
+	if c.playbacks == nil {
+		c.playbacks = make(map[uint32]*playbackStream)
+	}
+	stream := &playbackStream{channel: c.nextChannel, sink: s.sinks[i].Index, spec: spec}
+	c.nextChannel++
+	c.playbacks[stream.channel] = stream
+
+	reply.PutU32(stream.channel)
+	reply.PutU32(s.allocIndex())
+	reply.PutU32(playbackBuffer) // missing
+	reply.PutU32(playbackBuffer) // maximum length
+	reply.PutU32(playbackBuffer) // target length
+	reply.PutU32(0)              // prebuffer
+	reply.PutU32(playbackBuffer) // minimum request
+	reply.PutSampleSpec(spec)
+	reply.PutChannelMap(channelMap)
+	reply.PutU32(s.sinks[i].Index)
+	reply.PutString(s.sinks[i].Name)
+	reply.PutBool(false) // suspended
+	reply.PutUsec(0)     // configured latency
+	reply.PutFormatInfo(pulse.FormatInfo{Encoding: 1, Properties: pulse.Proplist{}})
+	return nil
+}
+
+// receiveAudio plays an audio packet from a client and asks for as much
+// again. The audio is fed to the monitor of the sink and to its echo.
+func (s *Server) receiveAudio(c *conn, channel uint32, payload []byte) {
+	type feed struct {
+		source  string
+		samples []float32
+	}
+	var feeds []feed
+
+	s.mu.Lock()
+	stream, ok := c.playbacks[channel]
+	if ok {
+		if i := s.sinkIndex(stream.sink, ""); i >= 0 {
+			if s.played == nil {
+				s.played = make(map[string][]byte)
+			}
+			name := s.sinks[i].Name
+			s.played[name] = append(s.played[name], payload...)
+			monitor := s.sinks[i].MonitorSourceName
+			if samples := s.convertLocked(monitor, stream.spec, payload, 0); samples != nil {
+				feeds = append(feeds, feed{monitor, samples})
+			}
+			if e := s.echoes[name]; e != nil {
+				if samples := s.convertLocked(e.source, stream.spec, payload, e.delay); samples != nil {
+					feeds = append(feeds, feed{e.source, samples})
+					e.delay = 0
+				}
+			}
+		}
+	}
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+	for _, f := range feeds {
+		s.FeedSource(f.source, f.samples)
+	}
+
+	t := pulse.NewTagstruct()
+	t.PutU32(uint32(pulse.CommandRequest))
+	t.PutU32(pulse.Invalid)
+	t.PutU32(channel)
+	t.PutU32(uint32(len(payload)))
+	c.write(t)
+}
+
+// echo feeds what a sink plays into a source
+type echo struct {
+	source string
+	// delay is the number of frames of silence still to be fed before
+	// the audio
+	delay int
+}
+
+// Echo feeds the audio played to a sink into a source after delay frames
+// of silence, like a microphone next to a speaker. Channels are mixed to
+// the channel count of the source; the rate is not converted. An empty
+// source stops the echo.
+func (s *Server) Echo(sink, source string, delay int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if source == "" {
+		delete(s.echoes, sink)
+		return nil
+	}
+	if s.sinkIndex(pulse.Invalid, sink) < 0 {
+		return fmt.Errorf("no sink %s", sink)
+	}
+	if s.sourceIndex(pulse.Invalid, source) < 0 {
+		return fmt.Errorf("no source %s", source)
+	}
+	if s.echoes == nil {
+		s.echoes = make(map[string]*echo)
+	}
+	s.echoes[sink] = &echo{source: source, delay: delay}
+	return nil
+}
+
+// convertLocked returns the samples to feed to the named source for a
+// packet played in spec, after delay frames of silence, with s.mu held
+func (s *Server) convertLocked(source string, spec pulse.SampleSpec, payload []byte, delay int) []float32 {
+	i := s.sourceIndex(pulse.Invalid, source)
+	encoding, ok := spec.Format.Encoding()
+	if i < 0 || !ok || spec.Channels == 0 {
+		return nil
+	}
+	in := dsp.Decode(payload, encoding)
+	inChannels := int(spec.Channels)
+	outChannels := int(s.sources[i].SampleSpec.Channels)
+
+	out := make([]float32, (delay+len(in)/inChannels)*outChannels)
+	for f := 0; f < len(in)/inChannels; f++ {
+		var sum float32
+		for _, v := range in[f*inChannels : (f+1)*inChannels] {
+			sum += v
+		}
+		for ch := 0; ch < outChannels; ch++ {
+			v := sum / float32(inChannels)
+			if inChannels == outChannels {
+				v = in[f*inChannels+ch]
+			}
+			out[(delay+f)*outChannels+ch] = v
+		}
+	}
+	return out
+}
+
+// Played returns the audio played to the named sink so far
+func (s *Server) Played(name string) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]byte(nil), s.played[name]...)
+}
+
+// PlaybackStreams returns the number of playback streams open on the named
+// sink
+func (s *Server) PlaybackStreams(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.sinkIndex(pulse.Invalid, name)
+	if i < 0 {
+		return 0
+	}
+	n := 0
+	for c := range s.conns {
+		for _, stream := range c.playbacks {
+			if stream.sink == s.sinks[i].Index {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// killPlaybackStreams ends the streams on a removed sink with s.mu held
+func (s *Server) killPlaybackStreams(sink uint32) {
+	for c := range s.conns {
+		for channel, stream := range c.playbacks {
+			if stream.sink != sink {
+				continue
+			}
+			delete(c.playbacks, channel)
+			t := pulse.NewTagstruct()
+			t.PutU32(uint32(pulse.CommandPlaybackStreamKilled))
+			t.PutU32(pulse.Invalid)
+			t.PutU32(channel)
+			c.write(t)
+		}
+	}
+}
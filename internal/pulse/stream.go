@@ -0,0 +1,379 @@
+package pulse
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrStreamClosed is returned by reads from and writes to a closed stream
+var ErrStreamClosed = errors.New("pulse: stream closed")
+
+// ErrStreamKilled is returned by reads from and writes to a stream the
+// server ended, for example because its device went away
+var ErrStreamKilled = errors.New("pulse: stream killed by the server")
+
+// RecordOptions describes a record stream. The Fix flags make the server
+// use the format, rate or channel count of the source instead of those of
+// SampleSpec; the stream reports what it got.
+type RecordOptions struct {
+	Source      string
+	SampleSpec  SampleSpec
+	ChannelMap  ChannelMap
+	FixFormat   bool
+	FixRate     bool
+	FixChannels bool
+	// FragmentSize is the number of bytes the server sends at once, or 0
+	// for the server default
+	FragmentSize uint32
+	Properties   Proplist
+}
+
+// RecordStream receives the audio of a source. Audio arrives whether or
+// not it is read; when the reader falls more than the server buffer behind,
+// the oldest audio is dropped.
+type RecordStream struct {
+	client  *Client
+	channel uint32
+
+	Index      uint32
+	SampleSpec SampleSpec
+	ChannelMap ChannelMap
+	Source     uint32
+	SourceName string
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	buf       []byte
+	maxLength int
+	err       error
+}
+
+// CreateRecordStream opens a record stream. The stream is created corked
+// and started once it is registered, so no audio is lost.
+func (c *Client) CreateRecordStream(opts RecordOptions) (*RecordStream, error) {
+	fragment := opts.FragmentSize
+	if fragment == 0 {
+		fragment = Invalid
+	}
+	props := opts.Properties
+	if props == nil {
+		props = Proplist{}
+	}
+	// A null name selects the default source
+	var source interface{}
+	if opts.Source != "" {
+		source = opts.Source
+	}
+
+	reply, err := c.Request(CommandCreateRecordStream,
+		opts.SampleSpec, opts.ChannelMap, uint32(Invalid), source,
+		uint32(Invalid), true, fragment,
+		false, false, opts.FixFormat, opts.FixRate, opts.FixChannels, false, false,
+		false, opts.FragmentSize != 0, props, uint32(Invalid),
+		false,
+		false, false,
+		uint8(0), NewCVolume(int(opts.SampleSpec.Channels), VolumeNorm), false, false, false, false, false,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &RecordStream{client: c}
+	s.cond = sync.NewCond(&s.mu)
+	s.channel = reply.U32()
+	s.Index = reply.U32()
+	s.maxLength = int(reply.U32())
+	reply.U32() // fragment size
+	s.SampleSpec = reply.SampleSpec()
+	s.ChannelMap = reply.ChannelMap()
+	s.Source = reply.U32()
+	s.SourceName = reply.String()
+	if err := reply.Err(); err != nil {
+		return nil, fmt.Errorf("pulse: malformed record stream reply: %w", err)
+	}
+
+	c.mu.Lock()
+	if c.records == nil {
+		c.records = make(map[uint32]*RecordStream)
+	}
+	c.records[s.channel] = s
+	c.mu.Unlock()
+
+	if _, err := c.Request(CommandCorkRecordStream, s.channel, false); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Read reads recorded audio, blocking until some is available
+func (s *RecordStream) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.buf) == 0 && s.err == nil {
+		s.cond.Wait()
+	}
+	if len(s.buf) == 0 {
+		return 0, s.err
+	}
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+// Close deletes the stream. Pending reads return ErrStreamClosed.
+func (s *RecordStream) Close() error {
+	s.client.mu.Lock()
+	_, open := s.client.records[s.channel]
+	delete(s.client.records, s.channel)
+	s.client.mu.Unlock()
+
+	s.end(ErrStreamClosed)
+	if !open {
+		return nil
+	}
+	_, err := s.client.Request(CommandDeleteRecordStream, s.channel)
+	if err != nil && !errors.Is(err, ErrNoEntity) && !errors.Is(err, ErrClosed) {
+		return err
+	}
+	return nil
+}
+
+// deliver appends audio from the server
+func (s *RecordStream) deliver(data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buf = append(s.buf, data...)
+	if s.maxLength > 0 && len(s.buf) > s.maxLength {
+		// Drop whole frames so that channels stay aligned
+		drop := len(s.buf) - s.maxLength
+		if frame := s.SampleSpec.FrameSize(); frame > 0 {
+			drop += (frame - drop%frame) % frame
+		}
+		s.buf = s.buf[drop:]
+	}
+	s.cond.Broadcast()
+}
+
+// end makes reads fail with err once the buffer is drained
+func (s *RecordStream) end(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.cond.Broadcast()
+}
+
+// deliverData hands an audio packet to its stream
+func (c *Client) deliverData(channel uint32, payload []byte) {
+	c.mu.Lock()
+	s := c.records[channel]
+	c.mu.Unlock()
+	if s != nil {
+		s.deliver(payload)
+	}
+}
+
+// streamKilled ends the stream on channel
+func (c *Client) streamKilled(channel uint32) {
+	c.mu.Lock()
+	s := c.records[channel]
+	delete(c.records, channel)
+	c.mu.Unlock()
+	if s != nil {
+		s.end(ErrStreamKilled)
+	}
+}
+
+// PlaybackOptions describes a playback stream
+type PlaybackOptions struct {
+	Sink       string
+	SampleSpec SampleSpec
+	ChannelMap ChannelMap
+	Properties Proplist
+}
+
+// PlaybackStream plays audio to a sink. Writes block while the server
+// buffer is full.
+type PlaybackStream struct {
+	client  *Client
+	channel uint32
+
+	Index      uint32
+	SampleSpec SampleSpec
+	ChannelMap ChannelMap
+	Sink       uint32
+	SinkName   string
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	requested int
+	err       error
+}
+
+// maxWrite is the largest audio packet sent at once
+const maxWrite = 64 << 10
+
+// CreatePlaybackStream opens a playback stream with the server's default
+// buffering
+func (c *Client) CreatePlaybackStream(opts PlaybackOptions) (*PlaybackStream, error) {
+	props := opts.Properties
+	if props == nil {
+		props = Proplist{}
+	}
+	// A null name selects the default sink
+	var sink interface{}
+	if opts.Sink != "" {
+		sink = opts.Sink
+	}
+
+	reply, err := c.Request(CommandCreatePlaybackStream,
+		opts.SampleSpec, opts.ChannelMap, uint32(Invalid), sink,
+		uint32(Invalid), false, uint32(Invalid), uint32(Invalid), uint32(Invalid), uint32(0),
+		NewCVolume(int(opts.SampleSpec.Channels), VolumeNorm),
+		false, false, false, false, false, false, false,
+		false, false, props,
+		false, false,
+		false, false, false,
+		false,
+		false,
+		uint8(0),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &PlaybackStream{client: c}
+	s.cond = sync.NewCond(&s.mu)
+	s.channel = reply.U32()
+	s.Index = reply.U32()
+	s.requested = int(reply.U32())
+	reply.U32() // maximum length
+	reply.U32() // target length
+	reply.U32() // prebuffer
+	reply.U32() // minimum request
+	s.SampleSpec = reply.SampleSpec()
+	s.ChannelMap = reply.ChannelMap()
+	s.Sink = reply.U32()
+	s.SinkName = reply.String()
+	if err := reply.Err(); err != nil {
+		return nil, fmt.Errorf("pulse: malformed playback stream reply: %w", err)
+	}
+
+	c.mu.Lock()
+	if c.playbacks == nil {
+		c.playbacks = make(map[uint32]*PlaybackStream)
+	}
+	c.playbacks[s.channel] = s
+	// The server may request more before the stream is registered
+	s.requested += c.unclaimed[s.channel]
+	delete(c.unclaimed, s.channel)
+	c.mu.Unlock()
+	return s, nil
+}
+
+// Write sends audio, blocking until the server has room for it. Audio
+// should be written in whole frames.
+func (s *PlaybackStream) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		s.mu.Lock()
+		for s.requested == 0 && s.err == nil {
+			s.cond.Wait()
+		}
+		if s.err != nil {
+			s.mu.Unlock()
+			return written, s.err
+		}
+		n := len(p) - written
+		if n > s.requested {
+			n = s.requested
+		}
+		if n > maxWrite {
+			n = maxWrite
+		}
+		s.requested -= n
+		s.mu.Unlock()
+
+		if err := s.client.send(s.channel, p[written:written+n]); err != nil {
+			return written, err
+		}
+		written += n
+	}
+	return written, nil
+}
+
+// Drain waits until the audio written so far has been played
+func (s *PlaybackStream) Drain() error {
+	_, err := s.client.Request(CommandDrainPlaybackStream, s.channel)
+	return err
+}
+
+// Close deletes the stream, dropping audio that has not been played.
+// Pending writes return ErrStreamClosed.
+func (s *PlaybackStream) Close() error {
+	s.client.mu.Lock()
+	_, open := s.client.playbacks[s.channel]
+	delete(s.client.playbacks, s.channel)
+	s.client.mu.Unlock()
+
+	s.end(ErrStreamClosed)
+	if !open {
+		return nil
+	}
+	_, err := s.client.Request(CommandDeletePlaybackStream, s.channel)
+	s.client.mu.Lock()
+	delete(s.client.unclaimed, s.channel)
+	s.client.mu.Unlock()
+	if err != nil && !errors.Is(err, ErrNoEntity) && !errors.Is(err, ErrClosed) {
+		return err
+	}
+	return nil
+}
+
+// request grants room for n more bytes
+func (s *PlaybackStream) request(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requested += n
+	s.cond.Broadcast()
+}
+
+// end makes writes fail with err
+func (s *PlaybackStream) end(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.cond.Broadcast()
+}
+
+// playbackRequest handles a request for more audio on channel
+func (c *Client) playbackRequest(channel uint32, n uint32) {
+	c.mu.Lock()
+	s := c.playbacks[channel]
+	if s == nil {
+		if c.unclaimed == nil {
+			c.unclaimed = make(map[uint32]int)
+		}
+		c.unclaimed[channel] += int(n)
+	}
+	c.mu.Unlock()
+	if s != nil {
+		s.request(int(n))
+	}
+}
+
+// playbackKilled ends the playback stream on channel
+func (c *Client) playbackKilled(channel uint32) {
+	c.mu.Lock()
+	s := c.playbacks[channel]
+	delete(c.playbacks, channel)
+	c.mu.Unlock()
+	if s != nil {
+		s.end(ErrStreamKilled)
+	}
+}
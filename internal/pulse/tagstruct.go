@@ -0,0 +1,484 @@
+package pulse
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Tagstruct type tags
+const (
+	tagString       = 't'
+	tagStringNull   = 'N'
+	tagU32          = 'L'
+	tagU8           = 'B'
+	tagU64          = 'R'
+	tagS64          = 'r'
+	tagSampleSpec   = 'a'
+	tagArbitrary    = 'x'
+	tagBooleanTrue  = '1'
+	tagBooleanFalse = '0'
+	tagTimeval      = 'T'
+	tagUsec         = 'U'
+	tagChannelMap   = 'm'
+	tagCVolume      = 'v'
+	tagProplist     = 'P'
+	tagVolume       = 'V'
+	tagFormatInfo   = 'f'
+)
+
+// ErrShortTagstruct is returned when a tagstruct ends before all expected
+// values were read
+var ErrShortTagstruct = errors.New("pulse: tagstruct too short")
+
+// SampleSpec describes the sample format of a stream
+type SampleSpec struct {
+	Format   SampleFormat
+	Channels uint8
+	Rate     uint32
+}
+
+// ChannelMap lists the position of every channel
+type ChannelMap []ChannelPosition
+
+// CVolume holds one volume per channel
+type CVolume []Volume
+
+// Proplist is a property list. Values are stored without the trailing NUL
+// the protocol adds to string properties.
+type Proplist map[string]string
+
+// FormatInfo describes a stream encoding
+type FormatInfo struct {
+	Encoding   uint8
+	Properties Proplist
+}
+
+// Tagstruct builds or parses the typed values carried in command packets.
+// Getters record the first error and return zero values afterwards, so a
+// whole record can be read before checking Err.
+type Tagstruct struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+// NewTagstruct returns an empty Tagstruct for writing
+func NewTagstruct() *Tagstruct {
+	return &Tagstruct{}
+}
+
+// ParseTagstruct returns a Tagstruct reading data
+func ParseTagstruct(data []byte) *Tagstruct {
+	return &Tagstruct{data: data}
+}
+
+// Bytes returns the encoded values
+func (t *Tagstruct) Bytes() []byte {
+	return t.data
+}
+
+// Err returns the first error encountered while reading
+func (t *Tagstruct) Err() error {
+	return t.err
+}
+
+// Eof reports whether every value has been read
+func (t *Tagstruct) Eof() bool {
+	return t.pos >= len(t.data)
+}
+
+// Put appends v, choosing the tag from its Go type. It accepts uint32,
+// uint8, uint64, int64, bool, string, nil (a null string), []byte, Volume,
+// SampleSpec, ChannelMap, CVolume, Proplist and FormatInfo.
+func (t *Tagstruct) Put(v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		t.PutNullString()
+	case uint32:
+		t.PutU32(v)
+	case uint8:
+		t.PutU8(v)
+	case uint64:
+		t.PutU64(v)
+	case int64:
+		t.PutS64(v)
+	case bool:
+		t.PutBool(v)
+	case string:
+		t.PutString(v)
+	case []byte:
+		t.PutArbitrary(v)
+	case Volume:
+		t.PutVolume(v)
+	case SampleSpec:
+		t.PutSampleSpec(v)
+	case ChannelMap:
+		t.PutChannelMap(v)
+	case CVolume:
+		t.PutCVolume(v)
+	case Proplist:
+		t.PutProplist(v)
+	case FormatInfo:
+		t.PutFormatInfo(v)
+	default:
+		panic(fmt.Sprintf("pulse: cannot encode %T", v))
+	}
+}
+
+func (t *Tagstruct) putRawU32(v uint32) {
+	t.data = binary.BigEndian.AppendUint32(t.data, v)
+}
+
+// PutU32 appends an unsigned 32-bit value
+func (t *Tagstruct) PutU32(v uint32) {
+	t.data = append(t.data, tagU32)
+	t.putRawU32(v)
+}
+
+// PutU8 appends an unsigned 8-bit value
+func (t *Tagstruct) PutU8(v uint8) {
+	t.data = append(t.data, tagU8, v)
+}
+
+// PutU64 appends an unsigned 64-bit value
+func (t *Tagstruct) PutU64(v uint64) {
+	t.data = append(t.data, tagU64)
+	t.data = binary.BigEndian.AppendUint64(t.data, v)
+}
+
+// PutS64 appends a signed 64-bit value
+func (t *Tagstruct) PutS64(v int64) {
+	t.data = append(t.data, tagS64)
+	t.data = binary.BigEndian.AppendUint64(t.data, uint64(v))
+}
+
+// PutBool appends a boolean
+func (t *Tagstruct) PutBool(v bool) {
+	if v {
+		t.data = append(t.data, tagBooleanTrue)
+	} else {
+		t.data = append(t.data, tagBooleanFalse)
+	}
+}
+
+// PutString appends a NUL-terminated string
+func (t *Tagstruct) PutString(s string) {
+	t.data = append(t.data, tagString)
+	t.data = append(t.data, s...)
+	t.data = append(t.data, 0)
+}
+
+// PutNullString appends a null string
+func (t *Tagstruct) PutNullString() {
+	t.data = append(t.data, tagStringNull)
+}
+
+// PutArbitrary appends a length-prefixed byte string
+func (t *Tagstruct) PutArbitrary(b []byte) {
+	t.data = append(t.data, tagArbitrary)
+	t.putRawU32(uint32(len(b)))
+	t.data = append(t.data, b...)
+}
+
+// PutUsec appends a duration in microseconds
+func (t *Tagstruct) PutUsec(v uint64) {
+	t.data = append(t.data, tagUsec)
+	t.data = binary.BigEndian.AppendUint64(t.data, v)
+}
+
+// PutVolume appends a single volume
+func (t *Tagstruct) PutVolume(v Volume) {
+	t.data = append(t.data, tagVolume)
+	t.putRawU32(uint32(v))
+}
+
+// PutSampleSpec appends a sample specification
+func (t *Tagstruct) PutSampleSpec(s SampleSpec) {
+	t.data = append(t.data, tagSampleSpec, uint8(s.Format), s.Channels)
+	t.putRawU32(s.Rate)
+}
+
+// PutChannelMap appends a channel map
+func (t *Tagstruct) PutChannelMap(m ChannelMap) {
+	t.data = append(t.data, tagChannelMap, uint8(len(m)))
+	for _, p := range m {
+		t.data = append(t.data, uint8(p))
+	}
+}
+
+// PutCVolume appends a per-channel volume
+func (t *Tagstruct) PutCVolume(v CVolume) {
+	t.data = append(t.data, tagCVolume, uint8(len(v)))
+	for _, c := range v {
+		t.putRawU32(uint32(c))
+	}
+}
+
+// PutProplist appends a property list, with keys in sorted order so that
+// the encoding is deterministic
+func (t *Tagstruct) PutProplist(p Proplist) {
+	t.data = append(t.data, tagProplist)
+	keys := make([]string, 0, len(p))
+	for k := range p {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		value := append([]byte(p[k]), 0)
+		t.PutString(k)
+		t.PutU32(uint32(len(value)))
+		t.PutArbitrary(value)
+	}
+	t.PutNullString()
+}
+
+// PutFormatInfo appends a format description
+func (t *Tagstruct) PutFormatInfo(f FormatInfo) {
+	t.data = append(t.data, tagFormatInfo)
+	t.PutU8(f.Encoding)
+	t.PutProplist(f.Properties)
+}
+
+// fail records err if no error was recorded yet
+func (t *Tagstruct) fail(err error) {
+	if t.err == nil {
+		t.err = err
+	}
+}
+
+// take returns the next n bytes
+func (t *Tagstruct) take(n int) []byte {
+	if t.err != nil {
+		return nil
+	}
+	if n < 0 || t.pos+n > len(t.data) {
+		t.fail(ErrShortTagstruct)
+		return nil
+	}
+	b := t.data[t.pos : t.pos+n]
+	t.pos += n
+	return b
+}
+
+// expect consumes the tag byte and reports whether it was tag
+func (t *Tagstruct) expect(tag byte) bool {
+	b := t.take(1)
+	if b == nil {
+		return false
+	}
+	if b[0] != tag {
+		t.fail(fmt.Errorf("pulse: expected tag %q, got %q at offset %d", tag, b[0], t.pos-1))
+		return false
+	}
+	return true
+}
+
+func (t *Tagstruct) rawU32() uint32 {
+	b := t.take(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (t *Tagstruct) rawU64() uint64 {
+	b := t.take(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+// U32 reads an unsigned 32-bit value
+func (t *Tagstruct) U32() uint32 {
+	if !t.expect(tagU32) {
+		return 0
+	}
+	return t.rawU32()
+}
+
+// U8 reads an unsigned 8-bit value
+func (t *Tagstruct) U8() uint8 {
+	if !t.expect(tagU8) {
+		return 0
+	}
+	b := t.take(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+// U64 reads an unsigned 64-bit value
+func (t *Tagstruct) U64() uint64 {
+	if !t.expect(tagU64) {
+		return 0
+	}
+	return t.rawU64()
+}
+
+// S64 reads a signed 64-bit value
+func (t *Tagstruct) S64() int64 {
+	if !t.expect(tagS64) {
+		return 0
+	}
+	return int64(t.rawU64())
+}
+
+// Bool reads a boolean
+func (t *Tagstruct) Bool() bool {
+	b := t.take(1)
+	if b == nil {
+		return false
+	}
+	switch b[0] {
+	case tagBooleanTrue:
+		return true
+	case tagBooleanFalse:
+		return false
+	}
+	t.fail(fmt.Errorf("pulse: expected boolean, got tag %q", b[0]))
+	return false
+}
+
+// String reads a string. A null string reads as "".
+func (t *Tagstruct) String() string {
+	b := t.take(1)
+	if b == nil {
+		return ""
+	}
+	switch b[0] {
+	case tagStringNull:
+		return ""
+	case tagString:
+	default:
+		t.fail(fmt.Errorf("pulse: expected string, got tag %q", b[0]))
+		return ""
+	}
+	for i := t.pos; i < len(t.data); i++ {
+		if t.data[i] == 0 {
+			s := string(t.data[t.pos:i])
+			t.pos = i + 1
+			return s
+		}
+	}
+	t.fail(ErrShortTagstruct)
+	return ""
+}
+
+// Arbitrary reads a length-prefixed byte string
+func (t *Tagstruct) Arbitrary() []byte {
+	if !t.expect(tagArbitrary) {
+		return nil
+	}
+	n := t.rawU32()
+	return append([]byte(nil), t.take(int(n))...)
+}
+
+// Usec reads a duration in microseconds
+func (t *Tagstruct) Usec() uint64 {
+	if !t.expect(tagUsec) {
+		return 0
+	}
+	return t.rawU64()
+}
+
+// Timeval reads a timestamp as seconds and microseconds
+func (t *Tagstruct) Timeval() (sec, usec uint32) {
+	if !t.expect(tagTimeval) {
+		return 0, 0
+	}
+	return t.rawU32(), t.rawU32()
+}
+
+// Volume reads a single volume
+func (t *Tagstruct) Volume() Volume {
+	if !t.expect(tagVolume) {
+		return 0
+	}
+	return Volume(t.rawU32())
+}
+
+// SampleSpec reads a sample specification
+func (t *Tagstruct) SampleSpec() SampleSpec {
+	if !t.expect(tagSampleSpec) {
+		return SampleSpec{}
+	}
+	b := t.take(2)
+	if b == nil {
+		return SampleSpec{}
+	}
+	return SampleSpec{Format: SampleFormat(b[0]), Channels: b[1], Rate: t.rawU32()}
+}
+
+// ChannelMap reads a channel map
+func (t *Tagstruct) ChannelMap() ChannelMap {
+	if !t.expect(tagChannelMap) {
+		return nil
+	}
+	n := t.take(1)
+	if n == nil {
+		return nil
+	}
+	b := t.take(int(n[0]))
+	m := make(ChannelMap, len(b))
+	for i, p := range b {
+		m[i] = ChannelPosition(p)
+	}
+	return m
+}
+
+// CVolume reads a per-channel volume
+func (t *Tagstruct) CVolume() CVolume {
+	if !t.expect(tagCVolume) {
+		return nil
+	}
+	n := t.take(1)
+	if n == nil {
+		return nil
+	}
+	v := make(CVolume, n[0])
+	for i := range v {
+		v[i] = Volume(t.rawU32())
+	}
+	return v
+}
+
+// Proplist reads a property list
+func (t *Tagstruct) Proplist() Proplist {
+	if !t.expect(tagProplist) {
+		return nil
+	}
+	p := make(Proplist)
+	for t.err == nil {
+		if t.pos < len(t.data) && t.data[t.pos] == tagStringNull {
+			t.pos++
+			return p
+		}
+		key := t.String()
+		n := t.U32()
+		value := t.Arbitrary()
+		if t.err != nil {
+			break
+		}
+		if uint32(len(value)) != n {
+			t.fail(fmt.Errorf("pulse: property %q has length %d, expected %d", key, len(value), n))
+			break
+		}
+		if len(value) > 0 && value[len(value)-1] == 0 {
+			value = value[:len(value)-1]
+		}
+		p[key] = string(value)
+	}
+	return nil
+}
+
+// FormatInfo reads a format description
+func (t *Tagstruct) FormatInfo() FormatInfo {
+	if !t.expect(tagFormatInfo) {
+		return FormatInfo{}
+	}
+	return FormatInfo{Encoding: t.U8(), Properties: t.Proplist()}
+}
@@ -0,0 +1,242 @@
+package pulse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTagstructRoundTrip(t *testing.T) {
+	w := NewTagstruct()
+	w.PutU32(42)
+	w.PutU8(7)
+	w.PutU64(1 << 40)
+	w.PutS64(-5)
+	w.PutBool(true)
+	w.PutString("speakers")
+	w.PutNullString()
+	w.PutArbitrary([]byte{1, 2, 3})
+	w.PutUsec(1500)
+	w.PutVolume(VolumeNorm)
+	w.PutSampleSpec(SampleSpec{Format: SampleS16LE, Channels: 2, Rate: 44100})
+	w.PutChannelMap(StereoMap())
+	w.PutCVolume(CVolume{VolumeNorm, VolumeNorm / 2})
+	w.PutProplist(Proplist{"device.description": "Speakers", "media.role": "music"})
+	w.PutFormatInfo(FormatInfo{Encoding: 1, Properties: Proplist{}})
+
+	r := ParseTagstruct(w.Bytes())
+	if v := r.U32(); v != 42 {
+		t.Errorf("U32: got %d", v)
+	}
+	if v := r.U8(); v != 7 {
+		t.Errorf("U8: got %d", v)
+	}
+	if v := r.U64(); v != 1<<40 {
+		t.Errorf("U64: got %d", v)
+	}
+	if v := r.S64(); v != -5 {
+		t.Errorf("S64: got %d", v)
+	}
+	if !r.Bool() {
+		t.Error("Bool: got false")
+	}
+	if v := r.String(); v != "speakers" {
+		t.Errorf("String: got %q", v)
+	}
+	if v := r.String(); v != "" {
+		t.Errorf("null String: got %q", v)
+	}
+	if v := r.Arbitrary(); !reflect.DeepEqual(v, []byte{1, 2, 3}) {
+		t.Errorf("Arbitrary: got %v", v)
+	}
+	if v := r.Usec(); v != 1500 {
+		t.Errorf("Usec: got %d", v)
+	}
+	if v := r.Volume(); v != VolumeNorm {
+		t.Errorf("Volume: got %d", v)
+	}
+	if v := r.SampleSpec(); v != (SampleSpec{Format: SampleS16LE, Channels: 2, Rate: 44100}) {
+		t.Errorf("SampleSpec: got %+v", v)
+	}
+	if v := r.ChannelMap(); !reflect.DeepEqual(v, StereoMap()) {
+		t.Errorf("ChannelMap: got %v", v)
+	}
+	if v := r.CVolume(); !reflect.DeepEqual(v, CVolume{VolumeNorm, VolumeNorm / 2}) {
+		t.Errorf("CVolume: got %v", v)
+	}
+	if v := r.Proplist(); v["device.description"] != "Speakers" || v["media.role"] != "music" {
+		t.Errorf("Proplist: got %v", v)
+	}
+	if v := r.FormatInfo(); v.Encoding != 1 {
+		t.Errorf("FormatInfo: got %+v", v)
+	}
+	if r.Err() != nil || !r.Eof() {
+		t.Fatalf("Expected to consume every value, err=%v", r.Err())
+	}
+}
+
+func TestTagstructErrors(t *testing.T) {
+	w := NewTagstruct()
+	w.PutString("not a number")
+
+	r := ParseTagstruct(w.Bytes())
+	r.U32()
+	if r.Err() == nil {
+		t.Fatal("Expected a tag mismatch error")
+	}
+	// Errors are sticky
+	if r.String() != "" || r.Err() == nil {
+		t.Fatal("Expected reads after an error to fail")
+	}
+
+	r = ParseTagstruct([]byte{tagU32, 0, 0})
+	r.U32()
+	if r.Err() != ErrShortTagstruct {
+		t.Fatalf("Expected ErrShortTagstruct, got %v", r.Err())
+	}
+}
+
+func TestSinkInfoRoundTrip(t *testing.T) {
+	sink := SinkInfo{
+		Index:       3,
+		Name:        "alsa_output.usb",
+		Description: "USB Audio",
+		SampleSpec:  SampleSpec{Format: SampleS16LE, Channels: 2, Rate: 48000},
+		ChannelMap:  StereoMap(),
+		OwnerModule: 9,
+		Volume:      CVolume{VolumeNorm, VolumeNorm},
+		Properties:  Proplist{"device.bus": "usb"},
+		BaseVolume:  VolumeNorm,
+		Card:        Invalid,
+		Ports: []PortInfo{
+			{Name: "analog-output-headphones", Description: "Headphones", Priority: 9900, Available: AvailableNo},
+		},
+		ActivePort: "analog-output-headphones",
+		Formats:    []FormatInfo{{Encoding: 1, Properties: Proplist{}}},
+	}
+
+	w := NewTagstruct()
+	sink.Put(w)
+	got := ReadSinkInfo(ParseTagstruct(w.Bytes()))
+	if !reflect.DeepEqual(sink, got) {
+		t.Fatalf("Round trip mismatch:\nwant %+v\ngot  %+v", sink, got)
+	}
+}
+
+func TestCardInfoRoundTrip(t *testing.T) {
+	card := CardInfo{
+		Index:       1,
+		Name:        "bluez_card.00_11_22_33_44_55",
+		OwnerModule: 24,
+		Driver:      "module-bluez5-device.c",
+		Profiles: []ProfileInfo{
+			{Name: "a2dp_sink", Description: "High Fidelity Playback (A2DP Sink)", Sinks: 1, Priority: 40, Available: AvailableYes},
+			{Name: "headset_head_unit", Description: "Headset Head Unit (HSP/HFP)", Sinks: 1, Sources: 1, Priority: 30, Available: AvailableYes},
+			{Name: "off", Description: "Off"},
+		},
+		ActiveProfile: "a2dp_sink",
+		Properties:    Proplist{"device.bus": "bluetooth"},
+		Ports: []CardPortInfo{
+			{Name: "headset-output", Description: "Headset", Available: AvailableYes, Direction: 1, Properties: Proplist{}, Profiles: []string{"a2dp_sink", "headset_head_unit"}, LatencyOffset: -1500},
+		},
+	}
+
+	w := NewTagstruct()
+	card.Put(w)
+	got := ReadCardInfo(ParseTagstruct(w.Bytes()))
+	if !reflect.DeepEqual(card, got) {
+		t.Fatalf("Round trip mismatch:\nwant %+v\ngot  %+v", card, got)
+	}
+}
+
+func TestSinkInputInfoRoundTrip(t *testing.T) {
+	input := SinkInputInfo{
+		Index:          7,
+		Name:           "Playback",
+		OwnerModule:    Invalid,
+		Client:         12,
+		Sink:           3,
+		SampleSpec:     SampleSpec{Format: SampleFloat32LE, Channels: 2, Rate: 44100},
+		ChannelMap:     StereoMap(),
+		Volume:         CVolume{VolumeNorm / 2, VolumeNorm / 2},
+		BufferLatency:  20000,
+		SinkLatency:    5000,
+		ResampleMethod: "speex-float-1",
+		Driver:         "protocol-native.c",
+		Mute:           true,
+		Properties:     Proplist{"application.name": "Firefox", "application.process.id": "4242"},
+		Corked:         true,
+		HasVolume:      true,
+		VolumeWritable: true,
+		Format:         FormatInfo{Encoding: 1, Properties: Proplist{}},
+	}
+
+	w := NewTagstruct()
+	input.Put(w)
+	got := ReadSinkInputInfo(ParseTagstruct(w.Bytes()))
+	if !reflect.DeepEqual(input, got) {
+		t.Fatalf("Round trip mismatch:\nwant %+v\ngot  %+v", input, got)
+	}
+}
+
+func TestSourceOutputInfoRoundTrip(t *testing.T) {
+	output := SourceOutputInfo{
+		Index:          4,
+		Name:           "Recording",
+		OwnerModule:    Invalid,
+		Client:         13,
+		Source:         2,
+		SampleSpec:     SampleSpec{Format: SampleS16LE, Channels: 1, Rate: 16000},
+		ChannelMap:     ChannelMap{ChannelMono},
+		BufferLatency:  10000,
+		SourceLatency:  2000,
+		Driver:         "protocol-native.c",
+		Properties:     Proplist{"application.name": "Zoom"},
+		Volume:         CVolume{VolumeNorm},
+		HasVolume:      true,
+		VolumeWritable: true,
+		Format:         FormatInfo{Encoding: 1, Properties: Proplist{}},
+	}
+
+	w := NewTagstruct()
+	output.Put(w)
+	got := ReadSourceOutputInfo(ParseTagstruct(w.Bytes()))
+	if !reflect.DeepEqual(output, got) {
+		t.Fatalf("Round trip mismatch:\nwant %+v\ngot  %+v", output, got)
+	}
+}
+
+func TestModuleInfoRoundTrip(t *testing.T) {
+	module := ModuleInfo{
+		Index:      21,
+		Name:       "module-null-sink",
+		Argument:   "sink_name=capture",
+		Used:       Invalid,
+		Properties: Proplist{"module.description": "Clocked NULL sink"},
+	}
+
+	w := NewTagstruct()
+	module.Put(w)
+	got := ReadModuleInfo(ParseTagstruct(w.Bytes()))
+	if !reflect.DeepEqual(module, got) {
+		t.Fatalf("Round trip mismatch:\nwant %+v\ngot  %+v", module, got)
+	}
+}
+
+func TestParseServer(t *testing.T) {
+	tests := []struct {
+		server, network, address string
+	}{
+		{"unix:/run/user/1000/pulse/native", "unix", "/run/user/1000/pulse/native"},
+		{"/tmp/pulse.sock", "unix", "/tmp/pulse.sock"},
+		{"tcp:localhost", "tcp", "localhost:4713"},
+		{"tcp4:127.0.0.1:4000", "tcp4", "127.0.0.1:4000"},
+		{"{abc123}unix:/tmp/native tcp:fallback", "unix", "/tmp/native"},
+		{"media-box", "tcp", "media-box:4713"},
+	}
+	for _, tt := range tests {
+		network, address, err := parseServer(tt.server)
+		if err != nil || network != tt.network || address != tt.address {
+			t.Errorf("parseServer(%q) = %q, %q, %v", tt.server, network, address, err)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+// Package wav reads and writes RIFF WAVE files holding little-endian PCM in
+// the encodings of package dsp: 16, 24 and 32-bit integers and 32-bit
+// floats.
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/audi70r/go-audio-control/internal/dsp"
+)
+
+// ErrFormat is returned for input that is not a supported WAVE file
+var ErrFormat = errors.New("wav: unsupported format")
+
+// Format tags of the fmt chunk
+const (
+	formatPCM        = 0x0001
+	formatFloat      = 0x0003
+	formatExtensible = 0xFFFE
+)
+
+// Sizes of the fmt chunk. The extensible form holds every field parseFormat
+// reads; chunks longer than maxFormatSize are rejected before reading them.
+const (
+	extensibleFormatSize = 40
+	maxFormatSize        = 1024
+)
+
+// Header is the audio format of a file
+type Header struct {
+	Encoding dsp.Encoding
+	Channels int
+	Rate     int
+}
+
+// Reader reads the samples of a WAVE file
+type Reader struct {
+	Header
+	data io.Reader
+}
+
+// NewReader reads the header of a WAVE file up to the start of its
+// samples. A data chunk of unknown length, as written by streaming tools,
+// extends to the end of r.
+func NewReader(r io.Reader) (*Reader, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return nil, fmt.Errorf("wav: failed to read header: %w", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("%w: not a RIFF WAVE file", ErrFormat)
+	}
+
+	var header *Header
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return nil, fmt.Errorf("wav: no data chunk: %w", err)
+		}
+		id := string(chunk[0:4])
+		size := binary.LittleEndian.Uint32(chunk[4:])
+
+		switch id {
+		case "fmt ":
+			if size > maxFormatSize {
+				return nil, fmt.Errorf("%w: fmt chunk of %d bytes", ErrFormat, size)
+			}
+			body := make([]byte, min(size, extensibleFormatSize))
+			if _, err := io.ReadFull(r, body); err != nil {
+				return nil, fmt.Errorf("wav: failed to read fmt chunk: %w", err)
+			}
+			h, err := parseFormat(body)
+			if err != nil {
+				return nil, err
+			}
+			header = &h
+			// Skip the fields not needed and the padding
+			rest := int64(size) - int64(len(body)) + int64(size%2)
+			if _, err := io.CopyN(io.Discard, r, rest); err != nil {
+				return nil, fmt.Errorf("wav: failed to read fmt chunk: %w", err)
+			}
+
+		case "data":
+			if header == nil {
+				return nil, fmt.Errorf("%w: data before fmt chunk", ErrFormat)
+			}
+			data := r
+			if size != 0 && size != 0xFFFFFFFF {
+				data = io.LimitReader(r, int64(size))
+			}
+			return &Reader{Header: *header, data: data}, nil
+
+		default:
+			// Chunks are padded to an even length
+			if _, err := io.CopyN(io.Discard, r, int64(size)+int64(size%2)); err != nil {
+				return nil, fmt.Errorf("wav: failed to skip %q chunk: %w", id, err)
+			}
+		}
+	}
+}
+
+// parseFormat decodes the body of a fmt chunk
+func parseFormat(body []byte) (Header, error) {
+	if len(body) < 16 {
+		return Header{}, fmt.Errorf("%w: short fmt chunk", ErrFormat)
+	}
+	tag := binary.LittleEndian.Uint16(body[0:])
+	h := Header{
+		Channels: int(binary.LittleEndian.Uint16(body[2:])),
+		Rate:     int(binary.LittleEndian.Uint32(body[4:])),
+	}
+	bits := binary.LittleEndian.Uint16(body[14:])
+	// The sub-format GUID of extensible files starts with the format tag
+	if tag == formatExtensible {
+		if len(body) < 26 {
+			return Header{}, fmt.Errorf("%w: short extensible fmt chunk", ErrFormat)
+		}
+		tag = binary.LittleEndian.Uint16(body[24:])
+	}
+
+	switch {
+	case tag == formatPCM && bits == 16:
+		h.Encoding = dsp.EncodingS16LE
+	case tag == formatPCM && bits == 24:
+		h.Encoding = dsp.EncodingS24LE
+	case tag == formatPCM && bits == 32:
+		h.Encoding = dsp.EncodingS32LE
+	case tag == formatFloat && bits == 32:
+		h.Encoding = dsp.EncodingFloat32LE
+	default:
+		return Header{}, fmt.Errorf("%w: format %#x with %d bits", ErrFormat, tag, bits)
+	}
+	if h.Channels == 0 || h.Rate == 0 {
+		return Header{}, fmt.Errorf("%w: %d channels at %d Hz", ErrFormat, h.Channels, h.Rate)
+	}
+	return h, nil
+}
+
+// Read reads samples, interleaved as in the file
+func (r *Reader) Read(p []byte) (int, error) {
+	return r.data.Read(p)
+}
+
+// errWriterClosed is returned by writes after Close
+var errWriterClosed = errors.New("wav: writer closed")
+
+// unknownSize marks the sizes of a file whose length was not known when
+// its header was written
+const unknownSize = 0xFFFFFFFF
+
+// headerSize is the length of the header written by Writer
+const headerSize = 44
+
+// Writer writes a WAVE file. Close fills in the sizes in the header when
+// the underlying writer can seek; otherwise they are left unknown, which
+// Reader and most tools take to mean the samples run to the end of the file.
+type Writer struct {
+	w       io.Writer
+	written int64
+	err     error
+}
+
+// NewWriter writes the header of a file in format h
+func NewWriter(w io.Writer, h Header) (*Writer, error) {
+	size := h.Encoding.Size()
+	if size == 0 || h.Channels <= 0 || h.Channels > 0xFFFF || h.Rate <= 0 {
+		return nil, fmt.Errorf("%w: %d channels at %d Hz", ErrFormat, h.Channels, h.Rate)
+	}
+	tag := uint16(formatPCM)
+	if h.Encoding == dsp.EncodingFloat32LE {
+		tag = formatFloat
+	}
+
+	header := make([]byte, headerSize)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], unknownSize)
+	copy(header[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], tag)
+	binary.LittleEndian.PutUint16(header[22:], uint16(h.Channels))
+	binary.LittleEndian.PutUint32(header[24:], uint32(h.Rate))
+	binary.LittleEndian.PutUint32(header[28:], uint32(h.Rate*h.Channels*size))
+	binary.LittleEndian.PutUint16(header[32:], uint16(h.Channels*size))
+	binary.LittleEndian.PutUint16(header[34:], uint16(8*size))
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], unknownSize)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w}, nil
+}
+
+// Write writes interleaved samples
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.w.Write(p)
+	w.written += int64(n)
+	if err != nil {
+		w.err = err
+	}
+	return n, err
+}
+
+// Close pads the samples to an even length and fills in the sizes in the
+// header if the underlying writer is an io.Seeker. It does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if w.err == errWriterClosed {
+		return nil
+	}
+	if w.err != nil {
+		return w.err
+	}
+	w.err = errWriterClosed
+	riffSize := headerSize - 8 + w.written
+	if w.written%2 == 1 {
+		if _, err := w.w.Write([]byte{0}); err != nil {
+			return err
+		}
+		riffSize++
+	}
+	seeker, ok := w.w.(io.Seeker)
+	if !ok || riffSize >= unknownSize {
+		return nil
+	}
+
+	var size [4]byte
+	for _, field := range []struct{ offset, value int64 }{{4, riffSize}, {40, w.written}} {
+		if _, err := seeker.Seek(field.offset, io.SeekStart); err != nil {
+			return err
+		}
+		binary.LittleEndian.PutUint32(size[:], uint32(field.value))
+		if _, err := w.w.Write(size[:]); err != nil {
+			return err
+		}
+	}
+	_, err := seeker.Seek(0, io.SeekEnd)
+	return err
+}
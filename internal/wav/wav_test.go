@@ -0,0 +1,199 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/audi70r/go-audio-control/internal/dsp"
+)
+
+// file builds a WAVE file from a fmt chunk body, extra chunks and samples
+func file(format []byte, data []byte, extra ...[]byte) []byte {
+	var body bytes.Buffer
+	body.WriteString("WAVE")
+	chunk := func(id string, b []byte) {
+		body.WriteString(id)
+		binary.Write(&body, binary.LittleEndian, uint32(len(b)))
+		body.Write(b)
+		if len(b)%2 == 1 {
+			body.WriteByte(0)
+		}
+	}
+	chunk("fmt ", format)
+	for _, e := range extra {
+		chunk("LIST", e)
+	}
+	chunk("data", data)
+
+	var out bytes.Buffer
+	out.WriteString("RIFF")
+	binary.Write(&out, binary.LittleEndian, uint32(body.Len()))
+	out.Write(body.Bytes())
+	return out.Bytes()
+}
+
+func fmtChunk(tag, channels uint16, rate uint32, bits uint16) []byte {
+	b := make([]byte, 16)
+	binary.LittleEndian.PutUint16(b[0:], tag)
+	binary.LittleEndian.PutUint16(b[2:], channels)
+	binary.LittleEndian.PutUint32(b[4:], rate)
+	blockAlign := channels * bits / 8
+	binary.LittleEndian.PutUint32(b[8:], rate*uint32(blockAlign))
+	binary.LittleEndian.PutUint16(b[12:], blockAlign)
+	binary.LittleEndian.PutUint16(b[14:], bits)
+	return b
+}
+
+func extensible(subformat, channels uint16, rate uint32, bits uint16) []byte {
+	b := fmtChunk(formatExtensible, channels, rate, bits)
+	ext := make([]byte, 24)
+	binary.LittleEndian.PutUint16(ext[0:], 22)
+	binary.LittleEndian.PutUint16(ext[2:], bits)
+	binary.LittleEndian.PutUint16(ext[8:], subformat)
+	return append(b, ext...)
+}
+
+func TestReader(t *testing.T) {
+	samples := []float32{0.5, -0.5, 0.25, -1}
+	tests := []struct {
+		name     string
+		format   []byte
+		encoding dsp.Encoding
+		channels int
+	}{
+		{"pcm16 stereo", fmtChunk(formatPCM, 2, 44100, 16), dsp.EncodingS16LE, 2},
+		{"pcm24 mono", fmtChunk(formatPCM, 1, 44100, 24), dsp.EncodingS24LE, 1},
+		{"pcm32", fmtChunk(formatPCM, 2, 44100, 32), dsp.EncodingS32LE, 2},
+		{"float32", fmtChunk(formatFloat, 2, 44100, 32), dsp.EncodingFloat32LE, 2},
+		{"extensible float32", extensible(formatFloat, 1, 44100, 32), dsp.EncodingFloat32LE, 1},
+		{"trailing fmt fields", append(extensible(formatPCM, 2, 44100, 16), "extra"...), dsp.EncodingS16LE, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := dsp.Encode(samples, tt.encoding)
+			// An odd-length chunk before the samples is skipped with its padding
+			r, err := NewReader(bytes.NewReader(file(tt.format, data, []byte("odd"))))
+			if err != nil {
+				t.Fatalf("Failed to read header: %v", err)
+			}
+			if r.Encoding != tt.encoding || r.Channels != tt.channels || r.Rate != 44100 {
+				t.Errorf("Header is %+v", r.Header)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil || !bytes.Equal(got, data) {
+				t.Errorf("Read % x, %v, want % x", got, err, data)
+			}
+		})
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not riff", []byte("RIFX\x00\x00\x00\x00WAVE")},
+		{"pcm8", file(fmtChunk(formatPCM, 1, 8000, 8), []byte{0})},
+		{"float64", file(fmtChunk(formatFloat, 1, 8000, 64), make([]byte, 8))},
+		{"no channels", file(fmtChunk(formatPCM, 0, 8000, 16), nil)},
+	}
+	for _, tt := range tests {
+		if _, err := NewReader(bytes.NewReader(tt.data)); !errors.Is(err, ErrFormat) {
+			t.Errorf("%s: expected ErrFormat, got %v", tt.name, err)
+		}
+	}
+	// A size this large is rejected before anything is allocated for it
+	oversized := []byte("RIFF\x00\x00\x00\x00WAVEfmt \xf0\xff\xff\xff")
+	oversized = append(oversized, fmtChunk(formatPCM, 2, 44100, 16)...)
+	if _, err := NewReader(bytes.NewReader(oversized)); !errors.Is(err, ErrFormat) {
+		t.Errorf("oversized fmt chunk: expected ErrFormat, got %v", err)
+	}
+	if _, err := NewReader(bytes.NewReader([]byte("RIFF\x04\x00\x00\x00WAVE"))); err == nil {
+		t.Error("Expected an error for a file without chunks")
+	}
+}
+
+// seekBuffer is an in-memory io.WriteSeeker
+type seekBuffer struct {
+	data []byte
+	pos  int
+}
+
+func (b *seekBuffer) Write(p []byte) (int, error) {
+	if end := b.pos + len(p); end > len(b.data) {
+		b.data = append(b.data, make([]byte, end-len(b.data))...)
+	}
+	b.pos += copy(b.data[b.pos:], p)
+	return len(p), nil
+}
+
+func (b *seekBuffer) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		b.pos = int(offset)
+	case io.SeekEnd:
+		b.pos = len(b.data) + int(offset)
+	}
+	return int64(b.pos), nil
+}
+
+func TestWriter(t *testing.T) {
+	// Mono 24-bit audio of an odd length is padded
+	h := Header{Encoding: dsp.EncodingS24LE, Channels: 1, Rate: 16000}
+	samples := dsp.Encode([]float32{0.5, -0.25, 1}, h.Encoding)
+
+	var seekable seekBuffer
+	var stream bytes.Buffer
+	for _, out := range []io.Writer{&seekable, &stream} {
+		w, err := NewWriter(out, h)
+		if err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		w.Write(samples[:4])
+		w.Write(samples[4:])
+		if err := w.Close(); err != nil {
+			t.Fatalf("Failed to close: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Errorf("Second close failed: %v", err)
+		}
+		if _, err := w.Write(samples); err == nil {
+			t.Error("Write after Close succeeded")
+		}
+	}
+
+	if len(seekable.data) != headerSize+len(samples)+1 {
+		t.Fatalf("Wrote %d bytes", len(seekable.data))
+	}
+	if size := binary.LittleEndian.Uint32(seekable.data[4:]); size != uint32(len(seekable.data)-8) {
+		t.Errorf("RIFF size is %d", size)
+	}
+	if size := binary.LittleEndian.Uint32(stream.Bytes()[40:]); size != unknownSize {
+		t.Errorf("Unseekable data size is %d, want unknown", size)
+	}
+
+	// Both read back, with the padding dropped from the sized file only
+	for name, data := range map[string][]byte{"seekable": seekable.data, "stream": stream.Bytes()} {
+		r, err := NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: failed to read back: %v", name, err)
+		}
+		if r.Header != h {
+			t.Errorf("%s: header is %+v", name, r.Header)
+		}
+		got, _ := io.ReadAll(r)
+		if name == "stream" {
+			got = got[:len(got)-1]
+		}
+		if !bytes.Equal(got, samples) {
+			t.Errorf("%s: read % x, want % x", name, got, samples)
+		}
+	}
+
+	if _, err := NewWriter(io.Discard, Header{Encoding: dsp.EncodingS16LE, Rate: 8000}); !errors.Is(err, ErrFormat) {
+		t.Errorf("Expected ErrFormat for no channels, got %v", err)
+	}
+}
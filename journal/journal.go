@@ -0,0 +1,319 @@
+// Package journal keeps a persistent history of audio device events.
+//
+// Every event is appended as one JSON line, together with the time it was
+// received and a snapshot of the full device list, to events.jsonl in the
+// journal directory. When the file grows past Options.MaxSize it is rotated
+// to events.1.jsonl, shifting older files up to Options.MaxFiles.
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+)
+
+const (
+	// DefaultMaxSize is the size at which the journal file is rotated
+	DefaultMaxSize = 10 << 20
+	// DefaultMaxFiles is the number of rotated files kept
+	DefaultMaxFiles = 5
+
+	currentFile = "events.jsonl"
+)
+
+// Entry is one journal record
+type Entry struct {
+	Time     time.Time
+	Type     audiocontrol.EventType
+	DeviceID string
+	Info     *audiocontrol.AudioDevice
+	Devices  []audiocontrol.AudioDevice
+}
+
+// entryJSON is the on-disk form of Entry. The event type is stored by name
+// so that journals survive reordering of the EventType constants.
+type entryJSON struct {
+	Time     time.Time
+	Type     string
+	DeviceID string
+	Info     *audiocontrol.AudioDevice `json:",omitempty"`
+	Devices  []audiocontrol.AudioDevice
+}
+
+// MarshalJSON implements json.Marshaler
+func (e Entry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(entryJSON{
+		Time:     e.Time,
+		Type:     e.Type.String(),
+		DeviceID: e.DeviceID,
+		Info:     e.Info,
+		Devices:  e.Devices,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	var raw entryJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	t, err := audiocontrol.ParseEventType(raw.Type)
+	if err != nil {
+		return err
+	}
+	*e = Entry{
+		Time:     raw.Time,
+		Type:     t,
+		DeviceID: raw.DeviceID,
+		Info:     raw.Info,
+		Devices:  raw.Devices,
+	}
+	return nil
+}
+
+// Options configures a Journal
+type Options struct {
+	// Dir is the directory holding the journal files; see DefaultDir
+	Dir string
+	// MaxSize is the size in bytes at which the current file is rotated
+	MaxSize int64
+	// MaxFiles is the number of rotated files kept besides the current one
+	MaxFiles int
+}
+
+// DefaultDir returns the per-user journal directory
+func DefaultDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "audiocontrol", "journal")
+}
+
+// Journal appends entries to a rotating JSON-lines file
+type Journal struct {
+	opts Options
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+	now  func() time.Time
+}
+
+// Open opens the journal in opts.Dir, creating it if needed
+func Open(opts Options) (*Journal, error) {
+	if opts.Dir == "" {
+		opts.Dir = DefaultDir()
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultMaxSize
+	}
+	if opts.MaxFiles <= 0 {
+		opts.MaxFiles = DefaultMaxFiles
+	}
+	if err := os.MkdirAll(opts.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	j := &Journal{opts: opts, now: time.Now}
+	if err := j.openCurrent(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func (j *Journal) openCurrent() error {
+	file, err := os.OpenFile(filepath.Join(j.opts.Dir, currentFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat journal: %w", err)
+	}
+	j.file = file
+	j.size = info.Size()
+	return nil
+}
+
+// Attach records every event of backend, with a snapshot of its device list
+// taken when the event arrives
+func (j *Journal) Attach(backend audiocontrol.Backend) {
+	backend.OnDeviceChange(func(event audiocontrol.Event) {
+		devices, _ := backend.ListAudioDevices()
+		j.Append(Entry{
+			Time:     j.now(),
+			Type:     event.Type,
+			DeviceID: event.DeviceID,
+			Info:     event.Info,
+			Devices:  devices,
+		})
+	})
+}
+
+// Append writes one entry, rotating the file first if it is full
+func (j *Journal) Append(entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = j.now()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return fmt.Errorf("journal is closed")
+	}
+	if j.size > 0 && j.size+int64(len(data)) > j.opts.MaxSize {
+		if err := j.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := j.file.Write(data)
+	j.size += int64(n)
+	return err
+}
+
+// rotate shifts events.N.jsonl to events.N+1.jsonl, dropping the oldest,
+// and starts a new current file
+func (j *Journal) rotate() error {
+	j.file.Close()
+	j.file = nil
+
+	os.Remove(rotatedPath(j.opts.Dir, j.opts.MaxFiles))
+	for i := j.opts.MaxFiles - 1; i >= 1; i-- {
+		os.Rename(rotatedPath(j.opts.Dir, i), rotatedPath(j.opts.Dir, i+1))
+	}
+	if err := os.Rename(filepath.Join(j.opts.Dir, currentFile), rotatedPath(j.opts.Dir, 1)); err != nil {
+		return fmt.Errorf("failed to rotate journal: %w", err)
+	}
+	return j.openCurrent()
+}
+
+// Close closes the journal file
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+// Query returns the entries of this journal matching q
+func (j *Journal) Query(q Query) ([]Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return Read(j.opts.Dir, q)
+}
+
+func rotatedPath(dir string, n int) string {
+	return filepath.Join(dir, fmt.Sprintf("events.%d.jsonl", n))
+}
+
+// Query selects journal entries. Zero fields match everything.
+type Query struct {
+	// DeviceID matches entries for this device
+	DeviceID string
+	// Types matches entries of any of these types
+	Types []audiocontrol.EventType
+	// Since and Until bound the entry time; Until is exclusive
+	Since time.Time
+	Until time.Time
+	// Limit keeps only the most recent matching entries
+	Limit int
+}
+
+// Match reports whether e is selected by q
+func (q Query) Match(e Entry) bool {
+	if q.DeviceID != "" && e.DeviceID != q.DeviceID {
+		return false
+	}
+	if len(q.Types) > 0 {
+		found := false
+		for _, t := range q.Types {
+			if t == e.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !q.Since.IsZero() && e.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !e.Time.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+// Read returns the entries matching q from the journal in dir, oldest first.
+// It does not need the journal to be open, so other processes can read the
+// history a daemon is writing. Lines that cannot be parsed are skipped.
+func Read(dir string, q Query) ([]Entry, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "events.*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	// Rotated files hold older entries the higher their number
+	sort.Slice(paths, func(a, b int) bool { return rotatedIndex(paths[a]) > rotatedIndex(paths[b]) })
+	paths = append(paths, filepath.Join(dir, currentFile))
+
+	var entries []Entry
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 16<<20)
+		for scanner.Scan() {
+			var e Entry
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				continue
+			}
+			if q.Match(e) {
+				entries = append(entries, e)
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if q.Limit > 0 && len(entries) > q.Limit {
+		entries = entries[len(entries)-q.Limit:]
+	}
+	return entries, nil
+}
+
+func rotatedIndex(path string) int {
+	var n int
+	fmt.Sscanf(filepath.Base(path), "events.%d.jsonl", &n)
+	return n
+}
@@ -0,0 +1,157 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+	"github.com/audi70r/go-audio-control/audiotest"
+)
+
+var start = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func openTestJournal(t *testing.T, opts Options) *Journal {
+	t.Helper()
+	if opts.Dir == "" {
+		opts.Dir = t.TempDir()
+	}
+	j, err := Open(opts)
+	if err != nil {
+		t.Fatalf("Failed to open journal: %v", err)
+	}
+	t.Cleanup(func() { j.Close() })
+	return j
+}
+
+func TestAttachRecordsEventsWithSnapshot(t *testing.T) {
+	j := openTestJournal(t, Options{})
+	backend := audiotest.NewBackend(
+		audiocontrol.AudioDevice{ID: "speakers", Name: "Speakers", IsOutput: true, IsActive: true, IsConnected: true},
+	)
+	j.Attach(backend)
+
+	backend.AddDevice(audiocontrol.AudioDevice{ID: "usb", Name: "USB Headset", IsOutput: true, IsConnected: true})
+	backend.RemoveDevice("usb")
+
+	entries, err := j.Query(Query{})
+	if err != nil {
+		t.Fatalf("Failed to query journal: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Type != audiocontrol.DeviceAdded || entries[0].DeviceID != "usb" || len(entries[0].Devices) != 2 {
+		t.Fatalf("Unexpected first entry %+v", entries[0])
+	}
+	if entries[1].Type != audiocontrol.DeviceRemoved || len(entries[1].Devices) != 1 {
+		t.Fatalf("Unexpected second entry %+v", entries[1])
+	}
+	if entries[0].Info == nil || entries[0].Info.Name != "USB Headset" {
+		t.Fatalf("Expected device info to be recorded, got %+v", entries[0].Info)
+	}
+}
+
+func TestQueryFilters(t *testing.T) {
+	j := openTestJournal(t, Options{})
+	events := []Entry{
+		{Time: start, Type: audiocontrol.DeviceAdded, DeviceID: "a"},
+		{Time: start.Add(time.Minute), Type: audiocontrol.ActiveDeviceChanged, DeviceID: "a"},
+		{Time: start.Add(2 * time.Minute), Type: audiocontrol.DeviceAdded, DeviceID: "b"},
+		{Time: start.Add(3 * time.Minute), Type: audiocontrol.DeviceRemoved, DeviceID: "a"},
+	}
+	for _, e := range events {
+		if err := j.Append(e); err != nil {
+			t.Fatalf("Failed to append: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  int
+	}{
+		{"all", Query{}, 4},
+		{"device", Query{DeviceID: "a"}, 3},
+		{"type", Query{Types: []audiocontrol.EventType{audiocontrol.DeviceAdded}}, 2},
+		{"since", Query{Since: start.Add(time.Minute)}, 3},
+		{"until", Query{Until: start.Add(2 * time.Minute)}, 2},
+		{"limit", Query{DeviceID: "a", Limit: 1}, 1},
+	}
+	for _, tt := range tests {
+		entries, err := j.Query(tt.query)
+		if err != nil {
+			t.Fatalf("%s: failed to query: %v", tt.name, err)
+		}
+		if len(entries) != tt.want {
+			t.Errorf("%s: expected %d entries, got %d", tt.name, tt.want, len(entries))
+		}
+	}
+}
+
+func TestRotation(t *testing.T) {
+	dir := t.TempDir()
+	j := openTestJournal(t, Options{Dir: dir, MaxSize: 400, MaxFiles: 2})
+
+	for i := 0; i < 20; i++ {
+		err := j.Append(Entry{Time: start.Add(time.Duration(i) * time.Second), Type: audiocontrol.DeviceAdded, DeviceID: "device"})
+		if err != nil {
+			t.Fatalf("Failed to append: %v", err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "events.2.jsonl")); err != nil {
+		t.Fatalf("Expected a second rotated file: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "events.3.jsonl")); !os.IsNotExist(err) {
+		t.Fatalf("Expected at most 2 rotated files")
+	}
+
+	entries, err := Read(dir, Query{})
+	if err != nil {
+		t.Fatalf("Failed to read journal: %v", err)
+	}
+	if len(entries) == 0 || len(entries) == 20 {
+		t.Fatalf("Expected the oldest entries to be dropped, got %d", len(entries))
+	}
+	for i := 1; i < len(entries); i++ {
+		if entries[i].Time.Before(entries[i-1].Time) {
+			t.Fatalf("Entries out of order at %d", i)
+		}
+	}
+	if last := entries[len(entries)-1]; !last.Time.Equal(start.Add(19 * time.Second)) {
+		t.Fatalf("Expected the newest entry last, got %v", last.Time)
+	}
+}
+
+func TestStats(t *testing.T) {
+	speakers := audiocontrol.AudioDevice{ID: "speakers", Name: "Speakers", IsConnected: true}
+	headset := audiocontrol.AudioDevice{ID: "usb", Name: "USB Headset", IsConnected: true}
+
+	entries := []Entry{
+		{Time: start, Type: audiocontrol.DeviceAdded, DeviceID: "usb", Info: &headset, Devices: []audiocontrol.AudioDevice{speakers, headset}},
+		{Time: start.Add(10 * time.Minute), Type: audiocontrol.DeviceRemoved, DeviceID: "usb", Devices: []audiocontrol.AudioDevice{speakers}},
+		{Time: start.Add(20 * time.Minute), Type: audiocontrol.DeviceAdded, DeviceID: "usb", Info: &headset, Devices: []audiocontrol.AudioDevice{speakers, headset}},
+		{Time: start.Add(50 * time.Minute), Type: audiocontrol.DeviceDisconnected, DeviceID: "usb", Devices: []audiocontrol.AudioDevice{speakers}},
+		{Time: start.Add(60 * time.Minute), Type: audiocontrol.DeviceRemoved, DeviceID: "speakers"},
+	}
+
+	stats := Stats(entries)
+	if len(stats) != 2 {
+		t.Fatalf("Expected stats for 2 devices, got %d", len(stats))
+	}
+
+	s := stats[0]
+	if s.DeviceID != "speakers" || s.Disconnects != 1 || s.Sessions != 1 || s.MeanSession != time.Hour {
+		t.Fatalf("Unexpected speakers stats %+v", s)
+	}
+
+	u := stats[1]
+	if u.Name != "USB Headset" || u.Connects != 2 || u.Disconnects != 2 || u.Sessions != 2 {
+		t.Fatalf("Unexpected headset stats %+v", u)
+	}
+	if u.MeanSession != 20*time.Minute {
+		t.Fatalf("Expected mean session of 20m, got %v", u.MeanSession)
+	}
+}
@@ -0,0 +1,102 @@
+package journal
+
+import (
+	"sort"
+	"time"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+)
+
+// DeviceStats summarises the history of one device
+type DeviceStats struct {
+	DeviceID string
+	// Name is the most recent name seen for the device
+	Name string
+
+	// Connects counts DeviceAdded events
+	Connects int
+	// Disconnects counts DeviceRemoved and DeviceDisconnected events
+	Disconnects int
+
+	// Sessions counts completed connections, from the device appearing to
+	// it being removed or disconnected. A session still open at the end of
+	// the history is not included.
+	Sessions int
+	// TotalSession and MeanSession are the total and mean session durations
+	TotalSession time.Duration
+	MeanSession  time.Duration
+
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// Stats computes per-device statistics from entries, which must be in time
+// order as returned by Read. A device present in a snapshot is considered
+// connected from that entry on, so devices that were already plugged in when
+// the journal started are counted too.
+func Stats(entries []Entry) []DeviceStats {
+	stats := make(map[string]*DeviceStats)
+	open := make(map[string]time.Time)
+
+	get := func(id string, at time.Time) *DeviceStats {
+		s, ok := stats[id]
+		if !ok {
+			s = &DeviceStats{DeviceID: id, FirstSeen: at}
+			stats[id] = s
+		}
+		s.LastSeen = at
+		return s
+	}
+	closeSession := func(s *DeviceStats, at time.Time) {
+		start, ok := open[s.DeviceID]
+		if !ok {
+			return
+		}
+		delete(open, s.DeviceID)
+		s.Sessions++
+		s.TotalSession += at.Sub(start)
+	}
+
+	for _, e := range entries {
+		if e.DeviceID != "" {
+			s := get(e.DeviceID, e.Time)
+			if e.Info != nil && e.Info.Name != "" {
+				s.Name = e.Info.Name
+			}
+
+			switch e.Type {
+			case audiocontrol.DeviceAdded:
+				s.Connects++
+				if _, ok := open[e.DeviceID]; !ok {
+					open[e.DeviceID] = e.Time
+				}
+			case audiocontrol.DeviceRemoved, audiocontrol.DeviceDisconnected:
+				s.Disconnects++
+				closeSession(s, e.Time)
+			}
+		}
+
+		for _, d := range e.Devices {
+			s := get(d.ID, e.Time)
+			if d.Name != "" {
+				s.Name = d.Name
+			}
+			if d.ID == e.DeviceID && (e.Type == audiocontrol.DeviceRemoved || e.Type == audiocontrol.DeviceDisconnected) {
+				continue
+			}
+			if _, ok := open[d.ID]; !ok && d.IsConnected {
+				open[d.ID] = e.Time
+			}
+		}
+	}
+
+	result := make([]DeviceStats, 0, len(stats))
+	for _, s := range stats {
+		if s.Sessions > 0 {
+			s.MeanSession = s.TotalSession / time.Duration(s.Sessions)
+		}
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(a, b int) bool { return result[a].DeviceID < result[b].DeviceID })
+	return result
+}
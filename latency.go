@@ -0,0 +1,156 @@
+package audiocontrol
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/audi70r/go-audio-control/internal/dsp"
+)
+
+// LatencyOptions configures MeasureLatency. Zero fields select the
+// defaults.
+type LatencyOptions struct {
+	// Level is the peak amplitude of the chirp, where 1.0 is full scale
+	Level float64
+	// Duration is the length of the chirp
+	Duration time.Duration
+	// MaxLatency is the longest round trip that can be measured. Capture
+	// continues this long after the chirp has been played.
+	MaxLatency time.Duration
+}
+
+// Latency measurement defaults
+const (
+	DefaultChirpLevel    = 0.5
+	DefaultChirpDuration = 200 * time.Millisecond
+	DefaultMaxLatency    = time.Second
+)
+
+// The chirp sweeps the band that small speakers and microphones reproduce
+const (
+	chirpFrom = 500.0
+	chirpTo   = 5000.0
+)
+
+// LatencyResult is the outcome of MeasureLatency
+type LatencyResult struct {
+	// Latency is the time from handing the chirp to the output until it
+	// was captured by the input
+	Latency time.Duration
+	// Confidence is the normalised correlation between the chirp and the
+	// capture, from 0 to 1. Below about 0.3 the chirp was probably not
+	// heard and Latency is meaningless.
+	Confidence float64
+	// OutputLatency and InputLatency are what the devices report, or zero
+	// when the backend cannot tell
+	OutputLatency time.Duration
+	InputLatency  time.Duration
+}
+
+// LatencyReporter is implemented by backends that know the latency a
+// device reports
+type LatencyReporter interface {
+	DeviceLatency(deviceID string) (time.Duration, error)
+}
+
+// DeviceLatency returns the latency a device reports
+func DeviceLatency(deviceID string) (time.Duration, error) {
+	if r, ok := currentBackend().(LatencyReporter); ok {
+		return r.DeviceLatency(deviceID)
+	}
+	return 0, ErrNotImplemented
+}
+
+// MeasureLatency measures the round trip from an output to an input, such
+// as a speaker and the microphone next to it. It captures the input, plays
+// a chirp to the output and finds the chirp in the capture. The result
+// includes the time the sound server takes to start the streams, usually a
+// few milliseconds.
+func MeasureLatency(outputID, inputID string, opts LatencyOptions) (LatencyResult, error) {
+	if opts.Level == 0 {
+		opts.Level = DefaultChirpLevel
+	}
+	if opts.Duration == 0 {
+		opts.Duration = DefaultChirpDuration
+	}
+	if opts.MaxLatency == 0 {
+		opts.MaxLatency = DefaultMaxLatency
+	}
+	if opts.Level < 0 || opts.Level > 1 || opts.Duration < 0 || opts.MaxLatency < 0 {
+		return LatencyResult{}, fmt.Errorf("invalid latency options %+v", opts)
+	}
+
+	capture, err := OpenCapture(inputID, Format{Encoding: EncodingFloat32LE})
+	if err != nil {
+		return LatencyResult{}, err
+	}
+	captureStart := time.Now()
+	defer capture.Close()
+	var captured bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(&captured, capture)
+		done <- err
+	}()
+
+	// The chirp is played at the rate of the capture so that the two can
+	// be compared sample for sample
+	format := capture.Format()
+	rate := float64(format.Rate)
+	chirp := dsp.Chirp{
+		From:   chirpFrom,
+		To:     chirpTo,
+		Level:  opts.Level,
+		Rate:   format.Rate,
+		Frames: int(opts.Duration.Seconds() * rate),
+		Fade:   int(toneFade.Seconds() * rate),
+	}.Samples()
+	playStart := time.Now()
+	pcm := bytes.NewReader(dsp.Encode(chirp, dsp.EncodingFloat32LE))
+	if err := Play(outputID, Format{Encoding: EncodingFloat32LE, Channels: 1, Rate: format.Rate}, pcm); err != nil {
+		return LatencyResult{}, err
+	}
+
+	time.Sleep(opts.MaxLatency)
+	capture.Close()
+	if err := <-done; err != nil {
+		return LatencyResult{}, fmt.Errorf("capture failed: %w", err)
+	}
+
+	samples := dsp.Decode(captured.Bytes(), dsp.EncodingFloat32LE)
+	result, err := findChirp(samples, format.Channels, chirp, format.Rate)
+	if err != nil {
+		return LatencyResult{}, err
+	}
+	result.Latency -= playStart.Sub(captureStart)
+
+	// Reported latencies are informational, so failures leave them zero
+	result.OutputLatency, _ = DeviceLatency(outputID)
+	result.InputLatency, _ = DeviceLatency(inputID)
+	return result, nil
+}
+
+// findChirp finds chirp in interleaved samples of channels channels and
+// returns its position as a latency from the start of the samples
+func findChirp(samples []float32, channels int, chirp []float32, rate int) (LatencyResult, error) {
+	mono := make([]float32, len(samples)/channels)
+	for f := range mono {
+		var sum float32
+		for _, v := range samples[f*channels : (f+1)*channels] {
+			sum += v
+		}
+		mono[f] = sum / float32(channels)
+	}
+
+	offset, score := dsp.Correlate(mono, chirp)
+	if offset < 0 {
+		return LatencyResult{}, errors.New("captured too little audio to find the chirp")
+	}
+	return LatencyResult{
+		Latency:    time.Duration(float64(offset) / float64(rate) * float64(time.Second)),
+		Confidence: score,
+	}, nil
+}
@@ -0,0 +1,85 @@
+package audiocontrol
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/audi70r/go-audio-control/internal/logging"
+)
+
+// SetLogger sends structured records about the native backend to l:
+//
+//   - "native call" at Debug level for every call into the platform audio
+//     APIs, with the op, the device, card or session ID, the duration and
+//     the error, if any, in which case the level is Warn
+//   - "notification received" at Debug level for every notification from
+//     the operating system or sound server, before it is turned into events
+//   - "event emitted" at Debug level for every event passed to the
+//     OnDeviceChange callbacks
+//   - "error ignored" at Warn level for errors that cannot be returned, such
+//     as a failure to register for notifications or to describe a device
+//     named in one
+//
+// Errors come with an "osstatus" attribute on macOS and an "hresult"
+// attribute on Windows when the platform reported one. Logging is off by
+// default and costs an atomic load per call while off. Passing nil turns it
+// off again. Backends set with SetBackend are not logged.
+func SetLogger(l *slog.Logger) {
+	logging.Set(l)
+}
+
+// noID is passed to logCall for calls that take no ID
+var noID slog.Attr
+
+func deviceAttr(deviceID string) slog.Attr {
+	return slog.String("device", deviceID)
+}
+
+func noopDone(err error) error {
+	return err
+}
+
+// logCall starts logging a native call. The returned function logs its
+// result and returns err unchanged.
+func logCall(op string, id slog.Attr) func(err error) error {
+	l := logging.Logger()
+	if l == nil {
+		return noopDone
+	}
+	start := time.Now()
+	return func(err error) error {
+		level := slog.LevelDebug
+		attrs := make([]slog.Attr, 0, 5)
+		attrs = append(attrs, slog.String("op", op))
+		if id.Key != "" {
+			attrs = append(attrs, id)
+		}
+		attrs = append(attrs, slog.Duration("duration", time.Since(start)))
+		if err != nil {
+			level = slog.LevelWarn
+			attrs = append(attrs, logging.ErrorAttrs(err)...)
+		}
+		l.LogAttrs(context.Background(), level, "native call", attrs...)
+		return err
+	}
+}
+
+// logEvent logs an event delivered by the platform
+func logEvent(event Event) {
+	l := logging.Logger()
+	if l == nil {
+		return
+	}
+	attrs := []slog.Attr{slog.String("event", event.Type.String())}
+	if event.DeviceID != "" {
+		attrs = append(attrs, deviceAttr(event.DeviceID))
+	}
+	if event.Session != nil {
+		attrs = append(attrs, slog.String("session", event.Session.ID))
+	}
+	if event.Card != nil {
+		attrs = append(attrs, slog.String("card", event.Card.ID))
+	}
+	l.LogAttrs(context.Background(), slog.LevelDebug, "event emitted", attrs...)
+}
@@ -0,0 +1,118 @@
+package audiocontrol
+
+import (
+	"sync"
+	"time"
+
+	"github.com/audi70r/go-audio-control/internal/dsp"
+)
+
+// DefaultMeterInterval is the interval Meter uses when given none
+const DefaultMeterInterval = 50 * time.Millisecond
+
+// MinDBFS is the level in dBFS reported for silence
+const MinDBFS = dsp.MinDBFS
+
+// MeterDecayRate is the rate at which Level.Decayed falls, in dB per second
+const MeterDecayRate = dsp.DefaultDecayRate
+
+// Level is one reading of a level meter. Levels are linear fractions of
+// full scale, one per channel.
+type Level struct {
+	Time time.Time
+	// Peak is the highest sample of each channel during the interval
+	Peak []float64
+	// RMS is the root mean square of each channel during the interval. It
+	// is nil on platforms that only report peaks, such as Windows.
+	RMS []float64 `json:",omitempty"`
+	// Decayed is the peak with meter ballistics: it rises at once and falls
+	// by MeterDecayRate, so short peaks stay visible. Meter fills it in.
+	Decayed []float64 `json:",omitempty"`
+}
+
+// PeakDBFS returns the peaks in decibels relative to full scale
+func (l Level) PeakDBFS() []float64 {
+	return toDBFS(l.Peak)
+}
+
+// RMSDBFS returns the RMS levels in decibels relative to full scale
+func (l Level) RMSDBFS() []float64 {
+	return toDBFS(l.RMS)
+}
+
+// DecayedDBFS returns the decayed peaks in decibels relative to full scale
+func (l Level) DecayedDBFS() []float64 {
+	return toDBFS(l.Decayed)
+}
+
+func toDBFS(levels []float64) []float64 {
+	if levels == nil {
+		return nil
+	}
+	db := make([]float64, len(levels))
+	for i, level := range levels {
+		db[i] = dsp.DBFS(level)
+	}
+	return db
+}
+
+// LevelMeter is implemented by backends that can measure the level of a
+// device. The returned channel delivers a Level per interval and is closed
+// after stop is called or when the device goes away. Backends drop levels
+// while the receiver is behind rather than buffering them.
+type LevelMeter interface {
+	Meter(deviceID string, interval time.Duration) (levels <-chan Level, stop func(), err error)
+}
+
+// Meter measures the level of a device every interval, such as a
+// microphone for an "is my mic working" indicator. Outputs are measured
+// from what they play. Call stop to end metering; the channel is closed
+// afterwards or when the device goes away. An interval of zero selects
+// DefaultMeterInterval.
+func Meter(deviceID string, interval time.Duration) (<-chan Level, func(), error) {
+	m, ok := currentBackend().(LevelMeter)
+	if !ok {
+		return nil, nil, ErrNotImplemented
+	}
+	if interval <= 0 {
+		interval = DefaultMeterInterval
+	}
+	levels, stop, err := m.Meter(deviceID, interval)
+	if err != nil {
+		return nil, nil, err
+	}
+	return withBallistics(levels, stop)
+}
+
+// withBallistics fills in Level.Decayed for the levels of a backend
+func withBallistics(levels <-chan Level, stop func()) (<-chan Level, func(), error) {
+	out := make(chan Level, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(out)
+		decay := dsp.Decay{Rate: MeterDecayRate}
+		var last time.Time
+		for level := range levels {
+			var dt time.Duration
+			if !last.IsZero() {
+				dt = level.Time.Sub(last)
+			}
+			last = level.Time
+			level.Decayed = decay.Apply(level.Peak, dt)
+
+			select {
+			case out <- level:
+			case <-done:
+				// Drain until the backend closes its channel
+			}
+		}
+	}()
+
+	var once sync.Once
+	return out, func() {
+		once.Do(func() {
+			close(done)
+			stop()
+		})
+	}, nil
+}
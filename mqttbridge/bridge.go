@@ -0,0 +1,323 @@
+// Package mqttbridge mirrors an audiocontrol.Backend onto an MQTT broker for
+// home-automation systems such as Home Assistant.
+//
+// With the default prefix every topic lives under audiocontrol/<node>:
+//
+//	status                     "online" / "offline" (retained, last will)
+//	devices                    JSON list of devices (retained)
+//	default/output             JSON active output device (retained)
+//	default/input              JSON active input device (retained)
+//	device/<key>/volume        volume in [0.0, 1.0] (retained)
+//	device/<key>/mute          "ON" / "OFF" (retained)
+//	event                      each Event as JSON
+//
+// Commands are accepted on the matching set topics: default/output/set and
+// default/input/set take a device ID or name, device/<key>/volume/set a
+// number in [0.0, 1.0] and device/<key>/mute/set "ON" or "OFF". A device's
+// key is its ID with every character that is not a letter, digit, '-' or
+// '_' replaced by '_', so IDs containing MQTT wildcards stay addressable.
+//
+// When discovery is enabled the bridge also publishes Home Assistant MQTT
+// discovery payloads: a select for the default output and input, and a
+// volume number and mute switch per device.
+package mqttbridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+)
+
+// publishTimeout bounds how long the bridge waits for the broker to
+// acknowledge a publish or subscribe
+const publishTimeout = 5 * time.Second
+
+// Config configures a Bridge
+type Config struct {
+	// Broker is the broker URL, for example tcp://localhost:1883
+	Broker   string
+	Username string
+	Password string
+
+	// TopicPrefix defaults to "audiocontrol"
+	TopicPrefix string
+	// NodeID identifies this machine in topics; defaults to the host name
+	NodeID string
+
+	// Discovery enables Home Assistant discovery payloads under
+	// DiscoveryPrefix, which defaults to "homeassistant"
+	Discovery       bool
+	DiscoveryPrefix string
+}
+
+// Bridge publishes device state and applies commands received over MQTT
+type Bridge struct {
+	backend audiocontrol.Backend
+	config  Config
+	base    string
+	client  mqtt.Client
+
+	events chan audiocontrol.Event
+	done   chan struct{}
+
+	mu         sync.Mutex
+	keys       map[string]string // topic key -> device ID
+	discovered map[string]bool   // discovery config topics currently published
+}
+
+// eventBuffer is the number of events queued for publishing before new ones
+// are dropped
+const eventBuffer = 64
+
+// New returns a Bridge for backend. Call Start to connect.
+func New(backend audiocontrol.Backend, config Config) *Bridge {
+	if config.TopicPrefix == "" {
+		config.TopicPrefix = "audiocontrol"
+	}
+	if config.NodeID == "" {
+		host, _ := os.Hostname()
+		config.NodeID = topicKey(host)
+	}
+	if config.DiscoveryPrefix == "" {
+		config.DiscoveryPrefix = "homeassistant"
+	}
+
+	return &Bridge{
+		backend:    backend,
+		config:     config,
+		base:       config.TopicPrefix + "/" + config.NodeID,
+		events:     make(chan audiocontrol.Event, eventBuffer),
+		done:       make(chan struct{}),
+		keys:       make(map[string]string),
+		discovered: make(map[string]bool),
+	}
+}
+
+// Start connects to the broker, subscribes to the command topics and
+// publishes the current state. The state is republished after every
+// reconnect.
+func (b *Bridge) Start() error {
+	opts := mqtt.NewClientOptions().
+		AddBroker(b.config.Broker).
+		SetClientID("audiocontrol-"+b.config.NodeID).
+		SetUsername(b.config.Username).
+		SetPassword(b.config.Password).
+		SetWill(b.topic("status"), "offline", 1, true).
+		SetAutoReconnect(true).
+		SetOrderMatters(false).
+		SetOnConnectHandler(func(mqtt.Client) { b.onConnect() })
+
+	b.client = mqtt.NewClient(opts)
+	if err := wait(b.client.Connect()); err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", b.config.Broker, err)
+	}
+
+	go b.publishEvents()
+	b.backend.OnDeviceChange(b.queueEvent)
+	return nil
+}
+
+// Stop marks the node offline and disconnects
+func (b *Bridge) Stop() {
+	if b.client == nil {
+		return
+	}
+	close(b.done)
+	wait(b.client.Publish(b.topic("status"), 1, true, "offline"))
+	b.client.Disconnect(250)
+}
+
+func (b *Bridge) topic(parts ...string) string {
+	return b.base + "/" + strings.Join(parts, "/")
+}
+
+func (b *Bridge) onConnect() {
+	filters := map[string]byte{
+		b.topic("default", "output", "set"):     1,
+		b.topic("default", "input", "set"):      1,
+		b.topic("device", "+", "volume", "set"): 1,
+		b.topic("device", "+", "mute", "set"):   1,
+	}
+	wait(b.client.SubscribeMultiple(filters, b.handleCommand))
+
+	b.publish("status", "online", true)
+	b.publishState()
+}
+
+// publishState publishes the device list, defaults, per-device controls and
+// discovery payloads
+func (b *Bridge) publishState() {
+	devices, err := b.backend.ListAudioDevices()
+	if err != nil {
+		return
+	}
+
+	keys := make(map[string]string, len(devices))
+	for _, d := range devices {
+		keys[topicKey(d.ID)] = d.ID
+	}
+	b.mu.Lock()
+	b.keys = keys
+	b.mu.Unlock()
+
+	b.publishJSON("devices", devices, true)
+	if output, err := b.backend.GetActiveOutputDevice(); err == nil {
+		b.publishJSON("default/output", output, true)
+	}
+	if input, err := b.backend.GetActiveInputDevice(); err == nil {
+		b.publishJSON("default/input", input, true)
+	}
+	for _, d := range devices {
+		b.publishControls(d.ID)
+	}
+
+	if b.config.Discovery {
+		b.publishDiscovery(devices)
+	}
+}
+
+// publishControls publishes the volume and mute state of one device
+func (b *Bridge) publishControls(deviceID string) {
+	key := topicKey(deviceID)
+	if volume, err := b.backend.GetVolume(deviceID); err == nil {
+		b.publish("device/"+key+"/volume", strconv.FormatFloat(volume, 'f', 2, 64), true)
+	}
+	if muted, err := b.backend.GetMute(deviceID); err == nil {
+		b.publish("device/"+key+"/mute", onOff(muted), true)
+	}
+}
+
+func (b *Bridge) publish(topic, payload string, retained bool) {
+	wait(b.client.Publish(b.base+"/"+topic, 1, retained, payload))
+}
+
+func (b *Bridge) publishJSON(topic string, v interface{}, retained bool) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	b.publish(topic, string(data), retained)
+}
+
+// eventMessage is the payload published on the event topic
+type eventMessage struct {
+	Type     string
+	DeviceID string
+	Info     *audiocontrol.AudioDevice
+	Card     *audiocontrol.Card    `json:",omitempty"`
+	Session  *audiocontrol.Session `json:",omitempty"`
+}
+
+// queueEvent hands an event to the publisher without blocking the backend's
+// notification thread
+func (b *Bridge) queueEvent(event audiocontrol.Event) {
+	select {
+	case b.events <- event:
+	default:
+	}
+}
+
+// publishEvents publishes queued events in order until Stop
+func (b *Bridge) publishEvents() {
+	for {
+		select {
+		case <-b.done:
+			return
+		case event := <-b.events:
+			b.publishJSON("event", eventMessage{
+				Type:     event.Type.String(),
+				DeviceID: event.DeviceID,
+				Info:     event.Info,
+				Card:     event.Card,
+				Session:  event.Session,
+			}, false)
+			b.publishState()
+		}
+	}
+}
+
+func (b *Bridge) handleCommand(_ mqtt.Client, msg mqtt.Message) {
+	parts := strings.Split(strings.TrimPrefix(msg.Topic(), b.base+"/"), "/")
+	payload := strings.TrimSpace(string(msg.Payload()))
+
+	switch {
+	case len(parts) == 3 && parts[0] == "default":
+		deviceID := b.resolveDevice(payload)
+		if parts[1] == "output" {
+			b.backend.SetActiveOutputDevice(deviceID)
+		} else {
+			b.backend.SetActiveInputDevice(deviceID)
+		}
+		b.publishState()
+	case len(parts) == 4 && parts[0] == "device":
+		b.mu.Lock()
+		deviceID, ok := b.keys[parts[1]]
+		b.mu.Unlock()
+		if !ok {
+			return
+		}
+		switch parts[2] {
+		case "volume":
+			volume, err := strconv.ParseFloat(payload, 64)
+			if err != nil {
+				return
+			}
+			b.backend.SetVolume(deviceID, volume)
+		case "mute":
+			b.backend.SetMute(deviceID, strings.EqualFold(payload, "ON"))
+		}
+		b.publishControls(deviceID)
+	}
+}
+
+// resolveDevice maps a device ID or name received in a command to an ID
+func (b *Bridge) resolveDevice(payload string) string {
+	devices, err := b.backend.ListAudioDevices()
+	if err != nil {
+		return payload
+	}
+	for _, d := range devices {
+		if d.ID == payload {
+			return d.ID
+		}
+	}
+	for _, d := range devices {
+		if d.Name == payload {
+			return d.ID
+		}
+	}
+	return payload
+}
+
+// topicKey turns a device ID into a single MQTT topic level
+func topicKey(id string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, id)
+}
+
+func onOff(on bool) string {
+	if on {
+		return "ON"
+	}
+	return "OFF"
+}
+
+// wait waits for a token and returns its error
+func wait(token mqtt.Token) error {
+	if !token.WaitTimeout(publishTimeout) {
+		return fmt.Errorf("timed out waiting for broker")
+	}
+	return token.Error()
+}
@@ -0,0 +1,176 @@
+package mqttbridge
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	server "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+	"github.com/audi70r/go-audio-control/audiotest"
+)
+
+// startBroker runs an in-process broker on a free port and returns its URL
+func startBroker(t *testing.T) string {
+	t.Helper()
+	broker := server.New(nil)
+	if err := broker.AddHook(new(auth.AllowHook), nil); err != nil {
+		t.Fatalf("Failed to add auth hook: %v", err)
+	}
+	tcp := listeners.NewTCP("tcp", "127.0.0.1:0", nil)
+	if err := broker.AddListener(tcp); err != nil {
+		t.Fatalf("Failed to add listener: %v", err)
+	}
+	go broker.Serve()
+	t.Cleanup(func() { broker.Close() })
+	return "tcp://" + tcp.Address()
+}
+
+// recorder keeps the latest payload received on every topic
+type recorder struct {
+	mu       sync.Mutex
+	messages map[string]string
+}
+
+func (r *recorder) handle(_ mqtt.Client, msg mqtt.Message) {
+	r.mu.Lock()
+	r.messages[msg.Topic()] = string(msg.Payload())
+	r.mu.Unlock()
+}
+
+// waitFor polls until topic carries a payload accepted by match
+func (r *recorder) waitFor(t *testing.T, topic string, match func(string) bool) string {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		r.mu.Lock()
+		payload, ok := r.messages[topic]
+		r.mu.Unlock()
+		if ok && match(payload) {
+			return payload
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("No matching message on %s", topic)
+	return ""
+}
+
+func anyPayload(string) bool { return true }
+
+func newTestBridge(t *testing.T) (*audiotest.Backend, mqtt.Client, *recorder) {
+	t.Helper()
+	url := startBroker(t)
+
+	backend := audiotest.NewBackend(
+		audiocontrol.AudioDevice{ID: "speakers", Name: "Speakers", IsOutput: true, IsActive: true, IsConnected: true},
+		audiocontrol.AudioDevice{ID: "usb/room+1", Name: "Room Speaker", IsOutput: true, IsConnected: true},
+	)
+	bridge := New(backend, Config{Broker: url, NodeID: "test", Discovery: true})
+	if err := bridge.Start(); err != nil {
+		t.Fatalf("Failed to start bridge: %v", err)
+	}
+	t.Cleanup(bridge.Stop)
+
+	rec := &recorder{messages: make(map[string]string)}
+	client := mqtt.NewClient(mqtt.NewClientOptions().AddBroker(url).SetClientID("observer"))
+	if err := wait(client.Connect()); err != nil {
+		t.Fatalf("Failed to connect observer: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect(100) })
+	if err := wait(client.SubscribeMultiple(map[string]byte{"audiocontrol/#": 1, "homeassistant/#": 1}, rec.handle)); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	return backend, client, rec
+}
+
+func TestBridgePublishesRetainedState(t *testing.T) {
+	_, _, rec := newTestBridge(t)
+
+	rec.waitFor(t, "audiocontrol/test/status", func(p string) bool { return p == "online" })
+
+	var devices []audiocontrol.AudioDevice
+	payload := rec.waitFor(t, "audiocontrol/test/devices", anyPayload)
+	if err := json.Unmarshal([]byte(payload), &devices); err != nil || len(devices) != 2 {
+		t.Fatalf("Unexpected device list %q (%v)", payload, err)
+	}
+
+	var output audiocontrol.AudioDevice
+	payload = rec.waitFor(t, "audiocontrol/test/default/output", anyPayload)
+	if err := json.Unmarshal([]byte(payload), &output); err != nil || output.ID != "speakers" {
+		t.Fatalf("Unexpected default output %q (%v)", payload, err)
+	}
+
+	rec.waitFor(t, "audiocontrol/test/device/usb_room_1/volume", func(p string) bool { return p == "1.00" })
+	rec.waitFor(t, "audiocontrol/test/device/usb_room_1/mute", func(p string) bool { return p == "OFF" })
+}
+
+func TestBridgeCommands(t *testing.T) {
+	backend, client, rec := newTestBridge(t)
+	rec.waitFor(t, "audiocontrol/test/status", func(p string) bool { return p == "online" })
+
+	wait(client.Publish("audiocontrol/test/default/output/set", 1, false, "Room Speaker"))
+	rec.waitFor(t, "audiocontrol/test/default/output", func(p string) bool {
+		var d audiocontrol.AudioDevice
+		return json.Unmarshal([]byte(p), &d) == nil && d.ID == "usb/room+1"
+	})
+
+	wait(client.Publish("audiocontrol/test/device/usb_room_1/volume/set", 1, false, "0.35"))
+	rec.waitFor(t, "audiocontrol/test/device/usb_room_1/volume", func(p string) bool { return p == "0.35" })
+	if volume, _ := backend.GetVolume("usb/room+1"); volume != 0.35 {
+		t.Fatalf("Expected volume 0.35, got %v", volume)
+	}
+
+	wait(client.Publish("audiocontrol/test/device/speakers/mute/set", 1, false, "ON"))
+	rec.waitFor(t, "audiocontrol/test/device/speakers/mute", func(p string) bool { return p == "ON" })
+	if muted, _ := backend.GetMute("speakers"); !muted {
+		t.Fatal("Expected speakers to be muted")
+	}
+}
+
+func TestBridgePublishesEvents(t *testing.T) {
+	backend, _, rec := newTestBridge(t)
+	rec.waitFor(t, "audiocontrol/test/status", func(p string) bool { return p == "online" })
+
+	backend.AddDevice(audiocontrol.AudioDevice{ID: "hdmi", Name: "HDMI", IsOutput: true})
+
+	payload := rec.waitFor(t, "audiocontrol/test/event", anyPayload)
+	var event struct {
+		Type     string
+		DeviceID string
+	}
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		t.Fatalf("Failed to decode event: %v", err)
+	}
+	if event.Type != "DeviceAdded" || event.DeviceID != "hdmi" {
+		t.Fatalf("Unexpected event %+v", event)
+	}
+
+	rec.waitFor(t, "homeassistant/number/test/hdmi_volume/config", anyPayload)
+}
+
+func TestBridgeDiscovery(t *testing.T) {
+	backend, _, rec := newTestBridge(t)
+
+	payload := rec.waitFor(t, "homeassistant/select/test/default_output/config", anyPayload)
+	var entity haEntity
+	if err := json.Unmarshal([]byte(payload), &entity); err != nil {
+		t.Fatalf("Failed to decode discovery payload: %v", err)
+	}
+	if entity.CommandTopic != "audiocontrol/test/default/output/set" || len(entity.Options) != 2 {
+		t.Fatalf("Unexpected select entity %+v", entity)
+	}
+	if entity.AvailabilityTopic != "audiocontrol/test/status" {
+		t.Fatalf("Unexpected availability topic %q", entity.AvailabilityTopic)
+	}
+
+	rec.waitFor(t, "homeassistant/switch/test/usb_room_1_mute/config", func(p string) bool { return p != "" })
+
+	// Removing a device clears its retained entities
+	backend.RemoveDevice("usb/room+1")
+	rec.waitFor(t, "homeassistant/switch/test/usb_room_1_mute/config", func(p string) bool { return p == "" })
+}
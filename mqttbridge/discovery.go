@@ -0,0 +1,128 @@
+package mqttbridge
+
+import (
+	"encoding/json"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+)
+
+// haDevice groups all entities of one node in Home Assistant
+type haDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer,omitempty"`
+}
+
+// haEntity is the subset of Home Assistant MQTT discovery fields used by the
+// bridge
+type haEntity struct {
+	Name              string   `json:"name"`
+	UniqueID          string   `json:"unique_id"`
+	ObjectID          string   `json:"object_id,omitempty"`
+	AvailabilityTopic string   `json:"availability_topic"`
+	StateTopic        string   `json:"state_topic"`
+	CommandTopic      string   `json:"command_topic"`
+	ValueTemplate     string   `json:"value_template,omitempty"`
+	Options           []string `json:"options,omitempty"`
+	Min               *float64 `json:"min,omitempty"`
+	Max               *float64 `json:"max,omitempty"`
+	Step              float64  `json:"step,omitempty"`
+	PayloadOn         string   `json:"payload_on,omitempty"`
+	PayloadOff        string   `json:"payload_off,omitempty"`
+	Icon              string   `json:"icon,omitempty"`
+	Device            haDevice `json:"device"`
+}
+
+// publishDiscovery publishes the discovery payloads for devices and removes
+// the entities of devices that are gone
+func (b *Bridge) publishDiscovery(devices []audiocontrol.AudioDevice) {
+	node := haDevice{
+		Identifiers:  []string{"audiocontrol_" + b.config.NodeID},
+		Name:         "Audio (" + b.config.NodeID + ")",
+		Manufacturer: "go-audio-control",
+	}
+
+	var outputs, inputs []string
+	for _, d := range devices {
+		if d.IsOutput {
+			outputs = append(outputs, d.Name)
+		}
+		if d.IsInput {
+			inputs = append(inputs, d.Name)
+		}
+	}
+
+	configs := make(map[string]haEntity)
+	if len(outputs) > 0 {
+		configs["select/"+b.config.NodeID+"/default_output/config"] = b.entity(node, "default_output", "Default output", haEntity{
+			StateTopic:    b.topic("default", "output"),
+			CommandTopic:  b.topic("default", "output", "set"),
+			ValueTemplate: "{{ value_json.Name }}",
+			Options:       outputs,
+			Icon:          "mdi:speaker",
+		})
+	}
+	if len(inputs) > 0 {
+		configs["select/"+b.config.NodeID+"/default_input/config"] = b.entity(node, "default_input", "Default input", haEntity{
+			StateTopic:    b.topic("default", "input"),
+			CommandTopic:  b.topic("default", "input", "set"),
+			ValueTemplate: "{{ value_json.Name }}",
+			Options:       inputs,
+			Icon:          "mdi:microphone",
+		})
+	}
+
+	minVolume, maxVolume := 0.0, 1.0
+	for _, d := range devices {
+		key := topicKey(d.ID)
+		configs["number/"+b.config.NodeID+"/"+key+"_volume/config"] = b.entity(node, key+"_volume", d.Name+" volume", haEntity{
+			StateTopic:   b.topic("device", key, "volume"),
+			CommandTopic: b.topic("device", key, "volume", "set"),
+			Min:          &minVolume,
+			Max:          &maxVolume,
+			Step:         0.01,
+			Icon:         "mdi:volume-high",
+		})
+		configs["switch/"+b.config.NodeID+"/"+key+"_mute/config"] = b.entity(node, key+"_mute", d.Name+" mute", haEntity{
+			StateTopic:   b.topic("device", key, "mute"),
+			CommandTopic: b.topic("device", key, "mute", "set"),
+			PayloadOn:    "ON",
+			PayloadOff:   "OFF",
+			Icon:         "mdi:volume-off",
+		})
+	}
+
+	b.mu.Lock()
+	previous := b.discovered
+	b.discovered = make(map[string]bool, len(configs))
+	for topic := range configs {
+		b.discovered[topic] = true
+	}
+	b.mu.Unlock()
+
+	prefix := b.config.DiscoveryPrefix + "/"
+	for topic, entity := range configs {
+		data, err := json.Marshal(entity)
+		if err != nil {
+			continue
+		}
+		wait(b.client.Publish(prefix+topic, 1, true, data))
+	}
+
+	// An empty retained payload deletes the entity in Home Assistant
+	for topic := range previous {
+		if _, ok := configs[topic]; !ok {
+			wait(b.client.Publish(prefix+topic, 1, true, ""))
+		}
+	}
+}
+
+// entity fills in the fields shared by every entity of the node
+func (b *Bridge) entity(node haDevice, objectID, name string, e haEntity) haEntity {
+	e.Name = name
+	e.UniqueID = "audiocontrol_" + b.config.NodeID + "_" + objectID
+	e.ObjectID = objectID
+	e.AvailabilityTopic = b.topic("status")
+	e.Device = node
+	return e
+}
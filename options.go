@@ -0,0 +1,116 @@
+package audiocontrol
+
+import "fmt"
+
+// Direction selects devices by the direction audio flows
+type Direction int
+
+const (
+	// DirectionAll selects inputs and outputs
+	DirectionAll Direction = iota
+	// DirectionOutput selects output devices
+	DirectionOutput
+	// DirectionInput selects input devices
+	DirectionInput
+)
+
+// String returns the name of the direction
+func (d Direction) String() string {
+	switch d {
+	case DirectionAll:
+		return "all"
+	case DirectionOutput:
+		return "output"
+	case DirectionInput:
+		return "input"
+	}
+	return fmt.Sprintf("Direction(%d)", int(d))
+}
+
+// ParseDirection returns the Direction whose String is name
+func ParseDirection(name string) (Direction, error) {
+	for d := DirectionAll; d <= DirectionInput; d++ {
+		if d.String() == name {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown direction %q", name)
+}
+
+// ListOptions selects the devices returned by ListAudioDevicesWithOptions.
+// The zero value selects active devices of both directions, the same devices
+// ListAudioDevices returns. IncludeHidden adds devices hidden by an alias.
+type ListOptions struct {
+	IncludeDisabled   bool
+	IncludeUnplugged  bool
+	IncludeNotPresent bool
+	IncludeHidden     bool
+	Direction         Direction
+}
+
+// Match reports whether a device is selected by o
+func (o ListOptions) Match(d AudioDevice) bool {
+	if d.Hidden && !o.IncludeHidden {
+		return false
+	}
+	switch o.Direction {
+	case DirectionOutput:
+		if !d.IsOutput {
+			return false
+		}
+	case DirectionInput:
+		if !d.IsInput {
+			return false
+		}
+	}
+
+	switch d.State {
+	case StateDisabled:
+		return o.IncludeDisabled
+	case StateUnplugged:
+		return o.IncludeUnplugged
+	case StateNotPresent:
+		return o.IncludeNotPresent
+	}
+	return true
+}
+
+// Filter returns the devices selected by o
+func (o ListOptions) Filter(devices []AudioDevice) []AudioDevice {
+	var matched []AudioDevice
+	for _, d := range devices {
+		if o.Match(d) {
+			matched = append(matched, d)
+		}
+	}
+	return matched
+}
+
+// DeviceLister is implemented by backends that can list devices that are
+// not active. Backends without it only report active devices.
+type DeviceLister interface {
+	ListAudioDevicesWithOptions(opts ListOptions) ([]AudioDevice, error)
+}
+
+// ListAudioDevicesWithOptions enumerates the devices selected by opts,
+// including disabled, unplugged or absent ones when requested
+func ListAudioDevicesWithOptions(opts ListOptions) ([]AudioDevice, error) {
+	devices, err := ListBackendDevices(currentBackend(), opts)
+	if err != nil {
+		return nil, err
+	}
+	return applyAliases(devices, opts.IncludeHidden), nil
+}
+
+// ListBackendDevices lists the devices of b selected by opts. It uses
+// DeviceLister when b implements it and otherwise filters ListAudioDevices.
+func ListBackendDevices(b Backend, opts ListOptions) ([]AudioDevice, error) {
+	if lister, ok := b.(DeviceLister); ok {
+		return lister.ListAudioDevicesWithOptions(opts)
+	}
+	devices, err := b.ListAudioDevices()
+	if err != nil {
+		return nil, err
+	}
+	return opts.Filter(devices), nil
+}
@@ -0,0 +1,109 @@
+package audiocontrol_test
+
+import (
+	"testing"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+	"github.com/audi70r/go-audio-control/audiotest"
+)
+
+// legacyBackend hides the DeviceLister implementation of the fake
+type legacyBackend struct {
+	audiocontrol.Backend
+}
+
+func newStateBackend(t *testing.T) *audiotest.Backend {
+	t.Helper()
+	b := audiotest.NewBackend(
+		audiocontrol.AudioDevice{ID: "speakers", Name: "Speakers", IsOutput: true, IsActive: true, IsConnected: true},
+		audiocontrol.AudioDevice{ID: "headphones", Name: "Headphones", IsOutput: true},
+		audiocontrol.AudioDevice{ID: "hdmi", Name: "HDMI", IsOutput: true},
+		audiocontrol.AudioDevice{ID: "dock", Name: "Dock", IsOutput: true},
+		audiocontrol.AudioDevice{ID: "mic", Name: "Microphone", IsInput: true, IsActive: true, IsConnected: true},
+		audiocontrol.AudioDevice{ID: "headset-mic", Name: "Headset Microphone", IsInput: true},
+	)
+	states := map[string]audiocontrol.DeviceState{
+		"headphones":  audiocontrol.StateUnplugged,
+		"hdmi":        audiocontrol.StateDisabled,
+		"dock":        audiocontrol.StateNotPresent,
+		"headset-mic": audiocontrol.StateUnplugged,
+	}
+	for id, state := range states {
+		if err := b.SetState(id, state); err != nil {
+			t.Fatalf("Failed to set state: %v", err)
+		}
+	}
+	return b
+}
+
+func deviceIDs(devices []audiocontrol.AudioDevice) []string {
+	ids := make([]string, len(devices))
+	for i, d := range devices {
+		ids[i] = d.ID
+	}
+	return ids
+}
+
+func TestListOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts audiocontrol.ListOptions
+		want []string
+	}{
+		{"default", audiocontrol.ListOptions{}, []string{"speakers", "mic"}},
+		{"unplugged", audiocontrol.ListOptions{IncludeUnplugged: true}, []string{"speakers", "headphones", "mic", "headset-mic"}},
+		{"disabled", audiocontrol.ListOptions{IncludeDisabled: true}, []string{"speakers", "hdmi", "mic"}},
+		{"not present", audiocontrol.ListOptions{IncludeNotPresent: true}, []string{"speakers", "dock", "mic"}},
+		{"outputs", audiocontrol.ListOptions{Direction: audiocontrol.DirectionOutput, IncludeUnplugged: true}, []string{"speakers", "headphones"}},
+		{"inputs", audiocontrol.ListOptions{Direction: audiocontrol.DirectionInput}, []string{"mic"}},
+		{"everything", audiocontrol.ListOptions{IncludeDisabled: true, IncludeUnplugged: true, IncludeNotPresent: true},
+			[]string{"speakers", "headphones", "hdmi", "dock", "mic", "headset-mic"}},
+	}
+
+	b := newStateBackend(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			devices, err := audiocontrol.ListBackendDevices(b, tt.opts)
+			if err != nil {
+				t.Fatalf("Failed to list audio devices: %v", err)
+			}
+			got := deviceIDs(devices)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestListOptionsWithoutDeviceLister(t *testing.T) {
+	b := legacyBackend{newStateBackend(t)}
+
+	// Backends without DeviceLister only know their active devices
+	devices, err := audiocontrol.ListBackendDevices(b, audiocontrol.ListOptions{
+		IncludeUnplugged: true,
+		Direction:        audiocontrol.DirectionOutput,
+	})
+	if err != nil {
+		t.Fatalf("Failed to list audio devices: %v", err)
+	}
+	if ids := deviceIDs(devices); len(ids) != 1 || ids[0] != "speakers" {
+		t.Errorf("Got %v, want [speakers]", ids)
+	}
+}
+
+func TestParseDirection(t *testing.T) {
+	for d := audiocontrol.DirectionAll; d <= audiocontrol.DirectionInput; d++ {
+		parsed, err := audiocontrol.ParseDirection(d.String())
+		if err != nil || parsed != d {
+			t.Errorf("ParseDirection(%q) = %v, %v", d.String(), parsed, err)
+		}
+	}
+	if _, err := audiocontrol.ParseDirection("sideways"); err == nil {
+		t.Error("Expected an error for an unknown direction")
+	}
+}
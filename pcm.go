@@ -0,0 +1,79 @@
+package audiocontrol
+
+import "fmt"
+
+// SampleEncoding is the layout of one PCM sample. Every encoding is
+// little-endian.
+type SampleEncoding int
+
+const (
+	EncodingS16LE SampleEncoding = iota
+	EncodingS24LE
+	EncodingS32LE
+	EncodingFloat32LE
+)
+
+// String returns the name of the encoding
+func (e SampleEncoding) String() string {
+	switch e {
+	case EncodingS16LE:
+		return "S16LE"
+	case EncodingS24LE:
+		return "S24LE"
+	case EncodingS32LE:
+		return "S32LE"
+	case EncodingFloat32LE:
+		return "Float32LE"
+	}
+	return fmt.Sprintf("SampleEncoding(%d)", int(e))
+}
+
+// MarshalText encodes the encoding by name
+func (e SampleEncoding) MarshalText() ([]byte, error) {
+	return []byte(e.String()), nil
+}
+
+// UnmarshalText decodes an encoding name
+func (e *SampleEncoding) UnmarshalText(text []byte) error {
+	for encoding := EncodingS16LE; encoding <= EncodingFloat32LE; encoding++ {
+		if encoding.String() == string(text) {
+			*e = encoding
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown sample encoding %q", text)
+}
+
+// Size returns the number of bytes of one sample, or 0 if unknown
+func (e SampleEncoding) Size() int {
+	switch e {
+	case EncodingS16LE:
+		return 2
+	case EncodingS24LE:
+		return 3
+	case EncodingS32LE, EncodingFloat32LE:
+		return 4
+	}
+	return 0
+}
+
+// Format describes interleaved PCM audio
+type Format struct {
+	Encoding SampleEncoding
+	Channels int
+	Rate     int
+}
+
+// FrameSize returns the number of bytes of one frame, a sample of every
+// channel
+func (f Format) FrameSize() int {
+	return f.Encoding.Size() * f.Channels
+}
+
+// validate reports formats that cannot describe audio
+func (f Format) validate() error {
+	if f.Encoding.Size() == 0 || f.Channels <= 0 || f.Rate <= 0 {
+		return fmt.Errorf("invalid format %v with %d channels at %d Hz", f.Encoding, f.Channels, f.Rate)
+	}
+	return nil
+}
@@ -87,6 +87,33 @@ static int isDeviceConnected(AudioObjectID deviceID) {
     return 1;
 }
 
+// Get the transport of a device: 1 for Bluetooth, 2 for USB, 3 for
+// built-in and 0 for anything else or unknown
+static int getTransportKind(AudioObjectID deviceID) {
+    UInt32 transportType = 0;
+    UInt32 size = sizeof(transportType);
+
+    AudioObjectPropertyAddress address = {
+        kAudioDevicePropertyTransportType,
+        kAudioObjectPropertyScopeGlobal,
+        kAudioObjectPropertyElementMain
+    };
+
+    if (AudioObjectGetPropertyData(deviceID, &address, 0, NULL, &size, &transportType) != noErr) {
+        return 0;
+    }
+    switch (transportType) {
+    case kAudioDeviceTransportTypeBluetooth:
+    case kAudioDeviceTransportTypeBluetoothLE:
+        return 1;
+    case kAudioDeviceTransportTypeUSB:
+        return 2;
+    case kAudioDeviceTransportTypeBuiltIn:
+        return 3;
+    }
+    return 0;
+}
+
 // Get all audio devices
 static AudioObjectID* getAllAudioDevices(int* count) {
     AudioObjectPropertyAddress address = {
@@ -141,6 +168,88 @@ static OSStatus setDefaultDevice(AudioObjectID deviceID, int isInput) {
     return AudioObjectSetPropertyData(kAudioObjectSystemObject, &address, 0, NULL, sizeof(deviceID), &deviceID);
 }
 
+// Get the volume scalar of a device, averaging the first two channels when
+// the device has no main volume control
+static OSStatus getDeviceVolume(AudioObjectID deviceID, AudioObjectPropertyScope scope, Float32* volume) {
+    AudioObjectPropertyAddress address = {
+        kAudioDevicePropertyVolumeScalar,
+        scope,
+        kAudioObjectPropertyElementMain
+    };
+
+    UInt32 size = sizeof(Float32);
+    if (AudioObjectHasProperty(deviceID, &address)) {
+        return AudioObjectGetPropertyData(deviceID, &address, 0, NULL, &size, volume);
+    }
+
+    Float32 total = 0;
+    int channels = 0;
+    for (UInt32 channel = 1; channel <= 2; channel++) {
+        address.mElement = channel;
+        Float32 value = 0;
+        size = sizeof(value);
+        if (AudioObjectHasProperty(deviceID, &address) &&
+            AudioObjectGetPropertyData(deviceID, &address, 0, NULL, &size, &value) == noErr) {
+            total += value;
+            channels++;
+        }
+    }
+    if (channels == 0) {
+        return kAudioHardwareUnknownPropertyError;
+    }
+
+    *volume = total / channels;
+    return noErr;
+}
+
+// Set the volume scalar of a device, falling back to the first two channels
+static OSStatus setDeviceVolume(AudioObjectID deviceID, AudioObjectPropertyScope scope, Float32 volume) {
+    AudioObjectPropertyAddress address = {
+        kAudioDevicePropertyVolumeScalar,
+        scope,
+        kAudioObjectPropertyElementMain
+    };
+
+    if (AudioObjectHasProperty(deviceID, &address)) {
+        return AudioObjectSetPropertyData(deviceID, &address, 0, NULL, sizeof(volume), &volume);
+    }
+
+    OSStatus status = kAudioHardwareUnknownPropertyError;
+    for (UInt32 channel = 1; channel <= 2; channel++) {
+        address.mElement = channel;
+        if (AudioObjectHasProperty(deviceID, &address)) {
+            status = AudioObjectSetPropertyData(deviceID, &address, 0, NULL, sizeof(volume), &volume);
+            if (status != noErr) {
+                return status;
+            }
+        }
+    }
+    return status;
+}
+
+// Get the mute state of a device
+static OSStatus getDeviceMute(AudioObjectID deviceID, AudioObjectPropertyScope scope, UInt32* muted) {
+    AudioObjectPropertyAddress address = {
+        kAudioDevicePropertyMute,
+        scope,
+        kAudioObjectPropertyElementMain
+    };
+
+    UInt32 size = sizeof(UInt32);
+    return AudioObjectGetPropertyData(deviceID, &address, 0, NULL, &size, muted);
+}
+
+// Set the mute state of a device
+static OSStatus setDeviceMute(AudioObjectID deviceID, AudioObjectPropertyScope scope, UInt32 muted) {
+    AudioObjectPropertyAddress address = {
+        kAudioDevicePropertyMute,
+        scope,
+        kAudioObjectPropertyElementMain
+    };
+
+    return AudioObjectSetPropertyData(deviceID, &address, 0, NULL, sizeof(muted), &muted);
+}
+
 // Property listener callback
 static OSStatus propertyListenerCallback(
     AudioObjectID objectID,
@@ -159,14 +268,19 @@ static OSStatus propertyListenerCallback(
         } else if (addresses[i].mSelector == kAudioDevicePropertyDeviceIsAlive) {
             // Device disconnected
             goDeviceChangeCallback(3, objectID); // 3 = DeviceDisconnected
+        } else if (addresses[i].mSelector == kAudioDevicePropertyDataSource ||
+                   addresses[i].mSelector == kAudioDevicePropertyJackIsConnected) {
+            // Data source switched or jack plugged
+            goDeviceChangeCallback(4, objectID); // 4 = PortChanged
         }
     }
 
     return noErr;
 }
 
-// Start monitoring
-static int startMonitoring() {
+// Start monitoring, returning the status of the first listener that could
+// not be added
+static OSStatus startMonitoring() {
     OSStatus status;
 
     // Monitor device list changes
@@ -182,7 +296,7 @@ static int startMonitoring() {
         propertyListenerCallback,
         NULL
     );
-    if (status != noErr) return -1;
+    if (status != noErr) return status;
 
     // Monitor default output device changes
     AudioObjectPropertyAddress outputAddress = {
@@ -197,7 +311,7 @@ static int startMonitoring() {
         propertyListenerCallback,
         NULL
     );
-    if (status != noErr) return -1;
+    if (status != noErr) return status;
 
     // Monitor default input device changes
     AudioObjectPropertyAddress inputAddress = {
@@ -212,9 +326,9 @@ static int startMonitoring() {
         propertyListenerCallback,
         NULL
     );
-    if (status != noErr) return -1;
+    if (status != noErr) return status;
 
-    return 0;
+    return noErr;
 }
 
 // Stop monitoring
@@ -257,14 +371,14 @@ static void stopMonitoring() {
 }
 
 // Add listener for device alive property
-static void addDeviceAliveListener(AudioObjectID deviceID) {
+static OSStatus addDeviceAliveListener(AudioObjectID deviceID) {
     AudioObjectPropertyAddress aliveAddress = {
         kAudioDevicePropertyDeviceIsAlive,
         kAudioObjectPropertyScopeGlobal,
         kAudioObjectPropertyElementMain
     };
 
-    AudioObjectAddPropertyListener(
+    return AudioObjectAddPropertyListener(
         deviceID,
         &aliveAddress,
         propertyListenerCallback,
@@ -287,13 +401,49 @@ static void removeDeviceAliveListener(AudioObjectID deviceID) {
         NULL
     );
 }
+
+// Add or remove listeners for the data source and jack of both scopes,
+// skipping properties the device does not have
+static void updatePortListeners(AudioObjectID deviceID, int add) {
+    AudioObjectPropertySelector selectors[] = {
+        kAudioDevicePropertyDataSource,
+        kAudioDevicePropertyJackIsConnected
+    };
+    AudioObjectPropertyScope scopes[] = {
+        kAudioDevicePropertyScopeOutput,
+        kAudioDevicePropertyScopeInput
+    };
+
+    for (int i = 0; i < 2; i++) {
+        for (int j = 0; j < 2; j++) {
+            AudioObjectPropertyAddress address = {
+                selectors[i],
+                scopes[j],
+                kAudioObjectPropertyElementMain
+            };
+            if (!AudioObjectHasProperty(deviceID, &address)) {
+                continue;
+            }
+            if (add) {
+                AudioObjectAddPropertyListener(deviceID, &address, propertyListenerCallback, NULL);
+            } else {
+                AudioObjectRemovePropertyListener(deviceID, &address, propertyListenerCallback, NULL);
+            }
+        }
+    }
+}
 */
 import "C"
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"strconv"
+	"strings"
 	"sync"
 	"unsafe"
+
+	"github.com/audi70r/go-audio-control/internal/logging"
 )
 
 // AudioDevice represents an audio device
@@ -304,6 +454,53 @@ type AudioDevice struct {
 	IsOutput    bool
 	IsActive    bool
 	IsConnected bool
+
+	IsDefaultOutput         bool
+	IsDefaultInput          bool
+	IsDefaultCommunications bool
+	State                   DeviceState
+	Ports                   []Port
+	Identity                Identity
+}
+
+// Identity holds durable properties of the hardware behind a device
+type Identity struct {
+	Bus              string
+	VendorID         string
+	ProductID        string
+	Serial           string
+	BluetoothAddress string
+	ModelUID         string
+	Endpoint         string
+}
+
+// DeviceState is the presence state of a device
+type DeviceState int
+
+const (
+	StateActive DeviceState = iota
+	StateDisabled
+	StateNotPresent
+	StateUnplugged
+)
+
+// connectedState maps the device's alive flag to a state. CoreAudio does not
+// report disabled devices or unplugged jacks for the whole device.
+func connectedState(isConnected bool) DeviceState {
+	if isConnected {
+		return StateActive
+	}
+	return StateNotPresent
+}
+
+// listedState is the state of a listed device. A device that is alive but
+// has no streams, such as a display with nothing attached to its audio
+// output, cannot be used until something is plugged in.
+func listedState(isConnected, hasStreams bool) DeviceState {
+	if isConnected && !hasStreams {
+		return StateUnplugged
+	}
+	return connectedState(isConnected)
 }
 
 // EventType represents the type of audio device event
@@ -314,6 +511,7 @@ const (
 	DeviceRemoved
 	ActiveDeviceChanged
 	DeviceDisconnected
+	PortChanged
 )
 
 // Event represents an audio device event
@@ -323,6 +521,25 @@ type Event struct {
 	Info     *AudioDevice
 }
 
+// ErrDeviceNotFound is returned for UIDs that name no device in the
+// requested scope
+var ErrDeviceNotFound = errors.New("device not found")
+
+// StatusError is a CoreAudio call that failed with an OSStatus
+type StatusError struct {
+	Op     string
+	Status int32
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("failed to %s: OSStatus %d", e.Op, e.Status)
+}
+
+// OSStatus returns the status the call failed with
+func (e *StatusError) OSStatus() int32 {
+	return e.Status
+}
+
 var (
 	callbackMutex sync.Mutex
 	userCallback  func(Event)
@@ -330,8 +547,15 @@ var (
 	stateMutex    sync.RWMutex
 )
 
-// ListAudioDevices enumerates all audio devices
+// ListAudioDevices enumerates the audio devices that have streams
 func ListAudioDevices() ([]AudioDevice, error) {
+	return ListAudioDevicesWithState(false)
+}
+
+// ListAudioDevicesWithState enumerates the audio devices, including those
+// without streams, in StateUnplugged, when includeUnplugged is set. Devices
+// that are no longer alive are always listed, in StateNotPresent.
+func ListAudioDevicesWithState(includeUnplugged bool) ([]AudioDevice, error) {
 	var count C.int
 	deviceIDs := C.getAllAudioDevices(&count)
 	if deviceIDs == nil {
@@ -366,8 +590,8 @@ func ListAudioDevices() ([]AudioDevice, error) {
 		hasInput := C.hasStreams(deviceID, C.kAudioDevicePropertyScopeInput) == 1
 		hasOutput := C.hasStreams(deviceID, C.kAudioDevicePropertyScopeOutput) == 1
 
-		// Skip devices with no streams
-		if !hasInput && !hasOutput {
+		// Skip devices with no streams unless asked for them
+		if !hasInput && !hasOutput && !includeUnplugged {
 			continue
 		}
 
@@ -378,6 +602,7 @@ func ListAudioDevices() ([]AudioDevice, error) {
 		// Check if connected
 		isConnected := C.isDeviceConnected(deviceID) == 1
 
+		// CoreAudio has no communications role; calls use the defaults
 		device := AudioDevice{
 			ID:          uid,
 			Name:        name,
@@ -385,6 +610,13 @@ func ListAudioDevices() ([]AudioDevice, error) {
 			IsOutput:    hasOutput,
 			IsActive:    isActiveInput || isActiveOutput,
 			IsConnected: isConnected,
+
+			IsDefaultOutput:         isActiveOutput,
+			IsDefaultInput:          isActiveInput,
+			IsDefaultCommunications: isActiveInput || isActiveOutput,
+			State:                   listedState(isConnected, hasInput || hasOutput),
+			Ports:                   devicePorts(deviceID, hasInput, hasOutput),
+			Identity:                deviceIdentity(deviceID, uid),
 		}
 
 		devices = append(devices, device)
@@ -400,48 +632,137 @@ func GetActiveOutputDevice() (AudioDevice, error) {
 		return AudioDevice{}, fmt.Errorf("no default output device found")
 	}
 
-	// Get device name
-	namePtr := C.getDeviceStringProperty(deviceID, C.kAudioObjectPropertyName)
-	if namePtr == nil {
-		return AudioDevice{}, fmt.Errorf("failed to get device name")
+	device := getDeviceInfo(deviceID)
+	if device == nil {
+		return AudioDevice{}, fmt.Errorf("failed to get device info")
 	}
-	name := C.GoString(namePtr)
-	C.free(unsafe.Pointer(namePtr))
+	return *device, nil
+}
 
-	// Get device UID
-	uidPtr := C.getDeviceStringProperty(deviceID, C.kAudioDevicePropertyDeviceUID)
-	if uidPtr == nil {
-		return AudioDevice{}, fmt.Errorf("failed to get device UID")
+// GetActiveInputDevice returns the currently active input device
+func GetActiveInputDevice() (AudioDevice, error) {
+	deviceID := C.getDefaultDevice(1)
+	if deviceID == C.kAudioObjectUnknown {
+		return AudioDevice{}, fmt.Errorf("no default input device found")
 	}
-	uid := C.GoString(uidPtr)
-	C.free(unsafe.Pointer(uidPtr))
 
-	// Check if connected
-	isConnected := C.isDeviceConnected(deviceID) == 1
-
-	return AudioDevice{
-		ID:          uid,
-		Name:        name,
-		IsInput:     false,
-		IsOutput:    true,
-		IsActive:    true,
-		IsConnected: isConnected,
-	}, nil
+	device := getDeviceInfo(deviceID)
+	if device == nil {
+		return AudioDevice{}, fmt.Errorf("failed to get device info")
+	}
+	return *device, nil
 }
 
 // SetActiveOutputDevice sets the active output device by ID
 func SetActiveOutputDevice(deviceUID string) error {
-	// First, find the device ID from the UID
+	targetDeviceID := findDeviceByUID(deviceUID, C.kAudioDevicePropertyScopeOutput)
+	if targetDeviceID == C.kAudioObjectUnknown {
+		return fmt.Errorf("output device with UID %s: %w", deviceUID, ErrDeviceNotFound)
+	}
+
+	// Set as default output device
+	status := C.setDefaultDevice(targetDeviceID, 0)
+	if status != C.noErr {
+		return &StatusError{Op: "set default output device", Status: int32(status)}
+	}
+
+	return nil
+}
+
+// SetActiveInputDevice sets the active input device by ID
+func SetActiveInputDevice(deviceUID string) error {
+	targetDeviceID := findDeviceByUID(deviceUID, C.kAudioDevicePropertyScopeInput)
+	if targetDeviceID == C.kAudioObjectUnknown {
+		return fmt.Errorf("input device with UID %s: %w", deviceUID, ErrDeviceNotFound)
+	}
+
+	// Set as default input device
+	status := C.setDefaultDevice(targetDeviceID, 1)
+	if status != C.noErr {
+		return &StatusError{Op: "set default input device", Status: int32(status)}
+	}
+
+	return nil
+}
+
+// GetVolume returns the volume scalar of a device
+func GetVolume(deviceUID string) (float64, error) {
+	deviceID, scope, err := findVolumeTarget(deviceUID)
+	if err != nil {
+		return 0, err
+	}
+
+	var volume C.Float32
+	status := C.getDeviceVolume(deviceID, scope, &volume)
+	if status != C.noErr {
+		return 0, &StatusError{Op: "get device volume", Status: int32(status)}
+	}
+
+	return float64(volume), nil
+}
+
+// SetVolume sets the volume scalar of a device
+func SetVolume(deviceUID string, volume float64) error {
+	deviceID, scope, err := findVolumeTarget(deviceUID)
+	if err != nil {
+		return err
+	}
+
+	status := C.setDeviceVolume(deviceID, scope, C.Float32(volume))
+	if status != C.noErr {
+		return &StatusError{Op: "set device volume", Status: int32(status)}
+	}
+
+	return nil
+}
+
+// GetMute reports whether a device is muted
+func GetMute(deviceUID string) (bool, error) {
+	deviceID, scope, err := findVolumeTarget(deviceUID)
+	if err != nil {
+		return false, err
+	}
+
+	var muted C.UInt32
+	status := C.getDeviceMute(deviceID, scope, &muted)
+	if status != C.noErr {
+		return false, &StatusError{Op: "get device mute state", Status: int32(status)}
+	}
+
+	return muted != 0, nil
+}
+
+// SetMute mutes or unmutes a device
+func SetMute(deviceUID string, muted bool) error {
+	deviceID, scope, err := findVolumeTarget(deviceUID)
+	if err != nil {
+		return err
+	}
+
+	var value C.UInt32
+	if muted {
+		value = 1
+	}
+
+	status := C.setDeviceMute(deviceID, scope, value)
+	if status != C.noErr {
+		return &StatusError{Op: "set device mute state", Status: int32(status)}
+	}
+
+	return nil
+}
+
+// findDeviceByUID returns the AudioObjectID of the device with the given UID
+// that has streams in scope, or kAudioObjectUnknown
+func findDeviceByUID(deviceUID string, scope C.AudioObjectPropertyScope) C.AudioObjectID {
 	var count C.int
 	deviceIDs := C.getAllAudioDevices(&count)
 	if deviceIDs == nil {
-		return fmt.Errorf("failed to enumerate audio devices")
+		return C.kAudioObjectUnknown
 	}
 	defer C.free(unsafe.Pointer(deviceIDs))
 
 	deviceArray := (*[1 << 30]C.AudioObjectID)(unsafe.Pointer(deviceIDs))[:count:count]
-	var targetDeviceID C.AudioObjectID = C.kAudioObjectUnknown
-
 	for _, deviceID := range deviceArray {
 		uidPtr := C.getDeviceStringProperty(deviceID, C.kAudioDevicePropertyDeviceUID)
 		if uidPtr == nil {
@@ -450,26 +771,24 @@ func SetActiveOutputDevice(deviceUID string) error {
 		uid := C.GoString(uidPtr)
 		C.free(unsafe.Pointer(uidPtr))
 
-		if uid == deviceUID {
-			// Check if it has output streams
-			if C.hasStreams(deviceID, C.kAudioDevicePropertyScopeOutput) == 1 {
-				targetDeviceID = deviceID
-				break
-			}
+		if uid == deviceUID && C.hasStreams(deviceID, scope) == 1 {
+			return deviceID
 		}
 	}
 
-	if targetDeviceID == C.kAudioObjectUnknown {
-		return fmt.Errorf("device with UID %s not found or is not an output device", deviceUID)
-	}
+	return C.kAudioObjectUnknown
+}
 
-	// Set as default output device
-	status := C.setDefaultDevice(targetDeviceID, 0)
-	if status != C.noErr {
-		return fmt.Errorf("failed to set default output device: OSStatus %d", status)
+// findVolumeTarget resolves a device UID and the scope its volume controls
+// live in, preferring the output scope for duplex devices
+func findVolumeTarget(deviceUID string) (C.AudioObjectID, C.AudioObjectPropertyScope, error) {
+	if deviceID := findDeviceByUID(deviceUID, C.kAudioDevicePropertyScopeOutput); deviceID != C.kAudioObjectUnknown {
+		return deviceID, C.kAudioDevicePropertyScopeOutput, nil
 	}
-
-	return nil
+	if deviceID := findDeviceByUID(deviceUID, C.kAudioDevicePropertyScopeInput); deviceID != C.kAudioObjectUnknown {
+		return deviceID, C.kAudioDevicePropertyScopeInput, nil
+	}
+	return C.kAudioObjectUnknown, 0, fmt.Errorf("device with UID %s: %w", deviceUID, ErrDeviceNotFound)
 }
 
 // Helper function to convert AudioObjectID to string
@@ -477,8 +796,19 @@ func audioObjectIDToString(id C.AudioObjectID) string {
 	return strconv.FormatUint(uint64(id), 10)
 }
 
+// notificationNames name the properties goDeviceChangeCallback is told about
+var notificationNames = map[C.int]string{
+	0: "devices",
+	2: "default device",
+	3: "device is alive",
+	4: "data source or jack",
+}
+
 //export goDeviceChangeCallback
 func goDeviceChangeCallback(eventType C.int, deviceID C.AudioObjectID) {
+	logging.Notification(slog.String("property", notificationNames[eventType]),
+		slog.Uint64("object", uint64(deviceID)))
+
 	callbackMutex.Lock()
 	callback := userCallback
 	callbackMutex.Unlock()
@@ -494,6 +824,8 @@ func goDeviceChangeCallback(eventType C.int, deviceID C.AudioObjectID) {
 		handleActiveDeviceChange(callback)
 	case 3: // Device disconnected
 		handleDeviceDisconnected(callback, deviceID)
+	case 4: // Data source or jack changed
+		handlePortChanged(callback, deviceID)
 	}
 }
 
@@ -502,6 +834,7 @@ func handleDeviceListChange(callback func(Event)) {
 	var count C.int
 	newDeviceIDs := C.getAllAudioDevices(&count)
 	if newDeviceIDs == nil {
+		logging.Ignored("enumerate audio devices", errors.New("no device list returned"))
 		return
 	}
 	defer C.free(unsafe.Pointer(newDeviceIDs))
@@ -527,8 +860,8 @@ func handleDeviceListChange(callback func(Event)) {
 					Info:     device,
 				})
 
-				// Add alive listener for this device
-				C.addDeviceAliveListener(deviceID)
+				// Add alive and port listeners for this device
+				addDeviceListeners(deviceID)
 			}
 		}
 	}
@@ -547,8 +880,9 @@ func handleDeviceListChange(callback func(Event)) {
 				})
 			}
 
-			// Remove alive listener
+			// Remove alive and port listeners
 			C.removeDeviceAliveListener(deviceID)
+			C.updatePortListeners(deviceID, 0)
 			delete(deviceStates, deviceID)
 		}
 	}
@@ -561,20 +895,43 @@ func handleDeviceListChange(callback func(Event)) {
 func handleActiveDeviceChange(callback func(Event)) {
 	// Get the new active output device
 	device, err := GetActiveOutputDevice()
-	if err == nil {
+	if err != nil {
+		logging.Ignored("get active output device", err)
+		return
+	}
+	callback(Event{
+		Type:     ActiveDeviceChanged,
+		DeviceID: device.ID,
+		Info:     &device,
+	})
+}
+
+// addDeviceListeners listens for a device going away and for changes of its
+// data sources and jacks
+func addDeviceListeners(deviceID C.AudioObjectID) {
+	if status := C.addDeviceAliveListener(deviceID); status != C.noErr {
+		logging.Ignored("add device alive listener", &StatusError{Op: "add device alive listener", Status: int32(status)},
+			slog.Uint64("object", uint64(deviceID)))
+	}
+	C.updatePortListeners(deviceID, 1)
+}
+
+func handleDeviceDisconnected(callback func(Event), deviceID C.AudioObjectID) {
+	device := getDeviceInfo(deviceID)
+	if device != nil {
 		callback(Event{
-			Type:     ActiveDeviceChanged,
+			Type:     DeviceDisconnected,
 			DeviceID: device.ID,
-			Info:     &device,
+			Info:     device,
 		})
 	}
 }
 
-func handleDeviceDisconnected(callback func(Event), deviceID C.AudioObjectID) {
+func handlePortChanged(callback func(Event), deviceID C.AudioObjectID) {
 	device := getDeviceInfo(deviceID)
 	if device != nil {
 		callback(Event{
-			Type:     DeviceDisconnected,
+			Type:     PortChanged,
 			DeviceID: device.ID,
 			Info:     device,
 		})
@@ -606,20 +963,50 @@ func getDeviceInfo(deviceID C.AudioObjectID) *AudioDevice {
 	// Check if active
 	defaultInputID := C.getDefaultDevice(1)
 	defaultOutputID := C.getDefaultDevice(0)
-	isActive := (hasInput && deviceID == defaultInputID) || (hasOutput && deviceID == defaultOutputID)
+	isDefaultInput := hasInput && deviceID == defaultInputID
+	isDefaultOutput := hasOutput && deviceID == defaultOutputID
 
 	return &AudioDevice{
 		ID:          uid,
 		Name:        name,
 		IsInput:     hasInput,
 		IsOutput:    hasOutput,
-		IsActive:    isActive,
+		IsActive:    isDefaultInput || isDefaultOutput,
 		IsConnected: isConnected,
+
+		IsDefaultOutput:         isDefaultOutput,
+		IsDefaultInput:          isDefaultInput,
+		IsDefaultCommunications: isDefaultInput || isDefaultOutput,
+		State:                   connectedState(isConnected),
+		Ports:                   devicePorts(deviceID, hasInput, hasOutput),
+		Identity:                deviceIdentity(deviceID, uid),
 	}
 }
 
-// OnDeviceChange registers a callback for audio device events
-func OnDeviceChange(callback func(Event)) {
+// deviceIdentity reads the durable properties of a device. The UID of a
+// Bluetooth device starts with its address, formatted differently across
+// macOS versions, such as "AC-BC-32-0A-1B-2C:output".
+func deviceIdentity(deviceID C.AudioObjectID, uid string) Identity {
+	var id Identity
+	if modelPtr := C.getDeviceStringProperty(deviceID, C.kAudioDevicePropertyModelUID); modelPtr != nil {
+		id.ModelUID = C.GoString(modelPtr)
+		C.free(unsafe.Pointer(modelPtr))
+	}
+	switch C.getTransportKind(deviceID) {
+	case 1:
+		id.Bus = "bluetooth"
+		id.BluetoothAddress, _, _ = strings.Cut(uid, ":")
+	case 2:
+		id.Bus = "usb"
+	case 3:
+		id.Bus = "builtin"
+	}
+	return id
+}
+
+// OnDeviceChange registers a callback for audio device events. It returns
+// an error if the system listeners cannot be added.
+func OnDeviceChange(callback func(Event)) error {
 	callbackMutex.Lock()
 	userCallback = callback
 	callbackMutex.Unlock()
@@ -632,13 +1019,16 @@ func OnDeviceChange(callback func(Event)) {
 		stateMutex.Lock()
 		for _, deviceID := range deviceArray {
 			deviceStates[deviceID] = true
-			// Add alive listener for each device
-			C.addDeviceAliveListener(deviceID)
+			// Add alive and port listeners for each device
+			addDeviceListeners(deviceID)
 		}
 		stateMutex.Unlock()
 		C.free(unsafe.Pointer(deviceIDs))
 	}
 
 	// Start monitoring
-	C.startMonitoring()
+	if status := C.startMonitoring(); status != C.noErr {
+		return &StatusError{Op: "add property listener", Status: int32(status)}
+	}
+	return nil
 }
@@ -0,0 +1,84 @@
+//go:build darwin
+// +build darwin
+
+package darwin
+
+/*
+#include <CoreAudio/CoreAudio.h>
+
+// Get a UInt32 property of a device, or 0 if it has none
+static UInt32 getDeviceUInt32(AudioObjectID deviceID, AudioObjectPropertySelector selector, AudioObjectPropertyScope scope) {
+    AudioObjectPropertyAddress address = {
+        selector,
+        scope,
+        kAudioObjectPropertyElementMain
+    };
+
+    UInt32 value = 0;
+    UInt32 size = sizeof(value);
+    if (!AudioObjectHasProperty(deviceID, &address) ||
+        AudioObjectGetPropertyData(deviceID, &address, 0, NULL, &size, &value) != noErr) {
+        return 0;
+    }
+    return value;
+}
+
+// Get the latency in frames of the first stream of a device in scope
+static UInt32 getStreamLatency(AudioObjectID deviceID, AudioObjectPropertyScope scope) {
+    AudioObjectPropertyAddress address = {
+        kAudioDevicePropertyStreams,
+        scope,
+        kAudioObjectPropertyElementMain
+    };
+
+    AudioStreamID stream = 0;
+    UInt32 size = sizeof(stream);
+    if (AudioObjectGetPropertyData(deviceID, &address, 0, NULL, &size, &stream) != noErr || size == 0) {
+        return 0;
+    }
+    return getDeviceUInt32(stream, kAudioStreamPropertyLatency, kAudioObjectPropertyScopeGlobal);
+}
+
+// Get the nominal sample rate of a device
+static Float64 getNominalSampleRate(AudioObjectID deviceID) {
+    AudioObjectPropertyAddress address = {
+        kAudioDevicePropertyNominalSampleRate,
+        kAudioObjectPropertyScopeGlobal,
+        kAudioObjectPropertyElementMain
+    };
+
+    Float64 rate = 0;
+    UInt32 size = sizeof(rate);
+    if (AudioObjectGetPropertyData(deviceID, &address, 0, NULL, &size, &rate) != noErr) {
+        return 0;
+    }
+    return rate;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"time"
+)
+
+// DeviceLatency returns the latency CoreAudio reports for a device: its
+// kAudioDevicePropertyLatency plus the safety offset, the IO buffer and
+// the latency of its first stream. Devices with both directions report
+// their output.
+func DeviceLatency(deviceUID string) (time.Duration, error) {
+	deviceID, scope, err := findVolumeTarget(deviceUID)
+	if err != nil {
+		return 0, err
+	}
+	rate := float64(C.getNominalSampleRate(deviceID))
+	if rate <= 0 {
+		return 0, fmt.Errorf("failed to get the sample rate of %s", deviceUID)
+	}
+
+	frames := C.getDeviceUInt32(deviceID, C.kAudioDevicePropertyLatency, scope) +
+		C.getDeviceUInt32(deviceID, C.kAudioDevicePropertySafetyOffset, scope) +
+		C.getDeviceUInt32(deviceID, C.kAudioDevicePropertyBufferFrameSize, scope) +
+		C.getStreamLatency(deviceID, scope)
+	return time.Duration(float64(frames) / rate * float64(time.Second)), nil
+}
@@ -0,0 +1,271 @@
+//go:build darwin
+// +build darwin
+
+package darwin
+
+/*
+#include <CoreAudio/CoreAudio.h>
+#include <stdlib.h>
+
+// Get the data source IDs a device offers in scope
+static UInt32* getDataSources(AudioObjectID deviceID, AudioObjectPropertyScope scope, int* count) {
+    AudioObjectPropertyAddress address = {
+        kAudioDevicePropertyDataSources,
+        scope,
+        kAudioObjectPropertyElementMain
+    };
+
+    *count = 0;
+    UInt32 size = 0;
+    if (!AudioObjectHasProperty(deviceID, &address) ||
+        AudioObjectGetPropertyDataSize(deviceID, &address, 0, NULL, &size) != noErr ||
+        size == 0) {
+        return NULL;
+    }
+
+    UInt32* sources = (UInt32*)malloc(size);
+    if (AudioObjectGetPropertyData(deviceID, &address, 0, NULL, &size, sources) != noErr) {
+        free(sources);
+        return NULL;
+    }
+
+    *count = size / sizeof(UInt32);
+    return sources;
+}
+
+// Get the current data source of a device
+static OSStatus getDataSource(AudioObjectID deviceID, AudioObjectPropertyScope scope, UInt32* source) {
+    AudioObjectPropertyAddress address = {
+        kAudioDevicePropertyDataSource,
+        scope,
+        kAudioObjectPropertyElementMain
+    };
+
+    UInt32 size = sizeof(UInt32);
+    return AudioObjectGetPropertyData(deviceID, &address, 0, NULL, &size, source);
+}
+
+// Set the current data source of a device
+static OSStatus setDataSource(AudioObjectID deviceID, AudioObjectPropertyScope scope, UInt32 source) {
+    AudioObjectPropertyAddress address = {
+        kAudioDevicePropertyDataSource,
+        scope,
+        kAudioObjectPropertyElementMain
+    };
+
+    return AudioObjectSetPropertyData(deviceID, &address, 0, NULL, sizeof(source), &source);
+}
+
+// Get the display name of a data source
+static char* getDataSourceName(AudioObjectID deviceID, AudioObjectPropertyScope scope, UInt32 source) {
+    CFStringRef name = NULL;
+    AudioValueTranslation translation = {
+        &source, sizeof(source),
+        &name, sizeof(name)
+    };
+    UInt32 size = sizeof(translation);
+
+    AudioObjectPropertyAddress address = {
+        kAudioDevicePropertyDataSourceNameForIDCFString,
+        scope,
+        kAudioObjectPropertyElementMain
+    };
+
+    OSStatus status = AudioObjectGetPropertyData(deviceID, &address, 0, NULL, &size, &translation);
+    if (status != noErr || name == NULL) {
+        return NULL;
+    }
+
+    CFIndex length = CFStringGetLength(name);
+    CFIndex maxSize = CFStringGetMaximumSizeForEncoding(length, kCFStringEncodingUTF8) + 1;
+    char *buffer = (char *)malloc(maxSize);
+
+    if (CFStringGetCString(name, buffer, maxSize, kCFStringEncodingUTF8)) {
+        CFRelease(name);
+        return buffer;
+    }
+
+    CFRelease(name);
+    free(buffer);
+    return NULL;
+}
+
+// Report whether something is plugged into the jack of a device: 1 or 0, or
+// -1 when the device has no jack detection
+static int getJackConnected(AudioObjectID deviceID, AudioObjectPropertyScope scope) {
+    AudioObjectPropertyAddress address = {
+        kAudioDevicePropertyJackIsConnected,
+        scope,
+        kAudioObjectPropertyElementMain
+    };
+
+    UInt32 connected = 0;
+    UInt32 size = sizeof(connected);
+    if (!AudioObjectHasProperty(deviceID, &address) ||
+        AudioObjectGetPropertyData(deviceID, &address, 0, NULL, &size, &connected) != noErr) {
+        return -1;
+    }
+    return connected ? 1 : 0;
+}
+*/
+import "C"
+import (
+	"fmt"
+	"unsafe"
+)
+
+// Port is a data source of a device, such as the internal speakers or the
+// headphone jack. Its name is the four-character code of the data source.
+type Port struct {
+	Name        string
+	Description string
+	Type        PortType
+	Available   PortAvailability
+	Priority    uint32
+	IsActive    bool
+}
+
+// PortType classifies what a port connects to
+type PortType int
+
+const (
+	PortTypeUnknown PortType = iota
+	PortTypeSpeaker
+	PortTypeHeadphones
+	PortTypeHeadset
+	PortTypeMicrophone
+	PortTypeLine
+	PortTypeHDMI
+	PortTypeDigital
+)
+
+// PortAvailability reports whether something is plugged into a port
+type PortAvailability int
+
+const (
+	PortAvailabilityUnknown PortAvailability = iota
+	PortAvailable
+	PortUnavailable
+)
+
+// dataSourceTypes maps the data sources of built-in devices to port types
+var dataSourceTypes = map[string]PortType{
+	"ispk": PortTypeSpeaker,
+	"hdpn": PortTypeHeadphones,
+	"imic": PortTypeMicrophone,
+	"emic": PortTypeHeadset,
+}
+
+// fourCC formats a data source ID as its four-character code
+func fourCC(id C.UInt32) string {
+	return string([]byte{byte(id >> 24), byte(id >> 16), byte(id >> 8), byte(id)})
+}
+
+// parseFourCC is the inverse of fourCC
+func parseFourCC(code string) (C.UInt32, error) {
+	if len(code) != 4 {
+		return 0, fmt.Errorf("invalid data source %q", code)
+	}
+	return C.UInt32(code[0])<<24 | C.UInt32(code[1])<<16 | C.UInt32(code[2])<<8 | C.UInt32(code[3]), nil
+}
+
+// scopePorts returns the data sources of a device in one scope. CoreAudio
+// only reports the jack of the current data source, so the availability of
+// the other ports is unknown.
+func scopePorts(deviceID C.AudioObjectID, scope C.AudioObjectPropertyScope) []Port {
+	var count C.int
+	sources := C.getDataSources(deviceID, scope, &count)
+	if sources == nil {
+		return nil
+	}
+	defer C.free(unsafe.Pointer(sources))
+
+	var current C.UInt32
+	hasCurrent := C.getDataSource(deviceID, scope, &current) == C.noErr
+	jack := C.getJackConnected(deviceID, scope)
+
+	sourceArray := (*[1 << 20]C.UInt32)(unsafe.Pointer(sources))[:count:count]
+	ports := make([]Port, 0, len(sourceArray))
+	for _, source := range sourceArray {
+		port := Port{
+			Name:     fourCC(source),
+			Type:     dataSourceTypes[fourCC(source)],
+			IsActive: hasCurrent && source == current,
+		}
+		if namePtr := C.getDataSourceName(deviceID, scope, source); namePtr != nil {
+			port.Description = C.GoString(namePtr)
+			C.free(unsafe.Pointer(namePtr))
+		}
+		if port.IsActive && jack >= 0 {
+			port.Available = PortUnavailable
+			if jack == 1 {
+				port.Available = PortAvailable
+			}
+		}
+		ports = append(ports, port)
+	}
+	return ports
+}
+
+// devicePorts returns the output and input data sources of a device
+func devicePorts(deviceID C.AudioObjectID, hasInput, hasOutput bool) []Port {
+	var ports []Port
+	if hasOutput {
+		ports = append(ports, scopePorts(deviceID, C.kAudioDevicePropertyScopeOutput)...)
+	}
+	if hasInput {
+		ports = append(ports, scopePorts(deviceID, C.kAudioDevicePropertyScopeInput)...)
+	}
+	return ports
+}
+
+// ListPorts returns the data sources of a device
+func ListPorts(deviceUID string) ([]Port, error) {
+	outputID := findDeviceByUID(deviceUID, C.kAudioDevicePropertyScopeOutput)
+	inputID := findDeviceByUID(deviceUID, C.kAudioDevicePropertyScopeInput)
+
+	var ports []Port
+	if outputID != C.kAudioObjectUnknown {
+		ports = append(ports, scopePorts(outputID, C.kAudioDevicePropertyScopeOutput)...)
+	}
+	if inputID != C.kAudioObjectUnknown {
+		ports = append(ports, scopePorts(inputID, C.kAudioDevicePropertyScopeInput)...)
+	}
+	if outputID == C.kAudioObjectUnknown && inputID == C.kAudioObjectUnknown {
+		return nil, fmt.Errorf("device with UID %s: %w", deviceUID, ErrDeviceNotFound)
+	}
+	return ports, nil
+}
+
+// SetActivePort selects the data source named by its four-character code
+func SetActivePort(deviceUID, port string) error {
+	source, err := parseFourCC(port)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, scope := range []C.AudioObjectPropertyScope{C.kAudioDevicePropertyScopeOutput, C.kAudioDevicePropertyScopeInput} {
+		deviceID := findDeviceByUID(deviceUID, scope)
+		if deviceID == C.kAudioObjectUnknown {
+			continue
+		}
+		found = true
+
+		for _, p := range scopePorts(deviceID, scope) {
+			if p.Name != port {
+				continue
+			}
+			status := C.setDataSource(deviceID, scope, source)
+			if status != C.noErr {
+				return &StatusError{Op: "set data source", Status: int32(status)}
+			}
+			return nil
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("device with UID %s: %w", deviceUID, ErrDeviceNotFound)
+	}
+	return fmt.Errorf("device with UID %s has no port %s", deviceUID, port)
+}
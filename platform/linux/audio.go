@@ -0,0 +1,567 @@
+//go:build linux
+// +build linux
+
+// Package linux controls audio devices on Linux through the PulseAudio
+// native protocol, which is served both by PulseAudio and by PipeWire's
+// pipewire-pulse. No C libraries are required.
+//
+// Sinks are reported as output devices and sources as input devices, with
+// the sink or source name as the device ID. Monitor sources are not listed.
+package linux
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/audi70r/go-audio-control/internal/pulse"
+)
+
+// AudioDevice represents an audio device
+type AudioDevice struct {
+	ID          string
+	Name        string
+	IsInput     bool
+	IsOutput    bool
+	IsActive    bool
+	IsConnected bool
+
+	IsDefaultOutput         bool
+	IsDefaultInput          bool
+	IsDefaultCommunications bool
+	State                   DeviceState
+	Ports                   []Port
+	Identity                Identity
+}
+
+// Identity holds durable properties of the hardware behind a device
+type Identity struct {
+	Bus              string
+	VendorID         string
+	ProductID        string
+	Serial           string
+	BluetoothAddress string
+	ModelUID         string
+	Endpoint         string
+}
+
+// DeviceState is the presence state of a device
+type DeviceState int
+
+const (
+	StateActive DeviceState = iota
+	StateDisabled
+	StateNotPresent
+	StateUnplugged
+)
+
+// EventType represents the type of audio device event
+type EventType int
+
+const (
+	DeviceAdded EventType = iota
+	DeviceRemoved
+	ActiveDeviceChanged
+	DeviceDisconnected
+	PortChanged
+	ProfileChanged
+	SessionAdded
+	SessionRemoved
+	ServerConnected
+	ServerDisconnected
+)
+
+// Event represents an audio device event. ProfileChanged events carry the
+// card ID and Card instead of a device; session events carry the Session
+// and the ID of its device. ServerConnected and ServerDisconnected carry
+// neither.
+type Event struct {
+	Type     EventType
+	DeviceID string
+	Info     *AudioDevice
+	Card     *Card
+	Session  *Session
+}
+
+// ErrDeviceNotFound is returned for IDs that name no sink or source
+var ErrDeviceNotFound = errors.New("device not found")
+
+// clientName is the application name announced to the server
+const clientName = "go-audio-control"
+
+// Controller is a connection to a sound server. It connects on first use
+// and reconnects on the next call after the connection is lost. Once a
+// callback is registered it also reconnects on its own; see OnDeviceChange.
+type Controller struct {
+	server string
+
+	mu        sync.Mutex
+	client    *pulse.Client
+	callbacks []func(Event)
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	// State used to describe events, which carry only an object index
+	stateMu       sync.Mutex
+	sinks         map[uint32]AudioDevice
+	sources       map[uint32]AudioDevice
+	defaultSink   string
+	defaultSource string
+	profiles      map[uint32]string
+	cardNames     map[uint32]string
+	sessions      map[string]Session
+	// synced is set once the state above has been read by a subscription,
+	// and resync holds the events found by comparing it with the state read
+	// after a reconnection
+	synced bool
+	resync []Event
+
+	// Modules loaded by CreateVirtualDevice, by index
+	virtualMu sync.Mutex
+	virtual   map[uint32]*VirtualDevice
+}
+
+// NewController returns a Controller for server in PULSE_SERVER syntax. An
+// empty server selects the user's default server.
+func NewController(server string) *Controller {
+	return &Controller{
+		server:    server,
+		sinks:     make(map[uint32]AudioDevice),
+		sources:   make(map[uint32]AudioDevice),
+		profiles:  make(map[uint32]string),
+		cardNames: make(map[uint32]string),
+		sessions:  make(map[string]Session),
+		virtual:   make(map[uint32]*VirtualDevice),
+		closed:    make(chan struct{}),
+	}
+}
+
+// Close closes the connection and stops reconnecting in the background
+func (c *Controller) Close() {
+	c.closeOnce.Do(func() { close(c.closed) })
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != nil {
+		c.client.Close()
+		c.client = nil
+	}
+}
+
+// conn returns the current connection, dialling and resubscribing if needed
+func (c *Controller) conn() (*pulse.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.client != nil {
+		select {
+		case <-c.client.Done():
+			c.client = nil
+		default:
+			return c.client, nil
+		}
+	}
+
+	server := c.server
+	if server == "" {
+		server = pulse.DefaultServer()
+	}
+	client, err := pulse.Dial(server, clientName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to sound server: %w", err)
+	}
+	if len(c.callbacks) > 0 {
+		if err := c.subscribe(client); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	c.client = client
+	return client, nil
+}
+
+// request sends one request on the current connection
+func (c *Controller) request(cmd pulse.Command, args ...interface{}) (*pulse.Tagstruct, error) {
+	client, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+	return client.Request(cmd, args...)
+}
+
+// snapshot is the device state read in one pass
+type snapshot struct {
+	server  pulse.ServerInfo
+	sinks   []pulse.SinkInfo
+	sources []pulse.SourceInfo
+}
+
+func readSnapshot(client *pulse.Client) (snapshot, error) {
+	var s snapshot
+
+	reply, err := client.Request(pulse.CommandGetServerInfo)
+	if err != nil {
+		return s, fmt.Errorf("failed to get server info: %w", err)
+	}
+	s.server = pulse.ReadServerInfo(reply)
+	if err := reply.Err(); err != nil {
+		return s, fmt.Errorf("failed to parse server info: %w", err)
+	}
+
+	reply, err = client.Request(pulse.CommandGetSinkInfoList)
+	if err != nil {
+		return s, fmt.Errorf("failed to list sinks: %w", err)
+	}
+	for !reply.Eof() && reply.Err() == nil {
+		s.sinks = append(s.sinks, pulse.ReadSinkInfo(reply))
+	}
+	if err := reply.Err(); err != nil {
+		return s, fmt.Errorf("failed to parse sinks: %w", err)
+	}
+
+	reply, err = client.Request(pulse.CommandGetSourceInfoList)
+	if err != nil {
+		return s, fmt.Errorf("failed to list sources: %w", err)
+	}
+	for !reply.Eof() && reply.Err() == nil {
+		source := pulse.ReadSourceInfo(reply)
+		if !source.IsMonitor() {
+			s.sources = append(s.sources, source)
+		}
+	}
+	if err := reply.Err(); err != nil {
+		return s, fmt.Errorf("failed to parse sources: %w", err)
+	}
+	return s, nil
+}
+
+// portState reports a device whose active port has nothing plugged in as
+// unplugged. Devices without ports, or whose availability is unknown, are
+// active.
+func portState(ports []pulse.PortInfo, active string) DeviceState {
+	for _, port := range ports {
+		if port.Name == active && port.Available == pulse.AvailableNo {
+			return StateUnplugged
+		}
+	}
+	return StateActive
+}
+
+// deviceIdentity reads the udev and Bluetooth properties of a sink or
+// source. PipeWire names the Bluetooth address api.bluez5.address; PulseAudio
+// puts it in device.string.
+func deviceIdentity(props pulse.Proplist) Identity {
+	id := Identity{
+		Bus:              props["device.bus"],
+		VendorID:         props["device.vendor.id"],
+		ProductID:        props["device.product.id"],
+		Serial:           props["device.serial"],
+		BluetoothAddress: props["api.bluez5.address"],
+		Endpoint:         props["alsa.device"],
+	}
+	if id.BluetoothAddress == "" && id.Bus == "bluetooth" {
+		id.BluetoothAddress = props["device.string"]
+	}
+	return id
+}
+
+// PulseAudio has no separate communications role, so the default sink and
+// source are also the communications defaults
+
+func sinkDevice(sink pulse.SinkInfo, defaultSink string) AudioDevice {
+	isDefault := sink.Name == defaultSink
+	return AudioDevice{
+		ID:                      sink.Name,
+		Name:                    sink.Description,
+		IsOutput:                true,
+		IsActive:                isDefault,
+		IsConnected:             true,
+		IsDefaultOutput:         isDefault,
+		IsDefaultCommunications: isDefault,
+		State:                   portState(sink.Ports, sink.ActivePort),
+		Ports:                   devicePorts(sink.Ports, sink.ActivePort),
+		Identity:                deviceIdentity(sink.Properties),
+	}
+}
+
+func sourceDevice(source pulse.SourceInfo, defaultSource string) AudioDevice {
+	isDefault := source.Name == defaultSource
+	return AudioDevice{
+		ID:                      source.Name,
+		Name:                    source.Description,
+		IsInput:                 true,
+		IsActive:                isDefault,
+		IsConnected:             true,
+		IsDefaultInput:          isDefault,
+		IsDefaultCommunications: isDefault,
+		State:                   portState(source.Ports, source.ActivePort),
+		Ports:                   devicePorts(source.Ports, source.ActivePort),
+		Identity:                deviceIdentity(source.Properties),
+	}
+}
+
+// remember replaces the cached state used to describe events. Only
+// subscribe calls it: the handlers keep the state current from then on, and
+// replacing it while notifications are queued would hide their changes.
+func (c *Controller) remember(s snapshot) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+
+	c.sinks = make(map[uint32]AudioDevice, len(s.sinks))
+	for _, sink := range s.sinks {
+		c.sinks[sink.Index] = sinkDevice(sink, s.server.DefaultSinkName)
+	}
+	c.sources = make(map[uint32]AudioDevice, len(s.sources))
+	for _, source := range s.sources {
+		c.sources[source.Index] = sourceDevice(source, s.server.DefaultSourceName)
+	}
+	c.defaultSink = s.server.DefaultSinkName
+	c.defaultSource = s.server.DefaultSourceName
+}
+
+// ListAudioDevices enumerates sinks and sources
+func (c *Controller) ListAudioDevices() ([]AudioDevice, error) {
+	client, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+	s, err := readSnapshot(client)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]AudioDevice, 0, len(s.sinks)+len(s.sources))
+	for _, sink := range s.sinks {
+		devices = append(devices, sinkDevice(sink, s.server.DefaultSinkName))
+	}
+	for _, source := range s.sources {
+		devices = append(devices, sourceDevice(source, s.server.DefaultSourceName))
+	}
+	return devices, nil
+}
+
+// GetActiveOutputDevice returns the default sink
+func (c *Controller) GetActiveOutputDevice() (AudioDevice, error) {
+	reply, err := c.request(pulse.CommandGetServerInfo)
+	if err != nil {
+		return AudioDevice{}, fmt.Errorf("failed to get server info: %w", err)
+	}
+	name := pulse.ReadServerInfo(reply).DefaultSinkName
+	if err := reply.Err(); err != nil {
+		return AudioDevice{}, fmt.Errorf("failed to parse server info: %w", err)
+	}
+	if name == "" {
+		return AudioDevice{}, fmt.Errorf("no default output device found")
+	}
+	sink, err := c.sinkInfo(name)
+	if err != nil {
+		return AudioDevice{}, err
+	}
+	return sinkDevice(sink, name), nil
+}
+
+// SetActiveOutputDevice makes a sink the default
+func (c *Controller) SetActiveOutputDevice(deviceID string) error {
+	if _, err := c.sinkInfo(deviceID); err != nil {
+		return err
+	}
+	if _, err := c.request(pulse.CommandSetDefaultSink, deviceID); err != nil {
+		return fmt.Errorf("failed to set default sink: %w", err)
+	}
+	return nil
+}
+
+// GetActiveInputDevice returns the default source
+func (c *Controller) GetActiveInputDevice() (AudioDevice, error) {
+	reply, err := c.request(pulse.CommandGetServerInfo)
+	if err != nil {
+		return AudioDevice{}, fmt.Errorf("failed to get server info: %w", err)
+	}
+	name := pulse.ReadServerInfo(reply).DefaultSourceName
+	if err := reply.Err(); err != nil {
+		return AudioDevice{}, fmt.Errorf("failed to parse server info: %w", err)
+	}
+	if name == "" {
+		return AudioDevice{}, fmt.Errorf("no default input device found")
+	}
+	source, err := c.sourceInfo(name)
+	if err != nil {
+		return AudioDevice{}, err
+	}
+	return sourceDevice(source, name), nil
+}
+
+// SetActiveInputDevice makes a source the default
+func (c *Controller) SetActiveInputDevice(deviceID string) error {
+	if _, err := c.sourceInfo(deviceID); err != nil {
+		return err
+	}
+	if _, err := c.request(pulse.CommandSetDefaultSource, deviceID); err != nil {
+		return fmt.Errorf("failed to set default source: %w", err)
+	}
+	return nil
+}
+
+// sinkInfo looks up a sink by name
+func (c *Controller) sinkInfo(name string) (pulse.SinkInfo, error) {
+	reply, err := c.request(pulse.CommandGetSinkInfo, uint32(pulse.Invalid), name)
+	if errors.Is(err, pulse.ErrNoEntity) {
+		return pulse.SinkInfo{}, fmt.Errorf("output %s: %w", name, ErrDeviceNotFound)
+	}
+	if err != nil {
+		return pulse.SinkInfo{}, fmt.Errorf("failed to get sink info: %w", err)
+	}
+	sink := pulse.ReadSinkInfo(reply)
+	return sink, reply.Err()
+}
+
+// sourceInfo looks up a source by name. Monitor sources are not devices.
+func (c *Controller) sourceInfo(name string) (pulse.SourceInfo, error) {
+	reply, err := c.request(pulse.CommandGetSourceInfo, uint32(pulse.Invalid), name)
+	if errors.Is(err, pulse.ErrNoEntity) {
+		return pulse.SourceInfo{}, fmt.Errorf("input %s: %w", name, ErrDeviceNotFound)
+	}
+	if err != nil {
+		return pulse.SourceInfo{}, fmt.Errorf("failed to get source info: %w", err)
+	}
+	source := pulse.ReadSourceInfo(reply)
+	if reply.Err() != nil {
+		return source, reply.Err()
+	}
+	if source.IsMonitor() {
+		return pulse.SourceInfo{}, fmt.Errorf("input %s: %w", name, ErrDeviceNotFound)
+	}
+	return source, nil
+}
+
+// volumeTarget is the sink or source a volume or mute request applies to
+type volumeTarget struct {
+	isSink   bool
+	volume   pulse.CVolume
+	muted    bool
+	channels int
+}
+
+func (c *Controller) volumeTarget(deviceID string) (volumeTarget, error) {
+	sink, err := c.sinkInfo(deviceID)
+	if err == nil {
+		return volumeTarget{isSink: true, volume: sink.Volume, muted: sink.Mute, channels: int(sink.SampleSpec.Channels)}, nil
+	}
+	if !errors.Is(err, ErrDeviceNotFound) {
+		return volumeTarget{}, err
+	}
+
+	source, err := c.sourceInfo(deviceID)
+	if errors.Is(err, ErrDeviceNotFound) {
+		return volumeTarget{}, fmt.Errorf("device %s: %w", deviceID, ErrDeviceNotFound)
+	}
+	if err != nil {
+		return volumeTarget{}, err
+	}
+	return volumeTarget{volume: source.Volume, muted: source.Mute, channels: int(source.SampleSpec.Channels)}, nil
+}
+
+// GetVolume returns the average channel volume of a device. Volumes above
+// 100% are reported as 1.0.
+func (c *Controller) GetVolume(deviceID string) (float64, error) {
+	target, err := c.volumeTarget(deviceID)
+	if err != nil {
+		return 0, err
+	}
+	return math.Min(target.volume.Avg().Float(), 1.0), nil
+}
+
+// SetVolume sets every channel of a device to volume
+func (c *Controller) SetVolume(deviceID string, volume float64) error {
+	target, err := c.volumeTarget(deviceID)
+	if err != nil {
+		return err
+	}
+	cmd := pulse.CommandSetSourceVolume
+	if target.isSink {
+		cmd = pulse.CommandSetSinkVolume
+	}
+	cv := pulse.NewCVolume(target.channels, pulse.VolumeFromFloat(volume))
+	if _, err := c.request(cmd, uint32(pulse.Invalid), deviceID, cv); err != nil {
+		return fmt.Errorf("failed to set volume: %w", err)
+	}
+	return nil
+}
+
+// GetMute reports whether a device is muted
+func (c *Controller) GetMute(deviceID string) (bool, error) {
+	target, err := c.volumeTarget(deviceID)
+	if err != nil {
+		return false, err
+	}
+	return target.muted, nil
+}
+
+// SetMute mutes or unmutes a device
+func (c *Controller) SetMute(deviceID string, muted bool) error {
+	target, err := c.volumeTarget(deviceID)
+	if err != nil {
+		return err
+	}
+	cmd := pulse.CommandSetSourceMute
+	if target.isSink {
+		cmd = pulse.CommandSetSinkMute
+	}
+	if _, err := c.request(cmd, uint32(pulse.Invalid), deviceID, muted); err != nil {
+		return fmt.Errorf("failed to set mute: %w", err)
+	}
+	return nil
+}
+
+// defaultController serves the package-level functions
+var defaultController = NewController("")
+
+// ListAudioDevices enumerates sinks and sources on the default server
+func ListAudioDevices() ([]AudioDevice, error) {
+	return defaultController.ListAudioDevices()
+}
+
+// GetActiveOutputDevice returns the default sink
+func GetActiveOutputDevice() (AudioDevice, error) {
+	return defaultController.GetActiveOutputDevice()
+}
+
+// SetActiveOutputDevice makes a sink the default
+func SetActiveOutputDevice(deviceID string) error {
+	return defaultController.SetActiveOutputDevice(deviceID)
+}
+
+// GetActiveInputDevice returns the default source
+func GetActiveInputDevice() (AudioDevice, error) {
+	return defaultController.GetActiveInputDevice()
+}
+
+// SetActiveInputDevice makes a source the default
+func SetActiveInputDevice(deviceID string) error {
+	return defaultController.SetActiveInputDevice(deviceID)
+}
+
+// GetVolume returns the volume of a device
+func GetVolume(deviceID string) (float64, error) {
+	return defaultController.GetVolume(deviceID)
+}
+
+// SetVolume sets the volume of a device
+func SetVolume(deviceID string, volume float64) error {
+	return defaultController.SetVolume(deviceID, volume)
+}
+
+// GetMute reports whether a device is muted
+func GetMute(deviceID string) (bool, error) {
+	return defaultController.GetMute(deviceID)
+}
+
+// SetMute mutes or unmutes a device
+func SetMute(deviceID string, muted bool) error {
+	return defaultController.SetMute(deviceID, muted)
+}
+
+// OnDeviceChange registers a callback for device events
+func OnDeviceChange(callback func(Event)) error {
+	return defaultController.OnDeviceChange(callback)
+}
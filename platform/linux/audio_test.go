@@ -0,0 +1,889 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/audi70r/go-audio-control/internal/dsp"
+	"github.com/audi70r/go-audio-control/internal/pulse"
+	"github.com/audi70r/go-audio-control/internal/pulse/pulsetest"
+)
+
+func newTestController(t *testing.T) (*Controller, *pulsetest.Server) {
+	t.Helper()
+	server := pulsetest.NewServer(t)
+	server.AddSink(pulsetest.NewSink("speakers", "Speakers"))
+	server.AddSource(pulsetest.NewSource("mic", "Microphone"))
+
+	c := NewController(server.Address())
+	t.Cleanup(c.Close)
+	return c, server
+}
+
+func waitEvent(t *testing.T, events <-chan Event, want EventType, deviceID string) Event {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case e := <-events:
+			if e.Type == want && e.DeviceID == deviceID {
+				return e
+			}
+		case <-timeout:
+			t.Fatalf("No event %d for %s", want, deviceID)
+		}
+	}
+}
+
+func TestListAudioDevices(t *testing.T) {
+	c, _ := newTestController(t)
+
+	devices, err := c.ListAudioDevices()
+	if err != nil {
+		t.Fatalf("Failed to list audio devices: %v", err)
+	}
+	// The monitor of "speakers" is not a device
+	if len(devices) != 2 {
+		t.Fatalf("Expected 2 devices, got %+v", devices)
+	}
+	want := []AudioDevice{
+		{ID: "speakers", Name: "Speakers", IsOutput: true, IsActive: true, IsConnected: true, IsDefaultOutput: true, IsDefaultCommunications: true},
+		{ID: "mic", Name: "Microphone", IsInput: true, IsActive: true, IsConnected: true, IsDefaultInput: true, IsDefaultCommunications: true},
+	}
+	for i := range want {
+		if !reflect.DeepEqual(devices[i], want[i]) {
+			t.Errorf("Device %d is %+v, want %+v", i, devices[i], want[i])
+		}
+	}
+
+	if _, err := c.GetVolume("speakers.monitor"); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound for a monitor source, got %v", err)
+	}
+}
+
+func TestDeviceIdentity(t *testing.T) {
+	c, server := newTestController(t)
+
+	usb := pulsetest.NewSink("alsa_output.usb-Burr-Brown_USB_Audio_CODEC-00.analog-stereo", "USB Audio CODEC")
+	usb.Properties = pulse.Proplist{
+		"device.bus":        "usb",
+		"device.vendor.id":  "08bb",
+		"device.product.id": "2902",
+		"device.serial":     "Burr-Brown_USB_Audio_CODEC",
+		"alsa.device":       "0",
+	}
+	server.AddSink(usb)
+	headset := pulsetest.NewSink("bluez_sink.AC_BC_32_0A_1B_2C.a2dp_sink", "Headset")
+	headset.Properties = pulse.Proplist{"device.bus": "bluetooth", "device.string": "AC:BC:32:0A:1B:2C"}
+	server.AddSink(headset)
+	pipewire := pulsetest.NewSource("bluez_input.AC:BC:32:0A:1B:2D", "Headset Microphone")
+	pipewire.Properties = pulse.Proplist{"device.bus": "bluetooth", "api.bluez5.address": "AC:BC:32:0A:1B:2D"}
+	server.AddSource(pipewire)
+
+	devices, err := c.ListAudioDevices()
+	if err != nil {
+		t.Fatalf("Failed to list audio devices: %v", err)
+	}
+	want := map[string]Identity{
+		usb.Name:      {Bus: "usb", VendorID: "08bb", ProductID: "2902", Serial: "Burr-Brown_USB_Audio_CODEC", Endpoint: "0"},
+		headset.Name:  {Bus: "bluetooth", BluetoothAddress: "AC:BC:32:0A:1B:2C"},
+		pipewire.Name: {Bus: "bluetooth", BluetoothAddress: "AC:BC:32:0A:1B:2D"},
+		"speakers":    {},
+	}
+	for _, d := range devices {
+		if id, ok := want[d.ID]; ok && d.Identity != id {
+			t.Errorf("Identity of %s is %+v, want %+v", d.ID, d.Identity, id)
+		}
+	}
+}
+
+func TestUnpluggedPort(t *testing.T) {
+	c, server := newTestController(t)
+
+	jack := pulsetest.NewSink("analog", "Analog Output")
+	jack.Ports = []pulse.PortInfo{
+		{Name: "analog-output-speaker", Description: "Speakers", Priority: 100, Available: pulse.AvailableUnknown},
+		{Name: "analog-output-headphones", Description: "Headphones", Priority: 200, Available: pulse.AvailableNo},
+	}
+	jack.ActivePort = "analog-output-headphones"
+	server.AddSink(jack)
+
+	devices, err := c.ListAudioDevices()
+	if err != nil {
+		t.Fatalf("Failed to list audio devices: %v", err)
+	}
+	for _, d := range devices {
+		want := StateActive
+		if d.ID == "analog" {
+			want = StateUnplugged
+		}
+		if d.State != want {
+			t.Errorf("Device %s has state %d, want %d", d.ID, d.State, want)
+		}
+	}
+}
+
+func TestPorts(t *testing.T) {
+	c, server := newTestController(t)
+
+	jack := pulsetest.NewSink("analog", "Analog Output")
+	jack.Ports = []pulse.PortInfo{
+		{Name: "analog-output-speaker", Description: "Speakers", Priority: 100, Available: pulse.AvailableUnknown},
+		{Name: "analog-output-headphones", Description: "Headphones", Priority: 200, Available: pulse.AvailableNo},
+	}
+	jack.ActivePort = "analog-output-speaker"
+	server.AddSink(jack)
+
+	ports, err := c.ListPorts("analog")
+	if err != nil {
+		t.Fatalf("Failed to list ports: %v", err)
+	}
+	want := []Port{
+		{Name: "analog-output-speaker", Description: "Speakers", Type: PortTypeSpeaker, Priority: 100, IsActive: true},
+		{Name: "analog-output-headphones", Description: "Headphones", Type: PortTypeHeadphones, Available: PortUnavailable, Priority: 200},
+	}
+	if !reflect.DeepEqual(ports, want) {
+		t.Errorf("Ports are %+v, want %+v", ports, want)
+	}
+
+	events := make(chan Event, 16)
+	if err := c.OnDeviceChange(func(e Event) { events <- e }); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	if err := c.SetActivePort("analog", "analog-output-headphones"); err != nil {
+		t.Fatalf("Failed to set port: %v", err)
+	}
+	if sink, _ := server.Sink("analog"); sink.ActivePort != "analog-output-headphones" {
+		t.Errorf("Active port is %s", sink.ActivePort)
+	}
+	e := waitEvent(t, events, PortChanged, "analog")
+	if e.Info == nil || e.Info.State != StateUnplugged || !e.Info.Ports[1].IsActive {
+		t.Errorf("Unexpected PortChanged info %+v", e.Info)
+	}
+
+	// Plugging headphones in is reported without switching ports
+	server.UpdateSink("analog", func(s *pulse.SinkInfo) {
+		s.Ports[1].Available = pulse.AvailableYes
+	})
+	e = waitEvent(t, events, PortChanged, "analog")
+	if e.Info == nil || e.Info.State != StateActive || e.Info.Ports[1].Available != PortAvailable {
+		t.Errorf("Unexpected PortChanged info %+v", e.Info)
+	}
+
+	// Volume changes are not port changes
+	c.SetVolume("analog", 0.5)
+	select {
+	case e := <-events:
+		if e.Type == PortChanged {
+			t.Errorf("Unexpected event %+v", e)
+		}
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := c.SetActivePort("analog", "hdmi-output-0"); err == nil {
+		t.Error("Expected an error for an unknown port")
+	}
+	if _, err := c.ListPorts("missing"); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound, got %v", err)
+	}
+	if ports, err := c.ListPorts("mic"); err != nil || len(ports) != 0 {
+		t.Errorf("Expected no ports for mic, got %+v, %v", ports, err)
+	}
+}
+
+func TestPortType(t *testing.T) {
+	tests := map[string]PortType{
+		"analog-output-speaker":     PortTypeSpeaker,
+		"analog-output-headphones":  PortTypeHeadphones,
+		"analog-input-headset-mic":  PortTypeHeadset,
+		"headphone-output":          PortTypeHeadphones,
+		"handsfree-input":           PortTypeHeadset,
+		"analog-input-internal-mic": PortTypeMicrophone,
+		"analog-output-lineout":     PortTypeLine,
+		"hdmi-output-0":             PortTypeHDMI,
+		"iec958-stereo-output":      PortTypeDigital,
+		"multichannel-output":       PortTypeUnknown,
+	}
+	for name, want := range tests {
+		if got := portType(name); got != want {
+			t.Errorf("portType(%q) = %d, want %d", name, got, want)
+		}
+	}
+}
+
+func TestCards(t *testing.T) {
+	c, server := newTestController(t)
+
+	index := server.AddCard(pulse.CardInfo{
+		Name:   "bluez_card.headset",
+		Driver: "module-bluez5-device.c",
+		Profiles: []pulse.ProfileInfo{
+			{Name: "a2dp_sink", Description: "High Fidelity Playback (A2DP Sink)", Sinks: 1, Priority: 40, Available: pulse.AvailableYes},
+			{Name: "headset_head_unit", Description: "Headset Head Unit (HSP/HFP)", Sinks: 1, Sources: 1, Priority: 30, Available: pulse.AvailableYes},
+			{Name: "off", Description: "Off"},
+		},
+		ActiveProfile: "a2dp_sink",
+		Properties:    pulse.Proplist{"device.description": "Headset"},
+	})
+	sink := pulsetest.NewSink("bluez_sink.headset", "Headset")
+	sink.Card = index
+	server.AddSink(sink)
+
+	cards, err := c.ListCards()
+	if err != nil {
+		t.Fatalf("Failed to list cards: %v", err)
+	}
+	if len(cards) != 1 {
+		t.Fatalf("Expected 1 card, got %+v", cards)
+	}
+	card := cards[0]
+	if card.ID != "bluez_card.headset" || card.Name != "Headset" || card.ActiveProfile != "a2dp_sink" {
+		t.Errorf("Unexpected card %+v", card)
+	}
+	if !reflect.DeepEqual(card.DeviceIDs, []string{"bluez_sink.headset"}) {
+		t.Errorf("Card devices are %v", card.DeviceIDs)
+	}
+	if len(card.Profiles) != 3 || !card.Profiles[0].IsActive || card.Profiles[1].Inputs != 1 {
+		t.Errorf("Unexpected profiles %+v", card.Profiles)
+	}
+
+	events := make(chan Event, 16)
+	if err := c.OnDeviceChange(func(e Event) { events <- e }); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	if err := c.SetProfile("bluez_card.headset", "headset_head_unit"); err != nil {
+		t.Fatalf("Failed to set profile: %v", err)
+	}
+	if info, _ := server.Card("bluez_card.headset"); info.ActiveProfile != "headset_head_unit" {
+		t.Errorf("Active profile is %s", info.ActiveProfile)
+	}
+	e := waitEvent(t, events, ProfileChanged, "bluez_card.headset")
+	if e.Card == nil || e.Card.ActiveProfile != "headset_head_unit" || e.Info != nil {
+		t.Errorf("Unexpected ProfileChanged event %+v", e)
+	}
+
+	// Switching back from another client is reported too
+	server.SetCardProfile("bluez_card.headset", "a2dp_sink")
+	waitEvent(t, events, ProfileChanged, "bluez_card.headset")
+
+	profiles, err := c.ListProfiles("bluez_card.headset")
+	if err != nil {
+		t.Fatalf("Failed to list profiles: %v", err)
+	}
+	if !profiles[0].IsActive || profiles[1].IsActive {
+		t.Errorf("Unexpected profiles %+v", profiles)
+	}
+
+	if err := c.SetProfile("bluez_card.headset", "surround"); err == nil {
+		t.Error("Expected an error for an unknown profile")
+	}
+	if _, err := c.ListProfiles("missing"); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound, got %v", err)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	c, server := newTestController(t)
+
+	music := pulsetest.NewSinkInput("Firefox", 4242)
+	music.Corked = true
+	musicIndex, err := server.AddSinkInput("speakers", music)
+	if err != nil {
+		t.Fatalf("Failed to add sink input: %v", err)
+	}
+	server.AddSourceOutput("mic", pulsetest.NewSourceOutput("Zoom", 5151))
+	// Streams recording a monitor are not sessions
+	server.AddSourceOutput("speakers.monitor", pulsetest.NewSourceOutput("pavucontrol", 6161))
+
+	sessions, err := c.ListSessions("")
+	if err != nil {
+		t.Fatalf("Failed to list sessions: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions, got %+v", sessions)
+	}
+	want := Session{ID: "sink-input:" + fmt.Sprint(musicIndex), DeviceID: "speakers", AppName: "Firefox", PID: 4242, Volume: 1, State: SessionInactive}
+	if sessions[0] != want {
+		t.Errorf("Session is %+v, want %+v", sessions[0], want)
+	}
+	if s := sessions[1]; s.AppName != "Zoom" || s.DeviceID != "mic" || !s.IsInput || s.State != SessionActive {
+		t.Errorf("Unexpected session %+v", s)
+	}
+
+	sessions, err = c.ListSessions("mic")
+	if err != nil || len(sessions) != 1 || sessions[0].AppName != "Zoom" {
+		t.Errorf("Expected the Zoom session on mic, got %+v, %v", sessions, err)
+	}
+	if _, err := c.ListSessions("missing"); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound, got %v", err)
+	}
+
+	if err := c.SetSessionVolume(want.ID, 0.25); err != nil {
+		t.Fatalf("Failed to set session volume: %v", err)
+	}
+	if err := c.SetSessionMute(want.ID, true); err != nil {
+		t.Fatalf("Failed to set session mute: %v", err)
+	}
+	input, _ := server.SinkInput(musicIndex)
+	if !input.Mute || len(input.Volume) != 2 || input.Volume[0] != pulse.VolumeFromFloat(0.25) || input.Volume[1] != input.Volume[0] {
+		t.Errorf("Unexpected sink input %+v", input)
+	}
+	if err := c.SetSessionMute("sink-input:999", true); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound, got %v", err)
+	}
+	if err := c.SetSessionVolume("speakers", 0.5); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound for a device ID, got %v", err)
+	}
+
+	events := make(chan Event, 16)
+	if err := c.OnDeviceChange(func(e Event) { events <- e }); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	index, _ := server.AddSinkInput("speakers", pulsetest.NewSinkInput("Spotify", 7272))
+	e := waitEvent(t, events, SessionAdded, "speakers")
+	if e.Session == nil || e.Session.AppName != "Spotify" || e.Session.PID != 7272 {
+		t.Errorf("Unexpected SessionAdded event %+v", e)
+	}
+
+	server.RemoveSinkInput(index)
+	e = waitEvent(t, events, SessionRemoved, "speakers")
+	if e.Session == nil || e.Session.AppName != "Spotify" || e.Session.State != SessionExpired {
+		t.Errorf("Unexpected SessionRemoved event %+v", e)
+	}
+}
+
+func TestMoveSession(t *testing.T) {
+	c, server := newTestController(t)
+	server.AddSink(pulsetest.NewSink("headphones", "Headphones"))
+	index, _ := server.AddSinkInput("speakers", pulsetest.NewSinkInput("Firefox", 4242))
+	id := "sink-input:" + fmt.Sprint(index)
+
+	if err := c.MoveSession(id, "headphones"); err != nil {
+		t.Fatalf("Failed to move session: %v", err)
+	}
+	sink, _ := server.Sink("headphones")
+	if input, _ := server.SinkInput(index); input.Sink != sink.Index {
+		t.Errorf("Sink input is on sink %d, want %d", input.Sink, sink.Index)
+	}
+	sessions, _ := c.ListSessions("headphones")
+	if len(sessions) != 1 || sessions[0].ID != id {
+		t.Errorf("Expected the session on headphones, got %+v", sessions)
+	}
+
+	// Playback streams cannot move to a source
+	if err := c.MoveSession(id, "mic"); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound, got %v", err)
+	}
+	if err := c.MoveSession("sink-input:999", "speakers"); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound, got %v", err)
+	}
+
+	server.PinStream(index)
+	if err := c.MoveSession(id, "speakers"); !errors.Is(err, pulse.ErrNotSupported) {
+		t.Errorf("Expected the server to refuse, got %v", err)
+	}
+}
+
+func TestVirtualDevices(t *testing.T) {
+	c, server := newTestController(t)
+	server.AddSink(pulsetest.NewSink("headphones", "Headphones"))
+
+	capture, err := c.CreateVirtualDevice(VirtualDeviceSpec{Kind: VirtualNullSink, Name: "capture", Description: "Stream 'Capture'"})
+	if err != nil {
+		t.Fatalf("Failed to create null sink: %v", err)
+	}
+	sink, ok := server.Sink("capture")
+	if !ok || sink.Description != "Stream 'Capture'" || sink.OwnerModule != capture.Module() {
+		t.Fatalf("Unexpected null sink %+v", sink)
+	}
+	if device, err := c.GetActiveOutputDevice(); err != nil || device.ID != "speakers" {
+		t.Errorf("Creating a sink changed the default to %+v, %v", device, err)
+	}
+	if volume, err := c.GetVolume("capture"); err != nil || volume != 1 {
+		t.Errorf("Expected capture to be a device, got %v, %v", volume, err)
+	}
+
+	combined, err := c.CreateVirtualDevice(VirtualDeviceSpec{Kind: VirtualCombinedSink, Name: "everywhere", Outputs: []string{"speakers", "headphones"}})
+	if err != nil {
+		t.Fatalf("Failed to create combined sink: %v", err)
+	}
+	if combined.DeviceID() != "everywhere" {
+		t.Errorf("Combined sink ID is %q", combined.DeviceID())
+	}
+	if _, err := c.CreateVirtualDevice(VirtualDeviceSpec{Kind: VirtualCombinedSink, Name: "broken", Outputs: []string{"missing"}}); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound, got %v", err)
+	}
+	if _, err := c.CreateVirtualDevice(VirtualDeviceSpec{Kind: VirtualNullSink, Name: "capture"}); err == nil {
+		t.Error("Expected an error for a duplicate name")
+	}
+
+	loopback, err := c.CreateVirtualDevice(VirtualDeviceSpec{Kind: VirtualLoopback, Source: "mic", Sink: "headphones", Description: "Monitor mic"})
+	if err != nil {
+		t.Fatalf("Failed to create loopback: %v", err)
+	}
+	if sessions, _ := c.ListSessions("headphones"); len(sessions) != 1 || sessions[0].AppName != "Loopback from Microphone" {
+		t.Errorf("Expected the loopback stream on headphones, got %+v", sessions)
+	}
+	if err := loopback.Close(); err != nil {
+		t.Fatalf("Failed to close loopback: %v", err)
+	}
+	if sessions, _ := c.ListSessions(""); len(sessions) != 0 {
+		t.Errorf("Expected no sessions after closing the loopback, got %+v", sessions)
+	}
+	// Closing twice is harmless
+	if err := loopback.Close(); err != nil {
+		t.Errorf("Second close failed: %v", err)
+	}
+
+	if err := capture.Close(); err != nil {
+		t.Fatalf("Failed to close null sink: %v", err)
+	}
+	if _, ok := server.Sink("capture"); ok {
+		t.Error("Null sink still exists after Close")
+	}
+	if err := c.CloseVirtualDevices(); err != nil {
+		t.Fatalf("Failed to close virtual devices: %v", err)
+	}
+	if modules := server.Modules(); len(modules) != 0 {
+		t.Errorf("Expected no modules, got %+v", modules)
+	}
+}
+
+func TestRemoveStaleVirtualDevices(t *testing.T) {
+	c, server := newTestController(t)
+	host, _ := os.Hostname()
+	owned := func(pid, host string) string {
+		props := pulse.FormatArguments(map[string]string{ownerPIDProperty: pid, ownerHostProperty: host})
+		return pulse.FormatArguments(map[string]string{"sink_name": "sink" + pid + host, "sink_properties": props})
+	}
+
+	// PIDs never exceed 2^22, so the first owner is gone
+	if _, err := server.LoadModule("module-null-sink", owned("99999999", host)); err != nil {
+		t.Fatalf("Failed to load module: %v", err)
+	}
+	server.LoadModule("module-null-sink", owned(strconv.Itoa(os.Getpid()), host))
+	server.LoadModule("module-null-sink", owned("99999999", "elsewhere"))
+	server.LoadModule("module-null-sink", "sink_name=user")
+
+	removed, err := c.RemoveStaleVirtualDevices()
+	if err != nil {
+		t.Fatalf("Failed to remove stale devices: %v", err)
+	}
+	if removed != 1 || len(server.Modules()) != 3 {
+		t.Errorf("Removed %d modules, leaving %+v", removed, server.Modules())
+	}
+	if _, ok := server.Sink("sink99999999" + host); ok {
+		t.Error("The stale sink still exists")
+	}
+}
+
+func TestMeter(t *testing.T) {
+	c, server := newTestController(t)
+
+	if _, _, err := c.Meter("nonexistent", 10*time.Millisecond); !errors.Is(err, ErrDeviceNotFound) {
+		t.Fatalf("Expected ErrDeviceNotFound, got %v", err)
+	}
+
+	// 10ms at 48kHz is 480 frames
+	levels, stop, err := c.Meter("mic", 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to meter the microphone: %v", err)
+	}
+	defer stop()
+	samples := make([]float32, 2*480)
+	for f := 0; f < 480; f++ {
+		samples[2*f] = 0.5
+		if f%2 == 0 {
+			samples[2*f] = -0.5
+		}
+		samples[2*f+1] = 0.25
+	}
+	// Split the interval across packets, ending in a partial frame
+	server.FeedSource("mic", samples[:301])
+	server.FeedSource("mic", samples[301:])
+
+	select {
+	case level := <-levels:
+		if len(level.Peak) != 2 || level.Peak[0] != 0.5 || level.Peak[1] != 0.25 {
+			t.Errorf("Peaks are %v", level.Peak)
+		}
+		if level.RMS[0] != 0.5 || level.RMS[1] != 0.25 {
+			t.Errorf("RMS levels are %v", level.RMS)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("No level received")
+	}
+
+	// Outputs are metered through their monitor
+	_, stopOutput, err := c.Meter("speakers", 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to meter the speakers: %v", err)
+	}
+	if n := server.RecordStreams("speakers.monitor"); n != 1 {
+		t.Errorf("%d streams record the monitor", n)
+	}
+	stopOutput()
+	stopOutput()
+
+	// The channel closes when the device goes away
+	server.RemoveSource("mic")
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case _, ok := <-levels:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("Level channel not closed after the device was removed")
+		}
+	}
+}
+
+func TestVolumeUsesAllChannels(t *testing.T) {
+	c, server := newTestController(t)
+
+	if err := c.SetVolume("speakers", 0.5); err != nil {
+		t.Fatalf("Failed to set volume: %v", err)
+	}
+	sink, _ := server.Sink("speakers")
+	for i, v := range sink.Volume {
+		if v != pulse.VolumeFromFloat(0.5) {
+			t.Errorf("Channel %d volume is %d", i, v)
+		}
+	}
+
+	// Volumes boosted above 100% by other clients read as 1.0
+	server.UpdateSink("speakers", func(s *pulse.SinkInfo) {
+		s.Volume = pulse.NewCVolume(2, pulse.VolumeNorm*3/2)
+	})
+	volume, err := c.GetVolume("speakers")
+	if err != nil {
+		t.Fatalf("Failed to get volume: %v", err)
+	}
+	if volume != 1.0 {
+		t.Errorf("Expected volume 1.0, got %v", volume)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	c, server := newTestController(t)
+
+	events := make(chan Event, 16)
+	if err := c.OnDeviceChange(func(e Event) { events <- e }); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	server.AddSink(pulsetest.NewSink("hdmi", "HDMI"))
+	e := waitEvent(t, events, DeviceAdded, "hdmi")
+	if e.Info == nil || e.Info.Name != "HDMI" || !e.Info.IsOutput {
+		t.Errorf("Unexpected DeviceAdded info %+v", e.Info)
+	}
+
+	server.SetDefaultSink("hdmi")
+	e = waitEvent(t, events, ActiveDeviceChanged, "hdmi")
+	if e.Info == nil || !e.Info.IsActive {
+		t.Errorf("Unexpected ActiveDeviceChanged info %+v", e.Info)
+	}
+
+	server.RemoveSink("hdmi")
+	waitEvent(t, events, DeviceRemoved, "hdmi")
+	// Removing the default sink makes "speakers" the default again
+	waitEvent(t, events, ActiveDeviceChanged, "speakers")
+
+	select {
+	case e := <-events:
+		t.Errorf("Unexpected event %+v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestReconnectAfterServerRestart(t *testing.T) {
+	c, server := newTestController(t)
+
+	if _, err := c.ListAudioDevices(); err != nil {
+		t.Fatalf("Failed to list audio devices: %v", err)
+	}
+	server.DropConnections()
+
+	// The next call after the connection is lost dials again
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		_, err := c.ListAudioDevices()
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Failed to reconnect: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReconnectEvents(t *testing.T) {
+	c, server := newTestController(t)
+
+	events := make(chan Event, 16)
+	if err := c.OnDeviceChange(func(e Event) { events <- e }); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	expect := func(want ...string) {
+		t.Helper()
+		for _, w := range want {
+			select {
+			case e := <-events:
+				if got := fmt.Sprintf("%d %s", e.Type, e.DeviceID); got != w {
+					t.Fatalf("Got event %q, want %q", got, w)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("No event %q", w)
+			}
+		}
+	}
+	event := func(typ EventType, deviceID string) string {
+		return fmt.Sprintf("%d %s", typ, deviceID)
+	}
+
+	// A restart that changes nothing is only announced
+	server.DropConnections()
+	expect(event(ServerDisconnected, ""), event(ServerConnected, ""))
+
+	// While the server is down the devices change. They are compared with
+	// the known ones once a later attempt reconnects.
+	server.Stop()
+	expect(event(ServerDisconnected, ""))
+	if err := server.RemoveSource("mic"); err != nil {
+		t.Fatalf("Failed to remove source: %v", err)
+	}
+	server.AddSink(pulsetest.NewSink("headphones", "Headphones"))
+	if err := server.SetDefaultSink("headphones"); err != nil {
+		t.Fatalf("Failed to set default sink: %v", err)
+	}
+	time.Sleep(4 * reconnectMinDelay)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to restart server: %v", err)
+	}
+	expect(
+		event(DeviceRemoved, "mic"),
+		event(DeviceAdded, "headphones"),
+		event(ActiveDeviceChanged, "headphones"),
+		event(ServerConnected, ""),
+	)
+
+	// Notifications are delivered on the new connection
+	server.AddSink(pulsetest.NewSink("hdmi", "HDMI"))
+	expect(event(DeviceAdded, "hdmi"))
+
+	// A closed Controller stays disconnected
+	c.Close()
+	server.DropConnections()
+	select {
+	case e := <-events:
+		t.Errorf("Unexpected event %+v after Close", e)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestReconnectResync(t *testing.T) {
+	c, server := newTestController(t)
+	server.AddCard(pulse.CardInfo{
+		Name: "bluez_card.headset",
+		Profiles: []pulse.ProfileInfo{
+			{Name: "a2dp_sink", Sinks: 1, Available: pulse.AvailableYes},
+			{Name: "headset_head_unit", Sinks: 1, Sources: 1, Available: pulse.AvailableYes},
+		},
+		ActiveProfile: "a2dp_sink",
+	})
+	music, err := server.AddSinkInput("speakers", pulsetest.NewSinkInput("Music", 100))
+	if err != nil {
+		t.Fatalf("Failed to add sink input: %v", err)
+	}
+
+	events := make(chan Event, 16)
+	if err := c.OnDeviceChange(func(e Event) { events <- e }); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	// The restarted server gives the index of the old stream to a stream of
+	// another application, and the card comes back with another profile
+	server.Stop()
+	waitEvent(t, events, ServerDisconnected, "")
+	if err := server.RemoveSinkInput(music); err != nil {
+		t.Fatalf("Failed to remove sink input: %v", err)
+	}
+	server.SetNextIndex(music)
+	if _, err := server.AddSinkInput("speakers", pulsetest.NewSinkInput("Call", 200)); err != nil {
+		t.Fatalf("Failed to add sink input: %v", err)
+	}
+	if err := server.SetCardProfile("bluez_card.headset", "headset_head_unit"); err != nil {
+		t.Fatalf("Failed to set profile: %v", err)
+	}
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to restart server: %v", err)
+	}
+
+	describe := func(e Event) string {
+		switch {
+		case e.Session != nil:
+			return fmt.Sprintf("%d %s %s", e.Type, e.Session.ID, e.Session.AppName)
+		case e.Card != nil:
+			return fmt.Sprintf("%d %s %s", e.Type, e.DeviceID, e.Card.ActiveProfile)
+		}
+		return fmt.Sprintf("%d %s", e.Type, e.DeviceID)
+	}
+	var got []string
+	timeout := time.After(2 * time.Second)
+	for connected := false; !connected; {
+		select {
+		case e := <-events:
+			got = append(got, describe(e))
+			connected = e.Type == ServerConnected
+		case <-timeout:
+			t.Fatalf("No ServerConnected after %q", got)
+		}
+	}
+
+	id := fmt.Sprintf("sink-input:%d", music)
+	want := []string{
+		describe(Event{Type: ProfileChanged, DeviceID: "bluez_card.headset", Card: &Card{ActiveProfile: "headset_head_unit"}}),
+		describe(Event{Type: SessionRemoved, Session: &Session{ID: id, AppName: "Music"}}),
+		describe(Event{Type: SessionAdded, Session: &Session{ID: id, AppName: "Call"}}),
+		describe(Event{Type: ServerConnected}),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got events %q, want %q", got, want)
+	}
+}
+
+func TestPlay(t *testing.T) {
+	c, server := newTestController(t)
+
+	format := Format{Encoding: EncodingS16LE, Channels: 2, Rate: 44100}
+	if err := c.Play("mic", format, bytes.NewReader(make([]byte, 4))); !errors.Is(err, ErrDeviceNotFound) {
+		t.Fatalf("Expected ErrDeviceNotFound for an input, got %v", err)
+	}
+
+	// More than the server buffers at once, read in uneven pieces and
+	// ending in a partial frame
+	data := make([]byte, 5*4096+3)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	if err := c.Play("speakers", format, iotest.HalfReader(bytes.NewReader(data))); err != nil {
+		t.Fatalf("Failed to play: %v", err)
+	}
+	if got := server.Played("speakers"); !bytes.Equal(got, data[:len(data)-3]) {
+		t.Errorf("Server played %d bytes, want the %d of whole frames", len(got), len(data)-3)
+	}
+	if n := server.PlaybackStreams("speakers"); n != 0 {
+		t.Errorf("%d playback streams left open", n)
+	}
+
+	if err := c.Play("speakers", Format{Encoding: EncodingS16LE, Rate: 44100}, bytes.NewReader(data)); err == nil {
+		t.Error("Expected an error for a format without channels")
+	}
+}
+
+func TestCapture(t *testing.T) {
+	c, server := newTestController(t)
+
+	if _, err := c.OpenCapture("speakers", Format{}); !errors.Is(err, ErrDeviceNotFound) {
+		t.Fatalf("Expected ErrDeviceNotFound for an output, got %v", err)
+	}
+
+	// The channel count and rate come from the microphone
+	capture, err := c.OpenCapture("mic", Format{Encoding: EncodingS24LE})
+	if err != nil {
+		t.Fatalf("Failed to capture: %v", err)
+	}
+	if f := capture.Format(); f != (Format{Encoding: EncodingS24LE, Channels: 2, Rate: 48000}) {
+		t.Errorf("Negotiated format is %+v", f)
+	}
+	samples := []float32{0.5, -0.5, 0.25, -0.25, 1, -1}
+	server.FeedSource("mic", samples[:2])
+	server.FeedSource("mic", samples[2:])
+	want := dsp.Encode(samples, dsp.EncodingS24LE)
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(capture, got); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("Captured % x, %v, want % x", got, err, want)
+	}
+
+	capture.Close()
+	if _, err := capture.Read(got); err != io.EOF {
+		t.Errorf("Expected io.EOF after Close, got %v", err)
+	}
+
+	// Reads fail once the device goes away
+	capture, err = c.OpenCapture("mic", Format{Encoding: EncodingFloat32LE})
+	if err != nil {
+		t.Fatalf("Failed to capture: %v", err)
+	}
+	defer capture.Close()
+	server.RemoveSource("mic")
+	if _, err := capture.Read(got); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound after removal, got %v", err)
+	}
+}
+
+func TestDeviceLatency(t *testing.T) {
+	c, server := newTestController(t)
+	// An idle sink reports the latency it is configured for
+	server.UpdateSink("speakers", func(s *pulse.SinkInfo) { s.ConfiguredLatency = 40000 })
+	server.UpdateSource("mic", func(s *pulse.SourceInfo) { s.Latency, s.ConfiguredLatency = 5000, 20000 })
+
+	if latency, err := c.DeviceLatency("speakers"); err != nil || latency != 40*time.Millisecond {
+		t.Errorf("Speaker latency is %v, %v", latency, err)
+	}
+	if latency, err := c.DeviceLatency("mic"); err != nil || latency != 5*time.Millisecond {
+		t.Errorf("Microphone latency is %v, %v", latency, err)
+	}
+	if _, err := c.DeviceLatency("nonexistent"); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound, got %v", err)
+	}
+}
+
+func TestLoopback(t *testing.T) {
+	c, server := newTestController(t)
+
+	if _, err := c.OpenLoopback("mic", Format{}); !errors.Is(err, ErrDeviceNotFound) {
+		t.Fatalf("Expected ErrDeviceNotFound for an input, got %v", err)
+	}
+
+	loopback, err := c.OpenLoopback("speakers", Format{Encoding: EncodingS16LE})
+	if err != nil {
+		t.Fatalf("Failed to open loopback: %v", err)
+	}
+	defer loopback.Close()
+	if n := server.RecordStreams("speakers.monitor"); n != 1 {
+		t.Errorf("%d streams record the monitor", n)
+	}
+
+	// What is played to the speakers comes back
+	played := dsp.Encode([]float32{0.5, -0.5, 0.25, -0.25}, dsp.EncodingS16LE)
+	if err := c.Play("speakers", Format{Encoding: EncodingS16LE, Channels: 2, Rate: 48000}, bytes.NewReader(played)); err != nil {
+		t.Fatalf("Failed to play: %v", err)
+	}
+	got := make([]byte, len(played))
+	if _, err := io.ReadFull(loopback, got); err != nil || !bytes.Equal(got, played) {
+		t.Errorf("Loopback captured % x, %v, want % x", got, err, played)
+	}
+
+	server.RemoveSink("speakers")
+	if _, err := loopback.Read(got); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound after removal, got %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/audi70r/go-audio-control/internal/pulse"
+)
+
+// Capture is interleaved PCM recorded from a device
+type Capture struct {
+	format   Format
+	deviceID string
+	stream   *pulse.RecordStream
+}
+
+// OpenCapture records an input in format. A format without a channel count
+// or rate takes those of the input; the server converts everything else.
+func (c *Controller) OpenCapture(deviceID string, format Format) (*Capture, error) {
+	source, err := c.sourceInfo(deviceID)
+	if err != nil {
+		return nil, err
+	}
+	return c.openCapture(deviceID, source.Name, source.SampleSpec, source.ChannelMap, format)
+}
+
+// OpenLoopback records what a sink plays, through its monitor source, in
+// the same way as OpenCapture
+func (c *Controller) OpenLoopback(deviceID string, format Format) (*Capture, error) {
+	sink, err := c.sinkInfo(deviceID)
+	if err != nil {
+		return nil, err
+	}
+	return c.openCapture(deviceID, sink.MonitorSourceName, sink.SampleSpec, sink.ChannelMap, format)
+}
+
+// openCapture records the named source, which may be the monitor of a sink.
+// native and nativeMap describe the source.
+func (c *Controller) openCapture(deviceID, source string, native pulse.SampleSpec, nativeMap pulse.ChannelMap, format Format) (*Capture, error) {
+	if format.Channels == 0 {
+		format.Channels = int(native.Channels)
+	}
+	if format.Rate == 0 {
+		format.Rate = int(native.Rate)
+	}
+	spec, err := format.sampleSpec()
+	if err != nil {
+		return nil, err
+	}
+	// The server remixes by channel position when the counts differ
+	channelMap := nativeMap
+	if format.Channels != int(native.Channels) {
+		channelMap = pulse.DefaultChannelMap(format.Channels)
+	}
+	client, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := client.CreateRecordStream(pulse.RecordOptions{
+		Source:     source,
+		SampleSpec: spec,
+		ChannelMap: channelMap,
+		Properties: pulse.Proplist{"media.name": "Capture"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record %s: %w", deviceID, err)
+	}
+	if stream.SampleSpec != spec {
+		stream.Close()
+		return nil, fmt.Errorf("server recorded %s in %+v instead of %+v", deviceID, stream.SampleSpec, spec)
+	}
+	return &Capture{format: format, deviceID: deviceID, stream: stream}, nil
+}
+
+// Format returns the format of the recorded PCM
+func (c *Capture) Format() Format {
+	return c.format
+}
+
+// Read reads recorded PCM, blocking until some is available. Audio that is
+// not read in time is dropped by the server. Once Close is called, reads
+// return io.EOF; when the device goes away they return ErrDeviceNotFound.
+func (c *Capture) Read(p []byte) (int, error) {
+	n, err := c.stream.Read(p)
+	switch {
+	case errors.Is(err, pulse.ErrStreamClosed):
+		err = io.EOF
+	case errors.Is(err, pulse.ErrStreamKilled):
+		err = fmt.Errorf("capture of %s ended: %w", c.deviceID, ErrDeviceNotFound)
+	}
+	return n, err
+}
+
+// Close stops recording
+func (c *Capture) Close() error {
+	return c.stream.Close()
+}
+
+// OpenCapture records an input of the default server
+func OpenCapture(deviceID string, format Format) (*Capture, error) {
+	return defaultController.OpenCapture(deviceID, format)
+}
+
+// OpenLoopback records a sink of the default server
+func OpenLoopback(deviceID string, format Format) (*Capture, error) {
+	return defaultController.OpenLoopback(deviceID, format)
+}
@@ -0,0 +1,223 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/audi70r/go-audio-control/internal/pulse"
+)
+
+// Card is a sound card, identified by its card name
+type Card struct {
+	ID            string
+	Name          string
+	Profiles      []Profile
+	ActiveProfile string
+	DeviceIDs     []string
+}
+
+// Profile is a profile of a card
+type Profile struct {
+	Name        string
+	Description string
+	Outputs     int
+	Inputs      int
+	Priority    uint32
+	Available   bool
+	IsActive    bool
+}
+
+func readCards(client *pulse.Client) ([]pulse.CardInfo, error) {
+	reply, err := client.Request(pulse.CommandGetCardInfoList)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cards: %w", err)
+	}
+	var cards []pulse.CardInfo
+	for !reply.Eof() && reply.Err() == nil {
+		cards = append(cards, pulse.ReadCardInfo(reply))
+	}
+	if err := reply.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse cards: %w", err)
+	}
+	return cards, nil
+}
+
+func cardProfiles(info pulse.CardInfo) []Profile {
+	profiles := make([]Profile, len(info.Profiles))
+	for i, p := range info.Profiles {
+		profiles[i] = Profile{
+			Name:        p.Name,
+			Description: p.Description,
+			Outputs:     int(p.Sinks),
+			Inputs:      int(p.Sources),
+			Priority:    p.Priority,
+			Available:   p.Available != pulse.AvailableNo,
+			IsActive:    p.Name == info.ActiveProfile,
+		}
+	}
+	return profiles
+}
+
+// newCard describes a card together with the devices it provides in s
+func newCard(info pulse.CardInfo, s snapshot) Card {
+	card := Card{
+		ID:            info.Name,
+		Name:          info.Properties["device.description"],
+		Profiles:      cardProfiles(info),
+		ActiveProfile: info.ActiveProfile,
+	}
+	if card.Name == "" {
+		card.Name = info.Name
+	}
+	for _, sink := range s.sinks {
+		if sink.Card == info.Index {
+			card.DeviceIDs = append(card.DeviceIDs, sink.Name)
+		}
+	}
+	for _, source := range s.sources {
+		if source.Card == info.Index {
+			card.DeviceIDs = append(card.DeviceIDs, source.Name)
+		}
+	}
+	return card
+}
+
+// rememberCards replaces the cached active profiles used to detect changes,
+// and the card names used to match cards across a server restart
+func (c *Controller) rememberCards(cards []pulse.CardInfo) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+
+	c.profiles = make(map[uint32]string, len(cards))
+	c.cardNames = make(map[uint32]string, len(cards))
+	for _, card := range cards {
+		c.profiles[card.Index] = card.ActiveProfile
+		c.cardNames[card.Index] = card.Name
+	}
+}
+
+// ListCards returns the cards with the devices they currently provide
+func (c *Controller) ListCards() ([]Card, error) {
+	client, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+	s, err := readSnapshot(client)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := readCards(client)
+	if err != nil {
+		return nil, err
+	}
+
+	cards := make([]Card, len(infos))
+	for i, info := range infos {
+		cards[i] = newCard(info, s)
+	}
+	return cards, nil
+}
+
+// cardInfo looks up a card by name
+func (c *Controller) cardInfo(name string) (pulse.CardInfo, error) {
+	reply, err := c.request(pulse.CommandGetCardInfo, uint32(pulse.Invalid), name)
+	if errors.Is(err, pulse.ErrNoEntity) {
+		return pulse.CardInfo{}, fmt.Errorf("card %s: %w", name, ErrDeviceNotFound)
+	}
+	if err != nil {
+		return pulse.CardInfo{}, fmt.Errorf("failed to get card info: %w", err)
+	}
+	card := pulse.ReadCardInfo(reply)
+	return card, reply.Err()
+}
+
+// ListProfiles returns the profiles of a card
+func (c *Controller) ListProfiles(cardID string) ([]Profile, error) {
+	info, err := c.cardInfo(cardID)
+	if err != nil {
+		return nil, err
+	}
+	return cardProfiles(info), nil
+}
+
+// SetProfile switches a card to the named profile
+func (c *Controller) SetProfile(cardID, profile string) error {
+	info, err := c.cardInfo(cardID)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, p := range info.Profiles {
+		found = found || p.Name == profile
+	}
+	if !found {
+		return fmt.Errorf("card %s has no profile %s", cardID, profile)
+	}
+	if _, err := c.request(pulse.CommandSetCardProfile, uint32(pulse.Invalid), cardID, profile); err != nil {
+		return fmt.Errorf("failed to set card profile: %w", err)
+	}
+	return nil
+}
+
+// handleCardEvent reports changes of the active profile of a card
+func (c *Controller) handleCardEvent(e pulse.SubscriptionEvent) {
+	if e.Type() == pulse.EventRemove {
+		c.stateMu.Lock()
+		delete(c.profiles, e.Index)
+		delete(c.cardNames, e.Index)
+		c.stateMu.Unlock()
+		return
+	}
+
+	reply, err := c.request(pulse.CommandGetCardInfo, e.Index, nil)
+	if err != nil {
+		ignoreEventError("get card info", e, err)
+		return
+	}
+	info := pulse.ReadCardInfo(reply)
+	if err := reply.Err(); err != nil {
+		ignoreEventError("read card info", e, err)
+		return
+	}
+
+	c.stateMu.Lock()
+	old, known := c.profiles[e.Index]
+	c.profiles[e.Index] = info.ActiveProfile
+	c.cardNames[e.Index] = info.Name
+	c.stateMu.Unlock()
+	if !known || old == info.ActiveProfile {
+		return
+	}
+
+	// The devices of the new profile exist by the time the card changes
+	client, err := c.conn()
+	if err != nil {
+		ignoreEventError("connect", e, err)
+		return
+	}
+	s, err := readSnapshot(client)
+	if err != nil {
+		ignoreEventError("read devices", e, err)
+		return
+	}
+	card := newCard(info, s)
+	c.emit(Event{Type: ProfileChanged, DeviceID: card.ID, Card: &card})
+}
+
+// ListCards returns the cards on the default server
+func ListCards() ([]Card, error) {
+	return defaultController.ListCards()
+}
+
+// ListProfiles returns the profiles of a card
+func ListProfiles(cardID string) ([]Profile, error) {
+	return defaultController.ListProfiles(cardID)
+}
+
+// SetProfile switches a card to the named profile
+func SetProfile(cardID, profile string) error {
+	return defaultController.SetProfile(cardID, profile)
+}
@@ -0,0 +1,238 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"github.com/audi70r/go-audio-control/internal/pulse"
+)
+
+// subscriptionMask selects the notifications that produce device events
+const subscriptionMask = pulse.SubscribeSink | pulse.SubscribeSource | pulse.SubscribeServer | pulse.SubscribeCard |
+	pulse.SubscribeSinkInput | pulse.SubscribeSourceOutput
+
+// OnDeviceChange registers a callback for device events. The first
+// registration subscribes to server notifications; it returns an error if
+// the server cannot be reached.
+//
+// From then on the Controller reconnects by itself when the connection is
+// lost, such as when PulseAudio or PipeWire restarts. Losing the connection
+// emits ServerDisconnected. Once reconnected, the devices, defaults and
+// card profiles are compared with those known before and the differences
+// are emitted as ordinary events. Sessions do not survive a restart, so
+// every known session is removed and every current one added. Then
+// ServerConnected is emitted. If the first registration fails,
+// ServerConnected announces the eventual connection.
+func (c *Controller) OnDeviceChange(callback func(Event)) error {
+	c.mu.Lock()
+	c.callbacks = append(c.callbacks, callback)
+	if len(c.callbacks) > 1 {
+		c.mu.Unlock()
+		return nil
+	}
+	defer func() { go c.supervise() }()
+	client := c.client
+	if client != nil {
+		select {
+		case <-client.Done():
+			client = nil
+		default:
+		}
+	}
+	if client != nil {
+		err := c.subscribe(client)
+		if err != nil {
+			// Dropping the connection leaves subscribing to the reconnection
+			client.Close()
+			c.client = nil
+		}
+		c.mu.Unlock()
+		return err
+	}
+	c.mu.Unlock()
+
+	// Connecting subscribes now that a callback is registered
+	_, err := c.conn()
+	return err
+}
+
+// subscribe reads the current state, so that later notifications can be
+// described, and subscribes client to changes
+func (c *Controller) subscribe(client *pulse.Client) error {
+	s, err := readSnapshot(client)
+	if err != nil {
+		return err
+	}
+	cards, err := readCards(client)
+	if err != nil {
+		return err
+	}
+	sessions, err := readSessions(client, s)
+	if err != nil {
+		return err
+	}
+
+	c.stateMu.Lock()
+	if c.synced {
+		c.resync = append(c.resync, c.diffLocked(s, cards, sessions)...)
+	}
+	c.synced = true
+	c.stateMu.Unlock()
+	c.remember(s)
+	c.rememberCards(cards)
+	c.rememberSessions(sessions)
+	return client.Subscribe(subscriptionMask, c.handleEvent)
+}
+
+func (c *Controller) emit(event Event) {
+	c.mu.Lock()
+	callbacks := append([]func(Event){}, c.callbacks...)
+	c.mu.Unlock()
+
+	for _, callback := range callbacks {
+		callback(event)
+	}
+}
+
+// handleEvent turns a server notification into device events
+func (c *Controller) handleEvent(e pulse.SubscriptionEvent) {
+	logNotification(e)
+	switch e.Facility() {
+	case pulse.FacilitySink:
+		c.handleSinkEvent(e)
+	case pulse.FacilitySource:
+		c.handleSourceEvent(e)
+	case pulse.FacilityServer:
+		c.handleServerEvent(e)
+	case pulse.FacilityCard:
+		c.handleCardEvent(e)
+	case pulse.FacilitySinkInput, pulse.FacilitySourceOutput:
+		c.handleStreamEvent(e)
+	}
+}
+
+func (c *Controller) handleSinkEvent(e pulse.SubscriptionEvent) {
+	if e.Type() == pulse.EventRemove {
+		c.stateMu.Lock()
+		device, ok := c.sinks[e.Index]
+		delete(c.sinks, e.Index)
+		c.stateMu.Unlock()
+		if ok {
+			c.emit(Event{Type: DeviceRemoved, DeviceID: device.ID, Info: &device})
+		}
+		return
+	}
+
+	reply, err := c.request(pulse.CommandGetSinkInfo, e.Index, nil)
+	if err != nil {
+		ignoreEventError("get sink info", e, err)
+		return
+	}
+	sink := pulse.ReadSinkInfo(reply)
+	if err := reply.Err(); err != nil {
+		ignoreEventError("read sink info", e, err)
+		return
+	}
+
+	c.stateMu.Lock()
+	device := sinkDevice(sink, c.defaultSink)
+	old, known := c.sinks[e.Index]
+	c.sinks[e.Index] = device
+	c.stateMu.Unlock()
+
+	if !known {
+		c.emit(Event{Type: DeviceAdded, DeviceID: device.ID, Info: &device})
+	} else if portsChanged(old.Ports, device.Ports) {
+		c.emit(Event{Type: PortChanged, DeviceID: device.ID, Info: &device})
+	}
+}
+
+func (c *Controller) handleSourceEvent(e pulse.SubscriptionEvent) {
+	if e.Type() == pulse.EventRemove {
+		c.stateMu.Lock()
+		device, ok := c.sources[e.Index]
+		delete(c.sources, e.Index)
+		c.stateMu.Unlock()
+		if ok {
+			c.emit(Event{Type: DeviceRemoved, DeviceID: device.ID, Info: &device})
+		}
+		return
+	}
+
+	reply, err := c.request(pulse.CommandGetSourceInfo, e.Index, nil)
+	if err != nil {
+		ignoreEventError("get source info", e, err)
+		return
+	}
+	source := pulse.ReadSourceInfo(reply)
+	if err := reply.Err(); err != nil {
+		ignoreEventError("read source info", e, err)
+		return
+	}
+	if source.IsMonitor() {
+		return
+	}
+
+	c.stateMu.Lock()
+	device := sourceDevice(source, c.defaultSource)
+	old, known := c.sources[e.Index]
+	c.sources[e.Index] = device
+	c.stateMu.Unlock()
+
+	if !known {
+		c.emit(Event{Type: DeviceAdded, DeviceID: device.ID, Info: &device})
+	} else if portsChanged(old.Ports, device.Ports) {
+		c.emit(Event{Type: PortChanged, DeviceID: device.ID, Info: &device})
+	}
+}
+
+// handleServerEvent reports changes of the default sink and source
+func (c *Controller) handleServerEvent(e pulse.SubscriptionEvent) {
+	reply, err := c.request(pulse.CommandGetServerInfo)
+	if err != nil {
+		ignoreEventError("get server info", e, err)
+		return
+	}
+	info := pulse.ReadServerInfo(reply)
+	if err := reply.Err(); err != nil {
+		ignoreEventError("read server info", e, err)
+		return
+	}
+
+	var events []Event
+	c.stateMu.Lock()
+	if info.DefaultSinkName != c.defaultSink {
+		c.defaultSink = info.DefaultSinkName
+		events = append(events, updateDefault(c.sinks, info.DefaultSinkName, true)...)
+	}
+	if info.DefaultSourceName != c.defaultSource {
+		c.defaultSource = info.DefaultSourceName
+		events = append(events, updateDefault(c.sources, info.DefaultSourceName, false)...)
+	}
+	c.stateMu.Unlock()
+
+	for _, event := range events {
+		c.emit(event)
+	}
+}
+
+// updateDefault marks the device named active as the only default one and
+// returns the ActiveDeviceChanged event for it
+func updateDefault(devices map[uint32]AudioDevice, active string, output bool) []Event {
+	var events []Event
+	for index, device := range devices {
+		device.IsActive = device.ID == active
+		device.IsDefaultCommunications = device.IsActive
+		if output {
+			device.IsDefaultOutput = device.IsActive
+		} else {
+			device.IsDefaultInput = device.IsActive
+		}
+		devices[index] = device
+		if device.IsActive {
+			info := device
+			events = append(events, Event{Type: ActiveDeviceChanged, DeviceID: device.ID, Info: &info})
+		}
+	}
+	return events
+}
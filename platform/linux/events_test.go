@@ -0,0 +1,46 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"testing"
+
+	"github.com/audi70r/go-audio-control/internal/pulse/pulsetest"
+)
+
+// Listing the devices while notifications are queued must not hide their
+// changes from the events
+func TestListDoesNotHideQueuedEvents(t *testing.T) {
+	c, server := newTestController(t)
+
+	events := make(chan Event, 16)
+	blocked := make(chan struct{})
+	release := make(chan struct{})
+	first := true
+	if err := c.OnDeviceChange(func(e Event) {
+		if first {
+			first = false
+			close(blocked)
+			<-release
+		}
+		events <- e
+	}); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	server.AddSink(pulsetest.NewSink("usb", "USB Speakers"))
+	<-blocked
+	server.AddSink(pulsetest.NewSink("hdmi", "HDMI"))
+	if err := server.SetDefaultSink("hdmi"); err != nil {
+		t.Fatalf("Failed to set default sink: %v", err)
+	}
+	if _, err := c.ListAudioDevices(); err != nil {
+		t.Fatalf("Failed to list devices: %v", err)
+	}
+	close(release)
+
+	waitEvent(t, events, DeviceAdded, "usb")
+	waitEvent(t, events, DeviceAdded, "hdmi")
+	waitEvent(t, events, ActiveDeviceChanged, "hdmi")
+}
@@ -0,0 +1,38 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"errors"
+	"time"
+)
+
+// DeviceLatency returns the latency the server reports for a sink or
+// source: the current latency when the device is running, otherwise the
+// latency it is configured for
+func (c *Controller) DeviceLatency(deviceID string) (time.Duration, error) {
+	var latency, configured uint64
+	sink, err := c.sinkInfo(deviceID)
+	switch {
+	case err == nil:
+		latency, configured = sink.Latency, sink.ConfiguredLatency
+	case errors.Is(err, ErrDeviceNotFound):
+		source, err := c.sourceInfo(deviceID)
+		if err != nil {
+			return 0, err
+		}
+		latency, configured = source.Latency, source.ConfiguredLatency
+	default:
+		return 0, err
+	}
+	if latency == 0 {
+		latency = configured
+	}
+	return time.Duration(latency) * time.Microsecond, nil
+}
+
+// DeviceLatency returns the latency of a device on the default server
+func DeviceLatency(deviceID string) (time.Duration, error) {
+	return defaultController.DeviceLatency(deviceID)
+}
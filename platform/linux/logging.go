@@ -0,0 +1,54 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/audi70r/go-audio-control/internal/logging"
+	"github.com/audi70r/go-audio-control/internal/pulse"
+)
+
+var facilityNames = map[uint32]string{
+	pulse.FacilitySink:         "sink",
+	pulse.FacilitySource:       "source",
+	pulse.FacilitySinkInput:    "sink-input",
+	pulse.FacilitySourceOutput: "source-output",
+	pulse.FacilityModule:       "module",
+	pulse.FacilityClient:       "client",
+	pulse.FacilityServer:       "server",
+	pulse.FacilityCard:         "card",
+}
+
+var eventTypeNames = map[uint32]string{
+	pulse.EventNew:    "new",
+	pulse.EventChange: "change",
+	pulse.EventRemove: "remove",
+}
+
+// notificationAttrs describes a server notification for the log
+func notificationAttrs(e pulse.SubscriptionEvent) []slog.Attr {
+	facility, ok := facilityNames[e.Facility()]
+	if !ok {
+		facility = fmt.Sprint(e.Facility())
+	}
+	return []slog.Attr{
+		slog.String("facility", facility),
+		slog.String("type", eventTypeNames[e.Type()]),
+		slog.Uint64("index", uint64(e.Index)),
+	}
+}
+
+func logNotification(e pulse.SubscriptionEvent) {
+	if logging.Logger() != nil {
+		logging.Notification(notificationAttrs(e)...)
+	}
+}
+
+// ignoreEventError logs an error that kept the notification e from being
+// turned into events
+func ignoreEventError(op string, e pulse.SubscriptionEvent, err error) {
+	logging.Ignored(op, err, notificationAttrs(e)...)
+}
@@ -0,0 +1,138 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/audi70r/go-audio-control/internal/dsp"
+	"github.com/audi70r/go-audio-control/internal/pulse"
+)
+
+// Level is the peak and RMS of each channel over one interval, as linear
+// fractions of full scale
+type Level struct {
+	Time time.Time
+	Peak []float64
+	RMS  []float64
+}
+
+// meterFormat is the sample format meters record in. The server converts
+// to it, so levels do not depend on the device format.
+const meterFormat = pulse.SampleFloat32LE
+
+// recordTarget returns the source that carries the audio of a device: the
+// source itself, or the monitor of a sink
+func (c *Controller) recordTarget(deviceID string) (string, pulse.SampleSpec, pulse.ChannelMap, error) {
+	sink, err := c.sinkInfo(deviceID)
+	if err == nil {
+		return sink.MonitorSourceName, sink.SampleSpec, sink.ChannelMap, nil
+	}
+	if !errors.Is(err, ErrDeviceNotFound) {
+		return "", pulse.SampleSpec{}, nil, err
+	}
+	source, err := c.sourceInfo(deviceID)
+	if errors.Is(err, ErrDeviceNotFound) {
+		return "", pulse.SampleSpec{}, nil, fmt.Errorf("device %s: %w", deviceID, ErrDeviceNotFound)
+	}
+	if err != nil {
+		return "", pulse.SampleSpec{}, nil, err
+	}
+	return source.Name, source.SampleSpec, source.ChannelMap, nil
+}
+
+// Meter records a source, or the monitor of a sink, and sends the levels of
+// each interval. Levels are dropped while the receiver is behind. The
+// channel is closed after stop is called or when the device goes away.
+//
+// The levels are measured from the PCM of a plain record stream rather than
+// a peak-detect stream, which would report peaks only.
+func (c *Controller) Meter(deviceID string, interval time.Duration) (<-chan Level, func(), error) {
+	if interval <= 0 {
+		return nil, nil, errors.New("meter interval must be positive")
+	}
+	source, spec, channelMap, err := c.recordTarget(deviceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := c.conn()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	spec.Format = meterFormat
+	frames := int(float64(spec.Rate) * interval.Seconds())
+	if frames < 1 {
+		frames = 1
+	}
+	stream, err := client.CreateRecordStream(pulse.RecordOptions{
+		Source:       source,
+		SampleSpec:   spec,
+		ChannelMap:   channelMap,
+		FragmentSize: uint32(frames * spec.FrameSize()),
+		Properties:   pulse.Proplist{"media.name": "Level meter"},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to record %s: %w", deviceID, err)
+	}
+	if stream.SampleSpec.Format != meterFormat {
+		stream.Close()
+		return nil, nil, fmt.Errorf("server recorded in format %d", stream.SampleSpec.Format)
+	}
+
+	levels := make(chan Level, 1)
+	go runMeter(stream, frames, levels)
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() { stream.Close() })
+	}
+	return levels, stop, nil
+}
+
+// runMeter measures the audio of stream in intervals of frames frames until
+// the stream ends
+func runMeter(stream *pulse.RecordStream, frames int, levels chan<- Level) {
+	defer close(levels)
+
+	frameSize := stream.SampleSpec.FrameSize()
+	acc := dsp.NewAccumulator(int(stream.SampleSpec.Channels))
+	buf := make([]byte, frames*frameSize)
+	var pending []byte
+	for {
+		n, err := stream.Read(buf)
+		if err != nil {
+			return
+		}
+		pending = append(pending, buf[:n]...)
+
+		// Measure whole frames, ending an interval exactly at its last frame
+		for len(pending) >= frameSize {
+			take := frames - acc.Frames()
+			if available := len(pending) / frameSize; available < take {
+				take = available
+			}
+			acc.Add(dsp.Decode(pending[:take*frameSize], dsp.EncodingFloat32LE))
+			pending = pending[take*frameSize:]
+
+			if acc.Frames() == frames {
+				peak, rms := acc.Levels()
+				acc.Reset()
+				select {
+				case levels <- Level{Time: time.Now(), Peak: peak, RMS: rms}:
+				default:
+				}
+			}
+		}
+		pending = append([]byte(nil), pending...)
+	}
+}
+
+// Meter measures the levels of a device on the default server
+func Meter(deviceID string, interval time.Duration) (<-chan Level, func(), error) {
+	return defaultController.Meter(deviceID, interval)
+}
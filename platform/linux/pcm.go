@@ -0,0 +1,42 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"fmt"
+
+	"github.com/audi70r/go-audio-control/internal/pulse"
+)
+
+// SampleEncoding is the layout of one little-endian PCM sample
+type SampleEncoding int
+
+const (
+	EncodingS16LE SampleEncoding = iota
+	EncodingS24LE
+	EncodingS32LE
+	EncodingFloat32LE
+)
+
+// Format describes interleaved PCM audio
+type Format struct {
+	Encoding SampleEncoding
+	Channels int
+	Rate     int
+}
+
+// sampleSpec returns the stream sample spec of f
+func (f Format) sampleSpec() (pulse.SampleSpec, error) {
+	formats := map[SampleEncoding]pulse.SampleFormat{
+		EncodingS16LE:     pulse.SampleS16LE,
+		EncodingS24LE:     pulse.SampleS24LE,
+		EncodingS32LE:     pulse.SampleS32LE,
+		EncodingFloat32LE: pulse.SampleFloat32LE,
+	}
+	format, ok := formats[f.Encoding]
+	if !ok || f.Channels <= 0 || f.Channels > 32 || f.Rate <= 0 {
+		return pulse.SampleSpec{}, fmt.Errorf("unsupported format %+v", f)
+	}
+	return pulse.SampleSpec{Format: format, Channels: uint8(f.Channels), Rate: uint32(f.Rate)}, nil
+}
@@ -0,0 +1,73 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/audi70r/go-audio-control/internal/pulse"
+)
+
+// playChunk is the number of frames read from the caller per write
+const playChunk = 4096
+
+// Play plays interleaved PCM to a sink through a stream of its own, so the
+// default sink and other streams are unaffected. It returns once the server
+// has played everything read from pcm. A trailing partial frame is dropped.
+func (c *Controller) Play(deviceID string, format Format, pcm io.Reader) error {
+	sink, err := c.sinkInfo(deviceID)
+	if err != nil {
+		return err
+	}
+	spec, err := format.sampleSpec()
+	if err != nil {
+		return err
+	}
+	client, err := c.conn()
+	if err != nil {
+		return err
+	}
+
+	stream, err := client.CreatePlaybackStream(pulse.PlaybackOptions{
+		Sink:       sink.Name,
+		SampleSpec: spec,
+		ChannelMap: pulse.DefaultChannelMap(format.Channels),
+		Properties: pulse.Proplist{"media.name": "Test sound", "media.role": "test"},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to play to %s: %w", deviceID, err)
+	}
+	defer stream.Close()
+
+	frameSize := spec.FrameSize()
+	buf := make([]byte, playChunk*frameSize)
+	pending := 0
+	for {
+		n, readErr := pcm.Read(buf[pending:])
+		pending += n
+		whole := pending - pending%frameSize
+		if whole > 0 {
+			if _, err := stream.Write(buf[:whole]); err != nil {
+				return fmt.Errorf("failed to play to %s: %w", deviceID, err)
+			}
+			pending = copy(buf, buf[whole:pending])
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+	if err := stream.Drain(); err != nil {
+		return fmt.Errorf("failed to drain playback to %s: %w", deviceID, err)
+	}
+	return nil
+}
+
+// Play plays interleaved PCM to a sink of the default server
+func Play(deviceID string, format Format, pcm io.Reader) error {
+	return defaultController.Play(deviceID, format, pcm)
+}
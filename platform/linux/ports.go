@@ -0,0 +1,180 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/audi70r/go-audio-control/internal/pulse"
+)
+
+// Port is a port of a sink or source
+type Port struct {
+	Name        string
+	Description string
+	Type        PortType
+	Available   PortAvailability
+	Priority    uint32
+	IsActive    bool
+}
+
+// PortType classifies what a port connects to
+type PortType int
+
+const (
+	PortTypeUnknown PortType = iota
+	PortTypeSpeaker
+	PortTypeHeadphones
+	PortTypeHeadset
+	PortTypeMicrophone
+	PortTypeLine
+	PortTypeHDMI
+	PortTypeDigital
+)
+
+// PortAvailability reports whether something is plugged into a port
+type PortAvailability int
+
+const (
+	PortAvailabilityUnknown PortAvailability = iota
+	PortAvailable
+	PortUnavailable
+)
+
+// portTypes maps substrings of ALSA and Bluetooth port names to types. The
+// first match wins, so headset ports are not taken for microphones.
+var portTypes = []struct {
+	substring string
+	portType  PortType
+}{
+	{"headset", PortTypeHeadset},
+	{"handsfree", PortTypeHeadset},
+	{"headphone", PortTypeHeadphones},
+	{"speaker", PortTypeSpeaker},
+	{"mic", PortTypeMicrophone},
+	{"line", PortTypeLine},
+	{"hdmi", PortTypeHDMI},
+	{"iec958", PortTypeDigital},
+	{"spdif", PortTypeDigital},
+}
+
+func portType(name string) PortType {
+	name = strings.ToLower(name)
+	for _, t := range portTypes {
+		if strings.Contains(name, t.substring) {
+			return t.portType
+		}
+	}
+	return PortTypeUnknown
+}
+
+func portAvailability(available uint32) PortAvailability {
+	switch available {
+	case pulse.AvailableYes:
+		return PortAvailable
+	case pulse.AvailableNo:
+		return PortUnavailable
+	}
+	return PortAvailabilityUnknown
+}
+
+func devicePorts(ports []pulse.PortInfo, active string) []Port {
+	if len(ports) == 0 {
+		return nil
+	}
+	result := make([]Port, len(ports))
+	for i, p := range ports {
+		result[i] = Port{
+			Name:        p.Name,
+			Description: p.Description,
+			Type:        portType(p.Name),
+			Available:   portAvailability(p.Available),
+			Priority:    p.Priority,
+			IsActive:    p.Name == active,
+		}
+	}
+	return result
+}
+
+// portsChanged reports whether the active port or the availability of any
+// port differs
+func portsChanged(old, cur []Port) bool {
+	if len(old) != len(cur) {
+		return true
+	}
+	for i := range old {
+		if old[i] != cur[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// ListPorts returns the ports of a sink or source
+func (c *Controller) ListPorts(deviceID string) ([]Port, error) {
+	sink, err := c.sinkInfo(deviceID)
+	if err == nil {
+		return devicePorts(sink.Ports, sink.ActivePort), nil
+	}
+	if !errors.Is(err, ErrDeviceNotFound) {
+		return nil, err
+	}
+
+	source, err := c.sourceInfo(deviceID)
+	if errors.Is(err, ErrDeviceNotFound) {
+		return nil, fmt.Errorf("device %s: %w", deviceID, ErrDeviceNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return devicePorts(source.Ports, source.ActivePort), nil
+}
+
+// SetActivePort switches a sink or source to the named port
+func (c *Controller) SetActivePort(deviceID, port string) error {
+	cmd := pulse.CommandSetSinkPort
+	sink, err := c.sinkInfo(deviceID)
+	ports := sink.Ports
+	if errors.Is(err, ErrDeviceNotFound) {
+		var source pulse.SourceInfo
+		source, err = c.sourceInfo(deviceID)
+		if errors.Is(err, ErrDeviceNotFound) {
+			return fmt.Errorf("device %s: %w", deviceID, ErrDeviceNotFound)
+		}
+		cmd = pulse.CommandSetSourcePort
+		ports = source.Ports
+	}
+	if err != nil {
+		return err
+	}
+
+	if !hasPort(ports, port) {
+		return fmt.Errorf("device %s has no port %s", deviceID, port)
+	}
+	if _, err := c.request(cmd, uint32(pulse.Invalid), deviceID, port); err != nil {
+		return fmt.Errorf("failed to set port: %w", err)
+	}
+	return nil
+}
+
+func hasPort(ports []pulse.PortInfo, name string) bool {
+	for _, p := range ports {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ListPorts returns the ports of a device
+func ListPorts(deviceID string) ([]Port, error) {
+	return defaultController.ListPorts(deviceID)
+}
+
+// SetActivePort switches a device to the named port
+func SetActivePort(deviceID, port string) error {
+	return defaultController.SetActivePort(deviceID, port)
+}
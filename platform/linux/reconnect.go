@@ -0,0 +1,171 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/audi70r/go-audio-control/internal/logging"
+	"github.com/audi70r/go-audio-control/internal/pulse"
+)
+
+// Delays between attempts to reconnect, doubling from the first to the last
+var (
+	reconnectMinDelay = 50 * time.Millisecond
+	reconnectMaxDelay = 5 * time.Second
+)
+
+// supervise keeps the event subscription alive once a callback is
+// registered, reconnecting whenever the connection is lost until Close
+func (c *Controller) supervise() {
+	for {
+		c.mu.Lock()
+		client := c.client
+		c.mu.Unlock()
+
+		if client != nil {
+			select {
+			case <-client.Done():
+			case <-c.closed:
+				return
+			}
+			if c.isClosed() {
+				return
+			}
+			c.emit(Event{Type: ServerDisconnected})
+		}
+		if !c.reconnect() {
+			return
+		}
+
+		c.stateMu.Lock()
+		events := c.resync
+		c.resync = nil
+		c.stateMu.Unlock()
+		for _, event := range events {
+			c.emit(event)
+		}
+		c.emit(Event{Type: ServerConnected})
+	}
+}
+
+// reconnect dials until it succeeds, backing off between attempts. It
+// returns false if the Controller is closed first.
+func (c *Controller) reconnect() bool {
+	delay := reconnectMinDelay
+	for {
+		_, err := c.conn()
+		if err == nil {
+			return true
+		}
+		logging.Ignored("reconnect", err, slog.Duration("retry", delay))
+		select {
+		case <-time.After(delay):
+		case <-c.closed:
+			return false
+		}
+		delay = min(2*delay, reconnectMaxDelay)
+	}
+}
+
+func (c *Controller) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// diffLocked returns the events that turn the remembered state into s,
+// cards and sessions. Indices do not survive a server restart, so devices
+// and cards are matched by name. Sessions do not survive it either, and
+// their IDs are built from indices the restarted server hands out again, so
+// every remembered session is removed and every current one added.
+func (c *Controller) diffLocked(s snapshot, cards []pulse.CardInfo, sessions []Session) []Event {
+	var events []Event
+
+	cur := make(map[string]AudioDevice, len(s.sinks)+len(s.sources))
+	for _, sink := range s.sinks {
+		cur[deviceKey(true, sink.Name)] = sinkDevice(sink, s.server.DefaultSinkName)
+	}
+	for _, source := range s.sources {
+		cur[deviceKey(false, source.Name)] = sourceDevice(source, s.server.DefaultSourceName)
+	}
+	old := make(map[string]AudioDevice, len(c.sinks)+len(c.sources))
+	for _, device := range c.sinks {
+		old[deviceKey(true, device.ID)] = device
+	}
+	for _, device := range c.sources {
+		old[deviceKey(false, device.ID)] = device
+	}
+
+	for _, key := range sortedKeys(old) {
+		if _, ok := cur[key]; !ok {
+			device := old[key]
+			events = append(events, Event{Type: DeviceRemoved, DeviceID: device.ID, Info: &device})
+		}
+	}
+	for _, key := range sortedKeys(cur) {
+		device := cur[key]
+		before, known := old[key]
+		if !known {
+			events = append(events, Event{Type: DeviceAdded, DeviceID: device.ID, Info: &device})
+		} else if portsChanged(before.Ports, device.Ports) {
+			events = append(events, Event{Type: PortChanged, DeviceID: device.ID, Info: &device})
+		}
+	}
+
+	if name := s.server.DefaultSinkName; name != c.defaultSink {
+		if device, ok := cur[deviceKey(true, name)]; ok {
+			events = append(events, Event{Type: ActiveDeviceChanged, DeviceID: device.ID, Info: &device})
+		}
+	}
+	if name := s.server.DefaultSourceName; name != c.defaultSource {
+		if device, ok := cur[deviceKey(false, name)]; ok {
+			events = append(events, Event{Type: ActiveDeviceChanged, DeviceID: device.ID, Info: &device})
+		}
+	}
+
+	profiles := make(map[string]string, len(c.cardNames))
+	for index, name := range c.cardNames {
+		profiles[name] = c.profiles[index]
+	}
+	for _, info := range cards {
+		if profile, known := profiles[info.Name]; known && profile != info.ActiveProfile {
+			card := newCard(info, s)
+			events = append(events, Event{Type: ProfileChanged, DeviceID: card.ID, Card: &card})
+		}
+	}
+
+	for _, id := range sortedKeys(c.sessions) {
+		session := c.sessions[id]
+		session.State = SessionExpired
+		events = append(events, Event{Type: SessionRemoved, DeviceID: session.DeviceID, Session: &session})
+	}
+	for _, session := range sessions {
+		session := session
+		events = append(events, Event{Type: SessionAdded, DeviceID: session.DeviceID, Session: &session})
+	}
+	return events
+}
+
+// deviceKey tells a sink and a source of the same name apart
+func deviceKey(output bool, id string) string {
+	if output {
+		return "sink:" + id
+	}
+	return "source:" + id
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,370 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/audi70r/go-audio-control/internal/pulse"
+)
+
+// Session is the stream of one application on a device. Playback streams
+// (sink inputs) have IDs of the form "sink-input:N" and record streams
+// (source outputs) "source-output:N".
+type Session struct {
+	ID       string
+	DeviceID string
+	AppName  string
+	PID      int
+	Volume   float64
+	Muted    bool
+	State    SessionState
+	IsInput  bool
+}
+
+// SessionState reports whether a session is playing or recording
+type SessionState int
+
+const (
+	SessionInactive SessionState = iota
+	SessionActive
+	SessionExpired
+)
+
+// Session ID prefixes
+const (
+	sinkInputPrefix    = "sink-input:"
+	sourceOutputPrefix = "source-output:"
+)
+
+// parseSessionID returns the stream index and whether it is a source output
+func parseSessionID(id string) (uint32, bool, error) {
+	var input bool
+	var rest string
+	switch {
+	case strings.HasPrefix(id, sinkInputPrefix):
+		rest = strings.TrimPrefix(id, sinkInputPrefix)
+	case strings.HasPrefix(id, sourceOutputPrefix):
+		rest = strings.TrimPrefix(id, sourceOutputPrefix)
+		input = true
+	default:
+		return 0, false, fmt.Errorf("session %s: %w", id, ErrDeviceNotFound)
+	}
+	index, err := strconv.ParseUint(rest, 10, 32)
+	if err != nil {
+		return 0, false, fmt.Errorf("session %s: %w", id, ErrDeviceNotFound)
+	}
+	return uint32(index), input, nil
+}
+
+// newSession fills the fields shared by both kinds of stream. The
+// application name falls back to the stream name.
+func newSession(name string, props pulse.Proplist, volume pulse.CVolume, muted, corked bool) Session {
+	session := Session{
+		AppName: props["application.name"],
+		Volume:  math.Min(volume.Avg().Float(), 1.0),
+		Muted:   muted,
+		State:   SessionActive,
+	}
+	if session.AppName == "" {
+		session.AppName = name
+	}
+	session.PID, _ = strconv.Atoi(props["application.process.id"])
+	if corked {
+		session.State = SessionInactive
+	}
+	return session
+}
+
+func sinkInputSession(input pulse.SinkInputInfo, sink string) Session {
+	session := newSession(input.Name, input.Properties, input.Volume, input.Mute, input.Corked)
+	session.ID = sinkInputPrefix + strconv.FormatUint(uint64(input.Index), 10)
+	session.DeviceID = sink
+	return session
+}
+
+func sourceOutputSession(output pulse.SourceOutputInfo, source string) Session {
+	session := newSession(output.Name, output.Properties, output.Volume, output.Mute, output.Corked)
+	session.ID = sourceOutputPrefix + strconv.FormatUint(uint64(output.Index), 10)
+	session.DeviceID = source
+	session.IsInput = true
+	return session
+}
+
+// readSessions lists the streams on the devices of s. Streams recording a
+// monitor source are left out, as monitors are not devices.
+func readSessions(client *pulse.Client, s snapshot) ([]Session, error) {
+	sinks := make(map[uint32]string, len(s.sinks))
+	for _, sink := range s.sinks {
+		sinks[sink.Index] = sink.Name
+	}
+	sources := make(map[uint32]string, len(s.sources))
+	for _, source := range s.sources {
+		sources[source.Index] = source.Name
+	}
+
+	reply, err := client.Request(pulse.CommandGetSinkInputInfoList)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sink inputs: %w", err)
+	}
+	var sessions []Session
+	for !reply.Eof() && reply.Err() == nil {
+		input := pulse.ReadSinkInputInfo(reply)
+		if sink, ok := sinks[input.Sink]; ok {
+			sessions = append(sessions, sinkInputSession(input, sink))
+		}
+	}
+	if err := reply.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse sink inputs: %w", err)
+	}
+
+	reply, err = client.Request(pulse.CommandGetSourceOutputInfoList)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list source outputs: %w", err)
+	}
+	for !reply.Eof() && reply.Err() == nil {
+		output := pulse.ReadSourceOutputInfo(reply)
+		if source, ok := sources[output.Source]; ok {
+			sessions = append(sessions, sourceOutputSession(output, source))
+		}
+	}
+	if err := reply.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse source outputs: %w", err)
+	}
+	return sessions, nil
+}
+
+// rememberSessions replaces the cached sessions used to describe events
+func (c *Controller) rememberSessions(sessions []Session) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+
+	c.sessions = make(map[string]Session, len(sessions))
+	for _, session := range sessions {
+		c.sessions[session.ID] = session
+	}
+}
+
+// ListSessions returns the streams on a device, or on every device when
+// deviceID is empty
+func (c *Controller) ListSessions(deviceID string) ([]Session, error) {
+	client, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+	s, err := readSnapshot(client)
+	if err != nil {
+		return nil, err
+	}
+	if deviceID != "" && !s.hasDevice(deviceID) {
+		return nil, fmt.Errorf("device %s: %w", deviceID, ErrDeviceNotFound)
+	}
+	sessions, err := readSessions(client, s)
+	if err != nil {
+		return nil, err
+	}
+	if deviceID == "" {
+		return sessions, nil
+	}
+
+	var result []Session
+	for _, session := range sessions {
+		if session.DeviceID == deviceID {
+			result = append(result, session)
+		}
+	}
+	return result, nil
+}
+
+// hasDevice reports whether a sink or source in s is named id
+func (s snapshot) hasDevice(id string) bool {
+	for _, sink := range s.sinks {
+		if sink.Name == id {
+			return true
+		}
+	}
+	for _, source := range s.sources {
+		if source.Name == id {
+			return true
+		}
+	}
+	return false
+}
+
+// streamChannels looks up a stream and returns its index, kind and channel
+// count
+func (c *Controller) streamChannels(sessionID string) (uint32, bool, int, error) {
+	index, input, err := parseSessionID(sessionID)
+	if err != nil {
+		return 0, false, 0, err
+	}
+	cmd := pulse.CommandGetSinkInputInfo
+	if input {
+		cmd = pulse.CommandGetSourceOutputInfo
+	}
+	reply, err := c.request(cmd, index)
+	if errors.Is(err, pulse.ErrNoEntity) {
+		return 0, false, 0, fmt.Errorf("session %s: %w", sessionID, ErrDeviceNotFound)
+	}
+	if err != nil {
+		return 0, false, 0, fmt.Errorf("failed to get session info: %w", err)
+	}
+	var volume pulse.CVolume
+	if input {
+		volume = pulse.ReadSourceOutputInfo(reply).Volume
+	} else {
+		volume = pulse.ReadSinkInputInfo(reply).Volume
+	}
+	return index, input, len(volume), reply.Err()
+}
+
+// SetSessionVolume sets every channel of a stream to volume
+func (c *Controller) SetSessionVolume(sessionID string, volume float64) error {
+	index, input, channels, err := c.streamChannels(sessionID)
+	if err != nil {
+		return err
+	}
+	cmd := pulse.CommandSetSinkInputVolume
+	if input {
+		cmd = pulse.CommandSetSourceOutputVolume
+	}
+	cv := pulse.NewCVolume(channels, pulse.VolumeFromFloat(volume))
+	if _, err := c.request(cmd, index, cv); err != nil {
+		return fmt.Errorf("failed to set session volume: %w", err)
+	}
+	return nil
+}
+
+// SetSessionMute mutes or unmutes a stream
+func (c *Controller) SetSessionMute(sessionID string, muted bool) error {
+	index, input, _, err := c.streamChannels(sessionID)
+	if err != nil {
+		return err
+	}
+	cmd := pulse.CommandSetSinkInputMute
+	if input {
+		cmd = pulse.CommandSetSourceOutputMute
+	}
+	if _, err := c.request(cmd, index, muted); err != nil {
+		return fmt.Errorf("failed to set session mute: %w", err)
+	}
+	return nil
+}
+
+// MoveSession moves a stream to another device of the same direction.
+// pipewire-pulse carries out the move by setting the target.object metadata
+// of the stream, so the same request serves both servers.
+func (c *Controller) MoveSession(sessionID, deviceID string) error {
+	index, input, err := parseSessionID(sessionID)
+	if err != nil {
+		return err
+	}
+	cmd := pulse.CommandMoveSinkInput
+	if input {
+		cmd = pulse.CommandMoveSourceOutput
+		_, err = c.sourceInfo(deviceID)
+	} else {
+		_, err = c.sinkInfo(deviceID)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = c.request(cmd, index, uint32(pulse.Invalid), deviceID)
+	if errors.Is(err, pulse.ErrNoEntity) {
+		return fmt.Errorf("session %s: %w", sessionID, ErrDeviceNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to move session %s: %w", sessionID, err)
+	}
+	return nil
+}
+
+// handleStreamEvent reports sink inputs and source outputs that appear or
+// go away
+func (c *Controller) handleStreamEvent(e pulse.SubscriptionEvent) {
+	input := e.Facility() == pulse.FacilitySourceOutput
+	prefix := sinkInputPrefix
+	if input {
+		prefix = sourceOutputPrefix
+	}
+	id := prefix + strconv.FormatUint(uint64(e.Index), 10)
+
+	if e.Type() == pulse.EventRemove {
+		c.stateMu.Lock()
+		session, ok := c.sessions[id]
+		delete(c.sessions, id)
+		c.stateMu.Unlock()
+		if ok {
+			session.State = SessionExpired
+			c.emit(Event{Type: SessionRemoved, DeviceID: session.DeviceID, Session: &session})
+		}
+		return
+	}
+
+	cmd := pulse.CommandGetSinkInputInfo
+	if input {
+		cmd = pulse.CommandGetSourceOutputInfo
+	}
+	reply, err := c.request(cmd, e.Index)
+	if err != nil {
+		ignoreEventError("get stream info", e, err)
+		return
+	}
+
+	var session Session
+	var device uint32
+	if input {
+		output := pulse.ReadSourceOutputInfo(reply)
+		session, device = sourceOutputSession(output, ""), output.Source
+	} else {
+		sinkInput := pulse.ReadSinkInputInfo(reply)
+		session, device = sinkInputSession(sinkInput, ""), sinkInput.Sink
+	}
+	if err := reply.Err(); err != nil {
+		ignoreEventError("read stream info", e, err)
+		return
+	}
+
+	c.stateMu.Lock()
+	devices := c.sinks
+	if input {
+		devices = c.sources
+	}
+	owner, ok := devices[device]
+	_, known := c.sessions[id]
+	if ok {
+		session.DeviceID = owner.ID
+		c.sessions[id] = session
+	}
+	c.stateMu.Unlock()
+
+	if ok && !known {
+		c.emit(Event{Type: SessionAdded, DeviceID: session.DeviceID, Session: &session})
+	}
+}
+
+// ListSessions returns the streams on a device of the default server
+func ListSessions(deviceID string) ([]Session, error) {
+	return defaultController.ListSessions(deviceID)
+}
+
+// SetSessionVolume sets the volume of a stream
+func SetSessionVolume(sessionID string, volume float64) error {
+	return defaultController.SetSessionVolume(sessionID, volume)
+}
+
+// SetSessionMute mutes or unmutes a stream
+func SetSessionMute(sessionID string, muted bool) error {
+	return defaultController.SetSessionMute(sessionID, muted)
+}
+
+// MoveSession moves a stream to another device
+func MoveSession(sessionID, deviceID string) error {
+	return defaultController.MoveSession(sessionID, deviceID)
+}
@@ -0,0 +1,264 @@
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/audi70r/go-audio-control/internal/logging"
+	"github.com/audi70r/go-audio-control/internal/pulse"
+)
+
+// VirtualDeviceKind selects the module that provides a virtual device
+type VirtualDeviceKind int
+
+const (
+	VirtualNullSink VirtualDeviceKind = iota
+	VirtualLoopback
+	VirtualCombinedSink
+)
+
+// VirtualDeviceSpec describes a virtual device. Name is the sink name of
+// null and combined sinks, which becomes their device ID. Source and Sink
+// are the ends of a loopback and default to the server defaults. Outputs
+// are the sinks a combined sink plays to.
+type VirtualDeviceSpec struct {
+	Kind        VirtualDeviceKind
+	Name        string
+	Description string
+	Source      string
+	Sink        string
+	Outputs     []string
+}
+
+// Properties recording the process that loaded a module, so that modules
+// left behind by a process that died can be told apart from those of the
+// user or of running processes
+const (
+	ownerPIDProperty  = "audiocontrol.owner.pid"
+	ownerHostProperty = "audiocontrol.owner.host"
+)
+
+// VirtualDevice is a module loaded by CreateVirtualDevice
+type VirtualDevice struct {
+	controller *Controller
+	module     uint32
+	deviceID   string
+
+	once sync.Once
+	err  error
+}
+
+// DeviceID returns the ID of the sink created, or "" for a loopback
+func (d *VirtualDevice) DeviceID() string {
+	return d.deviceID
+}
+
+// Module returns the index of the module behind the device
+func (d *VirtualDevice) Module() uint32 {
+	return d.module
+}
+
+// Close unloads the module, removing the device. A module that is already
+// gone is not an error.
+func (d *VirtualDevice) Close() error {
+	d.once.Do(func() {
+		d.controller.virtualMu.Lock()
+		delete(d.controller.virtual, d.module)
+		d.controller.virtualMu.Unlock()
+
+		_, err := d.controller.request(pulse.CommandUnloadModule, d.module)
+		if err != nil && !errors.Is(err, pulse.ErrNoEntity) {
+			d.err = fmt.Errorf("failed to unload module %d: %w", d.module, err)
+		}
+	})
+	return d.err
+}
+
+// CreateVirtualDevice loads module-null-sink, module-loopback or
+// module-combine-sink as spec asks. Modules of processes on this host that
+// have exited are unloaded first.
+func (c *Controller) CreateVirtualDevice(spec VirtualDeviceSpec) (*VirtualDevice, error) {
+	name, args, err := c.moduleArguments(spec)
+	if err != nil {
+		return nil, err
+	}
+	// Leftovers are removed again by the next call if this fails
+	if _, err := c.RemoveStaleVirtualDevices(); err != nil {
+		logging.Ignored("remove stale virtual devices", err)
+	}
+
+	reply, err := c.request(pulse.CommandLoadModule, name, pulse.FormatArguments(args))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", name, err)
+	}
+	module := reply.U32()
+	if err := reply.Err(); err != nil {
+		return nil, err
+	}
+
+	d := &VirtualDevice{controller: c, module: module}
+	if spec.Kind != VirtualLoopback {
+		d.deviceID = spec.Name
+	}
+	c.virtualMu.Lock()
+	c.virtual[module] = d
+	c.virtualMu.Unlock()
+	return d, nil
+}
+
+// moduleArguments returns the module and arguments for spec after checking
+// that the devices it names exist
+func (c *Controller) moduleArguments(spec VirtualDeviceSpec) (string, map[string]string, error) {
+	props := ownerProperties()
+	if spec.Description != "" {
+		props["device.description"] = spec.Description
+	}
+
+	switch spec.Kind {
+	case VirtualNullSink, VirtualCombinedSink:
+		if spec.Name == "" {
+			return "", nil, errors.New("virtual device name is required")
+		}
+		if _, err := c.sinkInfo(spec.Name); err == nil {
+			return "", nil, fmt.Errorf("output %s already exists", spec.Name)
+		}
+		args := map[string]string{
+			"sink_name":       spec.Name,
+			"sink_properties": pulse.FormatArguments(props),
+		}
+		if spec.Kind == VirtualNullSink {
+			return "module-null-sink", args, nil
+		}
+		for _, output := range spec.Outputs {
+			if _, err := c.sinkInfo(output); err != nil {
+				return "", nil, err
+			}
+		}
+		args["slaves"] = strings.Join(spec.Outputs, ",")
+		return "module-combine-sink", args, nil
+
+	case VirtualLoopback:
+		args := make(map[string]string)
+		if spec.Source != "" {
+			if _, err := c.sourceInfo(spec.Source); err != nil {
+				return "", nil, err
+			}
+			args["source"] = spec.Source
+		}
+		if spec.Sink != "" {
+			if _, err := c.sinkInfo(spec.Sink); err != nil {
+				return "", nil, err
+			}
+			args["sink"] = spec.Sink
+		}
+		if spec.Description != "" {
+			props["media.name"] = spec.Description
+			delete(props, "device.description")
+		}
+		args["sink_input_properties"] = pulse.FormatArguments(props)
+		return "module-loopback", args, nil
+	}
+	return "", nil, fmt.Errorf("unknown virtual device kind %d", spec.Kind)
+}
+
+func ownerProperties() map[string]string {
+	host, _ := os.Hostname()
+	return map[string]string{
+		ownerPIDProperty:  strconv.Itoa(os.Getpid()),
+		ownerHostProperty: host,
+	}
+}
+
+// CloseVirtualDevices unloads every module this controller loaded. Call it
+// before the process exits; modules left behind are removed by the next
+// CreateVirtualDevice on this host.
+func (c *Controller) CloseVirtualDevices() error {
+	c.virtualMu.Lock()
+	devices := make([]*VirtualDevice, 0, len(c.virtual))
+	for _, d := range c.virtual {
+		devices = append(devices, d)
+	}
+	c.virtualMu.Unlock()
+
+	var errs []error
+	for _, d := range devices {
+		if err := d.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RemoveStaleVirtualDevices unloads the modules loaded by processes on this
+// host that are no longer running and returns how many were removed
+func (c *Controller) RemoveStaleVirtualDevices() (int, error) {
+	reply, err := c.request(pulse.CommandGetModuleInfoList)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list modules: %w", err)
+	}
+	var stale []uint32
+	for !reply.Eof() && reply.Err() == nil {
+		module := pulse.ReadModuleInfo(reply)
+		if pid, ok := moduleOwner(module); ok && !processRunning(pid) {
+			stale = append(stale, module.Index)
+		}
+	}
+	if err := reply.Err(); err != nil {
+		return 0, fmt.Errorf("failed to parse modules: %w", err)
+	}
+
+	removed := 0
+	for _, index := range stale {
+		if _, err := c.request(pulse.CommandUnloadModule, index); err != nil && !errors.Is(err, pulse.ErrNoEntity) {
+			return removed, fmt.Errorf("failed to unload module %d: %w", index, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// moduleOwner returns the process that loaded module if it was loaded by
+// this package on this host
+func moduleOwner(module pulse.ModuleInfo) (int, bool) {
+	args, err := pulse.ParseArguments(module.Argument)
+	if err != nil {
+		return 0, false
+	}
+	for _, key := range []string{"sink_properties", "sink_input_properties"} {
+		props, err := pulse.ParseArguments(args[key])
+		if err != nil || props[ownerPIDProperty] == "" {
+			continue
+		}
+		if host, _ := os.Hostname(); props[ownerHostProperty] != host {
+			return 0, false
+		}
+		pid, err := strconv.Atoi(props[ownerPIDProperty])
+		return pid, err == nil && pid > 0
+	}
+	return 0, false
+}
+
+// processRunning reports whether a process with the given ID exists
+func processRunning(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// CreateVirtualDevice creates a virtual device on the default server
+func CreateVirtualDevice(spec VirtualDeviceSpec) (*VirtualDevice, error) {
+	return defaultController.CreateVirtualDevice(spec)
+}
+
+// CloseVirtualDevices removes the virtual devices created on the default
+// server
+func CloseVirtualDevices() error {
+	return defaultController.CloseVirtualDevices()
+}
@@ -0,0 +1,413 @@
+//go:build windows
+// +build windows
+
+package windows
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/go-ole/go-ole"
+)
+
+var (
+	IID_IAudioClient        = &ole.GUID{0x1CB9AD4C, 0xDBFA, 0x4C32, [8]byte{0xB1, 0x78, 0xC2, 0xF5, 0x68, 0xA7, 0x03, 0xB2}}
+	IID_IAudioCaptureClient = &ole.GUID{0xC8ADBD64, 0xE71E, 0x48A0, [8]byte{0xA4, 0xDE, 0x18, 0x5C, 0x39, 0x5C, 0xD3, 0x17}}
+)
+
+// Audio client stream flags and results
+const (
+	AUDCLNT_SHAREMODE_SHARED                = 0
+	AUDCLNT_STREAMFLAGS_LOOPBACK            = 0x00020000
+	AUDCLNT_STREAMFLAGS_SRC_DEFAULT_QUALITY = 0x08000000
+	AUDCLNT_STREAMFLAGS_AUTOCONVERTPCM      = 0x80000000
+	AUDCLNT_BUFFERFLAGS_SILENT              = 0x2
+	AUDCLNT_E_DEVICE_INVALIDATED            = 0x88890004
+)
+
+// IAudioClient interface
+type IAudioClient struct {
+	vtbl *IAudioClientVtbl
+}
+
+type IAudioClientVtbl struct {
+	QueryInterface uintptr
+	AddRef         uintptr
+	Release        uintptr
+
+	Initialize        uintptr
+	GetBufferSize     uintptr
+	GetStreamLatency  uintptr
+	GetCurrentPadding uintptr
+	IsFormatSupported uintptr
+	GetMixFormat      uintptr
+	GetDevicePeriod   uintptr
+	Start             uintptr
+	Stop              uintptr
+	Reset             uintptr
+	SetEventHandle    uintptr
+	GetService        uintptr
+}
+
+func (c *IAudioClient) Release() {
+	syscall.Syscall(c.vtbl.Release, 1, uintptr(unsafe.Pointer(c)), 0, 0)
+}
+
+// IAudioCaptureClient interface
+type IAudioCaptureClient struct {
+	vtbl *IAudioCaptureClientVtbl
+}
+
+type IAudioCaptureClientVtbl struct {
+	QueryInterface uintptr
+	AddRef         uintptr
+	Release        uintptr
+
+	GetBuffer         uintptr
+	ReleaseBuffer     uintptr
+	GetNextPacketSize uintptr
+}
+
+func (c *IAudioCaptureClient) Release() {
+	syscall.Syscall(c.vtbl.Release, 1, uintptr(unsafe.Pointer(c)), 0, 0)
+}
+
+// referenceTime passes a REFERENCE_TIME argument, which takes two slots
+// on 32-bit platforms
+func referenceTime(d time.Duration) []uintptr {
+	hns := uint64(d / 100)
+	if unsafe.Sizeof(uintptr(0)) == 4 {
+		return []uintptr{uintptr(uint32(hns)), uintptr(uint32(hns >> 32))}
+	}
+	return []uintptr{uintptr(hns)}
+}
+
+// Initialize sets up a shared-mode stream with a buffer of the given length
+func (c *IAudioClient) Initialize(flags uint32, buffer time.Duration, format *WAVEFORMATEX) error {
+	args := []uintptr{uintptr(unsafe.Pointer(c)), AUDCLNT_SHAREMODE_SHARED, uintptr(flags)}
+	args = append(args, referenceTime(buffer)...)
+	args = append(args, referenceTime(0)...)
+	args = append(args, uintptr(unsafe.Pointer(format)), 0)
+	hr, _, _ := syscall.SyscallN(c.vtbl.Initialize, args...)
+	if hr != 0 {
+		return ole.NewError(hr)
+	}
+	return nil
+}
+
+// GetMixFormat returns the channel count and rate the engine mixes at
+func (c *IAudioClient) GetMixFormat() (channels, rate int, err error) {
+	var format *WAVEFORMATEX
+	hr, _, _ := syscall.Syscall(
+		c.vtbl.GetMixFormat,
+		2,
+		uintptr(unsafe.Pointer(c)),
+		uintptr(unsafe.Pointer(&format)),
+		0,
+	)
+	if hr != 0 {
+		return 0, 0, ole.NewError(hr)
+	}
+	defer ole.CoTaskMemFree(uintptr(unsafe.Pointer(format)))
+	return int(format.Channels), int(format.SamplesPerSec), nil
+}
+
+func (c *IAudioClient) Start() error {
+	hr, _, _ := syscall.Syscall(c.vtbl.Start, 1, uintptr(unsafe.Pointer(c)), 0, 0)
+	if hr != 0 {
+		return ole.NewError(hr)
+	}
+	return nil
+}
+
+func (c *IAudioClient) Stop() {
+	syscall.Syscall(c.vtbl.Stop, 1, uintptr(unsafe.Pointer(c)), 0, 0)
+}
+
+// GetCaptureClient returns the capture service of an initialised client
+func (c *IAudioClient) GetCaptureClient() (*IAudioCaptureClient, error) {
+	var capture *IAudioCaptureClient
+	hr, _, _ := syscall.Syscall(
+		c.vtbl.GetService,
+		3,
+		uintptr(unsafe.Pointer(c)),
+		uintptr(unsafe.Pointer(IID_IAudioCaptureClient)),
+		uintptr(unsafe.Pointer(&capture)),
+	)
+	if hr != 0 {
+		return nil, ole.NewError(hr)
+	}
+	return capture, nil
+}
+
+// NextPacketSize returns the number of frames in the next packet
+func (c *IAudioCaptureClient) NextPacketSize() (uint32, error) {
+	var frames uint32
+	hr, _, _ := syscall.Syscall(
+		c.vtbl.GetNextPacketSize,
+		2,
+		uintptr(unsafe.Pointer(c)),
+		uintptr(unsafe.Pointer(&frames)),
+		0,
+	)
+	if hr != 0 {
+		return 0, ole.NewError(hr)
+	}
+	return frames, nil
+}
+
+// ReadPacket copies the next packet, of frameSize bytes per frame, and
+// releases it
+func (c *IAudioCaptureClient) ReadPacket(frameSize int) ([]byte, error) {
+	var data *byte
+	var frames, flags uint32
+	hr, _, _ := syscall.Syscall6(
+		c.vtbl.GetBuffer,
+		6,
+		uintptr(unsafe.Pointer(c)),
+		uintptr(unsafe.Pointer(&data)),
+		uintptr(unsafe.Pointer(&frames)),
+		uintptr(unsafe.Pointer(&flags)),
+		0,
+		0,
+	)
+	if hr != 0 {
+		return nil, ole.NewError(hr)
+	}
+	packet := make([]byte, int(frames)*frameSize)
+	if flags&AUDCLNT_BUFFERFLAGS_SILENT == 0 && len(packet) > 0 {
+		copy(packet, unsafe.Slice(data, len(packet)))
+	}
+	hr, _, _ = syscall.Syscall(c.vtbl.ReleaseBuffer, 2, uintptr(unsafe.Pointer(c)), uintptr(frames), 0)
+	if hr != 0 {
+		return nil, ole.NewError(hr)
+	}
+	return packet, nil
+}
+
+// Capture timing
+const (
+	captureBuffer = 200 * time.Millisecond
+	capturePoll   = 10 * time.Millisecond
+)
+
+// maxCaptured is the most audio kept for a reader that falls behind
+const maxCaptured = 1 << 20
+
+// Capture is interleaved PCM recorded from an endpoint
+type Capture struct {
+	format    Format
+	frameSize int
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	buf  []byte
+	err  error
+
+	done chan struct{}
+	once sync.Once
+}
+
+// OpenCapture records a capture endpoint in format. A format without a
+// channel count or rate takes those of the endpoint mix; the audio engine
+// converts everything else.
+func OpenCapture(deviceID string, format Format) (*Capture, error) {
+	return openCapture(deviceID, format, eCapture)
+}
+
+// OpenLoopback records what a render endpoint plays, in the same way as
+// OpenCapture. The engine delivers nothing while the endpoint is silent.
+func OpenLoopback(deviceID string, format Format) (*Capture, error) {
+	return openCapture(deviceID, format, eRender)
+}
+
+// openCapture records an endpoint of the given flow. Render endpoints are
+// recorded through the loopback of their mix.
+func openCapture(deviceID string, format Format, flow EDataFlow) (*Capture, error) {
+	c := &Capture{done: make(chan struct{})}
+	c.cond = sync.NewCond(&c.mu)
+
+	// The client is opened on the polling thread, which owns it
+	opened := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		if err := InitializeCOM(); err == nil {
+			defer UninitializeCOM()
+		}
+
+		client, capture, err := c.open(deviceID, format, flow)
+		if err != nil {
+			opened <- err
+			return
+		}
+		defer client.Release()
+		defer capture.Release()
+		defer client.Stop()
+		opened <- nil
+		c.end(c.poll(capture))
+	}()
+
+	if err := <-opened; err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// open activates and starts an audio client on the endpoint and settles
+// c.format
+func (c *Capture) open(deviceID string, format Format, flow EDataFlow) (*IAudioClient, *IAudioCaptureClient, error) {
+	enumerator, err := CreateDeviceEnumerator()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create device enumerator: %w", err)
+	}
+	defer enumerator.Release()
+
+	device, err := enumerator.GetDevice(deviceID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get device %s: %w", deviceID, err)
+	}
+	defer device.Release()
+	if endpointFlow(device) != flow {
+		if flow == eRender {
+			return nil, nil, fmt.Errorf("output %s: %w", deviceID, ErrDeviceNotFound)
+		}
+		return nil, nil, fmt.Errorf("input %s: %w", deviceID, ErrDeviceNotFound)
+	}
+
+	object, err := device.Activate(IID_IAudioClient, ole.CLSCTX_ALL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to activate audio client: %w", err)
+	}
+	client := (*IAudioClient)(object)
+
+	channels, rate, err := client.GetMixFormat()
+	if err != nil {
+		client.Release()
+		return nil, nil, fmt.Errorf("failed to get mix format: %w", err)
+	}
+	if format.Channels == 0 {
+		format.Channels = channels
+	}
+	if format.Rate == 0 {
+		format.Rate = rate
+	}
+	wave, err := format.waveFormat()
+	if err != nil {
+		client.Release()
+		return nil, nil, err
+	}
+	flags := uint32(AUDCLNT_STREAMFLAGS_AUTOCONVERTPCM | AUDCLNT_STREAMFLAGS_SRC_DEFAULT_QUALITY)
+	if flow == eRender {
+		flags |= AUDCLNT_STREAMFLAGS_LOOPBACK
+	}
+	if err := client.Initialize(flags, captureBuffer, wave); err != nil {
+		client.Release()
+		return nil, nil, fmt.Errorf("failed to initialize capture of %s: %w", deviceID, err)
+	}
+
+	capture, err := client.GetCaptureClient()
+	if err != nil {
+		client.Release()
+		return nil, nil, fmt.Errorf("failed to get capture client: %w", err)
+	}
+	if err := client.Start(); err != nil {
+		capture.Release()
+		client.Release()
+		return nil, nil, fmt.Errorf("failed to start capture of %s: %w", deviceID, err)
+	}
+	c.format = format
+	c.frameSize = int(wave.BlockAlign)
+	return client, capture, nil
+}
+
+// poll moves packets from the engine to the buffer until Close is called
+// or the endpoint fails, and returns the error reads should end with
+func (c *Capture) poll(capture *IAudioCaptureClient) error {
+	ticker := time.NewTicker(capturePoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return io.EOF
+		case <-ticker.C:
+		}
+		for {
+			frames, err := capture.NextPacketSize()
+			if err == nil && frames == 0 {
+				break
+			}
+			var packet []byte
+			if err == nil {
+				packet, err = capture.ReadPacket(c.frameSize)
+			}
+			var oleErr *ole.OleError
+			if errors.As(err, &oleErr) && oleErr.Code() == AUDCLNT_E_DEVICE_INVALIDATED {
+				return fmt.Errorf("capture ended: %w", ErrDeviceNotFound)
+			}
+			if err != nil {
+				return err
+			}
+			c.deliver(packet)
+		}
+	}
+}
+
+// deliver appends a packet, dropping the oldest whole frames beyond
+// maxCaptured
+func (c *Capture) deliver(packet []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.buf = append(c.buf, packet...)
+	if len(c.buf) > maxCaptured {
+		drop := len(c.buf) - maxCaptured
+		drop += (c.frameSize - drop%c.frameSize) % c.frameSize
+		c.buf = c.buf[drop:]
+	}
+	c.cond.Broadcast()
+}
+
+// end makes reads fail with err once the buffer is drained
+func (c *Capture) end(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = err
+	}
+	c.cond.Broadcast()
+}
+
+// Format returns the format of the recorded PCM
+func (c *Capture) Format() Format {
+	return c.format
+}
+
+// Read reads recorded PCM, blocking until some is available. Once Close is
+// called, reads return io.EOF; when the endpoint goes away they return
+// ErrDeviceNotFound.
+func (c *Capture) Read(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.buf) == 0 && c.err == nil {
+		c.cond.Wait()
+	}
+	if len(c.buf) == 0 {
+		return 0, c.err
+	}
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
+
+// Close stops recording
+func (c *Capture) Close() error {
+	c.once.Do(func() { close(c.done) })
+	c.end(io.EOF)
+	return nil
+}
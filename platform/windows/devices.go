@@ -24,6 +24,12 @@ var (
 	CLSID_PolicyConfigClient = &ole.GUID{0x870AF99C, 0x171D, 0x4F9E, [8]byte{0xAF, 0x0D, 0xE6, 0x3D, 0xF4, 0x0C, 0x2B, 0xC9}}
 )
 
+// E_NOTFOUND is returned by GetDevice for unknown endpoint IDs
+const E_NOTFOUND = 0x80070490
+
+// ErrDeviceNotFound is returned for IDs that name no endpoint
+var ErrDeviceNotFound = errors.New("device not found")
+
 // Property keys
 type PROPERTYKEY struct {
 	fmtid ole.GUID
@@ -195,6 +201,34 @@ type AudioDevice struct {
 	IsOutput    bool
 	IsActive    bool
 	IsConnected bool
+
+	IsDefaultOutput         bool
+	IsDefaultInput          bool
+	IsDefaultCommunications bool
+	State                   DeviceState
+}
+
+// DeviceState is the presence state of a device
+type DeviceState int
+
+const (
+	StateActive DeviceState = iota
+	StateDisabled
+	StateNotPresent
+	StateUnplugged
+)
+
+// deviceState maps a DEVICE_STATE_* value to a DeviceState
+func deviceState(state uint32) DeviceState {
+	switch state {
+	case DEVICE_STATE_DISABLED:
+		return StateDisabled
+	case DEVICE_STATE_NOTPRESENT:
+		return StateNotPresent
+	case DEVICE_STATE_UNPLUGGED:
+		return StateUnplugged
+	}
+	return StateActive
 }
 
 // Initialize COM
@@ -209,18 +243,11 @@ func UninitializeCOM() {
 
 // CreateDeviceEnumerator creates an IMMDeviceEnumerator instance
 func CreateDeviceEnumerator() (*IMMDeviceEnumerator, error) {
-	var enumerator *IMMDeviceEnumerator
-	hr := ole.CoCreateInstance(
-		CLSID_MMDeviceEnumerator,
-		nil,
-		ole.CLSCTX_ALL,
-		IID_IMMDeviceEnumerator,
-		(*unsafe.Pointer)(unsafe.Pointer(&enumerator)),
-	)
-	if hr != 0 {
-		return nil, ole.NewError(hr)
+	unknown, err := ole.CreateInstance(CLSID_MMDeviceEnumerator, IID_IMMDeviceEnumerator)
+	if err != nil {
+		return nil, err
 	}
-	return enumerator, nil
+	return (*IMMDeviceEnumerator)(unsafe.Pointer(unknown)), nil
 }
 
 // EnumAudioEndpoints enumerates audio endpoints
@@ -394,120 +421,97 @@ func GetDeviceName(device *IMMDevice) (string, error) {
 	return name, nil
 }
 
-// ListAudioDevices enumerates all audio devices
+// ListAudioDevices enumerates the active audio devices
 func ListAudioDevices() ([]AudioDevice, error) {
+	return ListAudioDevicesWithState(DEVICE_STATE_ACTIVE)
+}
+
+// ListAudioDevicesWithState enumerates the audio devices whose state is in
+// stateMask, a combination of DEVICE_STATE_* values
+func ListAudioDevicesWithState(stateMask uint32) ([]AudioDevice, error) {
 	enumerator, err := CreateDeviceEnumerator()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create device enumerator: %w", err)
 	}
 	defer enumerator.Release()
-	
-	var devices []AudioDevice
-	
-	// Get default output device ID
-	defaultOutputID := ""
-	defaultOutput, err := enumerator.GetDefaultAudioEndpoint(eRender, eConsole)
-	if err == nil {
-		defaultOutputID, _ = defaultOutput.GetId()
-		defaultOutput.Release()
-	}
-	
-	// Get default input device ID
-	defaultInputID := ""
-	defaultInput, err := enumerator.GetDefaultAudioEndpoint(eCapture, eConsole)
-	if err == nil {
-		defaultInputID, _ = defaultInput.GetId()
-		defaultInput.Release()
-	}
-	
-	// Enumerate output devices
-	outputCollection, err := enumerator.EnumAudioEndpoints(eRender, DEVICE_STATE_ACTIVE)
+
+	outputs, err := listEndpoints(enumerator, eRender, stateMask)
 	if err != nil {
 		return nil, fmt.Errorf("failed to enumerate output devices: %w", err)
 	}
-	defer outputCollection.Release()
-	
-	outputCount, err := outputCollection.GetCount()
+	inputs, err := listEndpoints(enumerator, eCapture, stateMask)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get output device count: %w", err)
+		return nil, fmt.Errorf("failed to enumerate input devices: %w", err)
 	}
-	
-	for i := uint32(0); i < outputCount; i++ {
-		device, err := outputCollection.Item(i)
-		if err != nil {
-			continue
-		}
-		
-		id, err := device.GetId()
-		if err != nil {
-			device.Release()
-			continue
-		}
-		
-		name, err := GetDeviceName(device)
-		if err != nil {
-			device.Release()
-			continue
-		}
-		
-		state, _ := device.GetState()
-		
-		devices = append(devices, AudioDevice{
-			ID:          id,
-			Name:        name,
-			IsInput:     false,
-			IsOutput:    true,
-			IsActive:    id == defaultOutputID,
-			IsConnected: state == DEVICE_STATE_ACTIVE,
-		})
-		
-		device.Release()
+	return append(outputs, inputs...), nil
+}
+
+// defaultEndpointID returns the ID of the default endpoint for flow and
+// role, or "" when there is none
+func defaultEndpointID(enumerator *IMMDeviceEnumerator, flow EDataFlow, role ERole) string {
+	device, err := enumerator.GetDefaultAudioEndpoint(flow, role)
+	if err != nil {
+		return ""
 	}
-	
-	// Enumerate input devices
-	inputCollection, err := enumerator.EnumAudioEndpoints(eCapture, DEVICE_STATE_ACTIVE)
+	defer device.Release()
+	id, _ := device.GetId()
+	return id
+}
+
+// listEndpoints returns the endpoints of one data flow whose state is in
+// stateMask
+func listEndpoints(enumerator *IMMDeviceEnumerator, flow EDataFlow, stateMask uint32) ([]AudioDevice, error) {
+	defaultID := defaultEndpointID(enumerator, flow, eConsole)
+	communicationsID := defaultEndpointID(enumerator, flow, eCommunications)
+
+	collection, err := enumerator.EnumAudioEndpoints(flow, stateMask)
 	if err != nil {
-		return nil, fmt.Errorf("failed to enumerate input devices: %w", err)
+		return nil, err
 	}
-	defer inputCollection.Release()
-	
-	inputCount, err := inputCollection.GetCount()
+	defer collection.Release()
+
+	count, err := collection.GetCount()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get input device count: %w", err)
+		return nil, fmt.Errorf("failed to get device count: %w", err)
 	}
-	
-	for i := uint32(0); i < inputCount; i++ {
-		device, err := inputCollection.Item(i)
+
+	var devices []AudioDevice
+	for i := uint32(0); i < count; i++ {
+		device, err := collection.Item(i)
 		if err != nil {
 			continue
 		}
-		
+
 		id, err := device.GetId()
 		if err != nil {
 			device.Release()
 			continue
 		}
-		
+
 		name, err := GetDeviceName(device)
 		if err != nil {
 			device.Release()
 			continue
 		}
-		
+
 		state, _ := device.GetState()
-		
+		device.Release()
+
+		isDefault := id == defaultID
 		devices = append(devices, AudioDevice{
 			ID:          id,
 			Name:        name,
-			IsInput:     true,
-			IsOutput:    false,
-			IsActive:    id == defaultInputID,
+			IsInput:     flow == eCapture,
+			IsOutput:    flow == eRender,
+			IsActive:    isDefault,
 			IsConnected: state == DEVICE_STATE_ACTIVE,
+
+			IsDefaultOutput:         isDefault && flow == eRender,
+			IsDefaultInput:          isDefault && flow == eCapture,
+			IsDefaultCommunications: id == communicationsID,
+			State:                   deviceState(state),
 		})
-		
-		device.Release()
 	}
-	
 	return devices, nil
 }
 
@@ -544,23 +548,20 @@ func GetActiveOutputDevice() (*AudioDevice, error) {
 		IsOutput:    true,
 		IsActive:    true,
 		IsConnected: state == DEVICE_STATE_ACTIVE,
+
+		IsDefaultOutput:         true,
+		IsDefaultCommunications: id == defaultEndpointID(enumerator, eRender, eCommunications),
+		State:                   deviceState(state),
 	}, nil
 }
 
 // CreatePolicyConfig creates an IPolicyConfig instance
 func CreatePolicyConfig() (*IPolicyConfig, error) {
-	var policyConfig *IPolicyConfig
-	hr := ole.CoCreateInstance(
-		CLSID_PolicyConfigClient,
-		nil,
-		ole.CLSCTX_ALL,
-		IID_IPolicyConfig,
-		(*unsafe.Pointer)(unsafe.Pointer(&policyConfig)),
-	)
-	if hr != 0 {
-		return nil, ole.NewError(hr)
+	unknown, err := ole.CreateInstance(CLSID_PolicyConfigClient, IID_IPolicyConfig)
+	if err != nil {
+		return nil, err
 	}
-	return policyConfig, nil
+	return (*IPolicyConfig)(unsafe.Pointer(unknown)), nil
 }
 
 // SetDefaultEndpoint sets the default audio endpoint
@@ -590,14 +591,77 @@ func PropVariantClear(pv *PropVariant) {
 	procPropVariantClear.Call(uintptr(unsafe.Pointer(pv)))
 }
 
+// GetActiveInputDevice returns the currently active input device
+func GetActiveInputDevice() (*AudioDevice, error) {
+	enumerator, err := CreateDeviceEnumerator()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create device enumerator: %w", err)
+	}
+	defer enumerator.Release()
+
+	device, err := enumerator.GetDefaultAudioEndpoint(eCapture, eConsole)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default input device: %w", err)
+	}
+	defer device.Release()
+
+	id, err := device.GetId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device ID: %w", err)
+	}
+
+	name, err := GetDeviceName(device)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device name: %w", err)
+	}
+
+	state, _ := device.GetState()
+
+	return &AudioDevice{
+		ID:          id,
+		Name:        name,
+		IsInput:     true,
+		IsOutput:    false,
+		IsActive:    true,
+		IsConnected: state == DEVICE_STATE_ACTIVE,
+
+		IsDefaultInput:          true,
+		IsDefaultCommunications: id == defaultEndpointID(enumerator, eCapture, eCommunications),
+		State:                   deviceState(state),
+	}, nil
+}
+
 // SetActiveOutputDevice sets the active output device by ID
 func SetActiveOutputDevice(deviceID string) error {
+	return setDefaultEndpoint(deviceID)
+}
+
+// SetActiveInputDevice sets the active input device by ID
+func SetActiveInputDevice(deviceID string) error {
+	return setDefaultEndpoint(deviceID)
+}
+
+// setDefaultEndpoint makes deviceID the default endpoint for all roles. The
+// data flow is implied by the endpoint itself.
+func setDefaultEndpoint(deviceID string) error {
+	enumerator, err := CreateDeviceEnumerator()
+	if err != nil {
+		return fmt.Errorf("failed to create device enumerator: %w", err)
+	}
+	defer enumerator.Release()
+
+	device, err := enumerator.GetDevice(deviceID)
+	if err != nil {
+		return err
+	}
+	device.Release()
+
 	policyConfig, err := CreatePolicyConfig()
 	if err != nil {
 		return fmt.Errorf("failed to create policy config: %w", err)
 	}
 	defer policyConfig.Release()
-	
+
 	// Set for all roles
 	if err := policyConfig.SetDefaultEndpoint(deviceID, eConsole); err != nil {
 		return fmt.Errorf("failed to set console endpoint: %w", err)
@@ -608,6 +672,6 @@ func SetActiveOutputDevice(deviceID string) error {
 	if err := policyConfig.SetDefaultEndpoint(deviceID, eCommunications); err != nil {
 		return fmt.Errorf("failed to set communications endpoint: %w", err)
 	}
-	
+
 	return nil
-}
\ No newline at end of file
+}
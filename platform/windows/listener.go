@@ -4,11 +4,16 @@
 package windows
 
 import (
+	"fmt"
+	"log/slog"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"unsafe"
 
 	"github.com/go-ole/go-ole"
+
+	"github.com/audi70r/go-audio-control/internal/logging"
 )
 
 // DeviceEvent represents an audio device event
@@ -72,6 +77,9 @@ func (e *IMMDeviceEnumerator) GetDevice(deviceID string) (*IMMDevice, error) {
 		uintptr(unsafe.Pointer(deviceIDPtr)),
 		uintptr(unsafe.Pointer(&device)),
 	)
+	if hr == E_NOTFOUND {
+		return nil, fmt.Errorf("device %s: %w", deviceID, ErrDeviceNotFound)
+	}
 	if hr != 0 {
 		return nil, ole.NewError(hr)
 	}
@@ -195,7 +203,6 @@ func (l *DeviceListener) Close() {
 
 // IUnknown methods for NotificationClient
 func notificationClientQueryInterface(this unsafe.Pointer, riid *ole.GUID, ppvObject *unsafe.Pointer) uintptr {
-	client := (*NotificationClient)(this)
 	if ole.IsEqualGUID(riid, ole.IID_IUnknown) || ole.IsEqualGUID(riid, IID_IMMNotificationClient) {
 		notificationClientAddRef(this)
 		*ppvObject = this
@@ -207,13 +214,13 @@ func notificationClientQueryInterface(this unsafe.Pointer, riid *ole.GUID, ppvOb
 
 func notificationClientAddRef(this unsafe.Pointer) uintptr {
 	client := (*NotificationClient)(this)
-	ref := syscall.AddInt32(&client.ref, 1)
+	ref := atomic.AddInt32(&client.ref, 1)
 	return uintptr(ref)
 }
 
 func notificationClientRelease(this unsafe.Pointer) uintptr {
 	client := (*NotificationClient)(this)
-	ref := syscall.AddInt32(&client.ref, -1)
+	ref := atomic.AddInt32(&client.ref, -1)
 	if ref == 0 {
 		// Cleanup if needed
 	}
@@ -228,6 +235,8 @@ func notificationClientOnDeviceStateChanged(this unsafe.Pointer, deviceID *uint1
 	}
 	
 	id := syscall.UTF16ToString((*[1024]uint16)(unsafe.Pointer(deviceID))[:])
+	logging.Notification(slog.String("notification", "OnDeviceStateChanged"), slog.String("device", id),
+		slog.Uint64("state", uint64(newState)))
 	
 	// Get device info
 	device := getDeviceInfo(id)
@@ -253,6 +262,7 @@ func notificationClientOnDeviceAdded(this unsafe.Pointer, deviceID *uint16) uint
 	}
 	
 	id := syscall.UTF16ToString((*[1024]uint16)(unsafe.Pointer(deviceID))[:])
+	logging.Notification(slog.String("notification", "OnDeviceAdded"), slog.String("device", id))
 	device := getDeviceInfo(id)
 	
 	client.listener.callback(DeviceEvent{
@@ -271,6 +281,7 @@ func notificationClientOnDeviceRemoved(this unsafe.Pointer, deviceID *uint16) ui
 	}
 	
 	id := syscall.UTF16ToString((*[1024]uint16)(unsafe.Pointer(deviceID))[:])
+	logging.Notification(slog.String("notification", "OnDeviceRemoved"), slog.String("device", id))
 	
 	client.listener.callback(DeviceEvent{
 		Type:     DeviceRemoved,
@@ -288,10 +299,15 @@ func notificationClientOnDefaultDeviceChanged(this unsafe.Pointer, flow EDataFlo
 	}
 	
 	if deviceID == nil {
+		// No default device is left for the flow and role
+		logging.Notification(slog.String("notification", "OnDefaultDeviceChanged"),
+			slog.Int("flow", int(flow)), slog.Int("role", int(role)))
 		return 0
 	}
 	
 	id := syscall.UTF16ToString((*[1024]uint16)(unsafe.Pointer(deviceID))[:])
+	logging.Notification(slog.String("notification", "OnDefaultDeviceChanged"), slog.String("device", id),
+		slog.Int("flow", int(flow)), slog.Int("role", int(role)))
 	device := getDeviceInfo(id)
 	
 	client.listener.callback(DeviceEvent{
@@ -304,7 +320,6 @@ func notificationClientOnDefaultDeviceChanged(this unsafe.Pointer, flow EDataFlo
 }
 
 func notificationClientOnPropertyValueChanged(this unsafe.Pointer, deviceID *uint16, key PROPERTYKEY) uintptr {
-	client := (*NotificationClient)(this)
 	// Not implemented for now
 	return 0
 }
@@ -313,6 +328,7 @@ func notificationClientOnPropertyValueChanged(this unsafe.Pointer, deviceID *uin
 func getDeviceInfo(deviceID string) *AudioDevice {
 	enumerator, err := CreateDeviceEnumerator()
 	if err != nil {
+		logging.Ignored("create device enumerator", err, slog.String("device", deviceID))
 		return nil
 	}
 	defer enumerator.Release()
@@ -320,6 +336,7 @@ func getDeviceInfo(deviceID string) *AudioDevice {
 	// Get device by ID
 	device, err := enumerator.GetDevice(deviceID)
 	if err != nil {
+		logging.Ignored("get device", err, slog.String("device", deviceID))
 		return nil
 	}
 	defer device.Release()
@@ -328,31 +345,13 @@ func getDeviceInfo(deviceID string) *AudioDevice {
 	state, _ := device.GetState()
 	
 	// Determine if it's input or output by trying to get it as default
-	isOutput := false
-	isInput := false
-	isActive := false
-	
-	// Check if it's the default output
-	defaultOutput, err := enumerator.GetDefaultAudioEndpoint(eRender, eConsole)
-	if err == nil {
-		defaultID, _ := defaultOutput.GetId()
-		if defaultID == deviceID {
-			isOutput = true
-			isActive = true
-		}
-		defaultOutput.Release()
-	}
-	
-	// Check if it's the default input
-	defaultInput, err := enumerator.GetDefaultAudioEndpoint(eCapture, eConsole)
-	if err == nil {
-		defaultID, _ := defaultInput.GetId()
-		if defaultID == deviceID {
-			isInput = true
-			isActive = true
-		}
-		defaultInput.Release()
-	}
+	isDefaultOutput := defaultEndpointID(enumerator, eRender, eConsole) == deviceID
+	isDefaultInput := defaultEndpointID(enumerator, eCapture, eConsole) == deviceID
+	isCommunications := defaultEndpointID(enumerator, eRender, eCommunications) == deviceID ||
+		defaultEndpointID(enumerator, eCapture, eCommunications) == deviceID
+	isOutput := isDefaultOutput
+	isInput := isDefaultInput
+	isActive := isDefaultOutput || isDefaultInput
 	
 	// If not default, try to determine type by enumeration
 	if !isOutput && !isInput {
@@ -398,5 +397,10 @@ func getDeviceInfo(deviceID string) *AudioDevice {
 		IsOutput:    isOutput,
 		IsActive:    isActive,
 		IsConnected: state == DEVICE_STATE_ACTIVE,
+
+		IsDefaultOutput:         isDefaultOutput,
+		IsDefaultInput:          isDefaultInput,
+		IsDefaultCommunications: isCommunications,
+		State:                   deviceState(state),
 	}
 }
\ No newline at end of file
@@ -0,0 +1,186 @@
+//go:build windows
+// +build windows
+
+package windows
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/go-ole/go-ole"
+)
+
+// IID_IAudioMeterInformation identifies the endpoint peak meter interface
+var IID_IAudioMeterInformation = &ole.GUID{0xC02216F6, 0x8C67, 0x4B5B, [8]byte{0x9D, 0x00, 0xD0, 0x08, 0xE7, 0x3E, 0x00, 0x64}}
+
+// IAudioMeterInformation interface
+type IAudioMeterInformation struct {
+	vtbl *IAudioMeterInformationVtbl
+}
+
+type IAudioMeterInformationVtbl struct {
+	QueryInterface uintptr
+	AddRef         uintptr
+	Release        uintptr
+
+	GetPeakValue            uintptr
+	GetMeteringChannelCount uintptr
+	GetChannelsPeakValues   uintptr
+	QueryHardwareSupport    uintptr
+}
+
+func (m *IAudioMeterInformation) Release() {
+	syscall.Syscall(m.vtbl.Release, 1, uintptr(unsafe.Pointer(m)), 0, 0)
+}
+
+// GetMeteringChannelCount returns the number of channels the meter reports
+func (m *IAudioMeterInformation) GetMeteringChannelCount() (uint32, error) {
+	var count uint32
+	hr, _, _ := syscall.Syscall(
+		m.vtbl.GetMeteringChannelCount,
+		2,
+		uintptr(unsafe.Pointer(m)),
+		uintptr(unsafe.Pointer(&count)),
+		0,
+	)
+	if hr != 0 {
+		return 0, ole.NewError(hr)
+	}
+	return count, nil
+}
+
+// GetChannelsPeakValues returns the peak of each channel in the last
+// processing period, in the range [0.0, 1.0]
+func (m *IAudioMeterInformation) GetChannelsPeakValues(count uint32) ([]float32, error) {
+	peaks := make([]float32, count)
+	if count == 0 {
+		return peaks, nil
+	}
+	hr, _, _ := syscall.Syscall(
+		m.vtbl.GetChannelsPeakValues,
+		3,
+		uintptr(unsafe.Pointer(m)),
+		uintptr(count),
+		uintptr(unsafe.Pointer(&peaks[0])),
+	)
+	if hr != 0 {
+		return nil, ole.NewError(hr)
+	}
+	return peaks, nil
+}
+
+// Level is the peak of each channel over one interval. Windows reports no
+// RMS, so RMS is nil.
+type Level struct {
+	Time time.Time
+	Peak []float64
+	RMS  []float64
+}
+
+// openMeter activates IAudioMeterInformation on the device with the given ID
+func openMeter(deviceID string) (*IAudioMeterInformation, error) {
+	enumerator, err := CreateDeviceEnumerator()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create device enumerator: %w", err)
+	}
+	defer enumerator.Release()
+
+	device, err := enumerator.GetDevice(deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device %s: %w", deviceID, err)
+	}
+	defer device.Release()
+
+	object, err := device.Activate(IID_IAudioMeterInformation, ole.CLSCTX_ALL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to activate meter: %w", err)
+	}
+	return (*IAudioMeterInformation)(object), nil
+}
+
+// meterSamples is the number of times the meter is read per interval. The
+// endpoint reports the peak of its last processing period only, so it is
+// sampled several times and the highest reading kept.
+const meterSamples = 4
+
+// Meter polls the peak meter of an endpoint and sends the levels of each
+// interval. Levels are dropped while the receiver is behind. The channel is
+// closed after stop is called or when the endpoint goes away.
+func Meter(deviceID string, interval time.Duration) (<-chan Level, func(), error) {
+	if interval <= 0 {
+		return nil, nil, errors.New("meter interval must be positive")
+	}
+
+	// The meter is opened on the polling thread, which owns it
+	opened := make(chan error, 1)
+	done := make(chan struct{})
+	levels := make(chan Level, 1)
+	go func() {
+		defer close(levels)
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		if err := InitializeCOM(); err == nil {
+			defer UninitializeCOM()
+		}
+
+		meter, err := openMeter(deviceID)
+		if err != nil {
+			opened <- err
+			return
+		}
+		defer meter.Release()
+		channels, err := meter.GetMeteringChannelCount()
+		if err != nil {
+			opened <- fmt.Errorf("failed to get meter channels: %w", err)
+			return
+		}
+		opened <- nil
+
+		tick := interval / meterSamples
+		if tick <= 0 {
+			tick = interval
+		}
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+		peak := make([]float64, channels)
+		for sample := 1; ; sample++ {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			values, err := meter.GetChannelsPeakValues(channels)
+			if err != nil {
+				// AUDCLNT_E_DEVICE_INVALIDATED once the endpoint is removed
+				return
+			}
+			for ch, v := range values {
+				if float64(v) > peak[ch] {
+					peak[ch] = float64(v)
+				}
+			}
+			if sample%meterSamples != 0 {
+				continue
+			}
+			select {
+			case levels <- Level{Time: time.Now(), Peak: peak}:
+			default:
+			}
+			peak = make([]float64, channels)
+		}
+	}()
+
+	if err := <-opened; err != nil {
+		return nil, nil, err
+	}
+	var once sync.Once
+	stop := func() {
+		once.Do(func() { close(done) })
+	}
+	return levels, stop, nil
+}
@@ -0,0 +1,68 @@
+//go:build windows
+// +build windows
+
+package windows
+
+import "fmt"
+
+// SampleEncoding is the layout of one little-endian PCM sample
+type SampleEncoding int
+
+const (
+	EncodingS16LE SampleEncoding = iota
+	EncodingS24LE
+	EncodingS32LE
+	EncodingFloat32LE
+)
+
+// Format describes interleaved PCM audio
+type Format struct {
+	Encoding SampleEncoding
+	Channels int
+	Rate     int
+}
+
+// Wave format tags
+const (
+	WAVE_FORMAT_PCM        = 0x0001
+	WAVE_FORMAT_IEEE_FLOAT = 0x0003
+)
+
+// WAVEFORMATEX describes a stream format. Only the fields of the base
+// structure are declared; cbSize is always 0 for formats built here.
+type WAVEFORMATEX struct {
+	FormatTag      uint16
+	Channels       uint16
+	SamplesPerSec  uint32
+	AvgBytesPerSec uint32
+	BlockAlign     uint16
+	BitsPerSample  uint16
+	Size           uint16
+}
+
+// waveFormat returns the WAVEFORMATEX of f
+func (f Format) waveFormat() (*WAVEFORMATEX, error) {
+	tag, bits := uint16(WAVE_FORMAT_PCM), 0
+	switch f.Encoding {
+	case EncodingS16LE:
+		bits = 16
+	case EncodingS24LE:
+		bits = 24
+	case EncodingS32LE:
+		bits = 32
+	case EncodingFloat32LE:
+		tag, bits = WAVE_FORMAT_IEEE_FLOAT, 32
+	}
+	if bits == 0 || f.Channels <= 0 || f.Channels > 0xFFFF || f.Rate <= 0 {
+		return nil, fmt.Errorf("unsupported format %+v", f)
+	}
+	blockAlign := f.Channels * bits / 8
+	return &WAVEFORMATEX{
+		FormatTag:      tag,
+		Channels:       uint16(f.Channels),
+		SamplesPerSec:  uint32(f.Rate),
+		AvgBytesPerSec: uint32(f.Rate * blockAlign),
+		BlockAlign:     uint16(blockAlign),
+		BitsPerSample:  uint16(bits),
+	}, nil
+}
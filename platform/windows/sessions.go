@@ -0,0 +1,595 @@
+//go:build windows
+// +build windows
+
+package windows
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/go-ole/go-ole"
+)
+
+// Audio session GUIDs
+var (
+	IID_IAudioSessionManager2 = &ole.GUID{0x77AA99A0, 0x1BD6, 0x484F, [8]byte{0x8B, 0xC7, 0x2C, 0x65, 0x4C, 0x9A, 0x9B, 0x6F}}
+	IID_IAudioSessionControl2 = &ole.GUID{0xBFB7FF88, 0x7239, 0x4FC9, [8]byte{0x8F, 0xA2, 0x07, 0xC9, 0x50, 0xBE, 0x9C, 0x6D}}
+	IID_ISimpleAudioVolume    = &ole.GUID{0x87CE5498, 0x68D6, 0x44E5, [8]byte{0x92, 0x15, 0x6D, 0xA4, 0x7E, 0xF8, 0x83, 0xD8}}
+)
+
+// Session is the audio session of one application on an endpoint. Its ID
+// is the session instance identifier.
+type Session struct {
+	ID       string
+	DeviceID string
+	AppName  string
+	PID      int
+	Volume   float64
+	Muted    bool
+	State    SessionState
+	IsInput  bool
+}
+
+// SessionState is the AudioSessionState of a session
+type SessionState int
+
+const (
+	SessionInactive SessionState = iota
+	SessionActive
+	SessionExpired
+)
+
+// IAudioSessionManager2 interface
+type IAudioSessionManager2 struct {
+	vtbl *IAudioSessionManager2Vtbl
+}
+
+type IAudioSessionManager2Vtbl struct {
+	QueryInterface uintptr
+	AddRef         uintptr
+	Release        uintptr
+
+	GetAudioSessionControl        uintptr
+	GetSimpleAudioVolume          uintptr
+	GetSessionEnumerator          uintptr
+	RegisterSessionNotification   uintptr
+	UnregisterSessionNotification uintptr
+	RegisterDuckNotification      uintptr
+	UnregisterDuckNotification    uintptr
+}
+
+// IAudioSessionEnumerator interface
+type IAudioSessionEnumerator struct {
+	vtbl *IAudioSessionEnumeratorVtbl
+}
+
+type IAudioSessionEnumeratorVtbl struct {
+	QueryInterface uintptr
+	AddRef         uintptr
+	Release        uintptr
+
+	GetCount   uintptr
+	GetSession uintptr
+}
+
+// IAudioSessionControl2 interface, which extends IAudioSessionControl
+type IAudioSessionControl2 struct {
+	vtbl *IAudioSessionControl2Vtbl
+}
+
+type IAudioSessionControl2Vtbl struct {
+	QueryInterface uintptr
+	AddRef         uintptr
+	Release        uintptr
+
+	GetState                           uintptr
+	GetDisplayName                     uintptr
+	SetDisplayName                     uintptr
+	GetIconPath                        uintptr
+	SetIconPath                        uintptr
+	GetGroupingParam                   uintptr
+	SetGroupingParam                   uintptr
+	RegisterAudioSessionNotification   uintptr
+	UnregisterAudioSessionNotification uintptr
+
+	GetSessionIdentifier         uintptr
+	GetSessionInstanceIdentifier uintptr
+	GetProcessId                 uintptr
+	IsSystemSoundsSession        uintptr
+	SetDuckingPreference         uintptr
+}
+
+// ISimpleAudioVolume interface
+type ISimpleAudioVolume struct {
+	vtbl *ISimpleAudioVolumeVtbl
+}
+
+type ISimpleAudioVolumeVtbl struct {
+	QueryInterface uintptr
+	AddRef         uintptr
+	Release        uintptr
+
+	SetMasterVolume uintptr
+	GetMasterVolume uintptr
+	SetMute         uintptr
+	GetMute         uintptr
+}
+
+func (m *IAudioSessionManager2) Release() {
+	syscall.Syscall(m.vtbl.Release, 1, uintptr(unsafe.Pointer(m)), 0, 0)
+}
+
+func (e *IAudioSessionEnumerator) Release() {
+	syscall.Syscall(e.vtbl.Release, 1, uintptr(unsafe.Pointer(e)), 0, 0)
+}
+
+func (s *IAudioSessionControl2) Release() {
+	syscall.Syscall(s.vtbl.Release, 1, uintptr(unsafe.Pointer(s)), 0, 0)
+}
+
+func (v *ISimpleAudioVolume) Release() {
+	syscall.Syscall(v.vtbl.Release, 1, uintptr(unsafe.Pointer(v)), 0, 0)
+}
+
+// GetSessionEnumerator returns an enumerator over the sessions of the
+// endpoint
+func (m *IAudioSessionManager2) GetSessionEnumerator() (*IAudioSessionEnumerator, error) {
+	var enumerator *IAudioSessionEnumerator
+	hr, _, _ := syscall.Syscall(
+		m.vtbl.GetSessionEnumerator,
+		2,
+		uintptr(unsafe.Pointer(m)),
+		uintptr(unsafe.Pointer(&enumerator)),
+		0,
+	)
+	if hr != 0 {
+		return nil, ole.NewError(hr)
+	}
+	return enumerator, nil
+}
+
+// GetCount returns the number of sessions
+func (e *IAudioSessionEnumerator) GetCount() (int, error) {
+	var count int32
+	hr, _, _ := syscall.Syscall(
+		e.vtbl.GetCount,
+		2,
+		uintptr(unsafe.Pointer(e)),
+		uintptr(unsafe.Pointer(&count)),
+		0,
+	)
+	if hr != 0 {
+		return 0, ole.NewError(hr)
+	}
+	return int(count), nil
+}
+
+// GetSession returns the session at index as IAudioSessionControl2
+func (e *IAudioSessionEnumerator) GetSession(index int) (*IAudioSessionControl2, error) {
+	var control *ole.IUnknown
+	hr, _, _ := syscall.Syscall(
+		e.vtbl.GetSession,
+		3,
+		uintptr(unsafe.Pointer(e)),
+		uintptr(index),
+		uintptr(unsafe.Pointer(&control)),
+	)
+	if hr != 0 {
+		return nil, ole.NewError(hr)
+	}
+	defer control.Release()
+
+	object, err := control.QueryInterface(IID_IAudioSessionControl2)
+	if err != nil {
+		return nil, err
+	}
+	return (*IAudioSessionControl2)(unsafe.Pointer(object)), nil
+}
+
+// GetState returns the AudioSessionState of the session
+func (s *IAudioSessionControl2) GetState() (SessionState, error) {
+	var state int32
+	hr, _, _ := syscall.Syscall(
+		s.vtbl.GetState,
+		2,
+		uintptr(unsafe.Pointer(s)),
+		uintptr(unsafe.Pointer(&state)),
+		0,
+	)
+	if hr != 0 {
+		return 0, ole.NewError(hr)
+	}
+	return SessionState(state), nil
+}
+
+// coTaskString calls a method that returns a string allocated with
+// CoTaskMemAlloc
+func coTaskString(method uintptr, this unsafe.Pointer) (string, error) {
+	var ptr *uint16
+	hr, _, _ := syscall.Syscall(method, 2, uintptr(this), uintptr(unsafe.Pointer(&ptr)), 0)
+	if hr != 0 {
+		return "", ole.NewError(hr)
+	}
+	if ptr == nil {
+		return "", nil
+	}
+	defer ole.CoTaskMemFree(uintptr(unsafe.Pointer(ptr)))
+	return syscall.UTF16ToString((*[1 << 16]uint16)(unsafe.Pointer(ptr))[:]), nil
+}
+
+// GetDisplayName returns the name the application gave the session, which
+// is often empty
+func (s *IAudioSessionControl2) GetDisplayName() (string, error) {
+	return coTaskString(s.vtbl.GetDisplayName, unsafe.Pointer(s))
+}
+
+// GetSessionInstanceIdentifier returns the identifier unique to this
+// session
+func (s *IAudioSessionControl2) GetSessionInstanceIdentifier() (string, error) {
+	return coTaskString(s.vtbl.GetSessionInstanceIdentifier, unsafe.Pointer(s))
+}
+
+// GetProcessId returns the process that owns the session
+func (s *IAudioSessionControl2) GetProcessId() (uint32, error) {
+	var pid uint32
+	hr, _, _ := syscall.Syscall(
+		s.vtbl.GetProcessId,
+		2,
+		uintptr(unsafe.Pointer(s)),
+		uintptr(unsafe.Pointer(&pid)),
+		0,
+	)
+	// Sessions spanning several processes report AUDCLNT_S_NO_CURRENT_PROCESS
+	if hr != 0 && hr != 0x0889000D {
+		return 0, ole.NewError(hr)
+	}
+	return pid, nil
+}
+
+// IsSystemSoundsSession reports whether the session plays system sounds
+func (s *IAudioSessionControl2) IsSystemSoundsSession() bool {
+	hr, _, _ := syscall.Syscall(s.vtbl.IsSystemSoundsSession, 1, uintptr(unsafe.Pointer(s)), 0, 0)
+	return hr == 0
+}
+
+// SimpleAudioVolume returns the volume control of the session
+func (s *IAudioSessionControl2) SimpleAudioVolume() (*ISimpleAudioVolume, error) {
+	unknown := (*ole.IUnknown)(unsafe.Pointer(s))
+	object, err := unknown.QueryInterface(IID_ISimpleAudioVolume)
+	if err != nil {
+		return nil, err
+	}
+	return (*ISimpleAudioVolume)(unsafe.Pointer(object)), nil
+}
+
+// GetMasterVolume gets the session volume in the range [0.0, 1.0]
+func (v *ISimpleAudioVolume) GetMasterVolume() (float32, error) {
+	var level float32
+	hr, _, _ := syscall.Syscall(
+		v.vtbl.GetMasterVolume,
+		2,
+		uintptr(unsafe.Pointer(v)),
+		uintptr(unsafe.Pointer(&level)),
+		0,
+	)
+	if hr != 0 {
+		return 0, ole.NewError(hr)
+	}
+	return level, nil
+}
+
+// SetMasterVolume sets the session volume in the range [0.0, 1.0]
+func (v *ISimpleAudioVolume) SetMasterVolume(level float32) error {
+	// As with SetMasterVolumeLevelScalar, the float travels in XMM1
+	hr, _, _ := syscall.Syscall(
+		v.vtbl.SetMasterVolume,
+		3,
+		uintptr(unsafe.Pointer(v)),
+		uintptr(math.Float32bits(level)),
+		0,
+	)
+	if hr != 0 {
+		return ole.NewError(hr)
+	}
+	return nil
+}
+
+// GetMute gets the mute state of the session
+func (v *ISimpleAudioVolume) GetMute() (bool, error) {
+	var muted int32
+	hr, _, _ := syscall.Syscall(
+		v.vtbl.GetMute,
+		2,
+		uintptr(unsafe.Pointer(v)),
+		uintptr(unsafe.Pointer(&muted)),
+		0,
+	)
+	if hr != 0 {
+		return false, ole.NewError(hr)
+	}
+	return muted != 0, nil
+}
+
+// SetMute sets the mute state of the session
+func (v *ISimpleAudioVolume) SetMute(muted bool) error {
+	var value uintptr
+	if muted {
+		value = 1
+	}
+	hr, _, _ := syscall.Syscall(
+		v.vtbl.SetMute,
+		3,
+		uintptr(unsafe.Pointer(v)),
+		value,
+		0,
+	)
+	if hr != 0 {
+		return ole.NewError(hr)
+	}
+	return nil
+}
+
+var procQueryFullProcessImageName = syscall.NewLazyDLL("kernel32.dll").NewProc("QueryFullProcessImageNameW")
+
+// processName returns the executable name of a process without its
+// extension, or "" if the process cannot be opened
+func processName(pid uint32) string {
+	const PROCESS_QUERY_LIMITED_INFORMATION = 0x1000
+
+	process, err := syscall.OpenProcess(PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return ""
+	}
+	defer syscall.CloseHandle(process)
+
+	buffer := make([]uint16, syscall.MAX_PATH)
+	size := uint32(len(buffer))
+	ok, _, _ := procQueryFullProcessImageName.Call(
+		uintptr(process),
+		0,
+		uintptr(unsafe.Pointer(&buffer[0])),
+		uintptr(unsafe.Pointer(&size)),
+	)
+	if ok == 0 {
+		return ""
+	}
+	path := syscall.UTF16ToString(buffer[:size])
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// describeSession reads a session. The application name is the display
+// name, falling back to the executable name.
+func describeSession(control *IAudioSessionControl2, deviceID string, input bool) (Session, error) {
+	id, err := control.GetSessionInstanceIdentifier()
+	if err != nil {
+		return Session{}, err
+	}
+	state, err := control.GetState()
+	if err != nil {
+		return Session{}, err
+	}
+	pid, _ := control.GetProcessId()
+	name, _ := control.GetDisplayName()
+	if name == "" || strings.HasPrefix(name, "@") {
+		// Resource references such as those of the system sounds session
+		// are not resolved
+		name = processName(pid)
+	}
+	if control.IsSystemSoundsSession() {
+		name = "System Sounds"
+	}
+
+	session := Session{
+		ID:       id,
+		DeviceID: deviceID,
+		AppName:  name,
+		PID:      int(pid),
+		State:    state,
+		IsInput:  input,
+	}
+
+	volume, err := control.SimpleAudioVolume()
+	if err != nil {
+		return Session{}, err
+	}
+	defer volume.Release()
+	level, err := volume.GetMasterVolume()
+	if err != nil {
+		return Session{}, err
+	}
+	session.Volume = float64(level)
+	session.Muted, err = volume.GetMute()
+	return session, err
+}
+
+// endpointSessions calls visit for every session on an endpoint until it
+// returns false. The control is released after visit returns.
+func endpointSessions(device *IMMDevice, visit func(*IAudioSessionControl2) bool) error {
+	object, err := device.Activate(IID_IAudioSessionManager2, ole.CLSCTX_ALL)
+	if err != nil {
+		return fmt.Errorf("failed to activate session manager: %w", err)
+	}
+	manager := (*IAudioSessionManager2)(object)
+	defer manager.Release()
+
+	enumerator, err := manager.GetSessionEnumerator()
+	if err != nil {
+		return fmt.Errorf("failed to enumerate sessions: %w", err)
+	}
+	defer enumerator.Release()
+
+	count, err := enumerator.GetCount()
+	if err != nil {
+		return fmt.Errorf("failed to get session count: %w", err)
+	}
+	for i := 0; i < count; i++ {
+		control, err := enumerator.GetSession(i)
+		if err != nil {
+			continue
+		}
+		more := visit(control)
+		control.Release()
+		if !more {
+			break
+		}
+	}
+	return nil
+}
+
+// visitSessions calls visit for every session on the active endpoints, or
+// only on the endpoint deviceID when it is not empty, until visit returns
+// false
+func visitSessions(deviceID string, visit func(control *IAudioSessionControl2, deviceID string, input bool) bool) error {
+	enumerator, err := CreateDeviceEnumerator()
+	if err != nil {
+		return fmt.Errorf("failed to create device enumerator: %w", err)
+	}
+	defer enumerator.Release()
+
+	if deviceID != "" {
+		device, err := enumerator.GetDevice(deviceID)
+		if err != nil {
+			return err
+		}
+		defer device.Release()
+		input := endpointFlow(device) == eCapture
+		return endpointSessions(device, func(control *IAudioSessionControl2) bool {
+			return visit(control, deviceID, input)
+		})
+	}
+
+	for _, flow := range []EDataFlow{eRender, eCapture} {
+		collection, err := enumerator.EnumAudioEndpoints(flow, DEVICE_STATE_ACTIVE)
+		if err != nil {
+			return fmt.Errorf("failed to enumerate devices: %w", err)
+		}
+		count, _ := collection.GetCount()
+		more := true
+		for i := uint32(0); i < count && more; i++ {
+			device, err := collection.Item(i)
+			if err != nil {
+				continue
+			}
+			id, err := device.GetId()
+			if err == nil {
+				endpointSessions(device, func(control *IAudioSessionControl2) bool {
+					more = visit(control, id, flow == eCapture)
+					return more
+				})
+			}
+			device.Release()
+		}
+		collection.Release()
+		if !more {
+			break
+		}
+	}
+	return nil
+}
+
+// endpointFlow returns whether an endpoint renders or captures
+func endpointFlow(device *IMMDevice) EDataFlow {
+	object, err := (*ole.IUnknown)(unsafe.Pointer(device)).QueryInterface(IID_IMMEndpoint)
+	if err != nil {
+		return eRender
+	}
+	endpoint := (*IMMEndpoint)(unsafe.Pointer(object))
+	defer endpoint.Release()
+
+	var flow EDataFlow
+	syscall.Syscall(endpoint.vtbl.GetDataFlow, 2, uintptr(unsafe.Pointer(endpoint)), uintptr(unsafe.Pointer(&flow)), 0)
+	return flow
+}
+
+// IID_IMMEndpoint identifies the interface reporting the data flow of an
+// endpoint
+var IID_IMMEndpoint = &ole.GUID{0x1BE09788, 0x6894, 0x4089, [8]byte{0x85, 0x86, 0x9A, 0x2A, 0x6C, 0x26, 0x5A, 0xC5}}
+
+// IMMEndpoint interface
+type IMMEndpoint struct {
+	vtbl *IMMEndpointVtbl
+}
+
+type IMMEndpointVtbl struct {
+	QueryInterface uintptr
+	AddRef         uintptr
+	Release        uintptr
+
+	GetDataFlow uintptr
+}
+
+func (e *IMMEndpoint) Release() {
+	syscall.Syscall(e.vtbl.Release, 1, uintptr(unsafe.Pointer(e)), 0, 0)
+}
+
+// ListSessions returns the audio sessions on an endpoint, or on every
+// active endpoint when deviceID is empty. Expired sessions are left out.
+func ListSessions(deviceID string) ([]Session, error) {
+	var sessions []Session
+	err := visitSessions(deviceID, func(control *IAudioSessionControl2, id string, input bool) bool {
+		session, err := describeSession(control, id, input)
+		if err == nil && session.State != SessionExpired {
+			sessions = append(sessions, session)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// sessionVolume finds a session by instance identifier and calls apply
+// with its volume control
+func sessionVolume(sessionID string, apply func(*ISimpleAudioVolume) error) error {
+	found := false
+	var applyErr error
+	err := visitSessions("", func(control *IAudioSessionControl2, _ string, _ bool) bool {
+		id, err := control.GetSessionInstanceIdentifier()
+		if err != nil || id != sessionID {
+			return true
+		}
+		found = true
+		volume, err := control.SimpleAudioVolume()
+		if err != nil {
+			applyErr = err
+			return false
+		}
+		applyErr = apply(volume)
+		volume.Release()
+		return false
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("session %s: %w", sessionID, ErrDeviceNotFound)
+	}
+	return applyErr
+}
+
+// SetSessionVolume sets the volume of a session
+func SetSessionVolume(sessionID string, level float64) error {
+	err := sessionVolume(sessionID, func(volume *ISimpleAudioVolume) error {
+		return volume.SetMasterVolume(float32(level))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set session volume: %w", err)
+	}
+	return nil
+}
+
+// SetSessionMute mutes or unmutes a session
+func SetSessionMute(sessionID string, muted bool) error {
+	err := sessionVolume(sessionID, func(volume *ISimpleAudioVolume) error {
+		return volume.SetMute(muted)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set session mute: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package audiocontrol
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/audi70r/go-audio-control/internal/dsp"
+	"github.com/audi70r/go-audio-control/internal/wav"
+)
+
+// ToneSpec describes a test tone. Zero fields select the defaults: a 440 Hz
+// sine for half a second at a quarter of full scale on both channels.
+type ToneSpec struct {
+	// Frequency is the pitch in Hz
+	Frequency float64
+	Duration  time.Duration
+	// Level is the peak amplitude, where 1.0 is full scale
+	Level float64
+	// Channel plays the tone on the left (1) or right (2) channel only
+	Channel int
+}
+
+// Test tone defaults
+const (
+	DefaultToneFrequency = 440.0
+	DefaultToneDuration  = 500 * time.Millisecond
+	DefaultToneLevel     = 0.25
+)
+
+// toneFormat is the format test tones are generated in. The sound server
+// converts it to the format of the device.
+var toneFormat = Format{Encoding: EncodingFloat32LE, Channels: 2, Rate: 48000}
+
+// toneFade is the length of the ramps at the start and end of a tone
+const toneFade = 5 * time.Millisecond
+
+// Player is implemented by backends that can play audio to an output
+// without changing the default device. Play returns once the audio has
+// been played.
+type Player interface {
+	Play(deviceID string, format Format, pcm io.Reader) error
+}
+
+// Play plays interleaved PCM to an output and returns once it has been
+// played
+func Play(deviceID string, format Format, pcm io.Reader) error {
+	if err := format.validate(); err != nil {
+		return err
+	}
+	if p, ok := currentBackend().(Player); ok {
+		return p.Play(deviceID, format, pcm)
+	}
+	return ErrNotImplemented
+}
+
+// PlayTestTone plays a sine to an output, such as for a "Test" button next
+// to a device picker. It returns once the tone has been played.
+func PlayTestTone(deviceID string, spec ToneSpec) error {
+	samples, err := toneSamples(spec)
+	if err != nil {
+		return err
+	}
+	data := dsp.Encode(samples, dsp.Encoding(toneFormat.Encoding))
+	return Play(deviceID, toneFormat, bytes.NewReader(data))
+}
+
+// toneSamples generates the samples of spec in toneFormat
+func toneSamples(spec ToneSpec) ([]float32, error) {
+	if spec.Frequency == 0 {
+		spec.Frequency = DefaultToneFrequency
+	}
+	if spec.Duration == 0 {
+		spec.Duration = DefaultToneDuration
+	}
+	if spec.Level == 0 {
+		spec.Level = DefaultToneLevel
+	}
+	if spec.Channel < 0 || spec.Channel > toneFormat.Channels {
+		return nil, fmt.Errorf("invalid tone channel %d", spec.Channel)
+	}
+	if spec.Frequency < 0 || spec.Duration < 0 || spec.Level < 0 || spec.Level > 1 {
+		return nil, fmt.Errorf("invalid tone %+v", spec)
+	}
+
+	rate := float64(toneFormat.Rate)
+	tone := dsp.Tone{
+		Frequency: spec.Frequency,
+		Level:     spec.Level,
+		Rate:      toneFormat.Rate,
+		Frames:    int(spec.Duration.Seconds() * rate),
+		Channels:  toneFormat.Channels,
+		Channel:   spec.Channel - 1,
+		Fade:      int(toneFade.Seconds() * rate),
+	}
+	return tone.Samples(), nil
+}
+
+// PlayWAV plays a WAVE file to an output and returns once it has been
+// played. Files of 16, 24 or 32-bit integer or 32-bit float PCM are
+// supported.
+func PlayWAV(deviceID string, r io.Reader) error {
+	w, err := wav.NewReader(r)
+	if err != nil {
+		return err
+	}
+	format := Format{Encoding: SampleEncoding(w.Encoding), Channels: w.Channels, Rate: w.Rate}
+	return Play(deviceID, format, w)
+}
@@ -0,0 +1,143 @@
+package audiocontrol
+
+import "fmt"
+
+// Port is one of the connectors a device can play or record through, such
+// as the internal speakers or the headphone jack of a laptop's sound card.
+// Plugging in headphones usually switches the active port rather than
+// adding a device; PortChanged reports it.
+type Port struct {
+	Name        string
+	Description string
+	Type        PortType
+	Available   PortAvailability
+	Priority    uint32
+	IsActive    bool
+}
+
+// PortType classifies what a port connects to
+type PortType int
+
+const (
+	PortTypeUnknown PortType = iota
+	PortTypeSpeaker
+	PortTypeHeadphones
+	PortTypeHeadset
+	PortTypeMicrophone
+	PortTypeLine
+	PortTypeHDMI
+	PortTypeDigital
+)
+
+// String returns the name of the port type
+func (t PortType) String() string {
+	switch t {
+	case PortTypeUnknown:
+		return "Unknown"
+	case PortTypeSpeaker:
+		return "Speaker"
+	case PortTypeHeadphones:
+		return "Headphones"
+	case PortTypeHeadset:
+		return "Headset"
+	case PortTypeMicrophone:
+		return "Microphone"
+	case PortTypeLine:
+		return "Line"
+	case PortTypeHDMI:
+		return "HDMI"
+	case PortTypeDigital:
+		return "Digital"
+	}
+	return fmt.Sprintf("PortType(%d)", int(t))
+}
+
+// MarshalText encodes the port type by name
+func (t PortType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText decodes a port type name
+func (t *PortType) UnmarshalText(text []byte) error {
+	for pt := PortTypeUnknown; pt <= PortTypeDigital; pt++ {
+		if pt.String() == string(text) {
+			*t = pt
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown port type %q", text)
+}
+
+// PortAvailability reports whether something is plugged into a port
+type PortAvailability int
+
+const (
+	// PortAvailabilityUnknown is reported by ports without jack detection
+	PortAvailabilityUnknown PortAvailability = iota
+	// PortAvailable ports have something plugged in
+	PortAvailable
+	// PortUnavailable ports have nothing plugged in
+	PortUnavailable
+)
+
+// String returns the name of the availability
+func (a PortAvailability) String() string {
+	switch a {
+	case PortAvailabilityUnknown:
+		return "Unknown"
+	case PortAvailable:
+		return "Available"
+	case PortUnavailable:
+		return "Unavailable"
+	}
+	return fmt.Sprintf("PortAvailability(%d)", int(a))
+}
+
+// MarshalText encodes the availability by name
+func (a PortAvailability) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText decodes an availability name
+func (a *PortAvailability) UnmarshalText(text []byte) error {
+	for pa := PortAvailabilityUnknown; pa <= PortUnavailable; pa++ {
+		if pa.String() == string(text) {
+			*a = pa
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown port availability %q", text)
+}
+
+// ActivePort returns the active port of a device, if it has ports
+func (d AudioDevice) ActivePort() (Port, bool) {
+	for _, p := range d.Ports {
+		if p.IsActive {
+			return p, true
+		}
+	}
+	return Port{}, false
+}
+
+// PortController is implemented by backends that can list and switch the
+// ports of a device
+type PortController interface {
+	ListPorts(deviceID string) ([]Port, error)
+	SetActivePort(deviceID, port string) error
+}
+
+// ListPorts returns the ports of a device
+func ListPorts(deviceID string) ([]Port, error) {
+	if c, ok := currentBackend().(PortController); ok {
+		return c.ListPorts(deviceID)
+	}
+	return nil, ErrNotImplemented
+}
+
+// SetActivePort switches a device to the named port
+func SetActivePort(deviceID, port string) error {
+	if c, ok := currentBackend().(PortController); ok {
+		return c.SetActivePort(deviceID, port)
+	}
+	return ErrNotImplemented
+}
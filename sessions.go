@@ -0,0 +1,254 @@
+package audiocontrol
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Session is the audio stream of one application on a device, such as a
+// browser playing music or a call recording the microphone. IDs are opaque
+// and only valid while the session exists.
+type Session struct {
+	ID       string
+	DeviceID string
+	AppName  string
+	PID      int
+	Volume   float64
+	Muted    bool
+	State    SessionState
+	IsInput  bool
+}
+
+// SessionState reports whether a session is playing or recording, mirroring
+// the Windows AudioSessionState values
+type SessionState int
+
+const (
+	// SessionInactive sessions are open but paused
+	SessionInactive SessionState = iota
+	// SessionActive sessions are playing or recording
+	SessionActive
+	// SessionExpired sessions have ended
+	SessionExpired
+)
+
+// String returns the name of the state
+func (s SessionState) String() string {
+	switch s {
+	case SessionInactive:
+		return "Inactive"
+	case SessionActive:
+		return "Active"
+	case SessionExpired:
+		return "Expired"
+	}
+	return fmt.Sprintf("SessionState(%d)", int(s))
+}
+
+// MarshalText encodes the state by name
+func (s SessionState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a state name
+func (s *SessionState) UnmarshalText(text []byte) error {
+	for state := SessionInactive; state <= SessionExpired; state++ {
+		if state.String() == string(text) {
+			*s = state
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown session state %q", text)
+}
+
+// SessionController is implemented by backends that can list and control
+// the sessions of applications. An empty device ID lists the sessions of
+// every device. Unknown device and session IDs are reported with
+// ErrDeviceNotFound.
+type SessionController interface {
+	ListSessions(deviceID string) ([]Session, error)
+	SetSessionVolume(sessionID string, volume float64) error
+	SetSessionMute(sessionID string, muted bool) error
+}
+
+// ListSessions returns the application sessions on a device, or on every
+// device when deviceID is empty
+func ListSessions(deviceID string) ([]Session, error) {
+	if c, ok := currentBackend().(SessionController); ok {
+		return c.ListSessions(deviceID)
+	}
+	return nil, ErrNotImplemented
+}
+
+// SetSessionVolume sets the volume of a session. Values outside [0.0, 1.0]
+// are clamped.
+func SetSessionVolume(sessionID string, volume float64) error {
+	if c, ok := currentBackend().(SessionController); ok {
+		return c.SetSessionVolume(sessionID, volume)
+	}
+	return ErrNotImplemented
+}
+
+// SetSessionMute mutes or unmutes a session
+func SetSessionMute(sessionID string, muted bool) error {
+	if c, ok := currentBackend().(SessionController); ok {
+		return c.SetSessionMute(sessionID, muted)
+	}
+	return ErrNotImplemented
+}
+
+// SessionMover is implemented by backends that can move a session to
+// another device of the same direction
+type SessionMover interface {
+	MoveSession(sessionID, deviceID string) error
+}
+
+// MoveSession moves a running session to another device, so that an
+// application keeps playing after the user switches speakers
+func MoveSession(sessionID, deviceID string) error {
+	if m, ok := currentBackend().(SessionMover); ok {
+		return m.MoveSession(sessionID, deviceID)
+	}
+	return ErrNotImplemented
+}
+
+// SessionError is the failure to move one session
+type SessionError struct {
+	SessionID string
+	AppName   string
+	Err       error
+}
+
+func (e SessionError) Error() string {
+	return fmt.Sprintf("session %s (%s): %v", e.SessionID, e.AppName, e.Err)
+}
+
+func (e SessionError) Unwrap() error { return e.Err }
+
+// MoveError reports the sessions that could not be moved. The other
+// sessions were moved.
+type MoveError struct {
+	Failures []SessionError
+}
+
+func (e *MoveError) Error() string {
+	messages := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		messages[i] = f.Error()
+	}
+	return fmt.Sprintf("failed to move %d sessions: %s", len(e.Failures), strings.Join(messages, "; "))
+}
+
+// Unwrap returns the error of every failed session
+func (e *MoveError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f
+	}
+	return errs
+}
+
+// MoveAllSessions moves the sessions on fromDeviceID to toDeviceID, or the
+// sessions on every other device when fromDeviceID is empty. Only sessions
+// of the direction of toDeviceID are moved. Streams that refuse to move are
+// reported in a *MoveError.
+func MoveAllSessions(fromDeviceID, toDeviceID string) error {
+	return moveAllSessions(currentBackend(), fromDeviceID, toDeviceID)
+}
+
+func moveAllSessions(b Backend, from, to string) error {
+	move, err := prepareMove(b, from, to)
+	if err != nil {
+		return err
+	}
+	return move()
+}
+
+// prepareMove finds the sessions to move to the device to, so that the
+// callers that also change the default device fail before changing it. The
+// returned function moves them.
+func prepareMove(b Backend, from, to string) (func() error, error) {
+	controller, ok := b.(SessionController)
+	mover, canMove := b.(SessionMover)
+	if !ok || !canMove {
+		return nil, ErrNotImplemented
+	}
+
+	devices, err := b.ListAudioDevices()
+	if err != nil {
+		return nil, err
+	}
+	var target *AudioDevice
+	for i := range devices {
+		if devices[i].ID == to {
+			target = &devices[i]
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("device %s: %w", to, ErrDeviceNotFound)
+	}
+
+	sessions, err := controller.ListSessions(from)
+	if err != nil {
+		return nil, err
+	}
+	var moving []Session
+	for _, session := range sessions {
+		if session.DeviceID == to || (session.IsInput && !target.IsInput) || (!session.IsInput && !target.IsOutput) {
+			continue
+		}
+		moving = append(moving, session)
+	}
+
+	return func() error {
+		var failures []SessionError
+		for _, session := range moving {
+			if err := mover.MoveSession(session.ID, to); err != nil {
+				failures = append(failures, SessionError{SessionID: session.ID, AppName: session.AppName, Err: err})
+			}
+		}
+		if len(failures) > 0 {
+			return &MoveError{Failures: failures}
+		}
+		return nil
+	}, nil
+}
+
+// SetActiveOptions changes how SetActiveOutputDeviceWithOptions and
+// SetActiveInputDeviceWithOptions switch devices
+type SetActiveOptions struct {
+	// MoveAllSessions also moves the sessions already open on other devices
+	// to the new default, which otherwise only applies to new streams
+	MoveAllSessions bool
+}
+
+// SetActiveOutputDeviceWithOptions sets the active output device. The
+// sessions to move are listed first, and an error doing so, including
+// ErrNotImplemented from a backend that cannot move sessions, leaves the
+// default unchanged. Only a *MoveError, for the sessions that refused to
+// move, is returned after the device became the default.
+func SetActiveOutputDeviceWithOptions(deviceID string, opts SetActiveOptions) error {
+	b := currentBackend()
+	return setActiveWithOptions(b, deviceID, opts, b.SetActiveOutputDevice)
+}
+
+// SetActiveInputDeviceWithOptions sets the active input device, moving
+// sessions as SetActiveOutputDeviceWithOptions does
+func SetActiveInputDeviceWithOptions(deviceID string, opts SetActiveOptions) error {
+	b := currentBackend()
+	return setActiveWithOptions(b, deviceID, opts, b.SetActiveInputDevice)
+}
+
+func setActiveWithOptions(b Backend, deviceID string, opts SetActiveOptions, set func(string) error) error {
+	if !opts.MoveAllSessions {
+		return set(deviceID)
+	}
+	move, err := prepareMove(b, "", deviceID)
+	if err != nil {
+		return err
+	}
+	if err := set(deviceID); err != nil {
+		return err
+	}
+	return move()
+}
@@ -0,0 +1,88 @@
+package audiocontrol_test
+
+import (
+	"errors"
+	"testing"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+	"github.com/audi70r/go-audio-control/audiotest"
+)
+
+// unlistedBackend fails to list sessions
+type unlistedBackend struct {
+	*audiotest.Backend
+}
+
+func (unlistedBackend) ListSessions(string) ([]audiocontrol.Session, error) {
+	return nil, errors.New("listing sessions failed")
+}
+
+// pinnedBackend refuses to move one session
+type pinnedBackend struct {
+	*audiotest.Backend
+	pinned string
+}
+
+func (b pinnedBackend) MoveSession(sessionID, deviceID string) error {
+	if sessionID == b.pinned {
+		return errors.New("stream cannot be moved")
+	}
+	return b.Backend.MoveSession(sessionID, deviceID)
+}
+
+func newSessionBackend(t *testing.T) *audiotest.Backend {
+	t.Helper()
+	b := newContextBackend(t, 0)
+	for _, session := range []audiocontrol.Session{
+		{ID: "music", AppName: "Spotify", DeviceID: "speakers"},
+		{ID: "alarm", AppName: "Clock", DeviceID: "speakers"},
+	} {
+		if err := b.AddSession(session); err != nil {
+			t.Fatalf("AddSession() failed: %v", err)
+		}
+	}
+	return b
+}
+
+func TestSetActiveWithOptionsKeepsDefaultOnFailure(t *testing.T) {
+	b := newSessionBackend(t)
+	opts := audiocontrol.SetActiveOptions{MoveAllSessions: true}
+
+	for _, tt := range []struct {
+		name    string
+		backend audiocontrol.Backend
+		wantErr error
+	}{
+		{"cannot move", struct{ audiocontrol.Backend }{b}, audiocontrol.ErrNotImplemented},
+		{"list fails", unlistedBackend{b}, nil},
+	} {
+		audiocontrol.SetBackend(tt.backend)
+		err := audiocontrol.SetActiveOutputDeviceWithOptions("headphones", opts)
+		if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+			t.Errorf("%s: SetActiveOutputDeviceWithOptions() = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if active, _ := b.GetActiveOutputDevice(); active.ID != "speakers" {
+			t.Errorf("%s: default changed to %q", tt.name, active.ID)
+		}
+	}
+}
+
+func TestSetActiveWithOptionsReportsPinnedSessions(t *testing.T) {
+	b := newSessionBackend(t)
+	audiocontrol.SetBackend(pinnedBackend{Backend: b, pinned: "alarm"})
+
+	err := audiocontrol.SetActiveOutputDeviceWithOptions("headphones", audiocontrol.SetActiveOptions{MoveAllSessions: true})
+	var moveErr *audiocontrol.MoveError
+	if !errors.As(err, &moveErr) {
+		t.Fatalf("SetActiveOutputDeviceWithOptions() = %v, want a MoveError", err)
+	}
+	if len(moveErr.Failures) != 1 || moveErr.Failures[0].SessionID != "alarm" {
+		t.Errorf("Failures = %+v, want alarm", moveErr.Failures)
+	}
+	if active, _ := b.GetActiveOutputDevice(); active.ID != "headphones" {
+		t.Errorf("Default is %q, want headphones", active.ID)
+	}
+	if sessions, _ := b.ListSessions("headphones"); len(sessions) != 1 || sessions[0].ID != "music" {
+		t.Errorf("Sessions on headphones = %+v, want music", sessions)
+	}
+}
@@ -0,0 +1,227 @@
+package audiocontrol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrSwitchNotVerified is returned by SwitchOutput and SwitchInput when the
+// backend accepted a switch but did not report the device as the default
+// before the timeout. Some macOS aggregate and AirPlay devices refuse to
+// become the default silently, and on Windows the calls for the three
+// endpoint roles can partially succeed.
+var ErrSwitchNotVerified = errors.New("audiocontrol: switch not verified")
+
+// DefaultSwitchTimeout bounds a switch and its verification when
+// SwitchOptions.Timeout is zero
+const DefaultSwitchTimeout = 5 * time.Second
+
+// switchPollInterval is how often the default device is read back while
+// verifying a switch, between the events that trigger a read
+const switchPollInterval = 100 * time.Millisecond
+
+// SwitchOptions controls SwitchOutput and SwitchInput
+type SwitchOptions struct {
+	// Verify waits until the backend reports the device as the default
+	Verify bool
+	// Timeout bounds the switch and its verification, and separately the
+	// rollback
+	Timeout time.Duration
+	// RollbackOnFailure makes the previous default device the default
+	// again when the switch fails or cannot be verified
+	RollbackOnFailure bool
+}
+
+// SwitchResult describes the outcome of SwitchOutput or SwitchInput
+type SwitchResult struct {
+	DeviceID string
+	// Previous is the default device before the switch
+	Previous AudioDevice
+	// Active is the default device as last read back. It is only read when
+	// verifying or after a rollback.
+	Active AudioDevice
+	// Verified reports that Active is the requested device
+	Verified bool
+	// Event reports that an ActiveDeviceChanged event for the device was
+	// received while verifying
+	Event bool
+	// RolledBack reports that the previous device was made the default
+	// again. RollbackErr holds the error if that failed.
+	RolledBack  bool
+	RollbackErr error `json:",omitempty"`
+	// Duration is the time the switch took, without the rollback
+	Duration time.Duration
+}
+
+// SwitchOutput makes deviceID the default output like
+// SetActiveOutputDevice, optionally confirming that the change took effect
+// and restoring the previous device if it did not. The result is filled in
+// as far as the switch got, also when an error is returned.
+func SwitchOutput(ctx context.Context, deviceID string, opts SwitchOptions) (SwitchResult, error) {
+	return switchDefault(ctx, deviceID, opts, true)
+}
+
+// SwitchInput makes deviceID the default input like SetActiveInputDevice,
+// with the verification and rollback of SwitchOutput
+func SwitchInput(ctx context.Context, deviceID string, opts SwitchOptions) (SwitchResult, error) {
+	return switchDefault(ctx, deviceID, opts, false)
+}
+
+func switchDefault(parent context.Context, deviceID string, opts SwitchOptions, output bool) (SwitchResult, error) {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultSwitchTimeout
+	}
+	b, id := currentBackendID()
+	get, set := b.GetActiveInputDevice, b.SetActiveInputDevice
+	if output {
+		get, set = b.GetActiveOutputDevice, b.SetActiveOutputDevice
+	}
+	result := SwitchResult{DeviceID: deviceID}
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	previous, err := withContext(ctx, get)
+	if err != nil {
+		return result, fmt.Errorf("failed to read the default device: %w", err)
+	}
+	result.Previous = previous
+
+	// Watch before switching so the event cannot be missed
+	var w *activeWatcher
+	if opts.Verify {
+		w = watchActive(b, id, deviceID)
+		defer w.stop()
+	}
+	err = withContextErr(ctx, func() error { return set(deviceID) })
+	if err == nil && opts.Verify {
+		err = verifySwitch(ctx, get, w, &result)
+	}
+	result.Duration = time.Since(start)
+	if err == nil || !opts.RollbackOnFailure || previous.ID == deviceID {
+		return result, err
+	}
+
+	// The caller's context may be what ended the switch, so the rollback
+	// gets a deadline of its own
+	ctx, cancel = context.WithTimeout(context.WithoutCancel(parent), timeout)
+	defer cancel()
+	result.RollbackErr = withContextErr(ctx, func() error { return set(previous.ID) })
+	result.RolledBack = result.RollbackErr == nil
+	if active, getErr := withContext(ctx, get); getErr == nil {
+		result.Active = active
+	}
+	return result, err
+}
+
+// verifySwitch reads the default device back until it is the requested one,
+// at every event for the device and every switchPollInterval
+func verifySwitch(ctx context.Context, get func() (AudioDevice, error), w *activeWatcher, result *SwitchResult) error {
+	ticker := time.NewTicker(switchPollInterval)
+	defer ticker.Stop()
+	for {
+		active, err := withContext(ctx, get)
+		if err == nil {
+			result.Active = active
+			if active.ID == result.DeviceID {
+				result.Verified = true
+				result.Event = w.seen()
+				return nil
+			}
+		}
+		select {
+		case <-w.wake:
+		case <-ticker.C:
+		case <-ctx.Done():
+			result.Event = w.seen()
+			if result.Active.ID == "" {
+				return fmt.Errorf("%w: %s: %w", ErrSwitchNotVerified, result.DeviceID, ctx.Err())
+			}
+			return fmt.Errorf("%w: %s is the default instead of %s: %w",
+				ErrSwitchNotVerified, result.Active.ID, result.DeviceID, ctx.Err())
+		}
+	}
+}
+
+// activeWatcher is woken by ActiveDeviceChanged events for a device
+type activeWatcher struct {
+	backendID uint64
+	deviceID  string
+	wake      chan struct{}
+
+	mu    sync.Mutex
+	event bool
+}
+
+// Backends keep their callbacks for good, so each backend set with
+// SetBackend gets a single callback, registered by its first switch, that
+// wakes the watchers of its switches in progress. Backends are known by the
+// ID SetBackend assigns, as they may not be comparable.
+var (
+	watchMutex      sync.Mutex
+	watchedBackends = make(map[uint64]bool)
+	activeWatchers  = make(map[*activeWatcher]bool)
+)
+
+// watchActive returns a watcher for events of b, registered as id, about
+// deviceID
+func watchActive(b Backend, id uint64, deviceID string) *activeWatcher {
+	w := &activeWatcher{backendID: id, deviceID: deviceID, wake: make(chan struct{}, 1)}
+
+	watchMutex.Lock()
+	activeWatchers[w] = true
+	register := !watchedBackends[id]
+	watchedBackends[id] = true
+	watchMutex.Unlock()
+
+	if register {
+		b.OnDeviceChange(func(event Event) {
+			if event.Type == ActiveDeviceChanged {
+				wakeWatchers(id, event.DeviceID)
+			}
+		})
+	}
+	return w
+}
+
+// unwatchBackend forgets the backend registered as id once SetBackend
+// replaced it. Its callback still wakes the switches in progress.
+func unwatchBackend(id uint64) {
+	watchMutex.Lock()
+	delete(watchedBackends, id)
+	watchMutex.Unlock()
+}
+
+func wakeWatchers(id uint64, deviceID string) {
+	watchMutex.Lock()
+	defer watchMutex.Unlock()
+	for w := range activeWatchers {
+		if w.backendID != id || w.deviceID != deviceID {
+			continue
+		}
+		w.mu.Lock()
+		w.event = true
+		w.mu.Unlock()
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (w *activeWatcher) seen() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.event
+}
+
+func (w *activeWatcher) stop() {
+	watchMutex.Lock()
+	delete(activeWatchers, w)
+	watchMutex.Unlock()
+}
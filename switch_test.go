@@ -0,0 +1,138 @@
+package audiocontrol_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+	"github.com/audi70r/go-audio-control/audiotest"
+)
+
+// refusingBackend accepts switching to one device without doing it, as
+// some macOS aggregate and AirPlay devices do
+type refusingBackend struct {
+	*audiotest.Backend
+	refused string
+}
+
+func (b refusingBackend) SetActiveOutputDevice(deviceID string) error {
+	if deviceID == b.refused {
+		return nil
+	}
+	return b.Backend.SetActiveOutputDevice(deviceID)
+}
+
+func TestSwitchOutput(t *testing.T) {
+	newContextBackend(t, 0)
+
+	result, err := audiocontrol.SwitchOutput(context.Background(), "headphones", audiocontrol.SwitchOptions{Verify: true})
+	if err != nil {
+		t.Fatalf("SwitchOutput() failed: %v", err)
+	}
+	if result.Previous.ID != "speakers" || result.Active.ID != "headphones" {
+		t.Errorf("SwitchOutput() switched from %q to %q", result.Previous.ID, result.Active.ID)
+	}
+	if !result.Verified || !result.Event || result.RolledBack {
+		t.Errorf("SwitchOutput() = %+v, want verified by event", result)
+	}
+}
+
+func TestSwitchOutputUnverified(t *testing.T) {
+	b := newContextBackend(t, 0)
+	audiocontrol.SetBackend(refusingBackend{Backend: b, refused: "hdmi"})
+
+	result, err := audiocontrol.SwitchOutput(context.Background(), "hdmi", audiocontrol.SwitchOptions{
+		Verify:  true,
+		Timeout: 250 * time.Millisecond,
+	})
+	if !errors.Is(err, audiocontrol.ErrSwitchNotVerified) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("SwitchOutput() = %v, want ErrSwitchNotVerified", err)
+	}
+	if result.Verified || result.Event || result.RolledBack || result.Active.ID != "speakers" {
+		t.Errorf("SwitchOutput() = %+v, want unverified on speakers", result)
+	}
+	if result.Duration < 250*time.Millisecond {
+		t.Errorf("SwitchOutput() gave up after %v", result.Duration)
+	}
+}
+
+func TestSwitchOutputRollback(t *testing.T) {
+	b := newContextBackend(t, 0)
+	if err := b.SetActiveOutputDevice("headphones"); err != nil {
+		t.Fatalf("SetActiveOutputDevice() failed: %v", err)
+	}
+	audiocontrol.SetBackend(refusingBackend{Backend: b, refused: "hdmi"})
+
+	// The switch is canceled by the caller, yet still rolled back
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	result, err := audiocontrol.SwitchOutput(ctx, "hdmi", audiocontrol.SwitchOptions{
+		Verify:            true,
+		RollbackOnFailure: true,
+	})
+	if !errors.Is(err, audiocontrol.ErrSwitchNotVerified) {
+		t.Fatalf("SwitchOutput() = %v, want ErrSwitchNotVerified", err)
+	}
+	if !result.RolledBack || result.RollbackErr != nil || result.Active.ID != "headphones" {
+		t.Errorf("SwitchOutput() = %+v, want rolled back to headphones", result)
+	}
+}
+
+func TestSwitchOutputFailure(t *testing.T) {
+	b := newContextBackend(t, 0)
+
+	result, err := audiocontrol.SwitchOutput(context.Background(), "missing", audiocontrol.SwitchOptions{
+		Verify:            true,
+		RollbackOnFailure: true,
+	})
+	if !errors.Is(err, audiocontrol.ErrDeviceNotFound) {
+		t.Fatalf("SwitchOutput() = %v, want ErrDeviceNotFound", err)
+	}
+	if !result.RolledBack || result.Previous.ID != "speakers" || result.Active.ID != "speakers" {
+		t.Errorf("SwitchOutput() = %+v, want rolled back to speakers", result)
+	}
+	if d, _ := b.GetActiveOutputDevice(); d.ID != "speakers" {
+		t.Errorf("Active output = %q, want speakers", d.ID)
+	}
+}
+
+func TestSwitchInputWithoutVerify(t *testing.T) {
+	b := audiotest.NewBackend(
+		audiocontrol.AudioDevice{ID: "mic", Name: "Microphone", IsInput: true, IsActive: true},
+		audiocontrol.AudioDevice{ID: "headset-mic", Name: "Headset Microphone", IsInput: true},
+	)
+	audiocontrol.SetBackend(b)
+	t.Cleanup(func() { audiocontrol.SetBackend(nil) })
+
+	result, err := audiocontrol.SwitchInput(context.Background(), "headset-mic", audiocontrol.SwitchOptions{})
+	if err != nil {
+		t.Fatalf("SwitchInput() failed: %v", err)
+	}
+	if result.Previous.ID != "mic" || result.Verified || result.Active.ID != "" {
+		t.Errorf("SwitchInput() = %+v, want an unverified switch from mic", result)
+	}
+	if d, _ := b.GetActiveInputDevice(); d.ID != "headset-mic" {
+		t.Errorf("Active input = %q, want headset-mic", d.ID)
+	}
+}
+
+// taggedBackend cannot be compared, and neither can an interface holding it
+type taggedBackend struct {
+	*audiotest.Backend
+	tags []string
+}
+
+func TestSwitchOutputUncomparableBackend(t *testing.T) {
+	b := newContextBackend(t, 0)
+	audiocontrol.SetBackend(struct{ audiocontrol.Backend }{taggedBackend{Backend: b, tags: []string{"test"}}})
+
+	result, err := audiocontrol.SwitchOutput(context.Background(), "headphones", audiocontrol.SwitchOptions{Verify: true})
+	if err != nil {
+		t.Fatalf("SwitchOutput() failed: %v", err)
+	}
+	if !result.Verified || !result.Event {
+		t.Errorf("SwitchOutput() = %+v, want verified by event", result)
+	}
+}
@@ -0,0 +1,226 @@
+package trace
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+)
+
+// Call is a state-changing call made on a Player
+type Call struct {
+	// Method is the Backend method name, e.g. "SetActiveOutputDevice"
+	Method   string
+	DeviceID string
+	// Value is the volume or mute argument, nil for the other methods
+	Value interface{}
+}
+
+// Player is an audiocontrol.Backend that replays a trace.
+//
+// Nothing happens on its own: each call to Step applies the next record and
+// delivers its event synchronously to the OnDeviceChange callbacks, so a
+// replay is deterministic regardless of scheduling. Queries answer from the
+// snapshot of the last applied record, or the header before the first Step.
+// Set calls do not change the replayed state; they are recorded and can be
+// inspected with Calls.
+type Player struct {
+	trace *Trace
+
+	mu        sync.Mutex
+	next      int
+	snapshot  Snapshot
+	callbacks []func(audiocontrol.Event)
+	calls     []Call
+}
+
+// NewPlayer returns a Player positioned at the start of t
+func NewPlayer(t *Trace) *Player {
+	return &Player{trace: t, snapshot: t.Header.Snapshot}
+}
+
+// Step applies the next record and delivers its event. It returns false when
+// the trace is exhausted.
+func (p *Player) Step() bool {
+	p.mu.Lock()
+	if p.next >= len(p.trace.Records) {
+		p.mu.Unlock()
+		return false
+	}
+	record := p.trace.Records[p.next]
+	p.next++
+	p.snapshot = record.Snapshot
+	callbacks := append([]func(audiocontrol.Event){}, p.callbacks...)
+	p.mu.Unlock()
+
+	for _, callback := range callbacks {
+		callback(record.Event())
+	}
+	return true
+}
+
+// Run applies every remaining record
+func (p *Player) Run() {
+	for p.Step() {
+	}
+}
+
+// RunUntil applies the records with an offset up to and including offset
+func (p *Player) RunUntil(offset time.Duration) {
+	for {
+		p.mu.Lock()
+		done := p.next >= len(p.trace.Records) || p.trace.Records[p.next].Offset > offset
+		p.mu.Unlock()
+		if done || !p.Step() {
+			return
+		}
+	}
+}
+
+// Position returns the number of records applied so far
+func (p *Player) Position() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.next
+}
+
+// Calls returns the state-changing calls made so far, in order
+func (p *Player) Calls() []Call {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Call(nil), p.calls...)
+}
+
+func (p *Player) addCall(call Call) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, call)
+	if p.snapshot.Error != "" {
+		return errors.New(p.snapshot.Error)
+	}
+	if indexOf(p.snapshot.Devices, call.DeviceID) < 0 {
+		return fmt.Errorf("%w: %s", audiocontrol.ErrDeviceNotFound, call.DeviceID)
+	}
+	return nil
+}
+
+func indexOf(devices []audiocontrol.AudioDevice, id string) int {
+	for i, d := range devices {
+		if d.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// ListAudioDevices returns the devices as of the current position
+func (p *Player) ListAudioDevices() ([]audiocontrol.AudioDevice, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.snapshot.Error != "" {
+		return nil, errors.New(p.snapshot.Error)
+	}
+	return append([]audiocontrol.AudioDevice{}, p.snapshot.Devices...), nil
+}
+
+func (p *Player) active(output bool) (audiocontrol.AudioDevice, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.snapshot.Error != "" {
+		return audiocontrol.AudioDevice{}, errors.New(p.snapshot.Error)
+	}
+	// Traces recorded before IsDefaultOutput and IsDefaultInput existed
+	// only mark the defaults with IsActive
+	legacy := true
+	for _, d := range p.snapshot.Devices {
+		if d.IsDefaultOutput || d.IsDefaultInput {
+			legacy = false
+			break
+		}
+	}
+	for _, d := range p.snapshot.Devices {
+		var isDefault bool
+		switch {
+		case legacy:
+			isDefault = d.IsActive && ((output && d.IsOutput) || (!output && d.IsInput))
+		case output:
+			isDefault = d.IsOutput && d.IsDefaultOutput
+		default:
+			isDefault = d.IsInput && d.IsDefaultInput
+		}
+		if isDefault {
+			return d, nil
+		}
+	}
+	if output {
+		return audiocontrol.AudioDevice{}, fmt.Errorf("no default output device found")
+	}
+	return audiocontrol.AudioDevice{}, fmt.Errorf("no default input device found")
+}
+
+// GetActiveOutputDevice returns the active output as of the current position
+func (p *Player) GetActiveOutputDevice() (audiocontrol.AudioDevice, error) {
+	return p.active(true)
+}
+
+// SetActiveOutputDevice records the call
+func (p *Player) SetActiveOutputDevice(deviceID string) error {
+	return p.addCall(Call{Method: "SetActiveOutputDevice", DeviceID: deviceID})
+}
+
+// GetActiveInputDevice returns the active input as of the current position
+func (p *Player) GetActiveInputDevice() (audiocontrol.AudioDevice, error) {
+	return p.active(false)
+}
+
+// SetActiveInputDevice records the call
+func (p *Player) SetActiveInputDevice(deviceID string) error {
+	return p.addCall(Call{Method: "SetActiveInputDevice", DeviceID: deviceID})
+}
+
+// GetVolume returns the recorded volume of a device
+func (p *Player) GetVolume(deviceID string) (float64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if indexOf(p.snapshot.Devices, deviceID) < 0 {
+		return 0, fmt.Errorf("%w: %s", audiocontrol.ErrDeviceNotFound, deviceID)
+	}
+	volume, ok := p.snapshot.Volumes[deviceID]
+	if !ok {
+		return 0, audiocontrol.ErrNotImplemented
+	}
+	return volume, nil
+}
+
+// SetVolume records the call
+func (p *Player) SetVolume(deviceID string, volume float64) error {
+	return p.addCall(Call{Method: "SetVolume", DeviceID: deviceID, Value: volume})
+}
+
+// GetMute returns the recorded mute state of a device
+func (p *Player) GetMute(deviceID string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if indexOf(p.snapshot.Devices, deviceID) < 0 {
+		return false, fmt.Errorf("%w: %s", audiocontrol.ErrDeviceNotFound, deviceID)
+	}
+	muted, ok := p.snapshot.Mutes[deviceID]
+	if !ok {
+		return false, audiocontrol.ErrNotImplemented
+	}
+	return muted, nil
+}
+
+// SetMute records the call
+func (p *Player) SetMute(deviceID string, muted bool) error {
+	return p.addCall(Call{Method: "SetMute", DeviceID: deviceID, Value: muted})
+}
+
+// OnDeviceChange registers a callback for replayed events
+func (p *Player) OnDeviceChange(callback func(audiocontrol.Event)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.callbacks = append(p.callbacks, callback)
+}
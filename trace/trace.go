@@ -0,0 +1,265 @@
+// Package trace records the notifications of an audiocontrol.Backend to a
+// file and replays them deterministically.
+//
+// A trace is a JSON-lines file. The first line is a Header holding the
+// device state when recording started; every following line is a Record
+// holding one event exactly as the backend delivered it, the time since the
+// start, and the device state observed while handling it:
+//
+//	{"Version":1,"Started":"2024-05-01T09:00:00Z","Snapshot":{"Devices":[...]}}
+//	{"Offset":"1.25s","Type":"DeviceAdded","DeviceID":"bluez_sink...","Snapshot":{...}}
+//
+// Record a live session with a Recorder (or audioctl record), then load the
+// file with ReadFile and drive the code under test with a Player. Traces
+// taken from field bug reports can be checked into testdata and replayed on
+// any platform.
+package trace
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+)
+
+// Version is the trace format version written by Recorder
+const Version = 1
+
+// Snapshot is the device state of a backend at one point in time
+type Snapshot struct {
+	Devices []audiocontrol.AudioDevice
+	Volumes map[string]float64 `json:",omitempty"`
+	Mutes   map[string]bool    `json:",omitempty"`
+	// Error is set when listing the devices failed
+	Error string `json:",omitempty"`
+}
+
+// Header is the first line of a trace
+type Header struct {
+	Version  int
+	Started  time.Time
+	Snapshot Snapshot
+}
+
+// Record is one notification in a trace
+type Record struct {
+	// Offset is the time since the start of the recording
+	Offset   time.Duration
+	Type     audiocontrol.EventType
+	DeviceID string
+	Info     *audiocontrol.AudioDevice
+	Card     *audiocontrol.Card
+	Session  *audiocontrol.Session
+	// Snapshot is the state observed while the event was delivered
+	Snapshot Snapshot
+}
+
+// Event returns the event carried by r
+func (r Record) Event() audiocontrol.Event {
+	return audiocontrol.Event{Type: r.Type, DeviceID: r.DeviceID, Info: r.Info, Card: r.Card, Session: r.Session}
+}
+
+// recordJSON is the on-disk form of Record, with the offset and event type
+// stored as text so that traces can be edited by hand
+type recordJSON struct {
+	Offset   string
+	Type     string
+	DeviceID string
+	Info     *audiocontrol.AudioDevice `json:",omitempty"`
+	Card     *audiocontrol.Card        `json:",omitempty"`
+	Session  *audiocontrol.Session     `json:",omitempty"`
+	Snapshot Snapshot
+}
+
+// MarshalJSON implements json.Marshaler
+func (r Record) MarshalJSON() ([]byte, error) {
+	return json.Marshal(recordJSON{
+		Offset:   r.Offset.String(),
+		Type:     r.Type.String(),
+		DeviceID: r.DeviceID,
+		Info:     r.Info,
+		Card:     r.Card,
+		Session:  r.Session,
+		Snapshot: r.Snapshot,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (r *Record) UnmarshalJSON(data []byte) error {
+	var raw recordJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	offset, err := time.ParseDuration(raw.Offset)
+	if err != nil {
+		return fmt.Errorf("invalid offset: %w", err)
+	}
+	t, err := audiocontrol.ParseEventType(raw.Type)
+	if err != nil {
+		return err
+	}
+	*r = Record{
+		Offset:   offset,
+		Type:     t,
+		DeviceID: raw.DeviceID,
+		Info:     raw.Info,
+		Card:     raw.Card,
+		Session:  raw.Session,
+		Snapshot: raw.Snapshot,
+	}
+	return nil
+}
+
+// Trace is a loaded trace file
+type Trace struct {
+	Header  Header
+	Records []Record
+}
+
+// Read parses a trace
+func Read(r io.Reader) (*Trace, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+
+	trace := &Trace{}
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := scanner.Bytes()
+		if len(data) == 0 {
+			continue
+		}
+		if line == 1 {
+			if err := json.Unmarshal(data, &trace.Header); err != nil {
+				return nil, fmt.Errorf("failed to parse trace header: %w", err)
+			}
+			if trace.Header.Version != Version {
+				return nil, fmt.Errorf("unsupported trace version %d", trace.Header.Version)
+			}
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, fmt.Errorf("failed to parse trace line %d: %w", line, err)
+		}
+		trace.Records = append(trace.Records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if line == 0 {
+		return nil, errors.New("empty trace")
+	}
+	return trace, nil
+}
+
+// ReadFile parses the trace file at path
+func ReadFile(path string) (*Trace, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Read(file)
+}
+
+// Recorder writes the notifications of a backend to a trace
+type Recorder struct {
+	backend audiocontrol.Backend
+
+	mu      sync.Mutex
+	enc     *json.Encoder
+	started time.Time
+	stopped bool
+	err     error
+	now     func() time.Time
+}
+
+// NewRecorder writes the trace header for backend to w and records every
+// notification until Stop. Writes to w are serialised.
+func NewRecorder(backend audiocontrol.Backend, w io.Writer) (*Recorder, error) {
+	r := &Recorder{
+		backend: backend,
+		enc:     json.NewEncoder(w),
+		now:     time.Now,
+	}
+	r.started = r.now()
+
+	header := Header{Version: Version, Started: r.started.UTC(), Snapshot: r.snapshot()}
+	if header.Snapshot.Error != "" {
+		return nil, fmt.Errorf("failed to list devices: %s", header.Snapshot.Error)
+	}
+	if err := r.enc.Encode(header); err != nil {
+		return nil, fmt.Errorf("failed to write trace header: %w", err)
+	}
+
+	backend.OnDeviceChange(r.record)
+	return r, nil
+}
+
+// snapshot reads the device list, volumes and mute states of the backend
+func (r *Recorder) snapshot() Snapshot {
+	devices, err := r.backend.ListAudioDevices()
+	if err != nil {
+		return Snapshot{Error: err.Error()}
+	}
+
+	snapshot := Snapshot{Devices: devices}
+	for _, d := range devices {
+		if volume, err := r.backend.GetVolume(d.ID); err == nil {
+			if snapshot.Volumes == nil {
+				snapshot.Volumes = make(map[string]float64)
+			}
+			snapshot.Volumes[d.ID] = volume
+		}
+		if muted, err := r.backend.GetMute(d.ID); err == nil {
+			if snapshot.Mutes == nil {
+				snapshot.Mutes = make(map[string]bool)
+			}
+			snapshot.Mutes[d.ID] = muted
+		}
+	}
+	return snapshot
+}
+
+func (r *Recorder) record(event audiocontrol.Event) {
+	r.mu.Lock()
+	stopped := r.stopped
+	r.mu.Unlock()
+	if stopped {
+		return
+	}
+
+	record := Record{
+		Offset:   r.now().Sub(r.started),
+		Type:     event.Type,
+		DeviceID: event.DeviceID,
+		Info:     event.Info,
+		Card:     event.Card,
+		Session:  event.Session,
+		Snapshot: r.snapshot(),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped || r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(record)
+}
+
+// Stop ends the recording. Notifications delivered after Stop are ignored.
+// It returns the first error encountered while writing.
+func (r *Recorder) Stop() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopped = true
+	return r.err
+}
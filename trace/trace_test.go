@@ -0,0 +1,202 @@
+package trace
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	audiocontrol "github.com/audi70r/go-audio-control"
+	"github.com/audi70r/go-audio-control/audiotest"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	backend := audiotest.NewBackend(
+		audiocontrol.AudioDevice{ID: "speakers", Name: "Speakers", IsOutput: true, IsActive: true, IsConnected: true},
+	)
+
+	var buf bytes.Buffer
+	recorder, err := NewRecorder(backend, &buf)
+	if err != nil {
+		t.Fatalf("Failed to start recorder: %v", err)
+	}
+
+	// Capture what a live consumer observes so the replay can be compared
+	type observation struct {
+		event   audiocontrol.Event
+		devices []audiocontrol.AudioDevice
+	}
+	var live []observation
+	backend.OnDeviceChange(func(event audiocontrol.Event) {
+		devices, _ := backend.ListAudioDevices()
+		live = append(live, observation{event, devices})
+	})
+
+	backend.AddDevice(audiocontrol.AudioDevice{ID: "usb", Name: "USB Headset", IsOutput: true, IsConnected: true})
+	backend.SetVolume("usb", 0.4)
+	backend.SetActiveOutputDevice("usb")
+	backend.RemoveDevice("usb")
+	if err := recorder.Stop(); err != nil {
+		t.Fatalf("Failed to record: %v", err)
+	}
+	backend.AddDevice(audiocontrol.AudioDevice{ID: "ignored", Name: "After Stop", IsOutput: true})
+
+	tr, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Failed to read trace: %v", err)
+	}
+	if len(tr.Records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(tr.Records))
+	}
+	if len(tr.Header.Snapshot.Devices) != 1 || tr.Header.Snapshot.Volumes["speakers"] != 1.0 {
+		t.Fatalf("Unexpected header snapshot %+v", tr.Header.Snapshot)
+	}
+
+	player := NewPlayer(tr)
+	var replayed []observation
+	player.OnDeviceChange(func(event audiocontrol.Event) {
+		devices, _ := player.ListAudioDevices()
+		replayed = append(replayed, observation{event, devices})
+	})
+	player.Run()
+
+	if !reflect.DeepEqual(live[:3], replayed) {
+		t.Fatalf("Replay differs from live session:\nlive:     %+v\nreplayed: %+v", live[:3], replayed)
+	}
+}
+
+func TestRecordVolumes(t *testing.T) {
+	backend := audiotest.NewBackend(
+		audiocontrol.AudioDevice{ID: "speakers", Name: "Speakers", IsOutput: true, IsActive: true, IsConnected: true},
+	)
+	var buf bytes.Buffer
+	recorder, err := NewRecorder(backend, &buf)
+	if err != nil {
+		t.Fatalf("Failed to start recorder: %v", err)
+	}
+	backend.SetMute("speakers", true)
+	backend.Emit(audiocontrol.Event{Type: audiocontrol.ActiveDeviceChanged, DeviceID: "speakers"})
+	recorder.Stop()
+
+	tr, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Failed to read trace: %v", err)
+	}
+	player := NewPlayer(tr)
+	if muted, _ := player.GetMute("speakers"); muted {
+		t.Fatal("Expected speakers to be unmuted at the start")
+	}
+	player.Step()
+	if muted, _ := player.GetMute("speakers"); !muted {
+		t.Fatal("Expected speakers to be muted after the first record")
+	}
+}
+
+// followHeadset is a minimal switching policy: make any newly added output
+// whose name mentions the headset the default
+func followHeadset(backend audiocontrol.Backend) {
+	backend.OnDeviceChange(func(event audiocontrol.Event) {
+		if event.Type != audiocontrol.DeviceAdded || event.Info == nil || !event.Info.IsOutput {
+			return
+		}
+		if strings.Contains(event.Info.Name, "WH-1000XM4") {
+			backend.SetActiveOutputDevice(event.DeviceID)
+		}
+	})
+}
+
+func TestReplayBluetoothProfileFlap(t *testing.T) {
+	tr, err := ReadFile("testdata/bluetooth_profile_flap.jsonl")
+	if err != nil {
+		t.Fatalf("Failed to read trace: %v", err)
+	}
+
+	player := NewPlayer(tr)
+	followHeadset(player)
+
+	output, err := player.GetActiveOutputDevice()
+	if err != nil || !strings.HasSuffix(output.ID, "a2dp_sink") {
+		t.Fatalf("Expected the A2DP sink to start active, got %+v (%v)", output, err)
+	}
+
+	// During the call the headset is only present in HFP mode
+	player.RunUntil(2 * time.Second)
+	if player.Position() != 4 {
+		t.Fatalf("Expected 4 records before 2s, got %d", player.Position())
+	}
+	devices, _ := player.ListAudioDevices()
+	for _, d := range devices {
+		if strings.HasSuffix(d.ID, "a2dp_sink") {
+			t.Fatal("A2DP sink should be gone during the call")
+		}
+	}
+
+	player.Run()
+
+	var switched []string
+	for _, call := range player.Calls() {
+		if call.Method != "SetActiveOutputDevice" {
+			t.Fatalf("Unexpected call %+v", call)
+		}
+		switched = append(switched, call.DeviceID[strings.LastIndex(call.DeviceID, ".")+1:])
+	}
+	want := []string{"handsfree_head_unit", "a2dp_sink", "handsfree_head_unit", "a2dp_sink"}
+	if !reflect.DeepEqual(switched, want) {
+		t.Fatalf("Expected switches %v, got %v", want, switched)
+	}
+}
+
+func TestReplayIsDeterministic(t *testing.T) {
+	run := func() []Call {
+		tr, err := ReadFile("testdata/bluetooth_profile_flap.jsonl")
+		if err != nil {
+			t.Fatalf("Failed to read trace: %v", err)
+		}
+		player := NewPlayer(tr)
+		followHeadset(player)
+		player.Run()
+		return player.Calls()
+	}
+
+	first := run()
+	for i := 0; i < 5; i++ {
+		if got := run(); !reflect.DeepEqual(first, got) {
+			t.Fatalf("Replay %d differs: %v vs %v", i, first, got)
+		}
+	}
+}
+
+func TestReplayDuplexDefaults(t *testing.T) {
+	// The headset is the default input only, but IsActive is set for it as
+	// for any default
+	headset := audiocontrol.AudioDevice{ID: "headset", IsOutput: true, IsInput: true, IsActive: true, IsDefaultInput: true}
+	speakers := audiocontrol.AudioDevice{ID: "speakers", IsOutput: true, IsActive: true, IsDefaultOutput: true}
+	player := NewPlayer(&Trace{Header: Header{Version: Version, Snapshot: Snapshot{
+		Devices: []audiocontrol.AudioDevice{headset, speakers},
+	}}})
+	if d, err := player.GetActiveOutputDevice(); err != nil || d.ID != "speakers" {
+		t.Errorf("GetActiveOutputDevice() = %q, %v, want speakers", d.ID, err)
+	}
+	if d, err := player.GetActiveInputDevice(); err != nil || d.ID != "headset" {
+		t.Errorf("GetActiveInputDevice() = %q, %v, want headset", d.ID, err)
+	}
+
+	// Traces recorded before the per-direction fields fall back to IsActive
+	player = NewPlayer(&Trace{Header: Header{Version: Version, Snapshot: Snapshot{
+		Devices: []audiocontrol.AudioDevice{
+			{ID: "mic", IsInput: true},
+			{ID: "headset", IsOutput: true, IsInput: true, IsActive: true},
+		},
+	}}})
+	if d, err := player.GetActiveInputDevice(); err != nil || d.ID != "headset" {
+		t.Errorf("GetActiveInputDevice() = %q, %v for a legacy trace, want headset", d.ID, err)
+	}
+}
+
+func TestReadRejectsUnknownVersion(t *testing.T) {
+	_, err := Read(strings.NewReader(`{"Version":99,"Snapshot":{}}` + "\n"))
+	if err == nil {
+		t.Fatal("Expected an error for an unknown trace version")
+	}
+}
@@ -0,0 +1,94 @@
+package audiocontrol
+
+import "fmt"
+
+// VirtualDeviceKind selects what CreateVirtualDevice creates
+type VirtualDeviceKind int
+
+const (
+	// VirtualNullSink is an output that discards its audio. Its monitor
+	// records everything played to it, which captures application audio.
+	VirtualNullSink VirtualDeviceKind = iota
+	// VirtualLoopback plays an input to an output, such as a microphone to
+	// the speakers. It creates no device.
+	VirtualLoopback
+	// VirtualCombinedSink is an output that plays to several outputs at once
+	VirtualCombinedSink
+)
+
+// String returns the name of the kind
+func (k VirtualDeviceKind) String() string {
+	switch k {
+	case VirtualNullSink:
+		return "NullSink"
+	case VirtualLoopback:
+		return "Loopback"
+	case VirtualCombinedSink:
+		return "CombinedSink"
+	}
+	return fmt.Sprintf("VirtualDeviceKind(%d)", int(k))
+}
+
+// MarshalText encodes the kind by name
+func (k VirtualDeviceKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText decodes a kind name
+func (k *VirtualDeviceKind) UnmarshalText(text []byte) error {
+	for kind := VirtualNullSink; kind <= VirtualCombinedSink; kind++ {
+		if kind.String() == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown virtual device kind %q", text)
+}
+
+// VirtualDeviceSpec describes a virtual device
+type VirtualDeviceSpec struct {
+	Kind VirtualDeviceKind
+	// Name is the device ID of a null or combined sink
+	Name        string
+	Description string
+	// Source and Sink are the input and output of a loopback. Empty IDs
+	// select the active devices.
+	Source string
+	Sink   string
+	// Outputs are the devices a combined sink plays to
+	Outputs []string
+}
+
+// VirtualDevice is a device created by CreateVirtualDevice. Close removes
+// it; closing twice is harmless.
+type VirtualDevice interface {
+	// DeviceID returns the ID of the new device, or "" for a loopback
+	DeviceID() string
+	Close() error
+}
+
+// VirtualDeviceCreator is implemented by backends that can create virtual
+// devices. Devices belong to the process that created them.
+type VirtualDeviceCreator interface {
+	CreateVirtualDevice(spec VirtualDeviceSpec) (VirtualDevice, error)
+	CloseVirtualDevices() error
+}
+
+// CreateVirtualDevice creates a virtual device. The device stays until it
+// is closed, so call CloseVirtualDevices before the process exits. On Linux
+// devices left behind by a process that crashed are removed the next time
+// a device is created.
+func CreateVirtualDevice(spec VirtualDeviceSpec) (VirtualDevice, error) {
+	if c, ok := currentBackend().(VirtualDeviceCreator); ok {
+		return c.CreateVirtualDevice(spec)
+	}
+	return nil, ErrNotImplemented
+}
+
+// CloseVirtualDevices removes every virtual device this process created
+func CloseVirtualDevices() error {
+	if c, ok := currentBackend().(VirtualDeviceCreator); ok {
+		return c.CloseVirtualDevices()
+	}
+	return ErrNotImplemented
+}